@@ -0,0 +1,82 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/techschool/simple-bank/apperror"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+)
+
+// sseKeepAliveInterval is how often an idle stream sends a comment, so proxies don't close it
+const sseKeepAliveInterval = 15 * time.Second
+
+// streamAccountEvents streams the balance changes of an account to its owner as server-sent events
+// the first event is a "snapshot" of the account, followed by one "balance_changed" event per new entry
+func (server *Server) streamAccountEvents(ctx *gin.Context) {
+	var req getAccountRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		abortWithBindingError(ctx, err)
+		return
+	}
+
+	// the account is checked before subscribing, an unknown id or someone else's account must not hold a
+	// subscription of the bus
+	account, err := server.store.GetAccount(ctx, req.ID)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+	if account.Owner != authorizationPayload(ctx).Owner {
+		abortWithError(ctx, apperror.New(apperror.CodePermissionDenied, "account doesn't belong to the authenticated user"))
+		return
+	}
+
+	// subscribe before reading the snapshot, so that no change can slip in between the snapshot and the stream
+	// the snapshot comes from the primary, a lagging replica would miss changes already notified
+	sub := server.bus.Subscribe(req.ID)
+	defer sub.Close()
+
+	account, err = server.store.GetAccount(db.WithReadYourWrites(ctx), req.ID)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	// the stream outlives the write timeout of the server, which is meant for regular requests
+	err = http.NewResponseController(ctx.Writer).SetWriteDeadline(time.Time{})
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("X-Accel-Buffering", "no") // disable response buffering in nginx
+	ctx.SSEvent("snapshot", account)
+	ctx.Writer.Flush() // Stream only flushes after its callback returns, which may take a while
+
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	// Stream flushes after every call and stops when the callback returns false or the client goes away
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Request.Context().Done():
+			return false
+		case <-server.shuttingDown:
+			return false
+		case event, ok := <-sub.Events():
+			if !ok {
+				return false // the stream fell behind, the client reconnects and gets a fresh snapshot
+			}
+			ctx.SSEvent("balance_changed", event)
+			return true
+		case <-keepAlive.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		}
+	})
+}
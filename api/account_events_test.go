@@ -0,0 +1,174 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/techschool/simple-bank/db2/mock"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/events"
+	"github.com/techschool/simple-bank/health"
+	"github.com/techschool/simple-bank/utils"
+	"go.uber.org/mock/gomock"
+)
+
+func TestStreamAccountEventsAPI(t *testing.T) {
+	account := randomAccount()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	// once before subscribing, once for the snapshot
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(2).Return(account, nil)
+
+	server := newTestServer(t, store)
+	// a real HTTP server, so the stream can be read while the handler is still writing it
+	httpServer := httptest.NewServer(server.router)
+	defer httpServer.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	url := fmt.Sprintf("%s/accounts/%d/events", httpServer.URL, account.ID)
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	require.NoError(t, err)
+	addAuthorization(t, request, account.Owner, time.Minute)
+
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.Contains(t, response.Header.Get("Content-Type"), "text/event-stream")
+
+	reader := bufio.NewReader(response.Body)
+	readEvent := func() (string, string) {
+		var name, data string
+		for {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+			line = strings.TrimRight(line, "\n")
+			switch {
+			case strings.HasPrefix(line, "event:"):
+				name = strings.TrimPrefix(line, "event:")
+			case strings.HasPrefix(line, "data:"):
+				data = strings.TrimPrefix(line, "data:")
+			case line == "" && name != "":
+				return name, data
+			}
+		}
+	}
+
+	// the snapshot is sent after subscribing, so the change published next can't be missed
+	name, data := readEvent()
+	require.Equal(t, "snapshot", name)
+	require.Contains(t, data, fmt.Sprintf(`"id":%d`, account.ID))
+
+	server.bus.Publish(db.BalanceChangedEvent{AccountID: account.ID, EntryID: 99, Amount: 7, Balance: account.Balance + 7})
+
+	name, data = readEvent()
+	require.Equal(t, "balance_changed", name)
+	require.Contains(t, data, `"entry_id":99`)
+
+	// a server that is shutting down ends the stream instead of waiting for the client to go away
+	server.closeStreams()
+	_, err = io.ReadAll(reader)
+	require.NoError(t, err)
+}
+
+func TestStreamAccountEventsNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, pgx.ErrNoRows)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, "/accounts/1/events", nil)
+	require.NoError(t, err)
+	addAuthorization(t, request, utils.RandomOwner(), time.Minute)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestStreamAccountEventsOtherOwner(t *testing.T) {
+	account := randomAccount()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// the account is read once, the stream never subscribes
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/accounts/%d/events", account.ID), nil)
+	require.NoError(t, err)
+	addAuthorization(t, request, "not_"+account.Owner, time.Minute)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+}
+
+func TestStreamAccountEventsUnauthorized(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// the token is checked before the account, which isn't read at all
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+
+	server := newTestServer(t, store)
+
+	// the admin token isn't an access token, the admin has no account to watch
+	for _, header := range []string{"", "Bearer wrong-token", "Bearer " + testAdminToken} {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, "/accounts/1/events", nil)
+		require.NoError(t, err)
+		if header != "" {
+			request.Header.Set(authorizationHeaderKey, header)
+		}
+
+		server.router.ServeHTTP(recorder, request)
+		require.Equal(t, http.StatusUnauthorized, recorder.Code, header)
+	}
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/accounts/1/events", nil)
+	require.NoError(t, err)
+	addAuthorization(t, request, utils.RandomOwner(), -time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "token has expired")
+}
+
+func TestStreamAccountEventsDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// without a key no access token can be verified, the stream is refused to everyone
+	server, err := NewServer(utils.Config{}, mockdb.NewMockStore(ctrl), events.NewBus(), health.NewChecker(time.Second), nil)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/accounts/1/events", nil)
+	require.NoError(t, err)
+	addAuthorization(t, request, utils.RandomOwner(), time.Minute)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+}
@@ -13,7 +13,7 @@ import (
 	"io"
 	"bytes"
 	"encoding/json"
-	"database/sql"
+	"github.com/jackc/pgx/v5"
 )
 func TestGetAccountAPI(t *testing.T) {
 	account := randomAccount()
@@ -50,7 +50,7 @@ func TestGetAccountAPI(t *testing.T) {
 				store.EXPECT().
 				GetAccount(gomock.Any(), gomock.Eq(account.ID)).
 				Times(1).
-				Return(db.Account{}, sql.ErrNoRows)
+				Return(db.Account{}, pgx.ErrNoRows)
 				// the return of this stubs should match the return type of the GetAccount method
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -66,7 +66,7 @@ func TestGetAccountAPI(t *testing.T) {
 				store.EXPECT().
 				GetAccount(gomock.Any(), gomock.Eq(account.ID)).
 				Times(1).
-				Return(db.Account{}, sql.ErrConnDone)
+				Return(db.Account{}, pgx.ErrTxClosed)
 				// the return of this stubs should match the return type of the GetAccount method
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -101,7 +101,7 @@ func TestGetAccountAPI(t *testing.T) {
 			tc.buildStubs(store)
 			
 			// start test server and send request
-			server := NewServer(store)
+			server := newTestServer(t, store)
 			recorder := httptest.NewRecorder()
 	
 			url := fmt.Sprintf("/accounts/%d", tc.accountID) // there was a bug where using top defined account.ID, should actuall use  the account
@@ -123,6 +123,7 @@ func randomAccount() db.Account {
 		Owner:  utils.RandomOwner(),
 		Balance: utils.RandomMoney(),
 		Currency: utils.RandomCurrency(),
+		Status:   db.AccountStatusActive,
 	}
 }
 
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+)
+
+// every filter is optional, an empty value means "don't filter on it"
+type listAuditEventsRequest struct {
+	Actor        string `form:"actor"`
+	Action       string `form:"action"`
+	ResourceType string `form:"resource_type"`
+	ResourceID   string `form:"resource_id"`
+	PageID       int32  `form:"page_id" binding:"required,min=1"`
+	PageSize     int32  `form:"page_size" binding:"required,min=5,max=100"`
+}
+
+// listAuditEvents returns the audit log, newest event first
+func (server *Server) listAuditEvents(ctx *gin.Context) {
+	var req listAuditEventsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		abortWithBindingError(ctx, err)
+		return
+	}
+
+	arg := db.ListAuditEventsParams{
+		Actor:        optionalString(req.Actor),
+		Action:       optionalString(req.Action),
+		ResourceType: optionalString(req.ResourceType),
+		ResourceID:   optionalString(req.ResourceID),
+		Limit:        req.PageSize,
+		Offset:       (req.PageID - 1) * req.PageSize,
+	}
+
+	events, err := server.store.ListAuditEvents(ctx, arg)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, events)
+}
+
+// optionalString turns an empty query parameter into a NULL filter
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
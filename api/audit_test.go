@@ -0,0 +1,151 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/techschool/simple-bank/db2/mock"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"go.uber.org/mock/gomock"
+)
+
+func TestListAuditEventsAPI(t *testing.T) {
+	events := []db.AuditEvent{
+		{ID: 2, Actor: anonymousActor, Action: db.AuditActionTransferCreate, ResourceType: db.AuditResourceTransfer, ResourceID: "1"},
+		{ID: 1, Actor: anonymousActor, Action: db.AuditActionAccountCreate, ResourceType: db.AuditResourceAccount, ResourceID: "1"},
+	}
+
+	testCases := []struct {
+		name          string
+		query         string
+		setupAuth     func(request *http.Request)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "page_id=1&page_size=5&resource_type=account",
+			setupAuth: func(request *http.Request) {
+				request.Header.Set(authorizationHeaderKey, "Bearer "+testAdminToken)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				resourceType := db.AuditResourceAccount
+				arg := db.ListAuditEventsParams{
+					ResourceType: &resourceType,
+					Limit:        5,
+					Offset:       0,
+				}
+				store.EXPECT().ListAuditEvents(gomock.Any(), gomock.Eq(arg)).Times(1).Return(events, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got []db.AuditEvent
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Len(t, got, len(events))
+			},
+		},
+		{
+			name:      "NoAuthorization",
+			query:     "page_id=1&page_size=5",
+			setupAuth: func(request *http.Request) {},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditEvents(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:  "WrongToken",
+			query: "page_id=1&page_size=5",
+			setupAuth: func(request *http.Request) {
+				request.Header.Set(authorizationHeaderKey, "Bearer not-the-token")
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditEvents(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:  "InvalidPageSize",
+			query: "page_id=1&page_size=1000",
+			setupAuth: func(request *http.Request) {
+				request.Header.Set(authorizationHeaderKey, "Bearer "+testAdminToken)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditEvents(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/admin/audit_events?%s", tc.query)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+			tc.setupAuth(request)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+// the audit info of the caller must reach the store, which writes it in the same transaction as the account
+func TestCreateAccountPassesAuditInfo(t *testing.T) {
+	account := randomAccount()
+	account.Currency = "USD"
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		CreateAccountTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, arg db.CreateAccountParams) (db.Account, error) {
+			info := db.AuditInfoFromContext(ctx)
+			require.Equal(t, anonymousActor, info.Actor)
+			require.Equal(t, "req-123", info.RequestID)
+			require.NotEmpty(t, info.ClientIP)
+			return account, nil
+		})
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	data, err := json.Marshal(gin.H{"owner": account.Owner, "currency": account.Currency})
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, "/accounts", bytes.NewReader(data))
+	require.NoError(t, err)
+	request.Header.Set(requestIDHeaderKey, "req-123")
+	request.RemoteAddr = "10.0.0.1:1234"
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
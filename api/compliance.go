@@ -0,0 +1,186 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+)
+
+// evidence is a transfer that raised an alert, with the link to its history in the audit log
+type evidence struct {
+	TransferID int64  `json:"transfer_id"`
+	AuditURL   string `json:"audit_url"`
+}
+
+// complianceAlertResponse is a compliance alert with links to its evidence, so investigators can follow them
+type complianceAlertResponse struct {
+	ID             int64      `json:"id"`
+	Kind           string     `json:"kind"`
+	AccountID      int64      `json:"account_id"`
+	Currency       string     `json:"currency"`
+	TotalAmount    int64      `json:"total_amount"`
+	Evidence       []evidence `json:"evidence"`
+	Status         string     `json:"status"`
+	Assignee       string     `json:"assignee"`
+	Resolution     string     `json:"resolution"`
+	ResolutionNote string     `json:"resolution_note"`
+	ResolvedBy     string     `json:"resolved_by"`
+	ResolvedAt     *time.Time `json:"resolved_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+func newComplianceAlertResponse(alert db.ComplianceAlert) complianceAlertResponse {
+	rsp := complianceAlertResponse{
+		ID:             alert.ID,
+		Kind:           alert.Kind,
+		AccountID:      alert.AccountID,
+		Currency:       alert.Currency,
+		TotalAmount:    alert.TotalAmount,
+		Evidence:       make([]evidence, len(alert.TransferIds)),
+		Status:         alert.Status,
+		Assignee:       alert.Assignee,
+		Resolution:     alert.Resolution,
+		ResolutionNote: alert.ResolutionNote,
+		ResolvedBy:     alert.ResolvedBy,
+		ResolvedAt:     alert.ResolvedAt,
+		CreatedAt:      alert.CreatedAt,
+		UpdatedAt:      alert.UpdatedAt,
+	}
+
+	for i, id := range alert.TransferIds {
+		rsp.Evidence[i] = evidence{
+			TransferID: id,
+			AuditURL: fmt.Sprintf(
+				"/admin/audit_events?resource_type=%s&resource_id=%d&page_id=1&page_size=5",
+				db.AuditResourceTransfer, id,
+			),
+		}
+	}
+	return rsp
+}
+
+// every filter is optional, an empty value means "don't filter on it"
+type listComplianceAlertsRequest struct {
+	Status   string `form:"status" binding:"omitempty,oneof=open assigned resolved"`
+	Kind     string `form:"kind" binding:"omitempty,oneof=large_transaction structuring"`
+	Assignee string `form:"assignee"`
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=100"`
+}
+
+// listComplianceAlerts returns the alerts raised by the AML analyzer, newest first
+func (server *Server) listComplianceAlerts(ctx *gin.Context) {
+	var req listComplianceAlertsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		abortWithBindingError(ctx, err)
+		return
+	}
+
+	arg := db.ListComplianceAlertsParams{
+		Status:   optionalString(req.Status),
+		Kind:     optionalString(req.Kind),
+		Assignee: optionalString(req.Assignee),
+		Limit:    req.PageSize,
+		Offset:   (req.PageID - 1) * req.PageSize,
+	}
+
+	alerts, err := server.store.ListComplianceAlerts(ctx, arg)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	rsp := make([]complianceAlertResponse, len(alerts))
+	for i, alert := range alerts {
+		rsp[i] = newComplianceAlertResponse(alert)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type complianceAlertIDRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// getComplianceAlert returns a single alert
+func (server *Server) getComplianceAlert(ctx *gin.Context) {
+	var uri complianceAlertIDRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		abortWithBindingError(ctx, err)
+		return
+	}
+
+	alert, err := server.store.GetComplianceAlert(ctx, uri.ID)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newComplianceAlertResponse(alert))
+}
+
+type assignComplianceAlertRequest struct {
+	Assignee string `json:"assignee" binding:"required,max=100"`
+}
+
+// assignComplianceAlert hands an unresolved alert over to an investigator, reassigning it is allowed
+func (server *Server) assignComplianceAlert(ctx *gin.Context) {
+	var uri complianceAlertIDRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		abortWithBindingError(ctx, err)
+		return
+	}
+
+	var req assignComplianceAlertRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		abortWithBindingError(ctx, err)
+		return
+	}
+
+	alert, err := server.store.AssignComplianceAlertTx(ctx, db.AssignComplianceAlertParams{
+		ID:       uri.ID,
+		Assignee: req.Assignee,
+	})
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newComplianceAlertResponse(alert))
+}
+
+// the resolution says whether the activity was reported to the authorities, the note why
+type resolveComplianceAlertRequest struct {
+	Resolution string `json:"resolution" binding:"required,oneof=false_positive reported"`
+	Note       string `json:"note" binding:"required,max=500"`
+}
+
+// resolveComplianceAlert closes an alert after the investigation
+func (server *Server) resolveComplianceAlert(ctx *gin.Context) {
+	var uri complianceAlertIDRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		abortWithBindingError(ctx, err)
+		return
+	}
+
+	var req resolveComplianceAlertRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		abortWithBindingError(ctx, err)
+		return
+	}
+
+	alert, err := server.store.ResolveComplianceAlertTx(ctx, db.ResolveComplianceAlertTxParams{
+		ID:         uri.ID,
+		Resolution: req.Resolution,
+		Note:       req.Note,
+	})
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newComplianceAlertResponse(alert))
+}
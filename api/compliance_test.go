@@ -0,0 +1,205 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simple-bank/apperror"
+	mockdb "github.com/techschool/simple-bank/db2/mock"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/utils"
+	"go.uber.org/mock/gomock"
+)
+
+func TestComplianceAlertAPI(t *testing.T) {
+	alert := db.ComplianceAlert{
+		ID:          utils.RandomInt(1, 1000),
+		Kind:        db.AlertKindStructuring,
+		AccountID:   utils.RandomInt(1, 1000),
+		Currency:    "USD",
+		TotalAmount: 2850000,
+		TransferIds: []int64{11, 12, 13},
+		Status:      db.AlertStatusOpen,
+		CreatedAt:   time.Now().UTC().Truncate(time.Second),
+		UpdatedAt:   time.Now().UTC().Truncate(time.Second),
+	}
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "List",
+			method: http.MethodGet,
+			url:    "/admin/compliance_alerts?status=open&page_id=2&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListComplianceAlertsParams{
+					Status: optionalString(db.AlertStatusOpen),
+					Limit:  5,
+					Offset: 5,
+				}
+				store.EXPECT().ListComplianceAlerts(gomock.Any(), gomock.Eq(arg)).Times(1).Return([]db.ComplianceAlert{alert}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var alerts []complianceAlertResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &alerts))
+				require.Len(t, alerts, 1)
+				require.Equal(t, alert.ID, alerts[0].ID)
+				require.Nil(t, alerts[0].ResolvedAt)
+
+				// every transfer of the alert links to its audit trail
+				require.Len(t, alerts[0].Evidence, 3)
+				require.Equal(t, int64(11), alerts[0].Evidence[0].TransferID)
+				require.Equal(t,
+					"/admin/audit_events?resource_type=transfer&resource_id=11&page_id=1&page_size=5",
+					alerts[0].Evidence[0].AuditURL,
+				)
+			},
+		},
+		{
+			name:   "ListInvalidStatus",
+			method: http.MethodGet,
+			url:    "/admin/compliance_alerts?status=closed&page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListComplianceAlerts(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "GetNotFound",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/admin/compliance_alerts/%d", alert.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetComplianceAlert(gomock.Any(), gomock.Eq(alert.ID)).Times(1).
+					Return(db.ComplianceAlert{}, &db.Error{Kind: db.ErrNotFound})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				requireErrorBody(t, recorder, apperror.CodeNotFound)
+			},
+		},
+		{
+			name:   "Assign",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/admin/compliance_alerts/%d/assign", alert.ID),
+			body:   gin.H{"assignee": "jdoe"},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.AssignComplianceAlertParams{ID: alert.ID, Assignee: "jdoe"}
+				assigned := alert
+				assigned.Status = db.AlertStatusAssigned
+				assigned.Assignee = "jdoe"
+				store.EXPECT().AssignComplianceAlertTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(assigned, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp complianceAlertResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, db.AlertStatusAssigned, rsp.Status)
+				require.Equal(t, "jdoe", rsp.Assignee)
+			},
+		},
+		{
+			name:   "AssignWithoutAssignee",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/admin/compliance_alerts/%d/assign", alert.ID),
+			body:   gin.H{},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().AssignComplianceAlertTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "Resolve",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/admin/compliance_alerts/%d/resolve", alert.ID),
+			body:   gin.H{"resolution": "reported", "note": "SAR filed"},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ResolveComplianceAlertTxParams{ID: alert.ID, Resolution: "reported", Note: "SAR filed"}
+				resolved := alert
+				resolved.Status = db.AlertStatusResolved
+				resolved.Resolution = "reported"
+				resolvedAt := time.Now()
+				resolved.ResolvedAt = &resolvedAt
+				store.EXPECT().ResolveComplianceAlertTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(resolved, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp complianceAlertResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, db.AlertStatusResolved, rsp.Status)
+				require.NotNil(t, rsp.ResolvedAt)
+			},
+		},
+		{
+			name:   "ResolveInvalidResolution",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/admin/compliance_alerts/%d/resolve", alert.ID),
+			body:   gin.H{"resolution": "ignored", "note": "too busy"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ResolveComplianceAlertTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "ResolveAlreadyResolved",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/admin/compliance_alerts/%d/resolve", alert.ID),
+			body:   gin.H{"resolution": "false_positive", "note": "payroll"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ResolveComplianceAlertTx(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.ComplianceAlert{}, &db.Error{Kind: db.ErrAlertResolved})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				requireErrorBody(t, recorder, apperror.CodeAlreadyResolved)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			var body bytes.Buffer
+			if tc.body != nil {
+				require.NoError(t, json.NewEncoder(&body).Encode(tc.body))
+			}
+
+			request, err := http.NewRequest(tc.method, tc.url, &body)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, "Bearer "+testAdminToken)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"log/slog"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/techschool/simple-bank/apperror"
+	"github.com/techschool/simple-bank/logging"
+)
+
+// errorBody is the JSON body of every error response
+type errorBody struct {
+	Code      apperror.Code `json:"code"`
+	Message   string        `json:"message"`
+	Details   any           `json:"details,omitempty"`
+	RequestID string        `json:"request_id,omitempty"`
+}
+
+// abortWithError writes err as an error response and stops the handler chain
+// the status code comes from the apperror table, internal errors are logged and only reported with a generic message
+func abortWithError(ctx *gin.Context, err error) {
+	appErr := apperror.From(err)
+	if appErr.Code == apperror.CodeInternal {
+		slog.ErrorContext(ctx.Request.Context(), "request failed",
+			"method", ctx.Request.Method,
+			"route", ctx.FullPath(),
+			"error", err,
+		)
+	}
+
+	ctx.AbortWithStatusJSON(appErr.HTTPStatus(), errorBody{
+		Code:      appErr.Code,
+		Message:   appErr.Message,
+		Details:   appErr.Details,
+		RequestID: logging.RequestIDFromContext(ctx.Request.Context()),
+	})
+}
+
+// abortWithBindingError reports a request that failed binding or validation
+func abortWithBindingError(ctx *gin.Context, err error) {
+	abortWithError(ctx, apperror.InvalidArgument(err))
+}
+
+// registerFieldNames makes validation errors name fields the way clients send them,
+// e.g. "from_account_id" instead of "FromAccountID"
+func registerFieldNames() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "uri", "form"} {
+			name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+			if name != "" && name != "-" {
+				return name
+			}
+		}
+		return field.Name
+	})
+}
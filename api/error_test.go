@@ -0,0 +1,47 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simple-bank/apperror"
+	mockdb "github.com/techschool/simple-bank/db2/mock"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"go.uber.org/mock/gomock"
+)
+
+// requireErrorBody checks that the response is an error response with the given code and returns its body
+func requireErrorBody(t *testing.T, recorder *httptest.ResponseRecorder, code apperror.Code) errorBody {
+	var body errorBody
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	require.Equal(t, code, body.Code)
+	require.NotEmpty(t, body.Message)
+	return body
+}
+
+func TestErrorResponse(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(int64(1))).Times(1).Return(db.Account{}, pgx.ErrTxClosed)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, "/accounts/1", nil)
+	require.NoError(t, err)
+	request.Header.Set(requestIDHeaderKey, "req-123")
+
+	server.router.ServeHTTP(recorder, request)
+
+	// the driver error is logged but never returned to the client
+	require.Equal(t, http.StatusInternalServerError, recorder.Code)
+	body := requireErrorBody(t, recorder, apperror.CodeInternal)
+	require.Equal(t, "req-123", body.RequestID)
+	require.NotContains(t, recorder.Body.String(), pgx.ErrTxClosed.Error())
+}
@@ -0,0 +1,24 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/techschool/simple-bank/health"
+)
+
+// liveness answers as long as the process can serve requests, it never checks dependencies:
+// a database outage must not get every replica restarted
+func (server *Server) liveness(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": health.StatusOK})
+}
+
+// readiness runs the readiness checks, the replica only gets traffic while they all pass
+func (server *Server) readiness(ctx *gin.Context) {
+	report := server.health.Run(ctx)
+	if !report.OK() {
+		ctx.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	ctx.JSON(http.StatusOK, report)
+}
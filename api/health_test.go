@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/techschool/simple-bank/db2/mock"
+	"github.com/techschool/simple-bank/events"
+	"github.com/techschool/simple-bank/health"
+	"github.com/techschool/simple-bank/utils"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHealthAPI(t *testing.T) {
+	testCases := []struct {
+		name          string
+		url           string
+		checkErr      error
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Liveness",
+			url:  "/healthz",
+			// liveness doesn't look at dependencies
+			checkErr: errors.New("connection refused"),
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "Ready",
+			url:  "/readyz",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var report health.Report
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+				require.Equal(t, health.StatusOK, report.Checks["database"].Status)
+			},
+		},
+		{
+			name:     "NotReady",
+			url:      "/readyz",
+			checkErr: errors.New("connection refused"),
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+				var report health.Report
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+				require.Equal(t, health.StatusUnavailable, report.Status)
+				require.Equal(t, "connection refused", report.Checks["database"].Error)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			checker := health.NewChecker(time.Second)
+			checker.Add("database", func(ctx context.Context) (string, error) {
+				return "", tc.checkErr
+			})
+
+			server, err := NewServer(utils.Config{}, mockdb.NewMockStore(ctrl), events.NewBus(), checker, nil)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, tc.url, nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simple-bank/apperror"
+	mockdb "github.com/techschool/simple-bank/db2/mock"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	testCases := []struct {
+		name          string
+		header        string
+		checkResponse func(t *testing.T, requestID string)
+	}{
+		{
+			name:   "FromClient",
+			header: "req-123",
+			checkResponse: func(t *testing.T, requestID string) {
+				require.Equal(t, "req-123", requestID)
+			},
+		},
+		{
+			name:   "Missing",
+			header: "",
+			checkResponse: func(t *testing.T, requestID string) {
+				require.Len(t, requestID, 32)
+			},
+		},
+		{
+			name:   "Invalid",
+			header: "forged\" id",
+			checkResponse: func(t *testing.T, requestID string) {
+				require.Len(t, requestID, 32)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			server := newTestServer(t, mockdb.NewMockStore(ctrl))
+			recorder := httptest.NewRecorder()
+
+			// an invalid id fails binding, so the request id also shows up in the error body
+			request, err := http.NewRequest(http.MethodGet, "/accounts/0", nil)
+			require.NoError(t, err)
+			if tc.header != "" {
+				request.Header.Set(requestIDHeaderKey, tc.header)
+			}
+
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusBadRequest, recorder.Code)
+
+			requestID := recorder.Header().Get(requestIDHeaderKey)
+			tc.checkResponse(t, requestID)
+
+			body := requireErrorBody(t, recorder, apperror.CodeInvalidArgument)
+			require.Equal(t, requestID, body.RequestID)
+		})
+	}
+}
+
+func TestRecoveryMiddleware(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+	server.router.GET("/panic", func(ctx *gin.Context) {
+		panic("boom")
+	})
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/panic", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusInternalServerError, recorder.Code)
+	body := requireErrorBody(t, recorder, apperror.CodeInternal)
+	require.Equal(t, recorder.Header().Get(requestIDHeaderKey), body.RequestID)
+	require.NotContains(t, recorder.Body.String(), "boom")
+}
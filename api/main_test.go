@@ -1,13 +1,65 @@
 package api
 
 import (
+	"context"
 	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/events"
+	"github.com/techschool/simple-bank/health"
+	"github.com/techschool/simple-bank/token"
+	"github.com/techschool/simple-bank/utils"
+	"net"
+	"net/http"
 	"os"
 	"testing"
+	"time"
 )
 
+// testAdminToken is the admin token of every server created by newTestServer
+const testAdminToken = "test-admin-token"
+
+// testTokenKey signs the access tokens of the servers created by newTestServer
+var testTokenKey = utils.RandomString(32)
+
+// addAuthorization makes request carry an access token of owner, valid for duration
+func addAuthorization(t *testing.T, request *http.Request, owner string, duration time.Duration) {
+	maker, err := token.NewMaker(testTokenKey)
+	require.NoError(t, err)
+	accessToken, _, err := maker.CreateToken(owner, duration)
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, "Bearer "+accessToken)
+}
+
+// newTestServer creates a server backed by store with a config suitable for tests
+func newTestServer(t *testing.T, store db.Store) *Server {
+	config := utils.Config{
+		AdminToken:        testAdminToken,
+		TokenSymmetricKey: testTokenKey,
+	}
+
+	server, err := NewServer(config, store, events.NewBus(), health.NewChecker(time.Second), nil)
+	require.NoError(t, err)
+	server.webhookResolver = testResolver{}
+	return server
+}
+
+// testResolver resolves the host of randomWebhook to a public address without DNS,
+// and the IP literals to themselves like net.DefaultResolver
+type testResolver struct{}
+
+func (testResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IPAddr{{IP: ip}}, nil
+	}
+	if host == "partner.example.com" {
+		return []net.IPAddr{{IP: net.ParseIP("203.0.113.10")}}, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
 func TestMain(m *testing.M) {
 	gin.SetMode(gin.TestMode) // set gin to test mode
 	// the reason is that in debug mode, gin will print many logs in console which is not what we want in tests
 	os.Exit(m.Run())
-}
\ No newline at end of file
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simple-bank/apperror"
+	mockdb "github.com/techschool/simple-bank/db2/mock"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/metrics"
+	"go.uber.org/mock/gomock"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	account := randomAccount()
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+
+	server := newTestServer(t, store)
+	requests := metrics.HTTPRequests.WithLabelValues(http.MethodGet, "/accounts/:id", "200")
+	unmatched := metrics.HTTPRequests.WithLabelValues(http.MethodGet, unmatchedRoute, "404")
+	before := testutil.ToFloat64(requests)
+	beforeUnmatched := testutil.ToFloat64(unmatched)
+
+	for _, url := range []string{fmt.Sprintf("/accounts/%d", account.ID), "/no/such/route"} {
+		request, err := http.NewRequest(http.MethodGet, url, nil)
+		require.NoError(t, err)
+		server.router.ServeHTTP(httptest.NewRecorder(), request)
+	}
+
+	// the route pattern is recorded, not the path, so account ids don't create new series
+	require.Equal(t, before+1, testutil.ToFloat64(requests))
+	require.Equal(t, beforeUnmatched+1, testutil.ToFloat64(unmatched))
+}
+
+func TestCreateTransferMetrics(t *testing.T) {
+	account1 := randomAccount()
+	account2 := randomAccount()
+	account1.Currency = "EUR"
+	account2.Currency = "EUR"
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(2).Return(account1, nil)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(2).Return(account2, nil)
+	gomock.InOrder(
+		store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, nil),
+		store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, db.ErrInsufficientFunds),
+	)
+
+	server := newTestServer(t, store)
+	failed := metrics.TransfersFailed.WithLabelValues(string(apperror.CodeInsufficientFunds))
+	beforeFailed := testutil.ToFloat64(failed)
+
+	for i := 0; i < 2; i++ {
+		data, err := json.Marshal(gin.H{
+			"from_account_id": account1.ID,
+			"to_account_id":   account2.ID,
+			"amount":          25,
+			"currency":        "EUR",
+		})
+		require.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(data))
+		require.NoError(t, err)
+		server.router.ServeHTTP(httptest.NewRecorder(), request)
+	}
+
+	// the completed transfer is counted by the store, which is mocked here
+	require.Equal(t, beforeFailed+1, testutil.ToFloat64(failed))
+}
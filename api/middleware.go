@@ -0,0 +1,225 @@
+package api
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/techschool/simple-bank/apperror"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/logging"
+	"github.com/techschool/simple-bank/metrics"
+	"github.com/techschool/simple-bank/ratelimit"
+	"github.com/techschool/simple-bank/token"
+)
+
+const (
+	authorizationHeaderKey  = "authorization"
+	authorizationTypeBearer = "bearer"
+	requestIDHeaderKey      = "X-Request-ID"
+
+	// anonymousActor is recorded in the audit log for requests that are not authenticated
+	anonymousActor = "anonymous"
+	// adminActor is recorded in the audit log for requests authenticated with the admin token
+	adminActor = "admin"
+	// ownerActorPrefix is put before the owner name in the audit log for requests authenticated with an access token
+	ownerActorPrefix = "owner:"
+	// authorizationPayloadKey is the key of the token payload of the authenticated owner in the gin context
+	authorizationPayloadKey = "authorization_payload"
+
+	// unmatchedRoute labels the metrics of requests that matched no route, using the path would let clients
+	// create an unbounded number of series
+	unmatchedRoute = "unmatched"
+)
+
+// notProbe keeps the requests of the orchestrator probes out of the traces, they would drown everything else
+func notProbe(request *http.Request) bool {
+	return request.URL.Path != "/healthz" && request.URL.Path != "/readyz"
+}
+
+// metricsMiddleware counts every request and records its latency, labelled with the route pattern such as /accounts/:id
+func metricsMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := strconv.Itoa(ctx.Writer.Status())
+
+		metrics.HTTPRequests.WithLabelValues(ctx.Request.Method, route, status).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(ctx.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// requestIDMiddleware gives every request an id, the one sent by the client in X-Request-ID if it is usable
+// the id is returned in the X-Request-ID response header, and stored on the request context
+// where the logger, the error responses and the audit log pick it up
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestID := logging.RequestIDOrNew(ctx.GetHeader(requestIDHeaderKey))
+		ctx.Header(requestIDHeaderKey, requestID)
+		ctx.Request = ctx.Request.WithContext(logging.WithRequestID(ctx.Request.Context(), requestID))
+		ctx.Next()
+	}
+}
+
+// accessLogMiddleware logs one line per request, replacing the plain text logger of gin.Default
+// the query string is left out, it may carry credentials
+func accessLogMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+
+		slog.LogAttrs(ctx.Request.Context(), level, "request",
+			slog.String("method", ctx.Request.Method),
+			slog.String("route", ctx.FullPath()),
+			slog.String("path", ctx.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.Int("size", ctx.Writer.Size()),
+			slog.String("client_ip", ctx.ClientIP()),
+		)
+	}
+}
+
+// recoveryMiddleware turns a panic into an internal error response and logs it with its stack trace
+func recoveryMiddleware() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(ctx *gin.Context, recovered any) {
+		slog.ErrorContext(ctx.Request.Context(), "panic while serving request",
+			"panic", fmt.Sprint(recovered),
+			"stack", string(debug.Stack()),
+		)
+		abortWithError(ctx, apperror.New(apperror.CodeInternal, "internal server error"))
+	})
+}
+
+// rateLimitMiddleware limits the requests of each client IP with the policy of the route
+// the IP is the one computed by gin, X-Forwarded-For is only trusted from the proxies in TRUSTED_PROXIES
+func rateLimitMiddleware(limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		limitRequest(ctx, limiter, "ip:"+ctx.ClientIP())
+	}
+}
+
+// userRateLimitMiddleware limits the requests of the authenticated user with the policy of the route
+// it must run after the authentication middleware, which records the user as the audit actor
+func userRateLimitMiddleware(limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		limitRequest(ctx, limiter, "user:"+db.AuditInfoFromContext(ctx.Request.Context()).Actor)
+	}
+}
+
+// limitRequest answers 429 with a Retry-After header when the bucket of key for the route is empty
+func limitRequest(ctx *gin.Context, limiter *ratelimit.Limiter, key string) {
+	route := ctx.FullPath()
+	if route == "" {
+		route = unmatchedRoute
+	}
+	route = ctx.Request.Method + " " + route
+
+	result := limiter.Allow(ctx.Request.Context(), route, key)
+	if !result.Allowed {
+		metrics.RateLimited.WithLabelValues(route).Inc()
+
+		retryAfter := result.RetryAfterSeconds()
+		ctx.Header("Retry-After", strconv.Itoa(retryAfter))
+		abortWithError(ctx, apperror.New(apperror.CodeRateLimited, "too many requests").
+			WithDetails(map[string]any{"retry_after_seconds": retryAfter}))
+		return
+	}
+	ctx.Next()
+}
+
+// auditMiddleware attaches the audit info of the caller to the request context
+// so that the store can record it together with every state change
+func auditMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		setAuditActor(ctx, anonymousActor)
+		ctx.Next()
+	}
+}
+
+// setAuditActor stores the audit info for actor on the request context
+func setAuditActor(ctx *gin.Context, actor string) {
+	info := db.AuditInfo{
+		Actor:     actor,
+		RequestID: logging.RequestIDFromContext(ctx.Request.Context()),
+		ClientIP:  ctx.ClientIP(),
+	}
+	ctx.Request = ctx.Request.WithContext(db.WithAuditInfo(ctx.Request.Context(), info))
+}
+
+// adminAuthMiddleware only lets requests through when they carry "Authorization: Bearer <token>"
+// an empty token disables the admin routes entirely
+func adminAuthMiddleware(token string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if token == "" {
+			abortWithError(ctx, apperror.New(apperror.CodePermissionDenied, "admin routes are disabled"))
+			return
+		}
+
+		fields := strings.Fields(ctx.GetHeader(authorizationHeaderKey))
+		if len(fields) != 2 || strings.ToLower(fields[0]) != authorizationTypeBearer {
+			abortWithError(ctx, apperror.New(apperror.CodeUnauthenticated, "invalid authorization header format"))
+			return
+		}
+
+		// constant time comparison so the token can't be guessed from response timings
+		if subtle.ConstantTimeCompare([]byte(fields[1]), []byte(token)) != 1 {
+			abortWithError(ctx, apperror.New(apperror.CodeUnauthenticated, "invalid admin token"))
+			return
+		}
+
+		setAuditActor(ctx, adminActor)
+		ctx.Next()
+	}
+}
+
+// ownerAuthMiddleware only lets requests through when they carry "Authorization: Bearer <access token>",
+// the handlers then find the owner with authorizationPayload
+// a nil maker, when no TOKEN_SYMMETRIC_KEY is configured, disables the owner routes entirely
+func ownerAuthMiddleware(maker *token.Maker) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if maker == nil {
+			abortWithError(ctx, apperror.New(apperror.CodePermissionDenied, "owner routes are disabled"))
+			return
+		}
+
+		fields := strings.Fields(ctx.GetHeader(authorizationHeaderKey))
+		if len(fields) != 2 || strings.ToLower(fields[0]) != authorizationTypeBearer {
+			abortWithError(ctx, apperror.New(apperror.CodeUnauthenticated, "invalid authorization header format"))
+			return
+		}
+
+		payload, err := maker.VerifyToken(fields[1])
+		if err != nil {
+			abortWithError(ctx, apperror.New(apperror.CodeUnauthenticated, err.Error()))
+			return
+		}
+
+		ctx.Set(authorizationPayloadKey, payload)
+		setAuditActor(ctx, ownerActorPrefix+payload.Owner)
+		ctx.Next()
+	}
+}
+
+// authorizationPayload returns the payload stored by ownerAuthMiddleware
+func authorizationPayload(ctx *gin.Context) token.Payload {
+	return ctx.MustGet(authorizationPayloadKey).(token.Payload)
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simple-bank/apperror"
+	mockdb "github.com/techschool/simple-bank/db2/mock"
+	"github.com/techschool/simple-bank/events"
+	"github.com/techschool/simple-bank/health"
+	"github.com/techschool/simple-bank/ratelimit"
+	"github.com/techschool/simple-bank/utils"
+	"go.uber.org/mock/gomock"
+)
+
+// newRateLimitedServer creates a test server enforcing the given route policies
+func newRateLimitedServer(t *testing.T, routes string) *Server {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	policies, err := ratelimit.ParsePolicies("", routes)
+	require.NoError(t, err)
+	limiter := ratelimit.NewLimiter(policies, ratelimit.NewMemoryStore())
+
+	config := utils.Config{AdminToken: testAdminToken}
+	server, err := NewServer(config, mockdb.NewMockStore(ctrl), events.NewBus(), health.NewChecker(time.Second), limiter)
+	require.NoError(t, err)
+	return server
+}
+
+func serveFrom(server *Server, request *http.Request, remoteAddr string) *httptest.ResponseRecorder {
+	request.RemoteAddr = remoteAddr
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestRateLimitByIP(t *testing.T) {
+	server := newRateLimitedServer(t, "GET /accounts/:id=1/m:2")
+
+	// the id is invalid, the handler answers 400 without touching the store, but the request still counts
+	newRequest := func() *http.Request {
+		request, err := http.NewRequest(http.MethodGet, "/accounts/0", nil)
+		require.NoError(t, err)
+		// not trusted, the client can't choose another bucket
+		request.Header.Set("X-Forwarded-For", utils.RandomString(6))
+		return request
+	}
+
+	for i := 0; i < 2; i++ {
+		recorder := serveFrom(server, newRequest(), "10.0.0.1:1234")
+		require.Equal(t, http.StatusBadRequest, recorder.Code)
+	}
+
+	recorder := serveFrom(server, newRequest(), "10.0.0.1:1234")
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	requireErrorBody(t, recorder, apperror.CodeRateLimited)
+	retryAfter, err := strconv.Atoi(recorder.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	require.InDelta(t, 60, retryAfter, 1)
+
+	// another IP has its own bucket
+	recorder = serveFrom(server, newRequest(), "10.0.0.2:1234")
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	// routes without a policy are not limited
+	request, err := http.NewRequest(http.MethodGet, "/healthz", nil)
+	require.NoError(t, err)
+	recorder = serveFrom(server, request, "10.0.0.1:1234")
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestRateLimitByUser(t *testing.T) {
+	server := newRateLimitedServer(t, "GET /admin/debug/vars=1/m:1")
+
+	newRequest := func() *http.Request {
+		request, err := http.NewRequest(http.MethodGet, "/admin/debug/vars", nil)
+		require.NoError(t, err)
+		request.Header.Set(authorizationHeaderKey, "Bearer "+testAdminToken)
+		return request
+	}
+
+	recorder := serveFrom(server, newRequest(), "10.0.0.1:1234")
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	// a new IP doesn't help, the admin user spent its token
+	recorder = serveFrom(server, newRequest(), "10.0.0.2:1234")
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.NotEmpty(t, recorder.Header().Get("Retry-After"))
+}
@@ -1,43 +1,175 @@
 package api
 
 import (
-	db "github.com/techschool/simple-bank/db2/sqlc"
+	"context"
+	"errors"
+	"expvar"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
 	"github.com/gin-gonic/gin"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/events"
+	"github.com/techschool/simple-bank/health"
+	"github.com/techschool/simple-bank/ratelimit"
+	"github.com/techschool/simple-bank/token"
+	"github.com/techschool/simple-bank/tracing"
+	"github.com/techschool/simple-bank/utils"
+	"github.com/techschool/simple-bank/webhook"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
-
 type Server struct {
-    store db.Store   // now store is interface, so removing the pointer
-	router *gin.Engine // HTTP request router
+	config          utils.Config
+	store           db.Store         // now store is interface, so removing the pointer
+	router          *gin.Engine      // HTTP request router
+	bus             *events.Bus      // live balance changes, fed by the account events listener
+	health          *health.Checker  // readiness checks of the database and the background workers
+	tokenMaker      *token.Maker     // verifies the access tokens of the account owners, nil when TOKEN_SYMMETRIC_KEY is empty
+	webhookResolver webhook.Resolver // resolves the hosts of the webhook URLs, which must all be public
+	shuttingDown    chan struct{}    // closed when Start begins draining, ends the event streams which would never finish on their own
+	shutdownOnce    sync.Once
 }
 
 // Constructor to create a new server instance, return a pointer to that instance
-func NewServer(store db.Store) *Server {
-	server := &Server{store: store}
-	router := gin.Default() // this use the gin default middleware
+// limiter applies the rate limits, nil disables them
+// it returns an error if config.TrustedProxies or config.TokenSymmetricKey is invalid
+func NewServer(config utils.Config, store db.Store, bus *events.Bus, checker *health.Checker, limiter *ratelimit.Limiter) (*Server, error) {
+	server := &Server{
+		config:          config,
+		store:           store,
+		bus:             bus,
+		health:          checker,
+		webhookResolver: net.DefaultResolver,
+		shuttingDown:    make(chan struct{}),
+	}
+	if config.TokenSymmetricKey != "" {
+		maker, err := token.NewMaker(config.TokenSymmetricKey)
+		if err != nil {
+			return nil, err
+		}
+		server.tokenMaker = maker
+	}
+	registerFieldNames()
+	router := gin.New() // not gin.Default: its logger and recovery write plain text, ours log JSON through slog
+	// gin trusts X-Forwarded-For from anyone by default, which would let clients pick the IP they are rate limited by
+	if err := router.SetTrustedProxies(splitList(config.TrustedProxies)); err != nil {
+		return nil, err
+	}
+	// let handlers pass ctx straight to the store: values such as the audit info set on the request context
+	// are only visible through gin.Context when this is enabled
+	router.ContextWithFallback = true
+	// the tracing middleware continues the trace of the caller when the request carries a traceparent header
+	router.Use(
+		requestIDMiddleware(),
+		otelgin.Middleware(tracing.ServiceName, otelgin.WithFilter(notProbe)),
+		metricsMiddleware(),
+		accessLogMiddleware(),
+		recoveryMiddleware(), // after the access log and the metrics, so that they see the 500 of a panic
+		auditMiddleware(),
+		rateLimitMiddleware(limiter),
+	)
 
+	// probes for the orchestrator
+	router.GET("/healthz", server.liveness)
+	router.GET("/readyz", server.readiness)
 
 	// add routes to the router
 	router.POST("/accounts", server.createAccount)
 	router.GET("/accounts/:id", server.getAccount) //http://localhost:8080/accounts/1  :id because we get from uri
-	router.GET("/accounts/", server.listAccounts) // we will get query parameters, not from uri
+	router.GET("/accounts/", server.listAccounts)  // we will get query parameters, not from uri
+	// server-sent events of every balance change of an account, only its owner may watch it
+	router.GET("/accounts/:id/events", ownerAuthMiddleware(server.tokenMaker), userRateLimitMiddleware(limiter), server.streamAccountEvents)
 	// router.GET("/accounts", server.listAccounts)
 	// router.PUT("/accounts/:id", server.updateAccount)
 	// router.DELETE("/accounts/:id", server.deleteAccount)
-	// router.POST("/transfers", server.createTransfer)
+	router.POST("/transfers", server.createTransfer)
 	// router.GET("/transfers/:id", server.getTransfer)
 	// router.GET("/transfers", server.listTransfers)
+
+	// admin routes, every request must carry the admin token
+	adminRoutes := router.Group("/admin").Use(adminAuthMiddleware(config.AdminToken), userRateLimitMiddleware(limiter))
+	adminRoutes.GET("/audit_events", server.listAuditEvents)
+	adminRoutes.GET("/debug/vars", gin.WrapH(expvar.Handler())) // runtime counters, the retries are on /metrics
+	adminRoutes.GET("/limit_tiers", server.listLimitTiers)
+	adminRoutes.PUT("/limit_tiers/:name", server.upsertLimitTier)
+	adminRoutes.GET("/accounts/:id/limits", server.getAccountLimits)
+	adminRoutes.PUT("/accounts/:id/limits", server.updateAccountLimits)
+	// the review queue of the transfers held by the fraud screening, worked by the tellers
+	adminRoutes.GET("/transfers/pending", server.listPendingTransfers)
+	adminRoutes.POST("/transfers/:id/approve", server.approveTransfer)
+	adminRoutes.POST("/transfers/:id/reject", server.rejectTransfer)
+	// the alerts raised by the AML analyzer, worked by the compliance officers
+	adminRoutes.GET("/compliance_alerts", server.listComplianceAlerts)
+	adminRoutes.GET("/compliance_alerts/:id", server.getComplianceAlert)
+	adminRoutes.POST("/compliance_alerts/:id/assign", server.assignComplianceAlert)
+	adminRoutes.POST("/compliance_alerts/:id/resolve", server.resolveComplianceAlert)
+
+	// webhook subscriptions are managed by operators on behalf of partners, so they need the admin token too
+	webhookRoutes := router.Group("/webhooks").Use(adminAuthMiddleware(config.AdminToken), userRateLimitMiddleware(limiter))
+	webhookRoutes.POST("", server.createWebhook)
+	webhookRoutes.GET("", server.listWebhooks)
+	webhookRoutes.GET("/:id", server.getWebhook)
+	webhookRoutes.PUT("/:id", server.updateWebhook)
+	webhookRoutes.DELETE("/:id", server.deleteWebhook)
+	webhookRoutes.GET("/:id/deliveries", server.listWebhookDeliveries)
+
 	server.router = router // assign the router to the server instance
 
-	return server
+	return server, nil
 }
 
-// helper function to return error response in JSON format
-func errorResponse(err error) gin.H {
-	return gin.H{"error": err.Error()}
+// splitList splits a comma separated config value, dropping the blanks
+func splitList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
 }
 
-// Start runs the HTTP server on a specific address.
-func (server *Server) Start(address string) error {
-	return server.router.Run(address)
-}
\ No newline at end of file
+// Start runs the HTTP server on a specific address until ctx is cancelled
+// it then stops accepting connections and waits up to config.ShutdownTimeout for in-flight requests to finish
+// returns nil after a clean shutdown
+func (server *Server) Start(ctx context.Context, address string) error {
+	httpServer := &http.Server{
+		Addr:              address,
+		Handler:           server.router,
+		ReadHeaderTimeout: server.config.HTTPReadTimeout,
+		ReadTimeout:       server.config.HTTPReadTimeout,
+		WriteTimeout:      server.config.HTTPWriteTimeout,
+		IdleTimeout:       server.config.HTTPIdleTimeout,
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn), // e.g. TLS handshake errors
+	}
+	httpServer.RegisterOnShutdown(server.closeStreams)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err // the server couldn't start, e.g. the address is in use
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), server.config.ShutdownTimeout)
+	defer cancel()
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// closeStreams ends the open event streams, the clients reconnect to another replica
+func (server *Server) closeStreams() {
+	server.shutdownOnce.Do(func() {
+		close(server.shuttingDown)
+	})
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/techschool/simple-bank/apperror"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/metrics"
+)
+
+// amount must be positive, and both accounts must hold the currency of the transfer
+type transferRequest struct {
+	FromAccountID int64  `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1,nefield=FromAccountID"` // the database rejects transfers to the same account
+	Amount        int64  `json:"amount" binding:"required,gt=0"`
+	Currency      string `json:"currency" binding:"required,oneof=USD EUR"`
+}
+
+func (server *Server) createTransfer(ctx *gin.Context) {
+	var req transferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		abortTransfer(ctx, apperror.InvalidArgument(err))
+		return
+	}
+
+	if !server.validAccount(ctx, req.FromAccountID, req.Currency) {
+		return
+	}
+
+	if !server.validAccount(ctx, req.ToAccountID, req.Currency) {
+		return
+	}
+
+	arg := db.TransferTxParams{
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount,
+	}
+
+	result, err := server.store.TransferTx(ctx, arg)
+	if err != nil {
+		abortTransfer(ctx, err)
+		return
+	}
+
+	// a transfer held by the fraud screening is accepted, but no money moved yet
+	if result.Transfer.Status == db.TransferStatusPendingReview {
+		metrics.TransfersPendingReview.WithLabelValues(req.Currency).Inc()
+		ctx.JSON(http.StatusAccepted, result)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// abortTransfer writes the error response of a failed transfer and counts the failure by its error code
+func abortTransfer(ctx *gin.Context, err error) {
+	appErr := apperror.From(err)
+	metrics.TransfersFailed.WithLabelValues(string(appErr.Code)).Inc()
+	abortWithError(ctx, appErr)
+}
+
+// validAccount checks that the account exists, that it isn't an equity account and that its currency matches
+// it writes the error response itself, so the caller only has to return when it is false
+// it reads the primary: an account created a moment ago may not have reached the replica yet
+func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency string) bool {
+	account, err := server.store.GetAccount(db.WithReadYourWrites(ctx), accountID)
+	if err != nil {
+		abortTransfer(ctx, err)
+		return false
+	}
+
+	if account.Kind == db.AccountKindEquity {
+		abortTransfer(ctx, &db.Error{Kind: db.ErrEquityAccount, Details: map[string]any{"account_id": account.ID}})
+		return false
+	}
+
+	if account.Currency != currency {
+		err := apperror.New(apperror.CodeInvalidArgument, "account currency mismatch").WithDetails(gin.H{
+			"account_id":       account.ID,
+			"account_currency": account.Currency,
+			"currency":         currency,
+		})
+		abortTransfer(ctx, err)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+)
+
+// listLimitTiers returns every transfer limit tier
+func (server *Server) listLimitTiers(ctx *gin.Context) {
+	tiers, err := server.store.ListTransferLimitTiers(ctx)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tiers)
+}
+
+type limitTierNameRequest struct {
+	Name string `uri:"name" binding:"required,alphanum,max=32"`
+}
+
+// amounts are in minor units, like the balances
+type upsertLimitTierRequest struct {
+	MaxSingleAmount int64 `json:"max_single_amount" binding:"required,gt=0"`
+	MaxDailyAmount  int64 `json:"max_daily_amount" binding:"required,gt=0,gtefield=MaxSingleAmount"`
+	MaxDailyCount   int32 `json:"max_daily_count" binding:"required,gt=0"`
+}
+
+// upsertLimitTier creates a tier or replaces its limits, the accounts of the tier get the new limits right away
+func (server *Server) upsertLimitTier(ctx *gin.Context) {
+	var uri limitTierNameRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		abortWithBindingError(ctx, err)
+		return
+	}
+
+	var req upsertLimitTierRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		abortWithBindingError(ctx, err)
+		return
+	}
+
+	arg := db.UpsertTransferLimitTierParams{
+		Name:            uri.Name,
+		MaxSingleAmount: req.MaxSingleAmount,
+		MaxDailyAmount:  req.MaxDailyAmount,
+		MaxDailyCount:   req.MaxDailyCount,
+	}
+
+	tier, err := server.store.UpsertTransferLimitTierTx(ctx, arg)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tier)
+}
+
+// accountLimitsResponse is the limits in effect for an account and what it used of them today
+type accountLimitsResponse struct {
+	db.GetEffectiveTransferLimitsRow
+	UsedTodayAmount int64 `json:"used_today_amount"`
+	UsedTodayCount  int32 `json:"used_today_count"`
+}
+
+// getAccountLimits returns the transfer limits in effect for an account and its usage of the current UTC day
+func (server *Server) getAccountLimits(ctx *gin.Context) {
+	var req getAccountRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		abortWithBindingError(ctx, err)
+		return
+	}
+
+	limits, err := server.store.GetEffectiveTransferLimits(ctx, req.ID)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	used, err := server.store.GetDailyOutgoingTransfers(ctx, db.GetDailyOutgoingTransfersParams{FromAccountID: req.ID, Day: time.Now()})
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, accountLimitsResponse{
+		GetEffectiveTransferLimitsRow: limits,
+		UsedTodayAmount:               used.TotalAmount,
+		UsedTodayCount:                used.TotalCount,
+	})
+}
+
+// the limits are pointers: a missing or null limit uses the one of the tier
+type updateAccountLimitsRequest struct {
+	Tier            string `json:"tier" binding:"required,alphanum,max=32"`
+	MaxSingleAmount *int64 `json:"max_single_amount" binding:"omitempty,gt=0"`
+	MaxDailyAmount  *int64 `json:"max_daily_amount" binding:"omitempty,gt=0"`
+	MaxDailyCount   *int32 `json:"max_daily_count" binding:"omitempty,gt=0"`
+}
+
+// updateAccountLimits moves an account to a tier and sets its own limits, it returns the limits now in effect
+func (server *Server) updateAccountLimits(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		abortWithBindingError(ctx, err)
+		return
+	}
+
+	var req updateAccountLimitsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		abortWithBindingError(ctx, err)
+		return
+	}
+
+	arg := db.UpdateAccountTransferLimitsParams{
+		AccountID:       uri.ID,
+		Tier:            req.Tier,
+		MaxSingleAmount: req.MaxSingleAmount,
+		MaxDailyAmount:  req.MaxDailyAmount,
+		MaxDailyCount:   req.MaxDailyCount,
+	}
+
+	limits, err := server.store.UpdateAccountTransferLimitsTx(ctx, arg)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, limits)
+}
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simple-bank/apperror"
+	mockdb "github.com/techschool/simple-bank/db2/mock"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/utils"
+	"go.uber.org/mock/gomock"
+)
+
+func TestTransferLimitAPI(t *testing.T) {
+	accountID := utils.RandomInt(1, 1000)
+	limits := db.GetEffectiveTransferLimitsRow{
+		AccountID:       accountID,
+		Tier:            db.DefaultLimitTier,
+		MaxSingleAmount: 1000,
+		MaxDailyAmount:  5000,
+		MaxDailyCount:   10,
+	}
+	tier := db.TransferLimitTier{
+		Name:            "premium",
+		MaxSingleAmount: 10000,
+		MaxDailyAmount:  50000,
+		MaxDailyCount:   100,
+	}
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "GetAccountLimits",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/admin/accounts/%d/limits", accountID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetEffectiveTransferLimits(gomock.Any(), gomock.Eq(accountID)).Times(1).Return(limits, nil)
+				// the usage of the current day
+				today := gomock.Cond(func(arg db.GetDailyOutgoingTransfersParams) bool {
+					return arg.FromAccountID == accountID && time.Since(arg.Day) < time.Minute
+				})
+				store.EXPECT().GetDailyOutgoingTransfers(gomock.Any(), today).Times(1).
+					Return(db.GetDailyOutgoingTransfersRow{TotalAmount: 1200, TotalCount: 3}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp accountLimitsResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, limits, rsp.GetEffectiveTransferLimitsRow)
+				require.Equal(t, int64(1200), rsp.UsedTodayAmount)
+				require.Equal(t, int32(3), rsp.UsedTodayCount)
+			},
+		},
+		{
+			name:   "GetAccountLimitsNotFound",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/admin/accounts/%d/limits", accountID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetEffectiveTransferLimits(gomock.Any(), gomock.Eq(accountID)).Times(1).
+					Return(db.GetEffectiveTransferLimitsRow{}, pgx.ErrNoRows)
+				store.EXPECT().GetDailyOutgoingTransfers(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "UpdateAccountLimits",
+			method: http.MethodPut,
+			url:    fmt.Sprintf("/admin/accounts/%d/limits", accountID),
+			body: gin.H{
+				"tier":             db.DefaultLimitTier,
+				"max_daily_amount": 5000,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				maxDailyAmount := int64(5000)
+				arg := db.UpdateAccountTransferLimitsParams{
+					AccountID:      accountID,
+					Tier:           db.DefaultLimitTier,
+					MaxDailyAmount: &maxDailyAmount,
+				}
+				store.EXPECT().UpdateAccountTransferLimitsTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(limits, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp db.GetEffectiveTransferLimitsRow
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, limits, rsp)
+			},
+		},
+		{
+			name:   "UpdateAccountLimitsUnknownTier",
+			method: http.MethodPut,
+			url:    fmt.Sprintf("/admin/accounts/%d/limits", accountID),
+			body:   gin.H{"tier": "gold"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateAccountTransferLimitsTx(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.GetEffectiveTransferLimitsRow{}, &db.Error{Kind: db.ErrForeignKeyViolation})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+				requireErrorBody(t, recorder, apperror.CodeInvalidReference)
+			},
+		},
+		{
+			name:   "UpdateAccountLimitsNegative",
+			method: http.MethodPut,
+			url:    fmt.Sprintf("/admin/accounts/%d/limits", accountID),
+			body:   gin.H{"tier": db.DefaultLimitTier, "max_daily_count": -1},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateAccountTransferLimitsTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				body := requireErrorBody(t, recorder, apperror.CodeInvalidArgument)
+				require.Contains(t, fmt.Sprint(body.Details), "max_daily_count")
+			},
+		},
+		{
+			name:   "ListLimitTiers",
+			method: http.MethodGet,
+			url:    "/admin/limit_tiers",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListTransferLimitTiers(gomock.Any()).Times(1).Return([]db.TransferLimitTier{tier}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Body.String(), tier.Name)
+			},
+		},
+		{
+			name:   "UpsertLimitTier",
+			method: http.MethodPut,
+			url:    "/admin/limit_tiers/" + tier.Name,
+			body: gin.H{
+				"max_single_amount": tier.MaxSingleAmount,
+				"max_daily_amount":  tier.MaxDailyAmount,
+				"max_daily_count":   tier.MaxDailyCount,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.UpsertTransferLimitTierParams{
+					Name:            tier.Name,
+					MaxSingleAmount: tier.MaxSingleAmount,
+					MaxDailyAmount:  tier.MaxDailyAmount,
+					MaxDailyCount:   tier.MaxDailyCount,
+				}
+				store.EXPECT().UpsertTransferLimitTierTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(tier, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "UpsertLimitTierDailyBelowSingle",
+			method: http.MethodPut,
+			url:    "/admin/limit_tiers/" + tier.Name,
+			body: gin.H{
+				"max_single_amount": 1000,
+				"max_daily_amount":  500,
+				"max_daily_count":   10,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertTransferLimitTierTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			var body bytes.Buffer
+			if tc.body != nil {
+				require.NoError(t, json.NewEncoder(&body).Encode(tc.body))
+			}
+
+			request, err := http.NewRequest(tc.method, tc.url, &body)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, "Bearer "+testAdminToken)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+)
+
+type listPendingTransfersRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=100"`
+}
+
+// listPendingTransfers returns the transfers waiting for review, oldest first
+func (server *Server) listPendingTransfers(ctx *gin.Context) {
+	var req listPendingTransfersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		abortWithBindingError(ctx, err)
+		return
+	}
+
+	arg := db.ListPendingTransfersParams{
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	}
+
+	transfers, err := server.store.ListPendingTransfers(ctx, arg)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, transfers)
+}
+
+type transferIDRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// the note is kept with the transfer, a rejection must say why, an approval may have no body at all
+type approveTransferRequest struct {
+	Note string `json:"note" binding:"max=500"`
+}
+
+type rejectTransferRequest struct {
+	Note string `json:"note" binding:"required,max=500"`
+}
+
+// approveTransfer completes a transfer held for review, the money moves now
+func (server *Server) approveTransfer(ctx *gin.Context) {
+	var uri transferIDRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		abortWithBindingError(ctx, err)
+		return
+	}
+
+	var req approveTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) { // io.EOF: no body, the note is optional
+		abortWithBindingError(ctx, err)
+		return
+	}
+
+	result, err := server.store.ApproveTransferTx(ctx, db.ReviewTransferTxParams{TransferID: uri.ID, Note: req.Note})
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// rejectTransfer refuses a transfer held for review
+func (server *Server) rejectTransfer(ctx *gin.Context) {
+	var uri transferIDRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		abortWithBindingError(ctx, err)
+		return
+	}
+
+	var req rejectTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		abortWithBindingError(ctx, err)
+		return
+	}
+
+	transfer, err := server.store.RejectTransferTx(ctx, db.ReviewTransferTxParams{TransferID: uri.ID, Note: req.Note})
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, transfer)
+}
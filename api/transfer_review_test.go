@@ -0,0 +1,154 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simple-bank/apperror"
+	mockdb "github.com/techschool/simple-bank/db2/mock"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/utils"
+	"go.uber.org/mock/gomock"
+)
+
+func TestTransferReviewAPI(t *testing.T) {
+	transfer := db.Transfer{
+		ID:            utils.RandomInt(1, 1000),
+		FromAccountID: utils.RandomInt(1, 1000),
+		ToAccountID:   utils.RandomInt(1, 1000),
+		Amount:        utils.RandomMoney(),
+		Status:        db.TransferStatusPendingReview,
+		ScreeningRule: "large_first_payment",
+	}
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "ListPending",
+			method: http.MethodGet,
+			url:    "/admin/transfers/pending?page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListPendingTransfersParams{Limit: 5, Offset: 0}
+				store.EXPECT().ListPendingTransfers(gomock.Any(), gomock.Eq(arg)).Times(1).Return([]db.Transfer{transfer}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var transfers []db.Transfer
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &transfers))
+				require.Equal(t, []db.Transfer{transfer}, transfers)
+			},
+		},
+		{
+			name:   "ApproveWithoutBody",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/admin/transfers/%d/approve", transfer.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ReviewTransferTxParams{TransferID: transfer.ID}
+				completed := transfer
+				completed.Status = db.TransferStatusCompleted
+				store.EXPECT().ApproveTransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).
+					Return(db.TransferTxResult{Transfer: completed, FromAccount: db.Account{Currency: "USD"}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp db.TransferTxResult
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, db.TransferStatusCompleted, rsp.Transfer.Status)
+			},
+		},
+		{
+			name:   "ApproveAlreadyReviewed",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/admin/transfers/%d/approve", transfer.ID),
+			body:   gin.H{"note": "checked with the customer"},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ReviewTransferTxParams{TransferID: transfer.ID, Note: "checked with the customer"}
+				notPending := &db.Error{Kind: db.ErrTransferNotPending, Details: map[string]any{"status": db.TransferStatusRejected}}
+				store.EXPECT().ApproveTransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(db.TransferTxResult{}, notPending)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				requireErrorBody(t, recorder, apperror.CodeNotPending)
+			},
+		},
+		{
+			name:   "ApproveInsufficientFunds",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/admin/transfers/%d/approve", transfer.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ApproveTransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, db.ErrInsufficientFunds)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+				requireErrorBody(t, recorder, apperror.CodeInsufficientFunds)
+			},
+		},
+		{
+			name:   "Reject",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/admin/transfers/%d/reject", transfer.ID),
+			body:   gin.H{"note": "customer did not make this transfer"},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ReviewTransferTxParams{TransferID: transfer.ID, Note: "customer did not make this transfer"}
+				rejected := transfer
+				rejected.Status = db.TransferStatusRejected
+				store.EXPECT().RejectTransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(rejected, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "RejectWithoutNote",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/admin/transfers/%d/reject", transfer.ID),
+			body:   gin.H{},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().RejectTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			var body bytes.Buffer
+			if tc.body != nil {
+				require.NoError(t, json.NewEncoder(&body).Encode(tc.body))
+			}
+
+			request, err := http.NewRequest(tc.method, tc.url, &body)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, "Bearer "+testAdminToken)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
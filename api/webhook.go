@@ -0,0 +1,244 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/techschool/simple-bank/apperror"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/webhook"
+)
+
+// webhookSecretBytes is the size of the secrets generated when the caller doesn't provide one
+const webhookSecretBytes = 32
+
+// the event types a webhook can subscribe to are the outbox event types
+type createWebhookRequest struct {
+	URL        string   `json:"url" binding:"required,url"`
+	EventTypes []string `json:"event_types" binding:"required,min=1,dive,oneof=AccountCreated TransferCompleted BalanceChanged AccountStatusChanged"`
+	Secret     string   `json:"secret" binding:"omitempty,min=16"`
+}
+
+// webhookResponse never includes the secret, except right after creation
+type webhookResponse struct {
+	ID         int64     `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+	Secret     string    `json:"secret,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func newWebhookResponse(webhook db.Webhook) webhookResponse {
+	return webhookResponse{
+		ID:         webhook.ID,
+		URL:        webhook.Url,
+		EventTypes: webhook.EventTypes,
+		Active:     webhook.Active,
+		CreatedAt:  webhook.CreatedAt,
+	}
+}
+
+func (server *Server) createWebhook(ctx *gin.Context) {
+	var req createWebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		abortWithBindingError(ctx, err)
+		return
+	}
+
+	if err := server.validateWebhookURL(ctx, req.URL); err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		var err error
+		secret, err = randomSecret()
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+	}
+
+	arg := db.CreateWebhookParams{
+		Url:        req.URL,
+		EventTypes: req.EventTypes,
+		Secret:     secret,
+	}
+
+	webhook, err := server.store.CreateWebhookTx(ctx, arg)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	// this is the only time the secret is returned, the receiver needs it to verify signatures
+	rsp := newWebhookResponse(webhook)
+	rsp.Secret = webhook.Secret
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type webhookIDRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (server *Server) getWebhook(ctx *gin.Context) {
+	var req webhookIDRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		abortWithBindingError(ctx, err)
+		return
+	}
+
+	webhook, err := server.store.GetWebhook(ctx, req.ID)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newWebhookResponse(webhook))
+}
+
+type listWebhooksRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+func (server *Server) listWebhooks(ctx *gin.Context) {
+	var req listWebhooksRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		abortWithBindingError(ctx, err)
+		return
+	}
+
+	arg := db.ListWebhooksParams{
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	}
+
+	webhooks, err := server.store.ListWebhooks(ctx, arg)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	rsp := make([]webhookResponse, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		rsp = append(rsp, newWebhookResponse(webhook))
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// active is a pointer so that "false" passes the required check
+type updateWebhookRequest struct {
+	URL        string   `json:"url" binding:"required,url"`
+	EventTypes []string `json:"event_types" binding:"required,min=1,dive,oneof=AccountCreated TransferCompleted BalanceChanged AccountStatusChanged"`
+	Active     *bool    `json:"active" binding:"required"`
+}
+
+func (server *Server) updateWebhook(ctx *gin.Context) {
+	var uri webhookIDRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		abortWithBindingError(ctx, err)
+		return
+	}
+
+	var req updateWebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		abortWithBindingError(ctx, err)
+		return
+	}
+	if err := server.validateWebhookURL(ctx, req.URL); err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	arg := db.UpdateWebhookParams{
+		ID:         uri.ID,
+		Url:        req.URL,
+		EventTypes: req.EventTypes,
+		Active:     *req.Active,
+	}
+
+	webhook, err := server.store.UpdateWebhookTx(ctx, arg)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newWebhookResponse(webhook))
+}
+
+func (server *Server) deleteWebhook(ctx *gin.Context) {
+	var req webhookIDRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		abortWithBindingError(ctx, err)
+		return
+	}
+
+	err := server.store.DeleteWebhookTx(ctx, req.ID)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+type listWebhookDeliveriesRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=100"`
+}
+
+// listWebhookDeliveries returns the delivery log of a webhook, newest delivery first
+func (server *Server) listWebhookDeliveries(ctx *gin.Context) {
+	var uri webhookIDRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		abortWithBindingError(ctx, err)
+		return
+	}
+
+	var req listWebhookDeliveriesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		abortWithBindingError(ctx, err)
+		return
+	}
+
+	arg := db.ListWebhookDeliveriesParams{
+		WebhookID: uri.ID,
+		Limit:     req.PageSize,
+		Offset:    (req.PageID - 1) * req.PageSize,
+	}
+
+	deliveries, err := server.store.ListWebhookDeliveries(ctx, arg)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, deliveries)
+}
+
+// validateWebhookURL rejects the URLs the worker must not post to, see webhook.ValidateURL
+// the message leaves out what the host resolved to, the error keeps it for the logs
+func (server *Server) validateWebhookURL(ctx context.Context, url string) error {
+	err := webhook.ValidateURL(ctx, server.webhookResolver, url, server.config.WebhookAllowPrivate)
+	if err != nil {
+		return apperror.Wrap(apperror.CodeInvalidArgument, webhook.ErrForbiddenURL.Error(), err).
+			WithDetails([]apperror.FieldViolation{{Field: "url", Reason: "public_url"}})
+	}
+	return nil
+}
+
+// randomSecret generates a hex encoded secret from the OS random source
+func randomSecret() (string, error) {
+	buf := make([]byte, webhookSecretBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
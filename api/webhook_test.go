@@ -0,0 +1,255 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simple-bank/apperror"
+	mockdb "github.com/techschool/simple-bank/db2/mock"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/utils"
+	"go.uber.org/mock/gomock"
+)
+
+func randomWebhook() db.Webhook {
+	return db.Webhook{
+		ID:         utils.RandomInt(1, 1000),
+		Url:        "https://partner.example.com/hooks",
+		EventTypes: []string{db.EventTransferCompleted},
+		Secret:     utils.RandomString(32),
+		Active:     true,
+	}
+}
+
+func TestWebhookAPI(t *testing.T) {
+	webhook := randomWebhook()
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "CreateOK",
+			method: http.MethodPost,
+			url:    "/webhooks",
+			body: gin.H{
+				"url":         webhook.Url,
+				"event_types": webhook.EventTypes,
+				"secret":      webhook.Secret,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreateWebhookParams{
+					Url:        webhook.Url,
+					EventTypes: webhook.EventTypes,
+					Secret:     webhook.Secret,
+				}
+				store.EXPECT().CreateWebhookTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(webhook, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp webhookResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, webhook.ID, rsp.ID)
+				require.Equal(t, webhook.Secret, rsp.Secret)
+			},
+		},
+		{
+			name:   "CreateGeneratesSecret",
+			method: http.MethodPost,
+			url:    "/webhooks",
+			body: gin.H{
+				"url":         webhook.Url,
+				"event_types": webhook.EventTypes,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateWebhookTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateWebhookParams) (db.Webhook, error) {
+						require.Len(t, arg.Secret, 2*webhookSecretBytes)
+						return webhook, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "CreateUnknownEventType",
+			method: http.MethodPost,
+			url:    "/webhooks",
+			body: gin.H{
+				"url":         webhook.Url,
+				"event_types": []string{"AccountDeleted"},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhookTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "CreateMetadataURL",
+			method: http.MethodPost,
+			url:    "/webhooks",
+			body: gin.H{
+				"url":         "http://169.254.169.254/latest/meta-data",
+				"event_types": webhook.EventTypes,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhookTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				body := requireErrorBody(t, recorder, apperror.CodeInvalidArgument)
+				require.Equal(t, []any{map[string]any{"field": "url", "reason": "public_url"}}, body.Details)
+				require.NotContains(t, body.Message, "169.254.169.254")
+			},
+		},
+		{
+			name:   "CreateUnresolvableHost",
+			method: http.MethodPost,
+			url:    "/webhooks",
+			body: gin.H{
+				"url":         "https://unknown.example.com/hooks",
+				"event_types": webhook.EventTypes,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhookTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "GetHidesSecret",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/webhooks/%d", webhook.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhook(gomock.Any(), gomock.Eq(webhook.ID)).Times(1).Return(webhook, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.NotContains(t, recorder.Body.String(), webhook.Secret)
+			},
+		},
+		{
+			name:   "GetNotFound",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/webhooks/%d", webhook.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhook(gomock.Any(), gomock.Eq(webhook.ID)).Times(1).Return(db.Webhook{}, pgx.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "UpdateOK",
+			method: http.MethodPut,
+			url:    fmt.Sprintf("/webhooks/%d", webhook.ID),
+			body: gin.H{
+				"url":         webhook.Url,
+				"event_types": []string{db.EventTransferCompleted, db.EventBalanceChanged},
+				"active":      false,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.UpdateWebhookParams{
+					ID:         webhook.ID,
+					Url:        webhook.Url,
+					EventTypes: []string{db.EventTransferCompleted, db.EventBalanceChanged},
+					Active:     false,
+				}
+				store.EXPECT().UpdateWebhookTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(webhook, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "UpdateLoopbackURL",
+			method: http.MethodPut,
+			url:    fmt.Sprintf("/webhooks/%d", webhook.ID),
+			body: gin.H{
+				"url":         "http://127.0.0.1:8080/admin",
+				"event_types": webhook.EventTypes,
+				"active":      true,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateWebhookTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorBody(t, recorder, apperror.CodeInvalidArgument)
+			},
+		},
+		{
+			name:   "DeleteOK",
+			method: http.MethodDelete,
+			url:    fmt.Sprintf("/webhooks/%d", webhook.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().DeleteWebhookTx(gomock.Any(), gomock.Eq(webhook.ID)).Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+		{
+			name:   "ListDeliveries",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/webhooks/%d/deliveries?page_id=2&page_size=5", webhook.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListWebhookDeliveriesParams{
+					WebhookID: webhook.ID,
+					Limit:     5,
+					Offset:    5,
+				}
+				deliveries := []db.WebhookDelivery{{ID: 1, WebhookID: webhook.ID, Status: db.WebhookDeliveryDead}}
+				store.EXPECT().ListWebhookDeliveries(gomock.Any(), gomock.Eq(arg)).Times(1).Return(deliveries, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Body.String(), db.WebhookDeliveryDead)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			var body bytes.Buffer
+			if tc.body != nil {
+				require.NoError(t, json.NewEncoder(&body).Encode(tc.body))
+			}
+
+			request, err := http.NewRequest(tc.method, tc.url, &body)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, "Bearer "+testAdminToken)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
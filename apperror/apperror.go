@@ -0,0 +1,172 @@
+package apperror
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Code identifies a class of errors in API responses, clients can switch on it
+type Code string
+
+const (
+	CodeInvalidArgument   Code = "invalid_argument"
+	CodeUnauthenticated   Code = "unauthenticated"
+	CodePermissionDenied  Code = "permission_denied"
+	CodeNotFound          Code = "not_found"
+	CodeAlreadyExists     Code = "already_exists"
+	CodeInvalidReference  Code = "invalid_reference"
+	CodeInsufficientFunds Code = "insufficient_funds"
+	CodeAccountFrozen     Code = "account_frozen"
+	CodeEquityAccount     Code = "equity_account"
+	CodeLimitExceeded     Code = "limit_exceeded"
+	CodeTransferDenied    Code = "transfer_denied"
+	CodeNotPending        Code = "not_pending"
+	CodeAlreadyResolved   Code = "already_resolved"
+	CodeRateLimited       Code = "rate_limited"
+	CodeUnavailable       Code = "unavailable"
+	CodeInternal          Code = "internal"
+)
+
+// table is the single place deciding how an error is reported over HTTP and gRPC
+// kind is the db error mapped to the code, nil for codes that are only raised by the API layers
+// invalid_argument is mostly raised by the request validation, the check constraints catch what it misses
+var table = []struct {
+	code       Code
+	kind       error
+	httpStatus int
+	grpcCode   codes.Code
+}{
+	{CodeInvalidArgument, db.ErrCheckViolation, http.StatusBadRequest, codes.InvalidArgument},
+	{CodeUnauthenticated, nil, http.StatusUnauthorized, codes.Unauthenticated},
+	{CodePermissionDenied, nil, http.StatusForbidden, codes.PermissionDenied},
+	{CodeNotFound, db.ErrNotFound, http.StatusNotFound, codes.NotFound},
+	{CodeAlreadyExists, db.ErrUniqueViolation, http.StatusConflict, codes.AlreadyExists},
+	{CodeInvalidReference, db.ErrForeignKeyViolation, http.StatusUnprocessableEntity, codes.FailedPrecondition},
+	{CodeInsufficientFunds, db.ErrInsufficientFunds, http.StatusUnprocessableEntity, codes.FailedPrecondition},
+	{CodeAccountFrozen, db.ErrAccountFrozen, http.StatusUnprocessableEntity, codes.FailedPrecondition},
+	{CodeEquityAccount, db.ErrEquityAccount, http.StatusUnprocessableEntity, codes.FailedPrecondition},
+	{CodeLimitExceeded, db.ErrLimitExceeded, http.StatusUnprocessableEntity, codes.FailedPrecondition},
+	{CodeTransferDenied, db.ErrTransferDenied, http.StatusUnprocessableEntity, codes.FailedPrecondition},
+	{CodeNotPending, db.ErrTransferNotPending, http.StatusConflict, codes.FailedPrecondition},
+	{CodeAlreadyResolved, db.ErrAlertResolved, http.StatusConflict, codes.FailedPrecondition},
+	{CodeRateLimited, nil, http.StatusTooManyRequests, codes.ResourceExhausted},
+	{CodeUnavailable, nil, http.StatusServiceUnavailable, codes.Unavailable},
+	{CodeInternal, nil, http.StatusInternalServerError, codes.Internal},
+}
+
+// internalMessage replaces the message of unexpected errors, which may contain driver or SQL details
+const internalMessage = "internal server error"
+
+// Error is an error whose code, message and details are safe to return to clients
+type Error struct {
+	Code    Code
+	Message string
+	Details any
+	Err     error // the underlying error, for logs only
+}
+
+// New creates an error with the given code and message
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Wrap creates an error with the given code, keeping err for logs
+func Wrap(code Code, message string, err error) *Error {
+	return &Error{Code: code, Message: message, Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WithDetails returns a copy of the error carrying details
+func (e *Error) WithDetails(details any) *Error {
+	copied := *e
+	copied.Details = details
+	return &copied
+}
+
+// HTTPStatus returns the HTTP status code of the error
+func (e *Error) HTTPStatus() int {
+	for _, row := range table {
+		if row.code == e.Code {
+			return row.httpStatus
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// GRPCStatus returns the gRPC status of the error, grpc-go uses it when a handler returns an *Error
+func (e *Error) GRPCStatus() *status.Status {
+	grpcCode := codes.Internal
+	for _, row := range table {
+		if row.code == e.Code {
+			grpcCode = row.grpcCode
+		}
+	}
+	return status.New(grpcCode, e.Message)
+}
+
+// From converts any error into an *Error
+// db error kinds are looked up in the table, anything unknown becomes an internal error with a generic message
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	err = db.ClassifyError(err)
+
+	for _, row := range table {
+		if row.kind == nil || !errors.Is(err, row.kind) {
+			continue
+		}
+
+		appErr := Wrap(row.code, row.kind.Error(), err)
+		var dbErr *db.Error
+		if errors.As(err, &dbErr) && dbErr.Details != nil {
+			appErr.Details = dbErr.Details
+		}
+		return appErr
+	}
+
+	return Wrap(CodeInternal, internalMessage, err)
+}
+
+// FieldViolation describes why one field of a request is invalid
+type FieldViolation struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// InvalidArgument converts a request binding error into an invalid_argument error
+// validation errors get one FieldViolation per field, other errors (e.g. malformed JSON) keep their message
+func InvalidArgument(err error) *Error {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return Wrap(CodeInvalidArgument, err.Error(), err)
+	}
+
+	violations := make([]FieldViolation, 0, len(validationErrs))
+	for _, fieldErr := range validationErrs {
+		reason := fieldErr.Tag()
+		if fieldErr.Param() != "" {
+			reason += "=" + fieldErr.Param()
+		}
+		violations = append(violations, FieldViolation{Field: fieldErr.Field(), Reason: reason})
+	}
+
+	return &Error{Code: CodeInvalidArgument, Message: "invalid request", Details: violations, Err: err}
+}
@@ -0,0 +1,149 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFrom(t *testing.T) {
+	testCases := []struct {
+		name       string
+		err        error
+		code       Code
+		httpStatus int
+		grpcCode   codes.Code
+	}{
+		{
+			name:       "NoRows",
+			err:        fmt.Errorf("get account: %w", pgx.ErrNoRows),
+			code:       CodeNotFound,
+			httpStatus: http.StatusNotFound,
+			grpcCode:   codes.NotFound,
+		},
+		{
+			name:       "UniqueViolation",
+			err:        &pgconn.PgError{Code: db.UniqueViolation, ConstraintName: "owner_currency_key", Message: "duplicate key value (owner)=(alice)"},
+			code:       CodeAlreadyExists,
+			httpStatus: http.StatusConflict,
+			grpcCode:   codes.AlreadyExists,
+		},
+		{
+			name:       "ForeignKeyViolation",
+			err:        &pgconn.PgError{Code: db.ForeignKeyViolation},
+			code:       CodeInvalidReference,
+			httpStatus: http.StatusUnprocessableEntity,
+			grpcCode:   codes.FailedPrecondition,
+		},
+		{
+			name:       "CheckViolation",
+			err:        &pgconn.PgError{Code: db.CheckViolation, ConstraintName: "transfers_amount_positive", Message: "failing row contains (alice)"},
+			code:       CodeInvalidArgument,
+			httpStatus: http.StatusBadRequest,
+			grpcCode:   codes.InvalidArgument,
+		},
+		{
+			name:       "NotNullViolation",
+			err:        &pgconn.PgError{Code: db.NotNullViolation, ColumnName: "created_at"},
+			code:       CodeInvalidArgument,
+			httpStatus: http.StatusBadRequest,
+			grpcCode:   codes.InvalidArgument,
+		},
+		{
+			name:       "InsufficientFunds",
+			err:        db.ErrInsufficientFunds,
+			code:       CodeInsufficientFunds,
+			httpStatus: http.StatusUnprocessableEntity,
+			grpcCode:   codes.FailedPrecondition,
+		},
+		{
+			name:       "AccountFrozen",
+			err:        &db.Error{Kind: db.ErrAccountFrozen},
+			code:       CodeAccountFrozen,
+			httpStatus: http.StatusUnprocessableEntity,
+			grpcCode:   codes.FailedPrecondition,
+		},
+		{
+			name:       "LimitExceeded",
+			err:        &db.Error{Kind: db.ErrLimitExceeded, Details: map[string]any{"limit": db.LimitDailyAmount}},
+			code:       CodeLimitExceeded,
+			httpStatus: http.StatusUnprocessableEntity,
+			grpcCode:   codes.FailedPrecondition,
+		},
+		{
+			name:       "TransferNotPending",
+			err:        &db.Error{Kind: db.ErrTransferNotPending},
+			code:       CodeNotPending,
+			httpStatus: http.StatusConflict,
+			grpcCode:   codes.FailedPrecondition,
+		},
+		{
+			name:       "AlertResolved",
+			err:        &db.Error{Kind: db.ErrAlertResolved},
+			code:       CodeAlreadyResolved,
+			httpStatus: http.StatusConflict,
+			grpcCode:   codes.FailedPrecondition,
+		},
+		{
+			name:       "AppError",
+			err:        New(CodeUnauthenticated, "invalid token"),
+			code:       CodeUnauthenticated,
+			httpStatus: http.StatusUnauthorized,
+			grpcCode:   codes.Unauthenticated,
+		},
+		{
+			name:       "Unknown",
+			err:        errors.New("pq: password authentication failed for user root"),
+			code:       CodeInternal,
+			httpStatus: http.StatusInternalServerError,
+			grpcCode:   codes.Internal,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			appErr := From(tc.err)
+			require.Equal(t, tc.code, appErr.Code)
+			require.Equal(t, tc.httpStatus, appErr.HTTPStatus())
+			require.Equal(t, tc.grpcCode, status.Code(appErr))
+
+			// the message goes to clients, it must never carry the driver text
+			require.NotContains(t, appErr.Message, "pq:")
+			require.NotContains(t, appErr.Message, "alice")
+		})
+	}
+}
+
+func TestFromKeepsDetails(t *testing.T) {
+	err := &pgconn.PgError{Code: db.UniqueViolation, ConstraintName: "owner_currency_key"}
+
+	appErr := From(err)
+	require.Equal(t, map[string]any{"constraint": "owner_currency_key"}, appErr.Details)
+	require.ErrorIs(t, appErr, db.ErrUniqueViolation)
+}
+
+func TestFromKeepsConstraintOfCheckViolation(t *testing.T) {
+	appErr := From(&pgconn.PgError{Code: db.CheckViolation, ConstraintName: "accounts_currency_format", ColumnName: ""})
+	require.Equal(t, map[string]any{"constraint": "accounts_currency_format"}, appErr.Details)
+	require.ErrorIs(t, appErr, db.ErrCheckViolation)
+
+	appErr = From(&pgconn.PgError{Code: db.NotNullViolation, ColumnName: "created_at"})
+	require.Equal(t, map[string]any{"column": "created_at"}, appErr.Details)
+}
+
+func TestTableHasNoDuplicateCodes(t *testing.T) {
+	seen := map[Code]bool{}
+	for _, row := range table {
+		require.False(t, seen[row.code], "code %s is mapped twice", row.code)
+		seen[row.code] = true
+	}
+}
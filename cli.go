@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/techschool/simple-bank/db2/migration"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/fraud"
+	"github.com/techschool/simple-bank/logging"
+	"github.com/techschool/simple-bank/seed"
+	"github.com/techschool/simple-bank/utils"
+)
+
+// cliActorPrefix is put before the operator name in the audit log, e.g. "cli:jdoe",
+// so changes made with the commands are told apart from the ones made through the API
+const cliActorPrefix = "cli:"
+
+// cli holds what the commands share, the root command loads config before any of them runs
+type cli struct {
+	config   utils.Config
+	operator string // --operator, recorded as the actor of every change
+
+	// openStore connects to the database for the one-off commands, the tests replace it with a mock store
+	openStore func(config utils.Config) (db.Store, func(), error)
+	// openDater connects the seed command to the database to date its rows, the tests replace it as well
+	openDater func(config utils.Config) (seed.Dater, func(), error)
+}
+
+func newCLI() *cli {
+	return &cli{openStore: openStore, openDater: openDater}
+}
+
+// newRootCommand builds the simple-bank command and its subcommands
+func newRootCommand(app *cli) *cobra.Command {
+	root := &cobra.Command{
+		Use:          "simple-bank",
+		Short:        "Simple bank server and operations tool",
+		SilenceUsage: true, // a command that fails prints its error, not the usage
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			config, err := utils.LoadConfig(".", cmd.Flags()) // because config file is in same directory as main.go
+			if err != nil {
+				return err
+			}
+
+			// the server logs to stdout for the log collectors, the other commands keep stdout for their output
+			logOutput := cmd.ErrOrStderr()
+			if cmd.Name() == "serve" {
+				logOutput = os.Stdout
+			}
+			logger, err := logging.New(logOutput, config.LogLevel, config.LogFormat)
+			if err != nil {
+				return err
+			}
+			slog.SetDefault(logger)
+
+			app.config = config
+			return nil
+		},
+	}
+
+	// every setting but the secrets can be overridden on the command line, e.g. --environment=prod
+	utils.RegisterFlags(root.PersistentFlags())
+	root.PersistentFlags().StringVar(&app.operator, "operator", os.Getenv("USER"),
+		"name recorded in the audit log as the author of the changes")
+
+	root.AddCommand(
+		newServeCommand(app),
+		newMigrateCommand(app),
+		newAccountsCommand(app),
+		newTransferCommand(app),
+		newReconcileCommand(app),
+		newTokenCommand(app),
+		newSeedCommand(app),
+		newLoadtestCommand(app),
+	)
+	return root
+}
+
+func newServeCommand(app *cli) *cobra.Command {
+	return &cobra.Command{
+		Use:   "serve",
+		Short: "Run the HTTP and gRPC servers and the background workers",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			serve(app.config)
+		},
+	}
+}
+
+func newMigrateCommand(app *cli) *cobra.Command {
+	return &cobra.Command{
+		Use:   "migrate up [N] | down [N] | version | force V",
+		Short: "Apply or revert the embedded database migrations",
+		Long: "Apply or revert the embedded database migrations.\n" +
+			"force takes -1 for an empty schema, give it after -- so that it isn't read as a flag.",
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, err := migration.New(app.config.DBSource)
+			if err != nil {
+				return err
+			}
+			defer m.Close()
+
+			return migration.Run(m, args, cmd.OutOrStdout())
+		},
+	}
+}
+
+// openStore connects to the primary database, the returned function closes the connections
+// the commands read from the primary too, what they print is never behind a replica
+func openStore(config utils.Config) (db.Store, func(), error) {
+	var storeOptions []db.StoreOption
+	screener, err := loadScreener(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	if screener != nil {
+		storeOptions = append(storeOptions, db.WithScreener(screener))
+	}
+
+	conn, err := openDatabase(config, config.DBSource)
+	if err != nil {
+		return nil, nil, err
+	}
+	return db.NewStore(conn, storeOptions...), conn.Close, nil
+}
+
+// loadScreener loads the fraud rules of config, it returns nil if there are none
+func loadScreener(config utils.Config) (fraud.Screener, error) {
+	if config.FraudRulesPath == "" {
+		return nil, nil
+	}
+	engine, err := fraud.LoadRules(config.FraudRulesPath)
+	if err != nil {
+		return nil, err
+	}
+	return engine, nil
+}
+
+// withStore opens the store for the duration of fn
+func (app *cli) withStore(fn func(store db.Store) error) error {
+	store, closeStore, err := app.openStore(app.config)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	return fn(store)
+}
+
+// auditContext returns a copy of ctx whose changes are recorded in the audit log as made by the operator,
+// commands that change something must not run without one
+func (app *cli) auditContext(ctx context.Context) (context.Context, error) {
+	if app.operator == "" {
+		return nil, errors.New("--operator is required, it is recorded in the audit log")
+	}
+	return db.WithAuditInfo(ctx, db.AuditInfo{Actor: cliActorPrefix + app.operator}), nil
+}
+
+// printJSON writes v as indented JSON, the output of the commands is meant for jq as much as for people
+func printJSON(out io.Writer, v any) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
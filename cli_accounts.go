@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+)
+
+// supportedCurrencies are the currencies accepted by the commands, the same as the API
+var supportedCurrencies = []string{"USD", "EUR"}
+
+func newAccountsCommand(app *cli) *cobra.Command {
+	accounts := &cobra.Command{
+		Use:   "accounts",
+		Short: "Create, inspect and freeze accounts",
+	}
+	accounts.AddCommand(
+		newAccountsCreateCommand(app),
+		newAccountsListCommand(app),
+		newAccountsShowCommand(app),
+		newAccountStatusCommand(app, "freeze", db.AccountStatusFrozen, "Freeze an account, it can't send or receive transfers until unfrozen"),
+		newAccountStatusCommand(app, "unfreeze", db.AccountStatusActive, "Unfreeze a frozen account"),
+	)
+	return accounts
+}
+
+func newAccountsCreateCommand(app *cli) *cobra.Command {
+	var arg db.CreateAccountParams
+
+	cmd := &cobra.Command{
+		Use:   "create --owner OWNER --currency CURRENCY",
+		Short: "Create an account",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !slices.Contains(supportedCurrencies, arg.Currency) {
+				return fmt.Errorf("unsupported currency %q, expected one of %v", arg.Currency, supportedCurrencies)
+			}
+			if arg.Balance < 0 {
+				return fmt.Errorf("balance must not be negative, got %d", arg.Balance)
+			}
+			ctx, err := app.auditContext(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			return app.withStore(func(store db.Store) error {
+				// the opening balance is a transfer from the equity account of the currency
+				account, err := store.CreateAccountTx(ctx, arg)
+				if err != nil {
+					return err
+				}
+				return printJSON(cmd.OutOrStdout(), account)
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&arg.Owner, "owner", "", "owner of the account")
+	cmd.Flags().StringVar(&arg.Currency, "currency", "", "currency of the account, USD or EUR")
+	cmd.Flags().Int64Var(&arg.Balance, "balance", 0, "opening balance in minor units")
+	_ = cmd.MarkFlagRequired("owner")
+	_ = cmd.MarkFlagRequired("currency")
+	return cmd
+}
+
+func newAccountsListCommand(app *cli) *cobra.Command {
+	var arg db.ListAccountsParams
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the accounts by id",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if arg.Limit <= 0 || arg.Offset < 0 {
+				return fmt.Errorf("--limit must be positive and --offset must not be negative")
+			}
+
+			return app.withStore(func(store db.Store) error {
+				accounts, err := store.ListAccounts(cmd.Context(), arg)
+				if err != nil {
+					return err
+				}
+				return printJSON(cmd.OutOrStdout(), accounts)
+			})
+		},
+	}
+
+	cmd.Flags().Int32Var(&arg.Limit, "limit", 20, "number of accounts to list")
+	cmd.Flags().Int32Var(&arg.Offset, "offset", 0, "number of accounts to skip")
+	return cmd
+}
+
+func newAccountsShowCommand(app *cli) *cobra.Command {
+	return &cobra.Command{
+		Use:   "show ID",
+		Short: "Show an account",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+
+			return app.withStore(func(store db.Store) error {
+				account, err := store.GetAccount(cmd.Context(), id)
+				if err != nil {
+					return db.ClassifyError(err)
+				}
+				return printJSON(cmd.OutOrStdout(), account)
+			})
+		},
+	}
+}
+
+// newAccountStatusCommand builds freeze and unfreeze, which only differ by the status they set
+func newAccountStatusCommand(app *cli, name string, status string, short string) *cobra.Command {
+	return &cobra.Command{
+		Use:   name + " ID",
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+			ctx, err := app.auditContext(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			return app.withStore(func(store db.Store) error {
+				account, err := store.UpdateAccountStatusTx(ctx, db.UpdateAccountStatusParams{ID: id, Status: status})
+				if err != nil {
+					return err
+				}
+				return printJSON(cmd.OutOrStdout(), account)
+			})
+		},
+	}
+}
+
+// parseID parses the id argument of a command
+func parseID(arg string) (int64, error) {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("invalid id %q", arg)
+	}
+	return id, nil
+}
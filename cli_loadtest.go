@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/spf13/cobra"
+	memdb "github.com/techschool/simple-bank/db2/memory"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/loadtest"
+)
+
+// the targets of the loadtest command
+const (
+	loadtestPostgres = "postgres" // TransferTx on the configured database
+	loadtestMemory   = "memory"   // TransferTx on an in-memory store, the cost of the code without the database
+	loadtestHTTP     = "http"     // POST /transfers on a running server
+)
+
+func newLoadtestCommand(app *cli) *cobra.Command {
+	config := loadtest.Config{Mix: loadtest.MixRandom}
+	var target, url string
+
+	cmd := &cobra.Command{
+		Use:   "loadtest",
+		Short: "Send concurrent transfers and report the throughput, the latencies and the outcomes",
+		Long: "Send concurrent transfers and report the throughput, the latencies and the outcomes.\n" +
+			"The accounts of the run are created first through the database, which checks at the end that their\n" +
+			"ledger still adds up; the command fails if it doesn't. The http target needs a server using the same\n" +
+			"database, with the rate limits of POST /transfers raised. The gRPC API has no transfer method to load.\n" +
+			"The default limit tier allows 50 transfers a day per sender, give --limit-tier for longer runs.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !slices.Contains(supportedCurrencies, config.Currency) {
+				return fmt.Errorf("unsupported currency %q, expected one of %v", config.Currency, supportedCurrencies)
+			}
+			ctx, err := app.auditContext(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			run := func(store db.Store, target loadtest.Target) error {
+				report, err := loadtest.Run(ctx, store, target, config)
+				if err != nil {
+					return err
+				}
+
+				slog.Info("Load test finished",
+					"target", report.Target,
+					"transfers", report.Transfers,
+					"transfers_per_second", report.Throughput,
+				)
+				if err := printJSON(cmd.OutOrStdout(), report); err != nil {
+					return err
+				}
+
+				if !report.Ledger.Consistent {
+					return fmt.Errorf("the ledger of the %d accounts doesn't add up", report.Ledger.Accounts)
+				}
+				return nil
+			}
+
+			switch target {
+			case loadtestMemory:
+				screener, err := loadScreener(app.config)
+				if err != nil {
+					return err
+				}
+				store := memdb.NewStore(memdb.WithScreener(screener))
+				return run(store, loadtest.StoreTarget{Store: store, Label: loadtestMemory})
+
+			case loadtestPostgres:
+				return app.withStore(func(store db.Store) error {
+					return run(store, loadtest.StoreTarget{Store: store, Label: loadtestPostgres})
+				})
+
+			case loadtestHTTP:
+				if url == "" {
+					url = "http://" + app.config.ServerAddress
+				}
+				return app.withStore(func(store db.Store) error {
+					return run(store, loadtest.NewHTTPTarget(url, config.Concurrency))
+				})
+			}
+			return fmt.Errorf("unknown target %q, expected %s, %s or %s", target, loadtestPostgres, loadtestMemory, loadtestHTTP)
+		},
+	}
+
+	cmd.Flags().StringVar(&target, "target", loadtestPostgres, "what the transfers are sent to: postgres, memory or http")
+	cmd.Flags().StringVar(&url, "url", "", "base URL of the server of the http target, SERVER_ADDRESS by default")
+	cmd.Flags().IntVar(&config.Concurrency, "concurrency", 16, "transfers in flight at once")
+	cmd.Flags().DurationVar(&config.Duration, "duration", 0, "how long to send transfers for, e.g. 30s")
+	cmd.Flags().IntVar(&config.Transfers, "transfers", 1000, "number of transfers to send, 0 to only stop after --duration")
+	cmd.Flags().IntVar(&config.Accounts, "accounts", 100, "number of accounts created for the run")
+	cmd.Flags().StringVar(&config.Currency, "currency", "USD", "currency of the accounts, USD or EUR")
+	cmd.Flags().Int64Var(&config.OpeningBalance, "opening-balance", 1000000, "balance of every account at the start, in minor units")
+	cmd.Flags().Int64Var(&config.MaxAmount, "max-amount", 1000, "largest amount of a transfer, in minor units")
+	cmd.Flags().StringVar(&config.Mix, "mix", loadtest.MixRandom, "random spreads the transfers over the accounts, hot sends many to a few")
+	cmd.Flags().IntVar(&config.HotAccounts, "hot-accounts", 2, "number of hot accounts of the hot mix")
+	cmd.Flags().IntVar(&config.HotPct, "hot-pct", 80, "percentage of the transfers of the hot mix that involve a hot account")
+	cmd.Flags().StringVar(&config.LimitTier, "limit-tier", "", "transfer limit tier of the accounts, the default tier if empty")
+	cmd.Flags().Uint64Var(&config.Seed, "seed", 1, "seed of the random choices")
+	return cmd
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+)
+
+func newReconcileCommand(app *cli) *cobra.Command {
+	return &cobra.Command{
+		Use:   "reconcile",
+		Short: "Check that the balances and entries agree with the transfers",
+		Long: "Check that the balances and entries agree with the transfers.\n" +
+			"It prints the accounts that don't add up and fails if there is any, e.g. to alert from a cron job.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return app.withStore(func(store db.Store) error {
+				mismatches, err := store.ReconcileAccounts(cmd.Context())
+				if err != nil {
+					return err
+				}
+				if err := printJSON(cmd.OutOrStdout(), mismatches); err != nil {
+					return err
+				}
+
+				if len(mismatches) > 0 {
+					return fmt.Errorf("%d accounts don't reconcile", len(mismatches))
+				}
+				return nil
+			})
+		},
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/seed"
+	"github.com/techschool/simple-bank/token"
+	"github.com/techschool/simple-bank/utils"
+)
+
+func newSeedCommand(app *cli) *cobra.Command {
+	config := seed.Config{Currencies: supportedCurrencies}
+	var manifestPath, until string
+
+	cmd := &cobra.Command{
+		Use:   "seed",
+		Short: "Fill the database with demo users, accounts and transfers",
+		Long: "Fill the database with demo users, accounts and transfers, all derived from --seed:\n" +
+			"the same seed on an empty database gives the same data. The accounts are opened --history before\n" +
+			"--until and the transfers are dated in between. The manifest lists what was created, with an access\n" +
+			"token per user when TOKEN_SYMMETRIC_KEY is set: the tokens are the only part that changes between runs.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, err := app.auditContext(cmd.Context())
+			if err != nil {
+				return err
+			}
+			config.Until, err = time.Parse(time.RFC3339, until)
+			if err != nil {
+				return fmt.Errorf("invalid --until: %w", err)
+			}
+			if err := seedCredentials(app, &config); err != nil {
+				return err
+			}
+
+			// the manifest is opened first, a typo in the path shouldn't leave an unrecorded seed behind
+			out := cmd.OutOrStdout()
+			if manifestPath != "-" {
+				file, err := os.Create(manifestPath)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				out = file
+			}
+
+			dater, closeDater, err := app.openDater(app.config)
+			if err != nil {
+				return err
+			}
+			defer closeDater()
+
+			return app.withStore(func(store db.Store) error {
+				manifest, err := seed.Run(ctx, store, dater, config)
+				if err != nil {
+					// what was created before the error is still worth listing
+					_ = writeManifest(out, manifest)
+					return err
+				}
+
+				slog.Info("Seeded database",
+					"seed", manifest.Seed,
+					"users", len(manifest.Users),
+					"completed_transfers", len(manifest.Transfers.Completed),
+					"pending_transfers", len(manifest.Transfers.PendingReview),
+				)
+				return writeManifest(out, manifest)
+			})
+		},
+	}
+
+	cmd.Flags().Uint64Var(&config.Seed, "seed", 1, "seed of the random choices")
+	cmd.Flags().IntVar(&config.Users, "users", 10, "number of users, each one gets accounts in one or more currencies")
+	cmd.Flags().IntVar(&config.Transfers, "transfers", 100, "number of transfers to attempt between the accounts")
+	cmd.Flags().Int64Var(&config.MaxBalance, "max-balance", 100000, "largest opening balance, in minor units")
+	cmd.Flags().Int64Var(&config.MaxTransferPct, "max-transfer-pct", 20, "largest share of its balance an account sends at once, in percent")
+	cmd.Flags().DurationVar(&config.History, "history", 30*24*time.Hour, "how long before --until the accounts are opened, the transfers are spread in between")
+	cmd.Flags().StringVar(&until, "until", seed.DefaultUntil.Format(time.RFC3339), "end of the history, RFC 3339")
+	cmd.Flags().DurationVar(&config.TokenDuration, "token-duration", 0, "how long the access tokens of the users are valid, ACCESS_TOKEN_DURATION when zero")
+	cmd.Flags().StringVar(&manifestPath, "manifest", "-", `file the manifest is written to, "-" for stdout`)
+	return cmd
+}
+
+func writeManifest(out io.Writer, manifest seed.Manifest) error {
+	if err := printJSON(out, manifest); err != nil {
+		return fmt.Errorf("cannot write manifest: %w", err)
+	}
+	return nil
+}
+
+// seedCredentials gives config the key of the servers to sign the access tokens of the users with
+// without a key the seed still runs, its manifest just has no credentials
+func seedCredentials(app *cli, config *seed.Config) error {
+	if app.config.TokenSymmetricKey == "" {
+		slog.Warn("TOKEN_SYMMETRIC_KEY is not set, the manifest has no access tokens")
+		return nil
+	}
+	maker, err := token.NewMaker(app.config.TokenSymmetricKey)
+	if err != nil {
+		return err
+	}
+	config.TokenMaker = maker
+	if config.TokenDuration <= 0 {
+		config.TokenDuration = app.config.AccessTokenDuration
+	}
+	return nil
+}
+
+// openDater dates the rows of the seed with a connection of its own, the store doesn't expose its pool
+func openDater(config utils.Config) (seed.Dater, func(), error) {
+	conn, err := openDatabase(config, config.DBSource)
+	if err != nil {
+		return nil, nil, err
+	}
+	return seed.NewSQLDater(conn), conn.Close, nil
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/techschool/simple-bank/db2/mock"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/seed"
+	"github.com/techschool/simple-bank/token"
+	"github.com/techschool/simple-bank/utils"
+	"go.uber.org/mock/gomock"
+)
+
+// runCommand runs simple-bank with args against store and returns what it printed
+func runCommand(t *testing.T, store db.Store, args ...string) (string, error) {
+	app := newCLI()
+	app.openStore = func(config utils.Config) (db.Store, func(), error) {
+		return store, func() {}, nil
+	}
+	// the mock store has no rows to date
+	app.openDater = func(config utils.Config) (seed.Dater, func(), error) {
+		return nil, func() {}, nil
+	}
+
+	root := newRootCommand(app)
+	var out bytes.Buffer
+	root.SetOut(&out)
+	root.SetErr(io.Discard)
+	root.SetArgs(args)
+
+	err := root.ExecuteContext(context.Background())
+	return out.String(), err
+}
+
+// requireActor makes the expected store call check that the change is audited as made by actor
+func requireActor(t *testing.T, actor string) func(ctx context.Context, arg any) {
+	return func(ctx context.Context, arg any) {
+		require.Equal(t, actor, db.AuditInfoFromContext(ctx).Actor)
+	}
+}
+
+func TestAccountsCommands(t *testing.T) {
+	account := db.Account{ID: 7, Owner: utils.RandomOwner(), Currency: "USD", Status: db.AccountStatusActive}
+	frozen := account
+	frozen.Status = db.AccountStatusFrozen
+
+	testCases := []struct {
+		name       string
+		args       []string
+		buildStubs func(store *mockdb.MockStore)
+		check      func(t *testing.T, output string, err error)
+	}{
+		{
+			name: "Create",
+			args: []string{"accounts", "create", "--operator", "jdoe", "--owner", account.Owner, "--currency", "USD", "--balance", "500"},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreateAccountParams{Owner: account.Owner, Currency: "USD", Balance: 500}
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Eq(arg)).Times(1).
+					Do(requireActor(t, "cli:jdoe")).Return(account, nil)
+			},
+			check: func(t *testing.T, output string, err error) {
+				require.NoError(t, err)
+				var got db.Account
+				require.NoError(t, json.Unmarshal([]byte(output), &got))
+				require.Equal(t, account, got)
+			},
+		},
+		{
+			name: "CreateUnsupportedCurrency",
+			args: []string{"accounts", "create", "--operator", "jdoe", "--owner", account.Owner, "--currency", "GBP"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, output string, err error) {
+				require.ErrorContains(t, err, `unsupported currency "GBP"`)
+			},
+		},
+		{
+			name: "CreateWithoutOperator",
+			args: []string{"accounts", "create", "--operator", "", "--owner", account.Owner, "--currency", "USD"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, output string, err error) {
+				require.ErrorContains(t, err, "--operator is required")
+			},
+		},
+		{
+			name: "List",
+			args: []string{"accounts", "list", "--limit", "5", "--offset", "10"},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListAccountsParams{Limit: 5, Offset: 10}
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Eq(arg)).Times(1).Return([]db.Account{account}, nil)
+			},
+			check: func(t *testing.T, output string, err error) {
+				require.NoError(t, err)
+				var got []db.Account
+				require.NoError(t, json.Unmarshal([]byte(output), &got))
+				require.Equal(t, []db.Account{account}, got)
+			},
+		},
+		{
+			name: "ShowNotFound",
+			args: []string{"accounts", "show", "7"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(int64(7))).Times(1).Return(db.Account{}, pgx.ErrNoRows)
+			},
+			check: func(t *testing.T, output string, err error) {
+				require.ErrorIs(t, err, db.ErrNotFound)
+			},
+		},
+		{
+			name: "ShowInvalidID",
+			args: []string{"accounts", "show", "seven"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, output string, err error) {
+				require.ErrorContains(t, err, `invalid id "seven"`)
+			},
+		},
+		{
+			name: "Freeze",
+			args: []string{"accounts", "freeze", "7", "--operator", "jdoe"},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.UpdateAccountStatusParams{ID: 7, Status: db.AccountStatusFrozen}
+				store.EXPECT().UpdateAccountStatusTx(gomock.Any(), gomock.Eq(arg)).Times(1).
+					Do(requireActor(t, "cli:jdoe")).Return(frozen, nil)
+			},
+			check: func(t *testing.T, output string, err error) {
+				require.NoError(t, err)
+				require.Contains(t, output, `"status": "frozen"`)
+			},
+		},
+		{
+			name: "Unfreeze",
+			args: []string{"accounts", "unfreeze", "7", "--operator", "jdoe"},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.UpdateAccountStatusParams{ID: 7, Status: db.AccountStatusActive}
+				store.EXPECT().UpdateAccountStatusTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(account, nil)
+			},
+			check: func(t *testing.T, output string, err error) {
+				require.NoError(t, err)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			output, err := runCommand(t, store, tc.args...)
+			tc.check(t, output, err)
+		})
+	}
+}
+
+func TestTransferCommand(t *testing.T) {
+	from := db.Account{ID: 1, Currency: "USD", Status: db.AccountStatusActive}
+	to := db.Account{ID: 2, Currency: "USD", Status: db.AccountStatusActive}
+	euro := db.Account{ID: 3, Currency: "EUR", Status: db.AccountStatusActive}
+
+	testCases := []struct {
+		name       string
+		args       []string
+		buildStubs func(store *mockdb.MockStore)
+		check      func(t *testing.T, output string, err error)
+	}{
+		{
+			name: "OK",
+			args: []string{"transfer", "--operator", "jdoe", "--from", "1", "--to", "2", "--amount", "100", "--currency", "USD"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(1).Return(from, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(to.ID)).Times(1).Return(to, nil)
+				arg := db.TransferTxParams{FromAccountID: from.ID, ToAccountID: to.ID, Amount: 100}
+				result := db.TransferTxResult{Transfer: db.Transfer{ID: 9, Amount: 100, Status: db.TransferStatusCompleted}}
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).
+					Do(requireActor(t, "cli:jdoe")).Return(result, nil)
+			},
+			check: func(t *testing.T, output string, err error) {
+				require.NoError(t, err)
+				var got db.TransferTxResult
+				require.NoError(t, json.Unmarshal([]byte(output), &got))
+				require.Equal(t, int64(9), got.Transfer.ID)
+			},
+		},
+		{
+			name: "CurrencyMismatch",
+			args: []string{"transfer", "--operator", "jdoe", "--from", "1", "--to", "3", "--amount", "100", "--currency", "USD"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(1).Return(from, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(euro.ID)).Times(1).Return(euro, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, output string, err error) {
+				require.ErrorContains(t, err, "account 3 currency mismatch: EUR vs USD")
+			},
+		},
+		{
+			name: "SameAccount",
+			args: []string{"transfer", "--operator", "jdoe", "--from", "1", "--to", "1", "--amount", "100", "--currency", "USD"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, output string, err error) {
+				require.ErrorContains(t, err, "two different account ids")
+			},
+		},
+		{
+			name: "InsufficientFunds",
+			args: []string{"transfer", "--operator", "jdoe", "--from", "1", "--to", "2", "--amount", "100", "--currency", "USD"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(2).Return(from, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, db.ErrInsufficientFunds)
+			},
+			check: func(t *testing.T, output string, err error) {
+				require.ErrorIs(t, err, db.ErrInsufficientFunds)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			output, err := runCommand(t, store, tc.args...)
+			tc.check(t, output, err)
+		})
+	}
+}
+
+func TestReconcileCommand(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+
+	store.EXPECT().ReconcileAccounts(gomock.Any()).Times(1).Return([]db.ReconcileAccountsRow{}, nil)
+	output, err := runCommand(t, store, "reconcile")
+	require.NoError(t, err)
+	require.JSONEq(t, "[]", output)
+
+	// the command fails when an account doesn't add up, so a cron job can alert on it
+	mismatch := db.ReconcileAccountsRow{ID: 4, Currency: "USD", Balance: 350, EntryTotal: 300, TransferTotal: 300}
+	store.EXPECT().ReconcileAccounts(gomock.Any()).Times(1).Return([]db.ReconcileAccountsRow{mismatch}, nil)
+	output, err = runCommand(t, store, "reconcile")
+	require.EqualError(t, err, "1 accounts don't reconcile")
+
+	var got []db.ReconcileAccountsRow
+	require.NoError(t, json.Unmarshal([]byte(output), &got))
+	require.Equal(t, []db.ReconcileAccountsRow{mismatch}, got)
+}
+
+func TestTokenCommand(t *testing.T) {
+	key := utils.RandomString(token.MinKeySize)
+	t.Setenv("TOKEN_SYMMETRIC_KEY", key)
+
+	// the token never touches the database
+	store := mockdb.NewMockStore(gomock.NewController(t))
+	output, err := runCommand(t, store, "token", "--operator", "jdoe", "--owner", "alice", "--duration", "1h")
+	require.NoError(t, err)
+
+	var issued struct {
+		Owner       string    `json:"owner"`
+		AccessToken string    `json:"access_token"`
+		ExpiredAt   time.Time `json:"expired_at"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(output), &issued))
+	require.Equal(t, "alice", issued.Owner)
+	require.WithinDuration(t, time.Now().Add(time.Hour), issued.ExpiredAt, time.Minute)
+
+	maker, err := token.NewMaker(key)
+	require.NoError(t, err)
+	payload, err := maker.VerifyToken(issued.AccessToken)
+	require.NoError(t, err)
+	require.Equal(t, "alice", payload.Owner)
+
+	t.Setenv("TOKEN_SYMMETRIC_KEY", "")
+	_, err = runCommand(t, store, "token", "--operator", "jdoe", "--owner", "alice")
+	require.ErrorContains(t, err, "TOKEN_SYMMETRIC_KEY is not set")
+}
+
+func TestSeedCommand(t *testing.T) {
+	key := utils.RandomString(token.MinKeySize)
+	t.Setenv("TOKEN_SYMMETRIC_KEY", key)
+
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+
+	var nextID int64
+	store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).Times(3).DoAndReturn(
+		func(ctx context.Context, arg db.CreateAccountParams) (db.Account, error) {
+			require.Equal(t, "cli:jdoe", db.AuditInfoFromContext(ctx).Actor)
+			nextID++
+			return db.Account{ID: nextID, Owner: arg.Owner, Currency: arg.Currency, Balance: arg.Balance}, nil
+		})
+	store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).AnyTimes().Return(db.TransferTxResult{}, db.ErrInsufficientFunds)
+
+	// one currency, so that every user gets exactly one account
+	manifestPath := filepath.Join(t.TempDir(), "manifest.json")
+	supported := supportedCurrencies
+	supportedCurrencies = []string{"USD"}
+	defer func() { supportedCurrencies = supported }()
+
+	output, err := runCommand(t, store, "seed", "--operator", "jdoe", "--seed", "7", "--users", "3", "--transfers", "5",
+		"--history", "48h", "--until", "2026-03-01T00:00:00Z", "--token-duration", "2h", "--manifest", manifestPath)
+	require.NoError(t, err)
+	require.Empty(t, output)
+
+	data, err := os.ReadFile(manifestPath)
+	require.NoError(t, err)
+	var manifest seed.Manifest
+	require.NoError(t, json.Unmarshal(data, &manifest))
+	require.Equal(t, uint64(7), manifest.Seed)
+	require.Len(t, manifest.Users, 3)
+	require.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), manifest.Until)
+	require.Equal(t, 48*time.Hour, manifest.Until.Sub(manifest.From))
+	require.Equal(t, map[string]int{db.ErrInsufficientFunds.Error(): 5}, manifest.Transfers.Rejected)
+
+	// the users get credentials when the servers have a key to check them with
+	maker, err := token.NewMaker(key)
+	require.NoError(t, err)
+	for _, user := range manifest.Users {
+		payload, err := maker.VerifyToken(user.AccessToken)
+		require.NoError(t, err)
+		require.Equal(t, user.Owner, payload.Owner)
+		require.WithinDuration(t, time.Now().Add(2*time.Hour), payload.ExpiredAt, time.Minute)
+	}
+}
+
+func TestLoadtestCommand(t *testing.T) {
+	// the memory target never opens the database, a mock without expectations fails any call to it
+	store := mockdb.NewMockStore(gomock.NewController(t))
+
+	output, err := runCommand(t, store, "loadtest", "--operator", "jdoe", "--target", "memory",
+		"--transfers", "200", "--accounts", "5", "--concurrency", "4", "--mix", "hot", "--hot-accounts", "1")
+	require.NoError(t, err)
+
+	var report struct {
+		Target    string         `json:"target"`
+		Transfers int            `json:"transfers"`
+		Outcomes  map[string]int `json:"outcomes"`
+		Ledger    struct {
+			Consistent bool `json:"consistent"`
+			Accounts   int  `json:"accounts"`
+		} `json:"ledger"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(output), &report))
+	require.Equal(t, "memory", report.Target)
+	require.Equal(t, 200, report.Transfers)
+	require.NotEmpty(t, report.Outcomes)
+	require.True(t, report.Ledger.Consistent)
+	require.Equal(t, 5, report.Ledger.Accounts)
+
+	_, err = runCommand(t, store, "loadtest", "--operator", "jdoe", "--target", "grpc")
+	require.EqualError(t, err, `unknown target "grpc", expected postgres, memory or http`)
+
+	_, err = runCommand(t, store, "loadtest", "--operator", "jdoe", "--target", "memory", "--mix", "hot", "--hot-accounts", "0")
+	require.ErrorContains(t, err, "the hot mix needs at least one hot account")
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/techschool/simple-bank/token"
+)
+
+// issuedToken is the output of the token command
+type issuedToken struct {
+	Owner       string    `json:"owner"`
+	AccessToken string    `json:"access_token"`
+	ExpiredAt   time.Time `json:"expired_at"`
+}
+
+func newTokenCommand(app *cli) *cobra.Command {
+	var owner string
+	var duration time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "token",
+		Short: "Issue an access token to the owner of accounts",
+		Long: "Issue an access token to the owner of accounts, signed with TOKEN_SYMMETRIC_KEY.\n" +
+			"The owner sends it as \"Authorization: Bearer <token>\" to watch the events of their accounts.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if app.operator == "" {
+				return errors.New("--operator is required, it is logged with the token issued")
+			}
+			issued, err := issueToken(app, owner, duration)
+			if err != nil {
+				return err
+			}
+
+			slog.Info("Issued access token", "operator", app.operator, "owner", owner, "expired_at", issued.ExpiredAt)
+			return printJSON(cmd.OutOrStdout(), issued)
+		},
+	}
+
+	cmd.Flags().StringVar(&owner, "owner", "", "owner of the accounts the token gives access to")
+	cmd.Flags().DurationVar(&duration, "duration", 0, "how long the token is valid, ACCESS_TOKEN_DURATION when zero")
+	_ = cmd.MarkFlagRequired("owner")
+	return cmd
+}
+
+// issueToken signs a token for owner with the key of the config, valid for duration or ACCESS_TOKEN_DURATION
+func issueToken(app *cli, owner string, duration time.Duration) (issuedToken, error) {
+	if app.config.TokenSymmetricKey == "" {
+		return issuedToken{}, errors.New("TOKEN_SYMMETRIC_KEY is not set, there is no key to sign the token with")
+	}
+	maker, err := token.NewMaker(app.config.TokenSymmetricKey)
+	if err != nil {
+		return issuedToken{}, err
+	}
+	if duration <= 0 {
+		duration = app.config.AccessTokenDuration
+	}
+
+	accessToken, payload, err := maker.CreateToken(owner, duration)
+	if err != nil {
+		return issuedToken{}, err
+	}
+	return issuedToken{Owner: owner, AccessToken: accessToken, ExpiredAt: payload.ExpiredAt}, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/spf13/cobra"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+)
+
+func newTransferCommand(app *cli) *cobra.Command {
+	var arg db.TransferTxParams
+	var currency string
+
+	cmd := &cobra.Command{
+		Use:   "transfer --from ID --to ID --amount AMOUNT --currency CURRENCY",
+		Short: "Move money between two accounts",
+		Long: "Move money between two accounts, with the same checks as POST /transfers:\n" +
+			"both accounts must be active and in the given currency, and the fraud screening applies.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if arg.FromAccountID < 1 || arg.ToAccountID < 1 || arg.FromAccountID == arg.ToAccountID {
+				return fmt.Errorf("--from and --to must be two different account ids")
+			}
+			if arg.Amount <= 0 {
+				return fmt.Errorf("amount must be positive, got %d", arg.Amount)
+			}
+			if !slices.Contains(supportedCurrencies, currency) {
+				return fmt.Errorf("unsupported currency %q, expected one of %v", currency, supportedCurrencies)
+			}
+			ctx, err := app.auditContext(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			return app.withStore(func(store db.Store) error {
+				for _, id := range []int64{arg.FromAccountID, arg.ToAccountID} {
+					if err := checkAccountCurrency(ctx, store, id, currency); err != nil {
+						return err
+					}
+				}
+
+				// a transfer flagged by the fraud screening comes back pending_review, a teller approves it
+				result, err := store.TransferTx(ctx, arg)
+				if err != nil {
+					return err
+				}
+				return printJSON(cmd.OutOrStdout(), result)
+			})
+		},
+	}
+
+	cmd.Flags().Int64Var(&arg.FromAccountID, "from", 0, "id of the account the money leaves")
+	cmd.Flags().Int64Var(&arg.ToAccountID, "to", 0, "id of the account the money goes to")
+	cmd.Flags().Int64Var(&arg.Amount, "amount", 0, "amount in minor units")
+	cmd.Flags().StringVar(&currency, "currency", "", "currency of both accounts, USD or EUR")
+	_ = cmd.MarkFlagRequired("from")
+	_ = cmd.MarkFlagRequired("to")
+	_ = cmd.MarkFlagRequired("amount")
+	_ = cmd.MarkFlagRequired("currency")
+	return cmd
+}
+
+// checkAccountCurrency is the validAccount check of the API: the account exists and holds currency
+func checkAccountCurrency(ctx context.Context, store db.Store, id int64, currency string) error {
+	account, err := store.GetAccount(ctx, id)
+	if err != nil {
+		return fmt.Errorf("account %d: %w", id, db.ClassifyError(err))
+	}
+	if account.Currency != currency {
+		return fmt.Errorf("account %d currency mismatch: %s vs %s", id, account.Currency, currency)
+	}
+	return nil
+}
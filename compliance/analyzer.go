@@ -0,0 +1,72 @@
+package compliance
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/health"
+)
+
+// Analyzer applies the AML rules to the completed transfers in the background and raises compliance alerts
+type Analyzer struct {
+	store     db.Store
+	rules     db.AMLRules
+	interval  time.Duration // how long to wait once every transfer is analyzed
+	batchSize int32
+	heartbeat *health.Heartbeat // beats after every batch that was analyzed without error
+}
+
+// NewAnalyzer creates an analyzer polling store every interval and analyzing up to batchSize transfers at a time
+func NewAnalyzer(store db.Store, rules db.AMLRules, interval time.Duration, batchSize int32) *Analyzer {
+	return &Analyzer{
+		store:     store,
+		rules:     rules,
+		interval:  interval,
+		batchSize: batchSize,
+		heartbeat: health.NewHeartbeat(interval),
+	}
+}
+
+// Heartbeat returns the heartbeat of the analyzer, for the readiness checks
+func (analyzer *Analyzer) Heartbeat() *health.Heartbeat {
+	return analyzer.heartbeat
+}
+
+// Run analyzes transfers until ctx is cancelled, it always returns ctx.Err()
+// errors are logged and retried on the next tick: a transfer is only marked analyzed together with its alerts
+func (analyzer *Analyzer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(analyzer.interval)
+	defer ticker.Stop()
+
+	for {
+		analyzer.drain(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain analyzes batches until every transfer is analyzed or a batch fails
+func (analyzer *Analyzer) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		n, err := analyzer.AnalyzeOnce(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "AML analysis failed", "error", err)
+			return
+		}
+		analyzer.heartbeat.Beat()
+		if n < int(analyzer.batchSize) {
+			return
+		}
+	}
+}
+
+// AnalyzeOnce analyzes a single batch and returns the number of transfers analyzed
+func (analyzer *Analyzer) AnalyzeOnce(ctx context.Context) (int, error) {
+	return analyzer.store.AnalyzeTransfersTx(ctx, analyzer.batchSize, analyzer.rules)
+}
@@ -0,0 +1,93 @@
+package compliance
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/techschool/simple-bank/db2/mock"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"go.uber.org/mock/gomock"
+)
+
+func testRules() db.AMLRules {
+	return db.AMLRules{
+		Thresholds:          map[string]int64{"USD": 1000000},
+		StructuringFloor:    90,
+		StructuringWindow:   24 * time.Hour,
+		StructuringMinCount: 3,
+	}
+}
+
+func TestAnalyzeOnce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	rules := testRules()
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		AnalyzeTransfersTx(gomock.Any(), gomock.Eq(int32(10)), gomock.Eq(rules)).
+		Times(1).
+		Return(4, nil)
+
+	analyzer := NewAnalyzer(store, rules, time.Second, 10)
+
+	n, err := analyzer.AnalyzeOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+}
+
+func TestAnalyzerDrain(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// full batches are followed by another batch right away, a partial one ends the drain
+	store := mockdb.NewMockStore(ctrl)
+	gomock.InOrder(
+		store.EXPECT().AnalyzeTransfersTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(10, nil),
+		store.EXPECT().AnalyzeTransfersTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(3, nil),
+	)
+
+	analyzer := NewAnalyzer(store, testRules(), time.Second, 10)
+	analyzer.drain(context.Background())
+	_, err := analyzer.Heartbeat().Check()(context.Background())
+	require.NoError(t, err)
+}
+
+func TestAnalyzerDrainStopsOnError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		AnalyzeTransfersTx(gomock.Any(), gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(0, errors.New("connection reset"))
+
+	analyzer := NewAnalyzer(store, testRules(), time.Second, 10)
+	analyzer.drain(context.Background())
+	_, err := analyzer.Heartbeat().Check()(context.Background())
+	require.Error(t, err, "a failed batch must not beat")
+}
+
+func TestAnalyzerRunStopsOnCancel(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		AnalyzeTransfersTx(gomock.Any(), gomock.Any(), gomock.Any()).
+		MinTimes(1).
+		DoAndReturn(func(ctx context.Context, batchSize int32, rules db.AMLRules) (int, error) {
+			cancel() // stop the analyzer after the first poll
+			return 0, nil
+		})
+
+	analyzer := NewAnalyzer(store, testRules(), time.Millisecond, 10)
+	err := analyzer.Run(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+}
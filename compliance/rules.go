@@ -0,0 +1,67 @@
+package compliance
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	db "github.com/techschool/simple-bank/db2/sqlc"
+)
+
+// ParseThresholds parses a comma separated list of "<currency>=<amount>", e.g. "USD=1000000,EUR=1000000"
+// amounts are in minor units; an empty string gives no threshold, so nothing is ever flagged
+func ParseThresholds(s string) (map[string]int64, error) {
+	thresholds := map[string]int64{}
+
+	for _, entry := range strings.Split(s, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		currency, amountSpec, ok := strings.Cut(entry, "=")
+		currency = strings.ToUpper(strings.TrimSpace(currency))
+		if !ok || currency == "" {
+			return nil, fmt.Errorf("invalid AML threshold %q: expected <currency>=<amount>", entry)
+		}
+
+		amount, err := strconv.ParseInt(strings.TrimSpace(amountSpec), 10, 64)
+		if err != nil || amount <= 0 {
+			return nil, fmt.Errorf("invalid AML threshold %q: amount must be a positive integer", entry)
+		}
+
+		if _, exists := thresholds[currency]; exists {
+			return nil, fmt.Errorf("invalid AML thresholds: %s is listed twice", currency)
+		}
+		thresholds[currency] = amount
+	}
+
+	return thresholds, nil
+}
+
+// NewRules checks and assembles the AML rules applied by the analyzer
+// floor is the percentage of the threshold from which an amount counts as structuring evidence,
+// minCount such amounts within window raise a structuring alert
+func NewRules(thresholds string, floor int64, window time.Duration, minCount int) (db.AMLRules, error) {
+	parsed, err := ParseThresholds(thresholds)
+	if err != nil {
+		return db.AMLRules{}, err
+	}
+
+	if floor <= 0 || floor >= 100 {
+		return db.AMLRules{}, fmt.Errorf("invalid AML structuring floor %d: must be a percentage between 1 and 99", floor)
+	}
+	if window <= 0 {
+		return db.AMLRules{}, fmt.Errorf("invalid AML structuring window %s: must be positive", window)
+	}
+	if minCount < 2 {
+		return db.AMLRules{}, fmt.Errorf("invalid AML structuring min count %d: must be at least 2", minCount)
+	}
+
+	return db.AMLRules{
+		Thresholds:          parsed,
+		StructuringFloor:    floor,
+		StructuringWindow:   window,
+		StructuringMinCount: minCount,
+	}, nil
+}
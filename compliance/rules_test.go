@@ -0,0 +1,44 @@
+package compliance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseThresholds(t *testing.T) {
+	thresholds, err := ParseThresholds(" usd=1000000, EUR = 900000 ,")
+	require.NoError(t, err)
+	require.Equal(t, map[string]int64{"USD": 1000000, "EUR": 900000}, thresholds)
+
+	thresholds, err = ParseThresholds("")
+	require.NoError(t, err)
+	require.Empty(t, thresholds)
+
+	for _, invalid := range []string{"USD", "=100", "USD=abc", "USD=0", "USD=-5", "USD=1,USD=2"} {
+		_, err := ParseThresholds(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestNewRules(t *testing.T) {
+	rules, err := NewRules("USD=1000000", 90, 24*time.Hour, 3)
+	require.NoError(t, err)
+	require.Equal(t, int64(1000000), rules.Thresholds["USD"])
+	require.Equal(t, int64(90), rules.StructuringFloor)
+	require.Equal(t, 24*time.Hour, rules.StructuringWindow)
+	require.Equal(t, 3, rules.StructuringMinCount)
+
+	_, err = NewRules("USD=1000000", 100, 24*time.Hour, 3)
+	require.Error(t, err)
+
+	_, err = NewRules("USD=1000000", 90, 0, 3)
+	require.Error(t, err)
+
+	_, err = NewRules("USD=1000000", 90, 24*time.Hour, 1)
+	require.Error(t, err)
+
+	_, err = NewRules("USD", 90, 24*time.Hour, 3)
+	require.Error(t, err)
+}
@@ -0,0 +1,486 @@
+package memdb
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+)
+
+func (tx *tx) CreateAccount(arg db.CreateAccountParams) (db.Account, error) {
+	if !currencyFormat.MatchString(arg.Currency) {
+		return db.Account{}, checkViolationError("accounts", "accounts_currency_format")
+	}
+
+	return tx.data.accounts.insert(tx, func(id int64) db.Account {
+		return db.Account{
+			ID:        id,
+			Owner:     arg.Owner,
+			Balance:   arg.Balance,
+			Currency:  arg.Currency,
+			CreatedAt: tx.now,
+			Status:    db.AccountStatusActive,
+			LimitTier: db.DefaultLimitTier,
+			Kind:      db.AccountKindCustomer,
+		}
+	}), nil
+}
+
+// CreateEquityAccount creates the equity account of currency, it does nothing if the currency already has one
+func (tx *tx) CreateEquityAccount(currency string) error {
+	if _, ok := tx.data.equityAccounts[currency]; ok {
+		return nil
+	}
+	if !currencyFormat.MatchString(currency) {
+		return checkViolationError("accounts", "accounts_currency_format")
+	}
+
+	equity := tx.data.accounts.insert(tx, func(id int64) db.Account {
+		return db.Account{
+			ID:        id,
+			Owner:     "equity",
+			Currency:  currency,
+			CreatedAt: tx.now,
+			Status:    db.AccountStatusActive,
+			LimitTier: db.DefaultLimitTier,
+			Kind:      db.AccountKindEquity,
+		}
+	})
+	set(tx, tx.data.equityAccounts, currency, equity.ID)
+	return nil
+}
+
+func (tx *tx) GetEquityAccount(currency string) (db.Account, error) {
+	id, ok := tx.data.equityAccounts[currency]
+	if !ok {
+		return db.Account{}, pgx.ErrNoRows
+	}
+	return tx.GetAccount(id)
+}
+
+func (tx *tx) GetAccount(id int64) (db.Account, error) {
+	account, ok := tx.data.accounts.get(id)
+	if !ok {
+		return db.Account{}, pgx.ErrNoRows
+	}
+	return account, nil
+}
+
+func (tx *tx) ListAccounts(arg db.ListAccountsParams) ([]db.Account, error) {
+	customers := filter(tx.data.accounts.scan(), func(account db.Account) bool {
+		return account.Kind == db.AccountKindCustomer
+	})
+	return page(customers, arg.Limit, arg.Offset)
+}
+
+// updateAccount applies change to the account and returns it, like an UPDATE ... RETURNING
+func (tx *tx) updateAccount(id int64, change func(account *db.Account) error) (db.Account, error) {
+	account, err := tx.GetAccount(id)
+	if err != nil {
+		return db.Account{}, err
+	}
+	if err := change(&account); err != nil {
+		return db.Account{}, err
+	}
+	return tx.data.accounts.update(tx, id, account), nil
+}
+
+func (tx *tx) UpdateAccount(arg db.UpdateAccountParams) (db.Account, error) {
+	return tx.updateAccount(arg.ID, func(account *db.Account) error {
+		account.Balance = arg.Balance
+		return nil
+	})
+}
+
+func (tx *tx) AddAccountBalance(arg db.AddAccountBalanceParams) (db.Account, error) {
+	return tx.updateAccount(arg.ID, func(account *db.Account) error {
+		account.Balance += arg.Amount
+		return nil
+	})
+}
+
+func (tx *tx) UpdateAccountStatus(arg db.UpdateAccountStatusParams) (db.Account, error) {
+	return tx.updateAccount(arg.ID, func(account *db.Account) error {
+		if arg.Status != db.AccountStatusActive && arg.Status != db.AccountStatusFrozen {
+			return checkViolationError("accounts", "accounts_status_check")
+		}
+		account.Status = arg.Status
+		return nil
+	})
+}
+
+func (tx *tx) UpdateAccountLimitTier(arg db.UpdateAccountLimitTierParams) (db.Account, error) {
+	return tx.updateAccount(arg.ID, func(account *db.Account) error {
+		if _, ok := tx.data.tiers[arg.LimitTier]; !ok {
+			return foreignKeyError("accounts", "accounts_limit_tier_fkey")
+		}
+		account.LimitTier = arg.LimitTier
+		return nil
+	})
+}
+
+// DeleteAccount fails like postgres while entries, transfers or alerts reference the account,
+// its limit overrides are deleted with it
+func (tx *tx) DeleteAccount(id int64) error {
+	account, ok := tx.data.accounts.get(id)
+	if !ok {
+		return nil
+	}
+
+	if len(tx.data.entriesByAccount[id]) > 0 {
+		return referencedError("accounts", "entries", "entries_account_id_fkey")
+	}
+	for transfer := range tx.data.transfers.scanIDs(tx.data.transfersByAccount[id]) {
+		if transfer.FromAccountID == id {
+			return referencedError("accounts", "transfers", "transfers_from_account_id_fkey")
+		}
+		return referencedError("accounts", "transfers", "transfers_to_account_id_fkey")
+	}
+	for alert := range tx.data.alerts.scan() {
+		if alert.AccountID == id {
+			return referencedError("accounts", "compliance_alerts", "compliance_alerts_account_id_fkey")
+		}
+	}
+
+	if account.Kind == db.AccountKindEquity {
+		unset(tx, tx.data.equityAccounts, account.Currency)
+	}
+	unset(tx, tx.data.overrides, id)
+	tx.data.accounts.delete(tx, id)
+	return nil
+}
+
+func (tx *tx) CreateEntry(arg db.CreateEntryParams) (db.Entry, error) {
+	if _, ok := tx.data.accounts.get(arg.AccountID); !ok {
+		return db.Entry{}, foreignKeyError("entries", "entries_account_id_fkey")
+	}
+	var transferID *int64
+	if arg.TransferID != nil {
+		if _, ok := tx.data.transfers.get(*arg.TransferID); !ok {
+			return db.Entry{}, foreignKeyError("entries", "entries_transfer_id_fkey")
+		}
+		id := *arg.TransferID // the row doesn't share the pointer of the caller
+		transferID = &id
+	}
+
+	entry := tx.data.entries.insert(tx, func(id int64) db.Entry {
+		return db.Entry{
+			ID:         id,
+			AccountID:  arg.AccountID,
+			Amount:     arg.Amount,
+			CreatedAt:  tx.now,
+			TransferID: transferID,
+		}
+	})
+	appendID(tx, tx.data.entriesByAccount, entry.AccountID, entry.ID)
+	return entry, nil
+}
+
+func (tx *tx) GetEntry(id int64) (db.Entry, error) {
+	entry, ok := tx.data.entries.get(id)
+	if !ok {
+		return db.Entry{}, pgx.ErrNoRows
+	}
+	return entry, nil
+}
+
+func (tx *tx) ListEntries(arg db.ListEntriesParams) ([]db.Entry, error) {
+	return page(tx.data.entries.scanIDs(tx.data.entriesByAccount[arg.AccountID]), arg.Limit, arg.Offset)
+}
+
+func (tx *tx) GetTransferLimitTier(name string) (db.TransferLimitTier, error) {
+	tier, ok := tx.data.tiers[name]
+	if !ok {
+		return db.TransferLimitTier{}, pgx.ErrNoRows
+	}
+	return tier, nil
+}
+
+func (tx *tx) ListTransferLimitTiers() ([]db.TransferLimitTier, error) {
+	tiers := []db.TransferLimitTier{}
+	for _, name := range slices.Sorted(maps.Keys(tx.data.tiers)) {
+		tiers = append(tiers, tx.data.tiers[name])
+	}
+	return tiers, nil
+}
+
+func (tx *tx) UpsertTransferLimitTier(arg db.UpsertTransferLimitTierParams) (db.TransferLimitTier, error) {
+	tier := db.TransferLimitTier{
+		Name:            arg.Name,
+		MaxSingleAmount: arg.MaxSingleAmount,
+		MaxDailyAmount:  arg.MaxDailyAmount,
+		MaxDailyCount:   arg.MaxDailyCount,
+		UpdatedAt:       tx.now,
+	}
+	set(tx, tx.data.tiers, tier.Name, tier)
+	return tier, nil
+}
+
+func (tx *tx) GetAccountTransferLimitOverride(accountID int64) (db.AccountTransferLimit, error) {
+	overrides, ok := tx.data.overrides[accountID]
+	if !ok {
+		return db.AccountTransferLimit{}, pgx.ErrNoRows
+	}
+	return overrides, nil
+}
+
+func (tx *tx) UpsertAccountTransferLimitOverride(arg db.UpsertAccountTransferLimitOverrideParams) (db.AccountTransferLimit, error) {
+	if _, ok := tx.data.accounts.get(arg.AccountID); !ok {
+		return db.AccountTransferLimit{}, foreignKeyError("account_transfer_limits", "account_transfer_limits_account_id_fkey")
+	}
+
+	overrides := db.AccountTransferLimit{
+		AccountID:       arg.AccountID,
+		MaxSingleAmount: arg.MaxSingleAmount,
+		MaxDailyAmount:  arg.MaxDailyAmount,
+		MaxDailyCount:   arg.MaxDailyCount,
+		UpdatedAt:       tx.now,
+	}
+	set(tx, tx.data.overrides, overrides.AccountID, overrides)
+	return overrides, nil
+}
+
+func (tx *tx) DeleteAccountTransferLimitOverride(accountID int64) error {
+	unset(tx, tx.data.overrides, accountID)
+	return nil
+}
+
+// GetEffectiveTransferLimits returns the limits of the tier of the account, replaced by its overrides where it has some
+func (tx *tx) GetEffectiveTransferLimits(id int64) (db.GetEffectiveTransferLimitsRow, error) {
+	account, ok := tx.data.accounts.get(id)
+	if !ok {
+		return db.GetEffectiveTransferLimitsRow{}, pgx.ErrNoRows
+	}
+	tier := tx.data.tiers[account.LimitTier]
+
+	limits := db.GetEffectiveTransferLimitsRow{
+		AccountID:       account.ID,
+		Tier:            account.LimitTier,
+		MaxSingleAmount: tier.MaxSingleAmount,
+		MaxDailyAmount:  tier.MaxDailyAmount,
+		MaxDailyCount:   tier.MaxDailyCount,
+	}
+	if overrides, ok := tx.data.overrides[id]; ok {
+		limits.MaxSingleAmount = coalesce(overrides.MaxSingleAmount, limits.MaxSingleAmount)
+		limits.MaxDailyAmount = coalesce(overrides.MaxDailyAmount, limits.MaxDailyAmount)
+		limits.MaxDailyCount = coalesce(overrides.MaxDailyCount, limits.MaxDailyCount)
+	}
+	return limits, nil
+}
+
+func coalesce[T any](value *T, fallback T) T {
+	if value == nil {
+		return fallback
+	}
+	return *value
+}
+
+// CreateAccountTx creates a new account, records an audit event and an AccountCreated event for it
+// a non zero arg.Balance is paid by the equity account of the currency, like db.SQLStore.CreateAccountTx
+func (store *Store) CreateAccountTx(ctx context.Context, arg db.CreateAccountParams) (db.Account, error) {
+	var account db.Account
+
+	err := store.execTx(ctx, func(tx *tx) error {
+		var err error
+		account, err = tx.CreateAccount(db.CreateAccountParams{Owner: arg.Owner, Currency: arg.Currency})
+		if err != nil {
+			return err
+		}
+
+		var opening db.TransferTxResult
+		if arg.Balance != 0 {
+			opening, err = tx.openAccount(account, arg.Balance)
+			if err != nil {
+				return err
+			}
+			account = opening.ToAccount
+		}
+
+		err = recordAudit(ctx, tx, db.AuditActionAccountCreate, db.AuditResourceAccount, account.ID, nil, account)
+		if err != nil {
+			return err
+		}
+
+		err = enqueueEvent(tx, db.EventAccountCreated, db.AuditResourceAccount, account.ID, db.AccountCreatedEvent{Account: account})
+		if err != nil || arg.Balance == 0 {
+			return err
+		}
+
+		err = recordAudit(ctx, tx, db.AuditActionTransferCreate, db.AuditResourceTransfer, opening.Transfer.ID, nil, opening)
+		if err != nil {
+			return err
+		}
+		return enqueueTransferEvents(tx, opening)
+	})
+
+	return account, err
+}
+
+// openAccount pays the opening balance of a new account from the equity account of its currency,
+// without the checks of TransferTx
+func (tx *tx) openAccount(account db.Account, balance int64) (db.TransferTxResult, error) {
+	if err := tx.CreateEquityAccount(account.Currency); err != nil {
+		return db.TransferTxResult{}, err
+	}
+	equity, err := tx.GetEquityAccount(account.Currency)
+	if err != nil {
+		return db.TransferTxResult{}, err
+	}
+
+	var result db.TransferTxResult
+	result.Transfer, err = tx.CreateOpeningTransfer(db.CreateOpeningTransferParams{
+		FromAccountID: equity.ID,
+		ToAccountID:   account.ID,
+		Amount:        balance,
+	})
+	if err != nil {
+		return db.TransferTxResult{}, err
+	}
+
+	result.FromEntry, err = tx.CreateEntry(db.CreateEntryParams{
+		AccountID:  equity.ID,
+		Amount:     -balance,
+		TransferID: &result.Transfer.ID,
+	})
+	if err != nil {
+		return db.TransferTxResult{}, err
+	}
+	result.ToEntry, err = tx.CreateEntry(db.CreateEntryParams{
+		AccountID:  account.ID,
+		Amount:     balance,
+		TransferID: &result.Transfer.ID,
+	})
+	if err != nil {
+		return db.TransferTxResult{}, err
+	}
+
+	result.ToAccount, err = tx.AddAccountBalance(db.AddAccountBalanceParams{ID: account.ID, Amount: balance})
+	if err != nil {
+		return db.TransferTxResult{}, err
+	}
+	result.FromAccount, err = tx.AddAccountBalance(db.AddAccountBalanceParams{ID: equity.ID, Amount: -balance})
+	return result, err
+}
+
+// UpdateAccountStatusTx freezes or unfreezes an account and records the change in the audit log
+// setting the status the account already has changes nothing and records nothing
+func (store *Store) UpdateAccountStatusTx(ctx context.Context, arg db.UpdateAccountStatusParams) (db.Account, error) {
+	var account db.Account
+
+	err := store.execTx(ctx, func(tx *tx) error {
+		before, err := tx.GetAccount(arg.ID)
+		if err != nil {
+			return err
+		}
+		if before.Kind == db.AccountKindEquity {
+			return accountError(db.ErrEquityAccount, before.ID)
+		}
+		if before.Status == arg.Status {
+			account = before
+			return nil
+		}
+
+		account, err = tx.UpdateAccountStatus(arg)
+		if err != nil {
+			return err
+		}
+
+		err = recordAudit(ctx, tx, db.AuditActionAccountStatusUpdate, db.AuditResourceAccount, account.ID, before, account)
+		if err != nil {
+			return err
+		}
+
+		event := db.AccountStatusChangedEvent{Account: account, PreviousStatus: before.Status}
+		return enqueueEvent(tx, db.EventAccountStatusChanged, db.AuditResourceAccount, account.ID, event)
+	})
+
+	return account, err
+}
+
+// UpsertTransferLimitTierTx creates or replaces a limit tier and records the change in the audit log
+func (store *Store) UpsertTransferLimitTierTx(ctx context.Context, arg db.UpsertTransferLimitTierParams) (db.TransferLimitTier, error) {
+	var tier db.TransferLimitTier
+
+	err := store.execTx(ctx, func(tx *tx) error {
+		var before any // nil for a new tier
+		previous, err := tx.GetTransferLimitTier(arg.Name)
+		switch {
+		case err == nil:
+			before = previous
+		case !errors.Is(err, pgx.ErrNoRows):
+			return err
+		}
+
+		tier, err = tx.UpsertTransferLimitTier(arg)
+		if err != nil {
+			return err
+		}
+
+		return recordAuditByKey(ctx, tx, db.AuditActionLimitTierUpdate, db.AuditResourceLimitTier, tier.Name, before, tier)
+	})
+
+	return tier, err
+}
+
+// accountLimits is the state of the limits of an account recorded in the audit log, like in package db
+type accountLimits struct {
+	Tier      string                   `json:"tier"`
+	Overrides *db.AccountTransferLimit `json:"overrides"`
+}
+
+// UpdateAccountTransferLimitsTx changes the tier and the limits of an account, records the change in the audit log
+// and returns the limits now in effect
+func (store *Store) UpdateAccountTransferLimitsTx(
+	ctx context.Context,
+	arg db.UpdateAccountTransferLimitsParams,
+) (db.GetEffectiveTransferLimitsRow, error) {
+	var limits db.GetEffectiveTransferLimitsRow
+
+	err := store.execTx(ctx, func(tx *tx) error {
+		account, err := tx.GetAccount(arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		before := accountLimits{Tier: account.LimitTier}
+		previous, err := tx.GetAccountTransferLimitOverride(arg.AccountID)
+		switch {
+		case err == nil:
+			before.Overrides = &previous
+		case !errors.Is(err, pgx.ErrNoRows):
+			return err
+		}
+
+		if _, err := tx.UpdateAccountLimitTier(db.UpdateAccountLimitTierParams{ID: arg.AccountID, LimitTier: arg.Tier}); err != nil {
+			return err
+		}
+
+		after := accountLimits{Tier: arg.Tier}
+		if arg.MaxSingleAmount != nil || arg.MaxDailyAmount != nil || arg.MaxDailyCount != nil {
+			overrides, err := tx.UpsertAccountTransferLimitOverride(db.UpsertAccountTransferLimitOverrideParams{
+				AccountID:       arg.AccountID,
+				MaxSingleAmount: arg.MaxSingleAmount,
+				MaxDailyAmount:  arg.MaxDailyAmount,
+				MaxDailyCount:   arg.MaxDailyCount,
+			})
+			if err != nil {
+				return err
+			}
+			after.Overrides = &overrides
+		} else if err := tx.DeleteAccountTransferLimitOverride(arg.AccountID); err != nil {
+			return err
+		}
+
+		limits, err = tx.GetEffectiveTransferLimits(arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, tx, db.AuditActionAccountLimitsUpdate, db.AuditResourceAccount, arg.AccountID, before, after)
+	})
+
+	return limits, err
+}
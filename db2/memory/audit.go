@@ -0,0 +1,112 @@
+package memdb
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+)
+
+func (tx *tx) CreateAuditEvent(arg db.CreateAuditEventParams) (db.AuditEvent, error) {
+	return tx.data.auditEvents.insert(tx, func(id int64) db.AuditEvent {
+		return db.AuditEvent{
+			ID:           id,
+			Actor:        arg.Actor,
+			Action:       arg.Action,
+			ResourceType: arg.ResourceType,
+			ResourceID:   arg.ResourceID,
+			Before:       arg.Before,
+			After:        arg.After,
+			RequestID:    arg.RequestID,
+			ClientIp:     arg.ClientIp,
+			CreatedAt:    tx.now,
+		}
+	}), nil
+}
+
+func (tx *tx) GetAuditEvent(id int64) (db.AuditEvent, error) {
+	event, ok := tx.data.auditEvents.get(id)
+	if !ok {
+		return db.AuditEvent{}, pgx.ErrNoRows
+	}
+	return event, nil
+}
+
+// ListAuditEvents returns the events matching the filters that are set, newest first
+func (tx *tx) ListAuditEvents(arg db.ListAuditEventsParams) ([]db.AuditEvent, error) {
+	events := filter(tx.data.auditEvents.scanBackward(), func(event db.AuditEvent) bool {
+		return matches(arg.Actor, event.Actor) &&
+			matches(arg.Action, event.Action) &&
+			matches(arg.ResourceType, event.ResourceType) &&
+			matches(arg.ResourceID, event.ResourceID)
+	})
+	return page(events, arg.Limit, arg.Offset)
+}
+
+// matches is an optional filter of a query: a nil filter matches everything
+func matches(filter *string, value string) bool {
+	return filter == nil || *filter == value
+}
+
+// recordAudit writes one audit event within tx, so it is rolled back together with the change
+func recordAudit(ctx context.Context, tx *tx, action string, resourceType string, resourceID int64, before any, after any) error {
+	return recordAuditByKey(ctx, tx, action, resourceType, strconv.FormatInt(resourceID, 10), before, after)
+}
+
+// recordAuditByKey is recordAudit for resources identified by a string
+func recordAuditByKey(ctx context.Context, tx *tx, action string, resourceType string, resourceID string, before any, after any) error {
+	beforeJSON, err := json.Marshal(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := json.Marshal(after)
+	if err != nil {
+		return err
+	}
+
+	info := db.AuditInfoFromContext(ctx)
+	_, err = tx.CreateAuditEvent(db.CreateAuditEventParams{
+		Actor:        info.Actor,
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Before:       beforeJSON,
+		After:        afterJSON,
+		RequestID:    info.RequestID,
+		ClientIp:     info.ClientIP,
+	})
+	return err
+}
+
+// ReconcileAccounts lists the accounts whose ledger doesn't add up, see the query of package db
+func (tx *tx) ReconcileAccounts() ([]db.ReconcileAccountsRow, error) {
+	entryTotals := map[int64]int64{}
+	for entry := range tx.data.entries.scan() {
+		entryTotals[entry.AccountID] += entry.Amount
+	}
+
+	transferTotals := map[int64]int64{}
+	for transfer := range tx.data.transfers.scan() {
+		if transfer.Status == db.TransferStatusCompleted {
+			transferTotals[transfer.ToAccountID] += transfer.Amount
+			transferTotals[transfer.FromAccountID] -= transfer.Amount
+		}
+	}
+
+	rows := []db.ReconcileAccountsRow{}
+	for account := range tx.data.accounts.scan() {
+		row := db.ReconcileAccountsRow{
+			ID:            account.ID,
+			Currency:      account.Currency,
+			Balance:       account.Balance,
+			EntryTotal:    entryTotals[account.ID],
+			TransferTotal: transferTotals[account.ID],
+		}
+		if row.EntryTotal != row.TransferTotal || row.Balance != row.EntryTotal {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
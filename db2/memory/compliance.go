@@ -0,0 +1,268 @@
+package memdb
+
+import (
+	"context"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+)
+
+// insertAlert checks the constraints of the compliance_alerts table and inserts the alert with the next id
+func (tx *tx) insertAlert(alert db.ComplianceAlert) (db.ComplianceAlert, error) {
+	if alert.Kind != db.AlertKindLargeTransaction && alert.Kind != db.AlertKindStructuring {
+		return db.ComplianceAlert{}, checkViolationError("compliance_alerts", "compliance_alerts_kind_check")
+	}
+	if !currencyFormat.MatchString(alert.Currency) {
+		return db.ComplianceAlert{}, checkViolationError("compliance_alerts", "compliance_alerts_currency_format")
+	}
+	if alert.Kind == db.AlertKindStructuring {
+		if _, ok := tx.unresolvedStructuringAlert(alert.AccountID); ok {
+			return db.ComplianceAlert{}, uniqueViolationError("compliance_alerts", "compliance_alerts_open_structuring_idx")
+		}
+	}
+	if _, ok := tx.data.accounts.get(alert.AccountID); !ok {
+		return db.ComplianceAlert{}, foreignKeyError("compliance_alerts", "compliance_alerts_account_id_fkey")
+	}
+
+	return tx.data.alerts.insert(tx, func(id int64) db.ComplianceAlert {
+		alert.ID = id
+		alert.Status = db.AlertStatusOpen
+		alert.CreatedAt = tx.now
+		alert.UpdatedAt = tx.now
+		return alert
+	}), nil
+}
+
+// unresolvedStructuringAlert returns the alert of the compliance_alerts_open_structuring_idx unique index
+func (tx *tx) unresolvedStructuringAlert(accountID int64) (db.ComplianceAlert, bool) {
+	for alert := range tx.data.alerts.scanBackward() {
+		if alert.AccountID == accountID && alert.Kind == db.AlertKindStructuring && alert.Status != db.AlertStatusResolved {
+			return alert, true
+		}
+	}
+	return db.ComplianceAlert{}, false
+}
+
+func (tx *tx) CreateComplianceAlert(arg db.CreateComplianceAlertParams) (db.ComplianceAlert, error) {
+	return tx.insertAlert(db.ComplianceAlert{
+		Kind:        arg.Kind,
+		AccountID:   arg.AccountID,
+		Currency:    arg.Currency,
+		TotalAmount: arg.TotalAmount,
+		TransferIds: arg.TransferIds,
+	})
+}
+
+// UpsertStructuringAlert raises a structuring alert for the account, or adds the transfers to its unresolved one
+func (tx *tx) UpsertStructuringAlert(arg db.UpsertStructuringAlertParams) (db.ComplianceAlert, error) {
+	alert, ok := tx.unresolvedStructuringAlert(arg.AccountID)
+	if !ok {
+		return tx.insertAlert(db.ComplianceAlert{
+			Kind:        db.AlertKindStructuring,
+			AccountID:   arg.AccountID,
+			Currency:    arg.Currency,
+			TotalAmount: arg.TotalAmount,
+			TransferIds: arg.TransferIds,
+		})
+	}
+
+	ids := slices.Concat(alert.TransferIds, arg.TransferIds)
+	slices.Sort(ids)
+	alert.TransferIds = slices.Compact(ids)
+
+	alert.TotalAmount = 0
+	for _, id := range alert.TransferIds {
+		if transfer, ok := tx.data.transfers.get(id); ok {
+			alert.TotalAmount += transfer.Amount
+		}
+	}
+	alert.UpdatedAt = tx.now
+	return tx.data.alerts.update(tx, alert.ID, alert), nil
+}
+
+func (tx *tx) GetComplianceAlert(id int64) (db.ComplianceAlert, error) {
+	alert, ok := tx.data.alerts.get(id)
+	if !ok {
+		return db.ComplianceAlert{}, pgx.ErrNoRows
+	}
+	return alert, nil
+}
+
+// ListComplianceAlerts returns the alerts matching the filters that are set, newest first
+func (tx *tx) ListComplianceAlerts(arg db.ListComplianceAlertsParams) ([]db.ComplianceAlert, error) {
+	return page(filter(tx.data.alerts.scanBackward(), func(alert db.ComplianceAlert) bool {
+		return matches(arg.Status, alert.Status) && matches(arg.Kind, alert.Kind) && matches(arg.Assignee, alert.Assignee)
+	}), arg.Limit, arg.Offset)
+}
+
+func (tx *tx) AssignComplianceAlert(arg db.AssignComplianceAlertParams) (db.ComplianceAlert, error) {
+	alert, err := tx.GetComplianceAlert(arg.ID)
+	if err != nil {
+		return db.ComplianceAlert{}, err
+	}
+
+	alert.Assignee = arg.Assignee
+	alert.Status = db.AlertStatusAssigned
+	alert.UpdatedAt = tx.now
+	return tx.data.alerts.update(tx, arg.ID, alert), nil
+}
+
+func (tx *tx) ResolveComplianceAlert(arg db.ResolveComplianceAlertParams) (db.ComplianceAlert, error) {
+	alert, err := tx.GetComplianceAlert(arg.ID)
+	if err != nil {
+		return db.ComplianceAlert{}, err
+	}
+
+	resolvedAt := tx.now
+	alert.Status = db.AlertStatusResolved
+	alert.Resolution = arg.Resolution
+	alert.ResolutionNote = arg.ResolutionNote
+	alert.ResolvedBy = arg.ResolvedBy
+	alert.ResolvedAt = &resolvedAt
+	alert.UpdatedAt = tx.now
+	return tx.data.alerts.update(tx, arg.ID, alert), nil
+}
+
+// AnalyzeTransfersTx applies rules to up to batchSize completed transfers not analyzed yet, oldest first,
+// raises the compliance alerts they call for and marks them analyzed
+// returns the number of transfers analyzed
+func (store *Store) AnalyzeTransfersTx(ctx context.Context, batchSize int32, rules db.AMLRules) (int, error) {
+	analyzed := 0
+
+	err := store.execTx(ctx, func(tx *tx) error {
+		analyzed = 0
+
+		transfers, err := tx.ListUnanalyzedTransfers(batchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, transfer := range transfers {
+			if err := tx.analyzeTransfer(transfer, rules); err != nil {
+				return err
+			}
+			if err := tx.MarkTransferAnalyzed(transfer.ID); err != nil {
+				return err
+			}
+			analyzed++
+		}
+		return nil
+	})
+
+	return analyzed, err
+}
+
+// analyzeTransfer raises the alerts called for by a single transfer
+func (tx *tx) analyzeTransfer(transfer db.ListUnanalyzedTransfersRow, rules db.AMLRules) error {
+	threshold, ok := rules.Thresholds[transfer.Currency]
+	if !ok {
+		return nil
+	}
+
+	if transfer.Amount >= threshold {
+		_, err := tx.CreateComplianceAlert(db.CreateComplianceAlertParams{
+			Kind:        db.AlertKindLargeTransaction,
+			AccountID:   transfer.FromAccountID,
+			Currency:    transfer.Currency,
+			TotalAmount: transfer.Amount,
+			TransferIds: []int64{transfer.ID},
+		})
+		return err
+	}
+
+	floor := threshold * rules.StructuringFloor / 100
+	if rules.StructuringMinCount <= 0 || transfer.Amount < floor {
+		return nil
+	}
+
+	until := transfer.CreatedAt
+	nearby, err := tx.ListTransfersInAmountRange(db.ListTransfersInAmountRangeParams{
+		AccountID: transfer.FromAccountID,
+		Since:     until.Add(-rules.StructuringWindow),
+		Until:     until,
+		MinAmount: floor,
+		MaxAmount: threshold,
+	})
+	if err != nil {
+		return err
+	}
+	if len(nearby) < rules.StructuringMinCount {
+		return nil
+	}
+
+	ids := make([]int64, len(nearby))
+	var total int64
+	for i, row := range nearby {
+		ids[i] = row.ID
+		total += row.Amount
+	}
+
+	_, err = tx.UpsertStructuringAlert(db.UpsertStructuringAlertParams{
+		AccountID:   transfer.FromAccountID,
+		Currency:    transfer.Currency,
+		TotalAmount: total,
+		TransferIds: ids,
+	})
+	return err
+}
+
+// AssignComplianceAlertTx hands an alert over to an investigator
+func (store *Store) AssignComplianceAlertTx(ctx context.Context, arg db.AssignComplianceAlertParams) (db.ComplianceAlert, error) {
+	var alert db.ComplianceAlert
+
+	err := store.execTx(ctx, func(tx *tx) error {
+		before, err := tx.unresolvedAlert(arg.ID)
+		if err != nil {
+			return err
+		}
+
+		alert, err = tx.AssignComplianceAlert(arg)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, tx, db.AuditActionAlertAssign, db.AuditResourceComplianceAlert, arg.ID, before, alert)
+	})
+
+	return alert, err
+}
+
+// ResolveComplianceAlertTx closes an alert
+func (store *Store) ResolveComplianceAlertTx(ctx context.Context, arg db.ResolveComplianceAlertTxParams) (db.ComplianceAlert, error) {
+	var alert db.ComplianceAlert
+
+	err := store.execTx(ctx, func(tx *tx) error {
+		before, err := tx.unresolvedAlert(arg.ID)
+		if err != nil {
+			return err
+		}
+
+		alert, err = tx.ResolveComplianceAlert(db.ResolveComplianceAlertParams{
+			ID:             arg.ID,
+			Resolution:     arg.Resolution,
+			ResolutionNote: arg.Note,
+			ResolvedBy:     db.AuditInfoFromContext(ctx).Actor,
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, tx, db.AuditActionAlertResolve, db.AuditResourceComplianceAlert, arg.ID, before, alert)
+	})
+
+	return alert, err
+}
+
+// unresolvedAlert returns the alert after checking that it isn't resolved
+func (tx *tx) unresolvedAlert(id int64) (db.ComplianceAlert, error) {
+	alert, err := tx.GetComplianceAlert(id)
+	if err != nil {
+		return db.ComplianceAlert{}, err
+	}
+
+	if alert.Status == db.AlertStatusResolved {
+		return db.ComplianceAlert{}, &db.Error{Kind: db.ErrAlertResolved, Details: map[string]any{"alert_id": id}}
+	}
+	return alert, nil
+}
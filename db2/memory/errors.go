@@ -0,0 +1,76 @@
+package memdb
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+)
+
+// SQLSTATE codes raised by the store besides the ones of package db
+const (
+	invalidLimitValue  = "2201W"
+	invalidOffsetValue = "2201X"
+)
+
+// currencyFormat is the accounts_currency_format and compliance_alerts_currency_format constraint
+var currencyFormat = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// the errors below are the ones postgres returns, so that db.ClassifyError and the callers see no difference
+
+func checkViolationError(table string, constraint string) error {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           db.CheckViolation,
+		Message:        fmt.Sprintf("new row for relation %q violates check constraint %q", table, constraint),
+		TableName:      table,
+		ConstraintName: constraint,
+	}
+}
+
+// foreignKeyError is raised by an insert or an update in table referencing a missing row
+func foreignKeyError(table string, constraint string) error {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           db.ForeignKeyViolation,
+		Message:        fmt.Sprintf("insert or update on table %q violates foreign key constraint %q", table, constraint),
+		TableName:      table,
+		ConstraintName: constraint,
+	}
+}
+
+// referencedError is raised by deleting a row of table still referenced by referencing
+func referencedError(table string, referencing string, constraint string) error {
+	return &pgconn.PgError{
+		Severity: "ERROR",
+		Code:     db.ForeignKeyViolation,
+		Message: fmt.Sprintf("update or delete on table %q violates foreign key constraint %q on table %q",
+			table, constraint, referencing),
+		TableName:      referencing,
+		ConstraintName: constraint,
+	}
+}
+
+func uniqueViolationError(table string, constraint string) error {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           db.UniqueViolation,
+		Message:        fmt.Sprintf("duplicate key value violates unique constraint %q", constraint),
+		TableName:      table,
+		ConstraintName: constraint,
+	}
+}
+
+// negativeLimit is raised by a negative LIMIT or OFFSET, clause is one of the two
+func negativeLimit(clause string) error {
+	code := invalidLimitValue
+	if clause == "OFFSET" {
+		code = invalidOffsetValue
+	}
+	return &pgconn.PgError{
+		Severity: "ERROR",
+		Code:     code,
+		Message:  clause + " must not be negative",
+	}
+}
@@ -0,0 +1,175 @@
+package memdb
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	db "github.com/techschool/simple-bank/db2/sqlc"
+)
+
+func (tx *tx) CreateOutboxEvent(arg db.CreateOutboxEventParams) (db.Outbox, error) {
+	return tx.data.outbox.insert(tx, func(id int64) db.Outbox {
+		return db.Outbox{
+			ID:            id,
+			EventType:     arg.EventType,
+			AggregateType: arg.AggregateType,
+			AggregateID:   arg.AggregateID,
+			Payload:       arg.Payload,
+			CreatedAt:     tx.now,
+		}
+	}), nil
+}
+
+// ListUnpublishedOutboxEvents returns the oldest unpublished events that aren't dead-lettered,
+// the ones claimed by a relay are skipped like the rows locked by another transaction with SKIP LOCKED
+func (tx *tx) ListUnpublishedOutboxEvents(arg db.ListUnpublishedOutboxEventsParams) ([]db.Outbox, error) {
+	return page(filter(tx.data.outbox.scan(), func(event db.Outbox) bool {
+		return event.PublishedAt == nil && event.Attempts < arg.MaxAttempts && !tx.data.claimedEvents[event.ID]
+	}), arg.Limit, 0)
+}
+
+func (tx *tx) MarkOutboxEventPublished(id int64) error {
+	event, ok := tx.data.outbox.get(id)
+	if !ok {
+		return nil
+	}
+	publishedAt := tx.now
+	event.PublishedAt = &publishedAt
+	event.Attempts++
+	event.LastError = ""
+	tx.data.outbox.update(tx, id, event)
+	return nil
+}
+
+func (tx *tx) MarkOutboxEventFailed(arg db.MarkOutboxEventFailedParams) error {
+	event, ok := tx.data.outbox.get(arg.ID)
+	if !ok {
+		return nil
+	}
+	event.Attempts++
+	event.LastError = arg.LastError
+	tx.data.outbox.update(tx, arg.ID, event)
+	return nil
+}
+
+// NotifyAccountEvent does nothing: the notifications of postgres reach the listeners of events.Listener,
+// which need a database connection of their own
+func (tx *tx) NotifyAccountEvent(payload string) error {
+	return nil
+}
+
+// enqueueEvent writes a domain event to the outbox within tx
+func enqueueEvent(tx *tx, eventType string, aggregateType string, aggregateID int64, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.CreateOutboxEvent(db.CreateOutboxEventParams{
+		EventType:     eventType,
+		AggregateType: aggregateType,
+		AggregateID:   strconv.FormatInt(aggregateID, 10),
+		Payload:       data,
+	})
+	return err
+}
+
+// enqueueTransferEvents writes the TransferCompleted event and one BalanceChanged event per account
+func enqueueTransferEvents(tx *tx, result db.TransferTxResult) error {
+	err := enqueueEvent(tx, db.EventTransferCompleted, db.AuditResourceTransfer, result.Transfer.ID, db.TransferCompletedEvent{
+		Transfer:  result.Transfer,
+		FromEntry: result.FromEntry,
+		ToEntry:   result.ToEntry,
+	})
+	if err != nil {
+		return err
+	}
+
+	changes := []struct {
+		account db.Account
+		entry   db.Entry
+	}{
+		{result.FromAccount, result.FromEntry},
+		{result.ToAccount, result.ToEntry},
+	}
+	for _, change := range changes {
+		event := db.BalanceChangedEvent{
+			AccountID: change.account.ID,
+			EntryID:   change.entry.ID,
+			Amount:    change.entry.Amount,
+			Balance:   change.account.Balance,
+			Currency:  change.account.Currency,
+			CreatedAt: change.entry.CreatedAt,
+		}
+		err = enqueueEvent(tx, db.EventBalanceChanged, db.AuditResourceAccount, change.account.ID, event)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RelayOutboxTx hands up to batchSize unpublished outbox events to publish, oldest first
+// the events are claimed until the call returns, so concurrent relays never publish the same batch. Unlike in
+// db.SQLStore the outcome of each publish is recorded right away, publish runs without the lock of the store.
+// The first failure is recorded on the event and stops the batch, the event is retried on the next call
+// until it has failed maxAttempts times and is dead-lettered, the batch then goes on without it
+// returns the number of events published, and the publish error if the batch was stopped by one
+func (store *Store) RelayOutboxTx(
+	ctx context.Context,
+	batchSize int32,
+	maxAttempts int32,
+	publish func(ctx context.Context, event db.Outbox) error,
+) (int, error) {
+	var events []db.Outbox
+	err := store.execTx(ctx, func(tx *tx) error {
+		var err error
+		events, err = tx.ListUnpublishedOutboxEvents(db.ListUnpublishedOutboxEventsParams{
+			MaxAttempts: maxAttempts,
+			Limit:       batchSize,
+		})
+		for _, event := range events {
+			tx.data.claimedEvents[event.ID] = true
+		}
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer store.release(store.data.claimedEvents, eventIDs(events))
+
+	published := 0
+	for _, event := range events {
+		if publishErr := publish(ctx, event); publishErr != nil {
+			err := store.execTx(ctx, func(tx *tx) error {
+				return tx.MarkOutboxEventFailed(db.MarkOutboxEventFailedParams{ID: event.ID, LastError: publishErr.Error()})
+			})
+			if err != nil {
+				return published, err
+			}
+			if db.IsOutboxEventDead(event, maxAttempts) {
+				continue
+			}
+			return published, publishErr
+		}
+
+		err := store.execTx(ctx, func(tx *tx) error {
+			return tx.MarkOutboxEventPublished(event.ID)
+		})
+		if err != nil {
+			return published, err
+		}
+		published++
+	}
+
+	return published, nil
+}
+
+func eventIDs(events []db.Outbox) []int64 {
+	ids := make([]int64, len(events))
+	for i, event := range events {
+		ids[i] = event.ID
+	}
+	return ids
+}
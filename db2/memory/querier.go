@@ -0,0 +1,255 @@
+package memdb
+
+import (
+	"context"
+
+	db "github.com/techschool/simple-bank/db2/sqlc"
+)
+
+// the queries of db.Querier, each one runs on its own like a statement outside of a transaction
+
+func (store *Store) AddAccountBalance(ctx context.Context, arg db.AddAccountBalanceParams) (db.Account, error) {
+	return query(ctx, store, func(tx *tx) (db.Account, error) { return tx.AddAccountBalance(arg) })
+}
+
+func (store *Store) AssignComplianceAlert(ctx context.Context, arg db.AssignComplianceAlertParams) (db.ComplianceAlert, error) {
+	return query(ctx, store, func(tx *tx) (db.ComplianceAlert, error) { return tx.AssignComplianceAlert(arg) })
+}
+
+func (store *Store) ClaimWebhookDeliveries(ctx context.Context, arg db.ClaimWebhookDeliveriesParams) error {
+	return store.exec(ctx, func(tx *tx) error { return tx.ClaimWebhookDeliveries(arg) })
+}
+
+func (store *Store) CreateAccount(ctx context.Context, arg db.CreateAccountParams) (db.Account, error) {
+	return query(ctx, store, func(tx *tx) (db.Account, error) { return tx.CreateAccount(arg) })
+}
+
+func (store *Store) CreateAuditEvent(ctx context.Context, arg db.CreateAuditEventParams) (db.AuditEvent, error) {
+	return query(ctx, store, func(tx *tx) (db.AuditEvent, error) { return tx.CreateAuditEvent(arg) })
+}
+
+func (store *Store) CreateComplianceAlert(ctx context.Context, arg db.CreateComplianceAlertParams) (db.ComplianceAlert, error) {
+	return query(ctx, store, func(tx *tx) (db.ComplianceAlert, error) { return tx.CreateComplianceAlert(arg) })
+}
+
+func (store *Store) CreateEntry(ctx context.Context, arg db.CreateEntryParams) (db.Entry, error) {
+	return query(ctx, store, func(tx *tx) (db.Entry, error) { return tx.CreateEntry(arg) })
+}
+
+func (store *Store) CreateEquityAccount(ctx context.Context, currency string) error {
+	return store.exec(ctx, func(tx *tx) error { return tx.CreateEquityAccount(currency) })
+}
+
+func (store *Store) CreateOpeningTransfer(ctx context.Context, arg db.CreateOpeningTransferParams) (db.Transfer, error) {
+	return query(ctx, store, func(tx *tx) (db.Transfer, error) { return tx.CreateOpeningTransfer(arg) })
+}
+
+func (store *Store) CreateOutboxEvent(ctx context.Context, arg db.CreateOutboxEventParams) (db.Outbox, error) {
+	return query(ctx, store, func(tx *tx) (db.Outbox, error) { return tx.CreateOutboxEvent(arg) })
+}
+
+func (store *Store) CreatePendingTransfer(ctx context.Context, arg db.CreatePendingTransferParams) (db.Transfer, error) {
+	return query(ctx, store, func(tx *tx) (db.Transfer, error) { return tx.CreatePendingTransfer(arg) })
+}
+
+func (store *Store) CreateTransfer(ctx context.Context, arg db.CreateTransferParams) (db.Transfer, error) {
+	return query(ctx, store, func(tx *tx) (db.Transfer, error) { return tx.CreateTransfer(arg) })
+}
+
+func (store *Store) CreateWebhook(ctx context.Context, arg db.CreateWebhookParams) (db.Webhook, error) {
+	return query(ctx, store, func(tx *tx) (db.Webhook, error) { return tx.CreateWebhook(arg) })
+}
+
+func (store *Store) CreateWebhookDeliveries(ctx context.Context, arg db.CreateWebhookDeliveriesParams) (int64, error) {
+	return query(ctx, store, func(tx *tx) (int64, error) { return tx.CreateWebhookDeliveries(arg) })
+}
+
+func (store *Store) DeleteAccount(ctx context.Context, id int64) error {
+	return store.exec(ctx, func(tx *tx) error { return tx.DeleteAccount(id) })
+}
+
+func (store *Store) DeleteAccountTransferLimitOverride(ctx context.Context, accountID int64) error {
+	return store.exec(ctx, func(tx *tx) error { return tx.DeleteAccountTransferLimitOverride(accountID) })
+}
+
+func (store *Store) DeleteWebhook(ctx context.Context, id int64) error {
+	return store.exec(ctx, func(tx *tx) error { return tx.DeleteWebhook(id) })
+}
+
+func (store *Store) GetAccount(ctx context.Context, id int64) (db.Account, error) {
+	return query(ctx, store, func(tx *tx) (db.Account, error) { return tx.GetAccount(id) })
+}
+
+func (store *Store) GetAccountForUpdate(ctx context.Context, id int64) (db.Account, error) {
+	return query(ctx, store, func(tx *tx) (db.Account, error) { return tx.GetAccount(id) })
+}
+
+func (store *Store) GetAccountTransferLimitOverride(ctx context.Context, accountID int64) (db.AccountTransferLimit, error) {
+	return query(ctx, store, func(tx *tx) (db.AccountTransferLimit, error) { return tx.GetAccountTransferLimitOverride(accountID) })
+}
+
+func (store *Store) GetAuditEvent(ctx context.Context, id int64) (db.AuditEvent, error) {
+	return query(ctx, store, func(tx *tx) (db.AuditEvent, error) { return tx.GetAuditEvent(id) })
+}
+
+func (store *Store) GetComplianceAlert(ctx context.Context, id int64) (db.ComplianceAlert, error) {
+	return query(ctx, store, func(tx *tx) (db.ComplianceAlert, error) { return tx.GetComplianceAlert(id) })
+}
+
+func (store *Store) GetComplianceAlertForUpdate(ctx context.Context, id int64) (db.ComplianceAlert, error) {
+	return query(ctx, store, func(tx *tx) (db.ComplianceAlert, error) { return tx.GetComplianceAlert(id) })
+}
+
+func (store *Store) GetDailyOutgoingTransfers(ctx context.Context, arg db.GetDailyOutgoingTransfersParams) (db.GetDailyOutgoingTransfersRow, error) {
+	return query(ctx, store, func(tx *tx) (db.GetDailyOutgoingTransfersRow, error) {
+		return tx.GetDailyOutgoingTransfers(arg)
+	})
+}
+
+func (store *Store) GetEffectiveTransferLimits(ctx context.Context, id int64) (db.GetEffectiveTransferLimitsRow, error) {
+	return query(ctx, store, func(tx *tx) (db.GetEffectiveTransferLimitsRow, error) { return tx.GetEffectiveTransferLimits(id) })
+}
+
+func (store *Store) GetEntry(ctx context.Context, id int64) (db.Entry, error) {
+	return query(ctx, store, func(tx *tx) (db.Entry, error) { return tx.GetEntry(id) })
+}
+
+func (store *Store) GetEquityAccount(ctx context.Context, currency string) (db.Account, error) {
+	return query(ctx, store, func(tx *tx) (db.Account, error) { return tx.GetEquityAccount(currency) })
+}
+
+func (store *Store) GetTransfer(ctx context.Context, id int64) (db.Transfer, error) {
+	return query(ctx, store, func(tx *tx) (db.Transfer, error) { return tx.GetTransfer(id) })
+}
+
+func (store *Store) GetTransferForUpdate(ctx context.Context, id int64) (db.Transfer, error) {
+	return query(ctx, store, func(tx *tx) (db.Transfer, error) { return tx.GetTransfer(id) })
+}
+
+func (store *Store) GetTransferLimitTier(ctx context.Context, name string) (db.TransferLimitTier, error) {
+	return query(ctx, store, func(tx *tx) (db.TransferLimitTier, error) { return tx.GetTransferLimitTier(name) })
+}
+
+func (store *Store) GetWebhook(ctx context.Context, id int64) (db.Webhook, error) {
+	return query(ctx, store, func(tx *tx) (db.Webhook, error) { return tx.GetWebhook(id) })
+}
+
+func (store *Store) HasCompletedTransfer(ctx context.Context, arg db.HasCompletedTransferParams) (bool, error) {
+	return query(ctx, store, func(tx *tx) (bool, error) { return tx.HasCompletedTransfer(arg) })
+}
+
+func (store *Store) ListAccounts(ctx context.Context, arg db.ListAccountsParams) ([]db.Account, error) {
+	return query(ctx, store, func(tx *tx) ([]db.Account, error) { return tx.ListAccounts(arg) })
+}
+
+func (store *Store) ListAuditEvents(ctx context.Context, arg db.ListAuditEventsParams) ([]db.AuditEvent, error) {
+	return query(ctx, store, func(tx *tx) ([]db.AuditEvent, error) { return tx.ListAuditEvents(arg) })
+}
+
+func (store *Store) ListComplianceAlerts(ctx context.Context, arg db.ListComplianceAlertsParams) ([]db.ComplianceAlert, error) {
+	return query(ctx, store, func(tx *tx) ([]db.ComplianceAlert, error) { return tx.ListComplianceAlerts(arg) })
+}
+
+func (store *Store) ListDueWebhookDeliveries(ctx context.Context, limit int32) ([]db.ListDueWebhookDeliveriesRow, error) {
+	return query(ctx, store, func(tx *tx) ([]db.ListDueWebhookDeliveriesRow, error) { return tx.ListDueWebhookDeliveries(limit) })
+}
+
+func (store *Store) ListEntries(ctx context.Context, arg db.ListEntriesParams) ([]db.Entry, error) {
+	return query(ctx, store, func(tx *tx) ([]db.Entry, error) { return tx.ListEntries(arg) })
+}
+
+func (store *Store) ListPendingTransfers(ctx context.Context, arg db.ListPendingTransfersParams) ([]db.Transfer, error) {
+	return query(ctx, store, func(tx *tx) ([]db.Transfer, error) { return tx.ListPendingTransfers(arg) })
+}
+
+func (store *Store) ListRecentOutgoingTransfers(ctx context.Context, arg db.ListRecentOutgoingTransfersParams) ([]db.Transfer, error) {
+	return query(ctx, store, func(tx *tx) ([]db.Transfer, error) { return tx.ListRecentOutgoingTransfers(arg) })
+}
+
+func (store *Store) ListTransferLimitTiers(ctx context.Context) ([]db.TransferLimitTier, error) {
+	return query(ctx, store, func(tx *tx) ([]db.TransferLimitTier, error) { return tx.ListTransferLimitTiers() })
+}
+
+func (store *Store) ListTransfers(ctx context.Context, arg db.ListTransfersParams) ([]db.Transfer, error) {
+	return query(ctx, store, func(tx *tx) ([]db.Transfer, error) { return tx.ListTransfers(arg) })
+}
+
+func (store *Store) ListTransfersInAmountRange(ctx context.Context, arg db.ListTransfersInAmountRangeParams) ([]db.ListTransfersInAmountRangeRow, error) {
+	return query(ctx, store, func(tx *tx) ([]db.ListTransfersInAmountRangeRow, error) { return tx.ListTransfersInAmountRange(arg) })
+}
+
+func (store *Store) ListUnanalyzedTransfers(ctx context.Context, limit int32) ([]db.ListUnanalyzedTransfersRow, error) {
+	return query(ctx, store, func(tx *tx) ([]db.ListUnanalyzedTransfersRow, error) { return tx.ListUnanalyzedTransfers(limit) })
+}
+
+func (store *Store) ListUnpublishedOutboxEvents(ctx context.Context, arg db.ListUnpublishedOutboxEventsParams) ([]db.Outbox, error) {
+	return query(ctx, store, func(tx *tx) ([]db.Outbox, error) { return tx.ListUnpublishedOutboxEvents(arg) })
+}
+
+func (store *Store) ListWebhookDeliveries(ctx context.Context, arg db.ListWebhookDeliveriesParams) ([]db.WebhookDelivery, error) {
+	return query(ctx, store, func(tx *tx) ([]db.WebhookDelivery, error) { return tx.ListWebhookDeliveries(arg) })
+}
+
+func (store *Store) ListWebhooks(ctx context.Context, arg db.ListWebhooksParams) ([]db.Webhook, error) {
+	return query(ctx, store, func(tx *tx) ([]db.Webhook, error) { return tx.ListWebhooks(arg) })
+}
+
+func (store *Store) MarkOutboxEventFailed(ctx context.Context, arg db.MarkOutboxEventFailedParams) error {
+	return store.exec(ctx, func(tx *tx) error { return tx.MarkOutboxEventFailed(arg) })
+}
+
+func (store *Store) MarkOutboxEventPublished(ctx context.Context, id int64) error {
+	return store.exec(ctx, func(tx *tx) error { return tx.MarkOutboxEventPublished(id) })
+}
+
+func (store *Store) MarkTransferAnalyzed(ctx context.Context, id int64) error {
+	return store.exec(ctx, func(tx *tx) error { return tx.MarkTransferAnalyzed(id) })
+}
+
+func (store *Store) NotifyAccountEvent(ctx context.Context, payload string) error {
+	return store.exec(ctx, func(tx *tx) error { return tx.NotifyAccountEvent(payload) })
+}
+
+func (store *Store) ReconcileAccounts(ctx context.Context) ([]db.ReconcileAccountsRow, error) {
+	return query(ctx, store, func(tx *tx) ([]db.ReconcileAccountsRow, error) { return tx.ReconcileAccounts() })
+}
+
+func (store *Store) RecordWebhookAttempt(ctx context.Context, arg db.RecordWebhookAttemptParams) (int64, error) {
+	return query(ctx, store, func(tx *tx) (int64, error) { return tx.RecordWebhookAttempt(arg) })
+}
+
+func (store *Store) ResolveComplianceAlert(ctx context.Context, arg db.ResolveComplianceAlertParams) (db.ComplianceAlert, error) {
+	return query(ctx, store, func(tx *tx) (db.ComplianceAlert, error) { return tx.ResolveComplianceAlert(arg) })
+}
+
+func (store *Store) ReviewTransfer(ctx context.Context, arg db.ReviewTransferParams) (db.Transfer, error) {
+	return query(ctx, store, func(tx *tx) (db.Transfer, error) { return tx.ReviewTransfer(arg) })
+}
+
+func (store *Store) UpdateAccount(ctx context.Context, arg db.UpdateAccountParams) (db.Account, error) {
+	return query(ctx, store, func(tx *tx) (db.Account, error) { return tx.UpdateAccount(arg) })
+}
+
+func (store *Store) UpdateAccountLimitTier(ctx context.Context, arg db.UpdateAccountLimitTierParams) (db.Account, error) {
+	return query(ctx, store, func(tx *tx) (db.Account, error) { return tx.UpdateAccountLimitTier(arg) })
+}
+
+func (store *Store) UpdateAccountStatus(ctx context.Context, arg db.UpdateAccountStatusParams) (db.Account, error) {
+	return query(ctx, store, func(tx *tx) (db.Account, error) { return tx.UpdateAccountStatus(arg) })
+}
+
+func (store *Store) UpdateWebhook(ctx context.Context, arg db.UpdateWebhookParams) (db.Webhook, error) {
+	return query(ctx, store, func(tx *tx) (db.Webhook, error) { return tx.UpdateWebhook(arg) })
+}
+
+func (store *Store) UpsertAccountTransferLimitOverride(ctx context.Context, arg db.UpsertAccountTransferLimitOverrideParams) (db.AccountTransferLimit, error) {
+	return query(ctx, store, func(tx *tx) (db.AccountTransferLimit, error) { return tx.UpsertAccountTransferLimitOverride(arg) })
+}
+
+func (store *Store) UpsertStructuringAlert(ctx context.Context, arg db.UpsertStructuringAlertParams) (db.ComplianceAlert, error) {
+	return query(ctx, store, func(tx *tx) (db.ComplianceAlert, error) { return tx.UpsertStructuringAlert(arg) })
+}
+
+func (store *Store) UpsertTransferLimitTier(ctx context.Context, arg db.UpsertTransferLimitTierParams) (db.TransferLimitTier, error) {
+	return query(ctx, store, func(tx *tx) (db.TransferLimitTier, error) { return tx.UpsertTransferLimitTier(arg) })
+}
@@ -0,0 +1,357 @@
+// Package memdb is a db.Store keeping its data in memory, for tests and local development without postgres.
+// It follows the schema and the queries of package db: the same defaults, constraints and errors, e.g.
+// pgx.ErrNoRows from a query that finds nothing and a *db.Error from the transactions.
+// The suite of package storetest checks both stores against each other
+package memdb
+
+import (
+	"context"
+	"iter"
+	"slices"
+	"sync"
+	"time"
+
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/fraud"
+)
+
+// Store is a db.Store keeping its data in memory
+// every query and transaction holds a single lock, so transactions are serializable and never need a retry.
+// The callbacks of RelayOutboxTx and ProcessWebhookDeliveriesTx run without the lock, they may use the store
+type Store struct {
+	mu       sync.Mutex
+	data     *database
+	screener fraud.Screener // screens every transfer, nil allows them all
+}
+
+var _ db.Store = (*Store)(nil)
+
+// StoreOption configures a store created by NewStore
+type StoreOption func(*Store)
+
+// WithScreener makes TransferTx screen every transfer with screener
+// the screener is called with the lock of the store held, it must not use the store
+func WithScreener(screener fraud.Screener) StoreOption {
+	return func(store *Store) {
+		store.screener = screener
+	}
+}
+
+// NewStore creates an empty store, holding what the migrations create: the standard limit tier
+func NewStore(opts ...StoreOption) db.Store {
+	store := &Store{data: newDatabase()}
+	for _, opt := range opts {
+		opt(store)
+	}
+	return store
+}
+
+// the standard limit tier inserted by the transfer limits migration
+const (
+	standardMaxSingleAmount = 1000000
+	standardMaxDailyAmount  = 5000000
+	standardMaxDailyCount   = 50
+)
+
+// database holds the tables and the indexes the queries need
+type database struct {
+	accounts    *table[db.Account]
+	entries     *table[db.Entry]
+	transfers   *table[db.Transfer]
+	auditEvents *table[db.AuditEvent]
+	outbox      *table[db.Outbox]
+	webhooks    *table[db.Webhook]
+	deliveries  *table[db.WebhookDelivery]
+	alerts      *table[db.ComplianceAlert]
+	tiers       map[string]db.TransferLimitTier
+	overrides   map[int64]db.AccountTransferLimit // by account id
+
+	entriesByAccount   map[int64][]int64    // entry ids by account, in increasing order
+	transfersByAccount map[int64][]int64    // ids of the transfers sent or received by an account, in increasing order
+	sentByAccount      map[int64][]int64    // ids of the transfers sent by an account, in increasing order
+	deliveryKeys       map[deliveryKey]bool // the unique (webhook_id, event_id) index of the deliveries
+	equityAccounts     map[string]int64     // the unique index of the equity accounts, their ids by currency
+	claimedEvents      map[int64]bool       // outbox events handed to a relay, the other relays skip them
+}
+
+type deliveryKey struct {
+	webhookID int64
+	eventID   int64
+}
+
+func newDatabase() *database {
+	return &database{
+		accounts:    newTable[db.Account](nil),
+		entries:     newTable[db.Entry](nil),
+		transfers:   newTable[db.Transfer](nil),
+		auditEvents: newTable(cloneAuditEvent),
+		outbox:      newTable(cloneOutbox),
+		webhooks:    newTable(cloneWebhook),
+		deliveries:  newTable(cloneDelivery),
+		alerts:      newTable(cloneAlert),
+		tiers: map[string]db.TransferLimitTier{
+			db.DefaultLimitTier: {
+				Name:            db.DefaultLimitTier,
+				MaxSingleAmount: standardMaxSingleAmount,
+				MaxDailyAmount:  standardMaxDailyAmount,
+				MaxDailyCount:   standardMaxDailyCount,
+				UpdatedAt:       now(),
+			},
+		},
+		overrides:          map[int64]db.AccountTransferLimit{},
+		entriesByAccount:   map[int64][]int64{},
+		transfersByAccount: map[int64][]int64{},
+		sentByAccount:      map[int64][]int64{},
+		deliveryKeys:       map[deliveryKey]bool{},
+		equityAccounts:     map[string]int64{},
+		claimedEvents:      map[int64]bool{},
+	}
+}
+
+// now returns the current time as postgres stores it, to the microsecond
+func now() time.Time {
+	return time.Now().Round(time.Microsecond)
+}
+
+// tx is a transaction on the data of the store, its changes are undone if it fails
+type tx struct {
+	data *database
+	now  time.Time // like now() in postgres, the time the transaction started
+	undo []func()  // reverts the changes made so far, in the order they were made
+}
+
+// onRollback registers a function reverting the last change
+func (tx *tx) onRollback(fn func()) {
+	tx.undo = append(tx.undo, fn)
+}
+
+func (tx *tx) rollback() {
+	for i := len(tx.undo) - 1; i >= 0; i-- {
+		tx.undo[i]()
+	}
+	tx.undo = nil
+}
+
+// exec runs fn in a transaction holding the lock of the store, a failing fn leaves the data unchanged
+// like in postgres, a cancelled context fails the transaction
+func (store *Store) exec(ctx context.Context, fn func(tx *tx) error) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	tx := &tx{data: store.data, now: now()}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.rollback()
+			panic(r)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		tx.rollback()
+		return err
+	}
+	return nil
+}
+
+// execTx is exec for the transactions of the Store interface, their errors are classified like the ones of db.SQLStore
+func (store *Store) execTx(ctx context.Context, fn func(tx *tx) error) error {
+	return db.ClassifyError(store.exec(ctx, fn))
+}
+
+// query runs a single statement returning a result, its errors are left unclassified like the ones of db.Queries
+func query[T any](ctx context.Context, store *Store, fn func(tx *tx) (T, error)) (T, error) {
+	var result T
+	err := store.exec(ctx, func(tx *tx) error {
+		var err error
+		result, err = fn(tx)
+		return err
+	})
+	return result, err
+}
+
+// release drops claims taken by a relay, even after its context was cancelled
+func (store *Store) release(claims map[int64]bool, ids []int64) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	for _, id := range ids {
+		delete(claims, id)
+	}
+}
+
+// table holds the rows of a table with a bigserial primary key
+// ids are never reused, a rolled back insert leaves a gap like a postgres sequence does
+type table[T any] struct {
+	rows  map[int64]T
+	ids   []int64   // the ids of the rows in increasing order, deleted rows are skipped when scanning
+	seq   int64     // the last id handed out
+	clone func(T) T // deep copies rows holding slices, so that callers never share them with the table
+}
+
+func newTable[T any](clone func(T) T) *table[T] {
+	if clone == nil {
+		clone = func(row T) T { return row }
+	}
+	return &table[T]{rows: map[int64]T{}, clone: clone}
+}
+
+// insert stores the row built for the next id and returns it
+func (t *table[T]) insert(tx *tx, build func(id int64) T) T {
+	t.seq++
+	id := t.seq
+	row := t.clone(build(id))
+
+	t.rows[id] = row
+	t.ids = append(t.ids, id)
+	tx.onRollback(func() {
+		delete(t.rows, id)
+		t.ids = t.ids[:len(t.ids)-1]
+	})
+	return t.clone(row)
+}
+
+func (t *table[T]) get(id int64) (T, bool) {
+	row, ok := t.rows[id]
+	return t.clone(row), ok
+}
+
+// update replaces the row with the given id, which must exist
+func (t *table[T]) update(tx *tx, id int64, row T) T {
+	set(tx, t.rows, id, t.clone(row))
+	return t.clone(row)
+}
+
+func (t *table[T]) delete(tx *tx, id int64) {
+	unset(tx, t.rows, id)
+}
+
+// scan returns the rows in increasing id order
+func (t *table[T]) scan() iter.Seq[T] {
+	return t.scanIDs(t.ids)
+}
+
+// scanBackward returns the rows in decreasing id order
+func (t *table[T]) scanBackward() iter.Seq[T] {
+	return t.scanIDsBackward(t.ids)
+}
+
+// scanIDs returns the rows with the given ids, in the order of ids
+func (t *table[T]) scanIDs(ids []int64) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for _, id := range ids {
+			if row, ok := t.rows[id]; ok && !yield(t.clone(row)) {
+				return
+			}
+		}
+	}
+}
+
+// scanIDsBackward returns the rows with the given ids, in the reverse order of ids
+func (t *table[T]) scanIDsBackward(ids []int64) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for i := len(ids) - 1; i >= 0; i-- {
+			if row, ok := t.rows[ids[i]]; ok && !yield(t.clone(row)) {
+				return
+			}
+		}
+	}
+}
+
+// set stores value under key, the previous value comes back on rollback
+func set[K comparable, V any](tx *tx, m map[K]V, key K, value V) {
+	previous, existed := m[key]
+	m[key] = value
+	tx.onRollback(func() {
+		if existed {
+			m[key] = previous
+		} else {
+			delete(m, key)
+		}
+	})
+}
+
+// unset deletes key, its value comes back on rollback
+func unset[K comparable, V any](tx *tx, m map[K]V, key K) {
+	previous, existed := m[key]
+	if !existed {
+		return
+	}
+	delete(m, key)
+	tx.onRollback(func() {
+		m[key] = previous
+	})
+}
+
+// appendID adds id to the ids indexed under key
+func appendID(tx *tx, index map[int64][]int64, key int64, id int64) {
+	index[key] = append(index[key], id)
+	tx.onRollback(func() {
+		ids := index[key]
+		index[key] = ids[:len(ids)-1]
+	})
+}
+
+// page applies LIMIT and OFFSET to rows, an empty page is an empty slice like the one sqlc returns
+func page[T any](rows iter.Seq[T], limit int32, offset int32) ([]T, error) {
+	if limit < 0 {
+		return nil, negativeLimit("LIMIT")
+	}
+	if offset < 0 {
+		return nil, negativeLimit("OFFSET")
+	}
+
+	items := []T{}
+	skipped := int32(0)
+	for row := range rows {
+		if int32(len(items)) == limit {
+			break
+		}
+		if skipped < offset {
+			skipped++
+			continue
+		}
+		items = append(items, row)
+	}
+	return items, nil
+}
+
+// filter returns the rows of seq matching keep
+func filter[T any](seq iter.Seq[T], keep func(T) bool) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for row := range seq {
+			if keep(row) && !yield(row) {
+				return
+			}
+		}
+	}
+}
+
+func cloneAuditEvent(event db.AuditEvent) db.AuditEvent {
+	event.Before = slices.Clone(event.Before)
+	event.After = slices.Clone(event.After)
+	return event
+}
+
+func cloneOutbox(event db.Outbox) db.Outbox {
+	event.Payload = slices.Clone(event.Payload)
+	return event
+}
+
+func cloneWebhook(webhook db.Webhook) db.Webhook {
+	webhook.EventTypes = slices.Clone(webhook.EventTypes)
+	return webhook
+}
+
+func cloneDelivery(delivery db.WebhookDelivery) db.WebhookDelivery {
+	delivery.Payload = slices.Clone(delivery.Payload)
+	return delivery
+}
+
+func cloneAlert(alert db.ComplianceAlert) db.ComplianceAlert {
+	alert.TransferIds = slices.Clone(alert.TransferIds)
+	return alert
+}
@@ -0,0 +1,162 @@
+package memdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/db2/storetest"
+	"github.com/techschool/simple-bank/fraud"
+)
+
+func TestStoreConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, screener fraud.Screener) db.Store {
+		return NewStore(WithScreener(screener))
+	})
+}
+
+// a relay publishing a batch holds no lock, a second relay must skip the claimed events instead of publishing them again
+func TestRelayOutboxTxClaims(t *testing.T) {
+	store := NewStore()
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_, err := store.CreateAccountTx(ctx, db.CreateAccountParams{Owner: "owner", Currency: "USD"})
+		require.NoError(t, err)
+	}
+
+	// the first relay reports back on done, require must not be called outside the test goroutine
+	type relayed struct {
+		n   int
+		err error
+	}
+	publishing := make(chan struct{})
+	done := make(chan relayed)
+	go func() {
+		n, err := store.RelayOutboxTx(ctx, 2, 3, func(ctx context.Context, event db.Outbox) error {
+			if event.ID == 1 {
+				publishing <- struct{}{}
+				<-publishing
+			}
+			return nil
+		})
+		done <- relayed{n, err}
+	}()
+	<-publishing
+
+	var published []int64
+	n, err := store.RelayOutboxTx(ctx, 10, 3, func(ctx context.Context, event db.Outbox) error {
+		published = append(published, event.ID)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, []int64{3}, published)
+
+	publishing <- struct{}{}
+	first := <-done
+	require.NoError(t, first.err)
+	require.Equal(t, 2, first.n)
+
+	// the claims are released, nothing is left to publish
+	events, err := store.ListUnpublishedOutboxEvents(ctx, db.ListUnpublishedOutboxEventsParams{MaxAttempts: 3, Limit: 10})
+	require.NoError(t, err)
+	require.Empty(t, events)
+}
+
+// a failing transaction leaves no row and no index entry behind
+func TestExecTxRollsBack(t *testing.T) {
+	store := NewStore().(*Store)
+	ctx := context.Background()
+
+	from, err := store.CreateAccountTx(ctx, db.CreateAccountParams{Owner: "from", Balance: 10, Currency: "USD"})
+	require.NoError(t, err)
+	to, err := store.CreateAccountTx(ctx, db.CreateAccountParams{Owner: "to", Currency: "USD"})
+	require.NoError(t, err)
+
+	_, err = store.TransferTx(ctx, db.TransferTxParams{FromAccountID: from.ID, ToAccountID: to.ID, Amount: 20})
+	require.ErrorIs(t, err, db.ErrInsufficientFunds)
+
+	// only the opening balance of from is left, paid by the equity account
+	require.Equal(t, int64(2), store.data.transfers.seq, "like a postgres sequence, the id stays used")
+	require.Len(t, store.data.transfers.rows, 1)
+	require.Len(t, store.data.transfers.ids, 1)
+	require.Len(t, store.data.entries.rows, 2)
+	require.Len(t, store.data.entriesByAccount[from.ID], 1)
+	require.Empty(t, store.data.sentByAccount[from.ID])
+
+	// the panics of the transaction roll it back too
+	require.Panics(t, func() {
+		_ = store.exec(ctx, func(tx *tx) error {
+			_, err := tx.AddAccountBalance(db.AddAccountBalanceParams{ID: from.ID, Amount: 100})
+			require.NoError(t, err)
+			panic("boom")
+		})
+	})
+	account, err := store.GetAccount(ctx, from.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), account.Balance)
+}
+
+// verdictScreener returns the same verdict for every transfer
+type verdictScreener struct {
+	verdict fraud.Verdict
+}
+
+func (screener *verdictScreener) Screen(ctx context.Context, input fraud.Input) (fraud.Verdict, error) {
+	return screener.verdict, nil
+}
+
+// a transfer held for review counts towards the daily limits of the day it is approved, not of the day it was made
+func TestApproveTransferTxFromPreviousDay(t *testing.T) {
+	screener := &verdictScreener{verdict: fraud.Verdict{Decision: fraud.Review, Rule: "test_rule"}}
+	store := NewStore(WithScreener(screener)).(*Store)
+	ctx := context.Background()
+
+	from, err := store.CreateAccountTx(ctx, db.CreateAccountParams{Owner: "from", Balance: 1000, Currency: "USD"})
+	require.NoError(t, err)
+	to, err := store.CreateAccountTx(ctx, db.CreateAccountParams{Owner: "to", Currency: "USD"})
+	require.NoError(t, err)
+	maxAmount, maxCount := int64(100), int32(3)
+	_, err = store.UpdateAccountTransferLimitsTx(ctx, db.UpdateAccountTransferLimitsParams{
+		AccountID:       from.ID,
+		Tier:            db.DefaultLimitTier,
+		MaxSingleAmount: &maxAmount,
+		MaxDailyAmount:  &maxAmount,
+		MaxDailyCount:   &maxCount,
+	})
+	require.NoError(t, err)
+
+	transfer := func(amount int64) db.Transfer {
+		result, err := store.TransferTx(ctx, db.TransferTxParams{FromAccountID: from.ID, ToAccountID: to.ID, Amount: amount})
+		require.NoError(t, err)
+		return result.Transfer
+	}
+	early, late := transfer(60), transfer(40)
+	for _, pending := range []db.Transfer{early, late} {
+		pending.CreatedAt = pending.CreatedAt.AddDate(0, 0, -1)
+		store.data.transfers.rows[pending.ID] = pending
+	}
+
+	screener.verdict = fraud.Verdict{Decision: fraud.Allow}
+	transfer(50)
+
+	// 50 today plus the 60 approved now is over the daily amount, the transfer stays pending
+	_, err = store.ApproveTransferTx(ctx, db.ReviewTransferTxParams{TransferID: early.ID})
+	require.ErrorIs(t, err, db.ErrLimitExceeded)
+	var dbErr *db.Error
+	require.ErrorAs(t, err, &dbErr)
+	require.Equal(t, db.LimitDailyAmount, dbErr.Details["limit"])
+	require.Equal(t, int64(50), dbErr.Details["remaining_daily_amount"])
+	require.Equal(t, int32(2), dbErr.Details["remaining_daily_count"])
+
+	// the 40 fits and is counted today
+	_, err = store.ApproveTransferTx(ctx, db.ReviewTransferTxParams{TransferID: late.ID})
+	require.NoError(t, err)
+
+	daily, err := store.GetDailyOutgoingTransfers(ctx, db.GetDailyOutgoingTransfersParams{FromAccountID: from.ID, Day: time.Now()})
+	require.NoError(t, err)
+	require.Equal(t, db.GetDailyOutgoingTransfersRow{TotalAmount: 90, TotalCount: 2}, daily)
+}
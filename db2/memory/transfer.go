@@ -0,0 +1,516 @@
+package memdb
+
+import (
+	"context"
+	"iter"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/fraud"
+	"github.com/techschool/simple-bank/metrics"
+)
+
+// insertTransfer checks the constraints of the transfers table and inserts transfer with the next id
+func (tx *tx) insertTransfer(transfer db.Transfer) (db.Transfer, error) {
+	// postgres checks the constraints of the row first and its foreign keys at the end of the statement
+	if transfer.Amount <= 0 {
+		return db.Transfer{}, checkViolationError("transfers", "transfers_amount_positive")
+	}
+	if transfer.FromAccountID == transfer.ToAccountID {
+		return db.Transfer{}, checkViolationError("transfers", "transfers_distinct_accounts")
+	}
+	if _, ok := tx.data.accounts.get(transfer.FromAccountID); !ok {
+		return db.Transfer{}, foreignKeyError("transfers", "transfers_from_account_id_fkey")
+	}
+	if _, ok := tx.data.accounts.get(transfer.ToAccountID); !ok {
+		return db.Transfer{}, foreignKeyError("transfers", "transfers_to_account_id_fkey")
+	}
+
+	transfer = tx.data.transfers.insert(tx, func(id int64) db.Transfer {
+		transfer.ID = id
+		transfer.CreatedAt = tx.now
+		return transfer
+	})
+	appendID(tx, tx.data.transfersByAccount, transfer.FromAccountID, transfer.ID)
+	appendID(tx, tx.data.transfersByAccount, transfer.ToAccountID, transfer.ID)
+	appendID(tx, tx.data.sentByAccount, transfer.FromAccountID, transfer.ID)
+	return transfer, nil
+}
+
+func (tx *tx) CreateTransfer(arg db.CreateTransferParams) (db.Transfer, error) {
+	return tx.insertTransfer(db.Transfer{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+		Status:        db.TransferStatusCompleted,
+	})
+}
+
+// CreateOpeningTransfer creates the transfer paying the opening balance of an account, the AML analyzer skips it
+func (tx *tx) CreateOpeningTransfer(arg db.CreateOpeningTransferParams) (db.Transfer, error) {
+	analyzedAt := tx.now
+	return tx.insertTransfer(db.Transfer{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+		Status:        db.TransferStatusCompleted,
+		AmlAnalyzedAt: &analyzedAt,
+	})
+}
+
+func (tx *tx) CreatePendingTransfer(arg db.CreatePendingTransferParams) (db.Transfer, error) {
+	return tx.insertTransfer(db.Transfer{
+		FromAccountID:   arg.FromAccountID,
+		ToAccountID:     arg.ToAccountID,
+		Amount:          arg.Amount,
+		Status:          db.TransferStatusPendingReview,
+		ScreeningRule:   arg.ScreeningRule,
+		ScreeningReason: arg.ScreeningReason,
+	})
+}
+
+func (tx *tx) GetTransfer(id int64) (db.Transfer, error) {
+	transfer, ok := tx.data.transfers.get(id)
+	if !ok {
+		return db.Transfer{}, pgx.ErrNoRows
+	}
+	return transfer, nil
+}
+
+// ListTransfers returns the transfers sent by arg.FromAccountID or received by arg.ToAccountID
+func (tx *tx) ListTransfers(arg db.ListTransfersParams) ([]db.Transfer, error) {
+	ids := slices.Concat(tx.data.transfersByAccount[arg.FromAccountID], tx.data.transfersByAccount[arg.ToAccountID])
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	transfers := filter(tx.data.transfers.scanIDs(ids), func(transfer db.Transfer) bool {
+		return transfer.FromAccountID == arg.FromAccountID || transfer.ToAccountID == arg.ToAccountID
+	})
+	return page(transfers, arg.Limit, arg.Offset)
+}
+
+// ListPendingTransfers returns the review queue, oldest transfer first
+func (tx *tx) ListPendingTransfers(arg db.ListPendingTransfersParams) ([]db.Transfer, error) {
+	pending := slices.Collect(filter(tx.data.transfers.scan(), func(transfer db.Transfer) bool {
+		return transfer.Status == db.TransferStatusPendingReview
+	}))
+	// the ids already follow created_at, the sort keeps the order of the query even if the clock went back
+	slices.SortStableFunc(pending, func(a, b db.Transfer) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return page(slices.Values(pending), arg.Limit, arg.Offset)
+}
+
+func (tx *tx) ReviewTransfer(arg db.ReviewTransferParams) (db.Transfer, error) {
+	transfer, err := tx.GetTransfer(arg.ID)
+	if err != nil {
+		return db.Transfer{}, err
+	}
+
+	switch arg.Status {
+	case db.TransferStatusCompleted, db.TransferStatusPendingReview, db.TransferStatusRejected:
+	default:
+		return db.Transfer{}, checkViolationError("transfers", "transfers_status_check")
+	}
+
+	reviewedAt := tx.now
+	transfer.Status = arg.Status
+	transfer.ReviewedBy = arg.ReviewedBy
+	transfer.ReviewNote = arg.ReviewNote
+	transfer.ReviewedAt = &reviewedAt
+	return tx.data.transfers.update(tx, transfer.ID, transfer), nil
+}
+
+// sentTransfers returns the transfers sent by the account, newest first
+// the ids follow created_at: both are taken with the lock of the store held, so a scan by created_at can stop at
+// the first transfer that is too old
+func (tx *tx) sentTransfers(accountID int64) iter.Seq[db.Transfer] {
+	return tx.data.transfers.scanIDsBackward(tx.data.sentByAccount[accountID])
+}
+
+// ListRecentOutgoingTransfers returns the screening history of an account, the rejected transfers left out
+func (tx *tx) ListRecentOutgoingTransfers(arg db.ListRecentOutgoingTransfersParams) ([]db.Transfer, error) {
+	recent := []db.Transfer{}
+	for transfer := range tx.sentTransfers(arg.AccountID) {
+		if transfer.CreatedAt.Before(arg.Since) {
+			break
+		}
+		if transfer.Status != db.TransferStatusRejected {
+			recent = append(recent, transfer)
+		}
+	}
+	return recent, nil
+}
+
+// HasCompletedTransfer returns whether the sender already paid the receiver
+func (tx *tx) HasCompletedTransfer(arg db.HasCompletedTransferParams) (bool, error) {
+	for transfer := range tx.sentTransfers(arg.FromAccountID) {
+		if transfer.ToAccountID == arg.ToAccountID && transfer.Status == db.TransferStatusCompleted {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetDailyOutgoingTransfers returns the transfers sent by the account that were completed on the UTC day of arg.Day,
+// an approved transfer counts on the day it was approved
+func (tx *tx) GetDailyOutgoingTransfers(arg db.GetDailyOutgoingTransfersParams) (db.GetDailyOutgoingTransfersRow, error) {
+	year, month, day := arg.Day.UTC().Date()
+	startOfDay := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	endOfDay := startOfDay.AddDate(0, 0, 1)
+
+	var daily db.GetDailyOutgoingTransfersRow
+	for transfer := range tx.sentTransfers(arg.FromAccountID) {
+		completedAt := transfer.CompletedAt()
+		if transfer.Status == db.TransferStatusCompleted && !completedAt.Before(startOfDay) && completedAt.Before(endOfDay) {
+			daily.TotalAmount += transfer.Amount
+			daily.TotalCount++
+		}
+	}
+	return daily, nil
+}
+
+func (tx *tx) MarkTransferAnalyzed(id int64) error {
+	transfer, ok := tx.data.transfers.get(id)
+	if !ok {
+		return nil
+	}
+	analyzedAt := tx.now
+	transfer.AmlAnalyzedAt = &analyzedAt
+	tx.data.transfers.update(tx, id, transfer)
+	return nil
+}
+
+// ListUnanalyzedTransfers returns the completed transfers the AML analyzer didn't look at yet,
+// with the currency of the sender
+func (tx *tx) ListUnanalyzedTransfers(limit int32) ([]db.ListUnanalyzedTransfersRow, error) {
+	unanalyzed := filter(tx.data.transfers.scan(), func(transfer db.Transfer) bool {
+		return transfer.AmlAnalyzedAt == nil && transfer.Status == db.TransferStatusCompleted
+	})
+	transfers, err := page(unanalyzed, limit, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]db.ListUnanalyzedTransfersRow, len(transfers))
+	for i, transfer := range transfers {
+		sender, _ := tx.data.accounts.get(transfer.FromAccountID)
+		rows[i] = db.ListUnanalyzedTransfersRow{
+			ID:            transfer.ID,
+			FromAccountID: transfer.FromAccountID,
+			Amount:        transfer.Amount,
+			CreatedAt:     transfer.CreatedAt,
+			Currency:      sender.Currency,
+		}
+	}
+	return rows, nil
+}
+
+// ListTransfersInAmountRange returns the completed transfers of the account between two times
+// with min_amount <= amount < max_amount
+func (tx *tx) ListTransfersInAmountRange(arg db.ListTransfersInAmountRangeParams) ([]db.ListTransfersInAmountRangeRow, error) {
+	rows := []db.ListTransfersInAmountRangeRow{}
+	for transfer := range tx.sentTransfers(arg.AccountID) {
+		if transfer.CreatedAt.Before(arg.Since) {
+			break
+		}
+		if transfer.Status == db.TransferStatusCompleted && !transfer.CreatedAt.After(arg.Until) &&
+			transfer.Amount >= arg.MinAmount && transfer.Amount < arg.MaxAmount {
+			rows = append(rows, db.ListTransfersInAmountRangeRow{ID: transfer.ID, Amount: transfer.Amount})
+		}
+	}
+	slices.Reverse(rows) // by id
+	return rows, nil
+}
+
+// TransferTx performs a money transfer from one account to another with the checks of db.SQLStore.TransferTx:
+// frozen accounts, insufficient funds, transfer limits and the fraud screener of the store
+func (store *Store) TransferTx(ctx context.Context, arg db.TransferTxParams) (db.TransferTxResult, error) {
+	var result db.TransferTxResult
+
+	err := store.execTx(ctx, func(tx *tx) error {
+		verdict, err := store.screenTransfer(ctx, tx, arg)
+		if err != nil {
+			return err
+		}
+
+		switch verdict.Decision {
+		case fraud.Deny:
+			slog.WarnContext(ctx, "transfer denied by fraud screening",
+				"from_account_id", arg.FromAccountID,
+				"to_account_id", arg.ToAccountID,
+				"rule", verdict.Rule,
+				"reason", verdict.Reason,
+			)
+			return accountError(db.ErrTransferDenied, arg.FromAccountID)
+
+		case fraud.Review:
+			result.Transfer, err = tx.CreatePendingTransfer(db.CreatePendingTransferParams{
+				FromAccountID:   arg.FromAccountID,
+				ToAccountID:     arg.ToAccountID,
+				Amount:          arg.Amount,
+				ScreeningRule:   verdict.Rule,
+				ScreeningReason: verdict.Reason,
+			})
+			if err != nil {
+				return err
+			}
+			return recordAudit(ctx, tx, db.AuditActionTransferCreate, db.AuditResourceTransfer, result.Transfer.ID, nil, result)
+		}
+
+		result.Transfer, err = tx.CreateTransfer(db.CreateTransferParams{
+			FromAccountID: arg.FromAccountID,
+			ToAccountID:   arg.ToAccountID,
+			Amount:        arg.Amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := tx.completeTransfer(&result); err != nil {
+			return err
+		}
+
+		if err := recordAudit(ctx, tx, db.AuditActionTransferCreate, db.AuditResourceTransfer, result.Transfer.ID, nil, result); err != nil {
+			return err
+		}
+		return enqueueTransferEvents(tx, result)
+	})
+
+	if err == nil && result.Transfer.Status == db.TransferStatusCompleted {
+		metrics.ObserveTransferCompleted(result.FromAccount.Currency, result.Transfer.Amount)
+	}
+	return result, err
+}
+
+// screenTransfer asks the screener of the store about the transfer, with the history of the sender
+func (store *Store) screenTransfer(ctx context.Context, tx *tx, arg db.TransferTxParams) (fraud.Verdict, error) {
+	if store.screener == nil {
+		return fraud.Verdict{Decision: fraud.Allow}, nil
+	}
+
+	now := time.Now()
+	recent, err := tx.ListRecentOutgoingTransfers(db.ListRecentOutgoingTransfersParams{
+		AccountID: arg.FromAccountID,
+		Since:     now.Add(-fraud.HistoryWindow),
+	})
+	if err != nil {
+		return fraud.Verdict{}, err
+	}
+
+	knownPayee, err := tx.HasCompletedTransfer(db.HasCompletedTransferParams{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+	})
+	if err != nil {
+		return fraud.Verdict{}, err
+	}
+
+	history := fraud.History{KnownPayee: knownPayee}
+	for _, transfer := range recent {
+		history.Recent = append(history.Recent, fraud.PastTransfer{
+			ToAccountID: transfer.ToAccountID,
+			Amount:      transfer.Amount,
+			CreatedAt:   transfer.CreatedAt,
+		})
+	}
+
+	info := db.AuditInfoFromContext(ctx)
+	return store.screener.Screen(ctx, fraud.Input{
+		Transfer: fraud.Transfer{
+			FromAccountID: arg.FromAccountID,
+			ToAccountID:   arg.ToAccountID,
+			Amount:        arg.Amount,
+		},
+		History: history,
+		Caller: fraud.Caller{
+			Actor:     info.Actor,
+			RequestID: info.RequestID,
+			ClientIP:  info.ClientIP,
+		},
+		Now: now,
+	})
+}
+
+// completeTransfer writes the entries of result.Transfer and moves the money between the two accounts
+// it fails, and the caller must roll back, if the accounts or the limits of the sender don't allow the transfer
+func (tx *tx) completeTransfer(result *db.TransferTxResult) error {
+	transfer := result.Transfer
+
+	var err error
+	result.FromEntry, err = tx.CreateEntry(db.CreateEntryParams{
+		AccountID:  transfer.FromAccountID,
+		Amount:     -transfer.Amount,
+		TransferID: &transfer.ID,
+	})
+	if err != nil {
+		return err
+	}
+	result.ToEntry, err = tx.CreateEntry(db.CreateEntryParams{
+		AccountID:  transfer.ToAccountID,
+		Amount:     transfer.Amount,
+		TransferID: &transfer.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	// there is no lock ordering to respect, the store is locked as a whole
+	result.FromAccount, err = tx.AddAccountBalance(db.AddAccountBalanceParams{ID: transfer.FromAccountID, Amount: -transfer.Amount})
+	if err != nil {
+		return err
+	}
+	result.ToAccount, err = tx.AddAccountBalance(db.AddAccountBalanceParams{ID: transfer.ToAccountID, Amount: transfer.Amount})
+	if err != nil {
+		return err
+	}
+
+	if err := checkTransferAccounts(*result); err != nil {
+		return err
+	}
+	return tx.checkTransferLimits(transfer)
+}
+
+// checkTransferAccounts makes sure both accounts are active and the sender didn't go below zero
+func checkTransferAccounts(result db.TransferTxResult) error {
+	for _, account := range []db.Account{result.FromAccount, result.ToAccount} {
+		if account.Kind == db.AccountKindEquity {
+			return accountError(db.ErrEquityAccount, account.ID)
+		}
+		if account.Status != db.AccountStatusActive {
+			return accountError(db.ErrAccountFrozen, account.ID)
+		}
+	}
+
+	if result.FromAccount.Balance < 0 {
+		return accountError(db.ErrInsufficientFunds, result.FromAccount.ID)
+	}
+	return nil
+}
+
+// checkTransferLimits fails with ErrLimitExceeded if the transfer, already written, takes the sender over one of its limits
+// the transfer counts on the day it completed, an approved transfer on the day of its approval
+func (tx *tx) checkTransferLimits(transfer db.Transfer) error {
+	limits, err := tx.GetEffectiveTransferLimits(transfer.FromAccountID)
+	if err != nil {
+		return err
+	}
+
+	daily, err := tx.GetDailyOutgoingTransfers(db.GetDailyOutgoingTransfersParams{
+		FromAccountID: transfer.FromAccountID,
+		Day:           transfer.CompletedAt(),
+	})
+	if err != nil {
+		return err
+	}
+
+	remainingAmount := max(0, limits.MaxDailyAmount-(daily.TotalAmount-transfer.Amount))
+	remainingCount := max(0, limits.MaxDailyCount-(daily.TotalCount-1))
+
+	var exceeded string
+	switch {
+	case transfer.Amount > limits.MaxSingleAmount:
+		exceeded = db.LimitSingleAmount
+	case daily.TotalCount > limits.MaxDailyCount:
+		exceeded = db.LimitDailyCount
+	case daily.TotalAmount > limits.MaxDailyAmount:
+		exceeded = db.LimitDailyAmount
+	default:
+		return nil
+	}
+
+	return &db.Error{Kind: db.ErrLimitExceeded, Details: map[string]any{
+		"account_id":             transfer.FromAccountID,
+		"limit":                  exceeded,
+		"max_single_amount":      limits.MaxSingleAmount,
+		"remaining_daily_amount": remainingAmount,
+		"remaining_daily_count":  remainingCount,
+	}}
+}
+
+// accountError returns an error of the given kind about accountID
+func accountError(kind error, accountID int64) *db.Error {
+	return &db.Error{Kind: kind, Details: map[string]any{"account_id": accountID}}
+}
+
+// ApproveTransferTx completes a transfer waiting for review: its entries are written and the money moves
+// the checks of TransferTx apply as if it was made now, if one fails the transfer stays pending
+func (store *Store) ApproveTransferTx(ctx context.Context, arg db.ReviewTransferTxParams) (db.TransferTxResult, error) {
+	var result db.TransferTxResult
+
+	err := store.execTx(ctx, func(tx *tx) error {
+		before, err := tx.pendingTransfer(arg.TransferID)
+		if err != nil {
+			return err
+		}
+
+		result.Transfer, err = tx.ReviewTransfer(db.ReviewTransferParams{
+			ID:         arg.TransferID,
+			Status:     db.TransferStatusCompleted,
+			ReviewedBy: db.AuditInfoFromContext(ctx).Actor,
+			ReviewNote: arg.Note,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := tx.completeTransfer(&result); err != nil {
+			return err
+		}
+
+		if err := recordAudit(ctx, tx, db.AuditActionTransferApprove, db.AuditResourceTransfer, arg.TransferID, before, result); err != nil {
+			return err
+		}
+		return enqueueTransferEvents(tx, result)
+	})
+
+	if err == nil && result.Transfer.Status == db.TransferStatusCompleted {
+		metrics.ObserveTransferCompleted(result.FromAccount.Currency, result.Transfer.Amount)
+	}
+	return result, err
+}
+
+// RejectTransferTx refuses a transfer waiting for review, no money ever moved for it
+func (store *Store) RejectTransferTx(ctx context.Context, arg db.ReviewTransferTxParams) (db.Transfer, error) {
+	var transfer db.Transfer
+
+	err := store.execTx(ctx, func(tx *tx) error {
+		before, err := tx.pendingTransfer(arg.TransferID)
+		if err != nil {
+			return err
+		}
+
+		transfer, err = tx.ReviewTransfer(db.ReviewTransferParams{
+			ID:         arg.TransferID,
+			Status:     db.TransferStatusRejected,
+			ReviewedBy: db.AuditInfoFromContext(ctx).Actor,
+			ReviewNote: arg.Note,
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, tx, db.AuditActionTransferReject, db.AuditResourceTransfer, arg.TransferID, before, transfer)
+	})
+
+	return transfer, err
+}
+
+// pendingTransfer returns the transfer after checking that it waits for review
+func (tx *tx) pendingTransfer(id int64) (db.Transfer, error) {
+	transfer, err := tx.GetTransfer(id)
+	if err != nil {
+		return db.Transfer{}, err
+	}
+
+	if transfer.Status != db.TransferStatusPendingReview {
+		return db.Transfer{}, &db.Error{Kind: db.ErrTransferNotPending, Details: map[string]any{
+			"transfer_id": id,
+			"status":      transfer.Status,
+		}}
+	}
+	return transfer, nil
+}
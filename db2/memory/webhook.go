@@ -0,0 +1,302 @@
+package memdb
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/metrics"
+)
+
+func (tx *tx) CreateWebhook(arg db.CreateWebhookParams) (db.Webhook, error) {
+	return tx.data.webhooks.insert(tx, func(id int64) db.Webhook {
+		return db.Webhook{
+			ID:         id,
+			Url:        arg.Url,
+			EventTypes: arg.EventTypes,
+			Secret:     arg.Secret,
+			Active:     true,
+			CreatedAt:  tx.now,
+		}
+	}), nil
+}
+
+func (tx *tx) GetWebhook(id int64) (db.Webhook, error) {
+	webhook, ok := tx.data.webhooks.get(id)
+	if !ok {
+		return db.Webhook{}, pgx.ErrNoRows
+	}
+	return webhook, nil
+}
+
+func (tx *tx) ListWebhooks(arg db.ListWebhooksParams) ([]db.Webhook, error) {
+	return page(tx.data.webhooks.scan(), arg.Limit, arg.Offset)
+}
+
+func (tx *tx) UpdateWebhook(arg db.UpdateWebhookParams) (db.Webhook, error) {
+	webhook, err := tx.GetWebhook(arg.ID)
+	if err != nil {
+		return db.Webhook{}, err
+	}
+
+	webhook.Url = arg.Url
+	webhook.EventTypes = arg.EventTypes
+	webhook.Active = arg.Active
+	return tx.data.webhooks.update(tx, arg.ID, webhook), nil
+}
+
+// DeleteWebhook deletes the webhook and, like ON DELETE CASCADE, its deliveries
+func (tx *tx) DeleteWebhook(id int64) error {
+	if _, ok := tx.data.webhooks.get(id); !ok {
+		return nil
+	}
+
+	for delivery := range tx.data.deliveries.scan() {
+		if delivery.WebhookID == id {
+			tx.data.deliveries.delete(tx, delivery.ID)
+			unset(tx, tx.data.deliveryKeys, deliveryKey{delivery.WebhookID, delivery.EventID})
+		}
+	}
+	tx.data.webhooks.delete(tx, id)
+	return nil
+}
+
+// CreateWebhookDeliveries creates one delivery per active webhook subscribed to the event type,
+// the webhooks that already have a delivery of the event are skipped
+// returns the number of deliveries created
+func (tx *tx) CreateWebhookDeliveries(arg db.CreateWebhookDeliveriesParams) (int64, error) {
+	var created int64
+	for webhook := range tx.data.webhooks.scan() {
+		key := deliveryKey{webhookID: webhook.ID, eventID: arg.EventID}
+		if !webhook.Active || !slices.Contains(webhook.EventTypes, arg.EventType) || tx.data.deliveryKeys[key] {
+			continue
+		}
+
+		tx.data.deliveries.insert(tx, func(id int64) db.WebhookDelivery {
+			return db.WebhookDelivery{
+				ID:            id,
+				WebhookID:     webhook.ID,
+				EventID:       arg.EventID,
+				EventType:     arg.EventType,
+				Payload:       arg.Payload,
+				Status:        db.WebhookDeliveryPending,
+				NextAttemptAt: tx.now,
+				CreatedAt:     tx.now,
+				UpdatedAt:     tx.now,
+			}
+		})
+		set(tx, tx.data.deliveryKeys, key, true)
+		created++
+	}
+	return created, nil
+}
+
+// ListDueWebhookDeliveries returns the pending deliveries whose next attempt is due, with their webhook
+// the ones claimed by a worker are skipped until their claim runs out
+func (tx *tx) ListDueWebhookDeliveries(limit int32) ([]db.ListDueWebhookDeliveriesRow, error) {
+	due := slices.Collect(filter(tx.data.deliveries.scan(), func(delivery db.WebhookDelivery) bool {
+		return delivery.Status == db.WebhookDeliveryPending &&
+			!delivery.NextAttemptAt.After(tx.now) &&
+			(delivery.LockedUntil == nil || !delivery.LockedUntil.After(tx.now))
+	}))
+	slices.SortStableFunc(due, func(a, b db.WebhookDelivery) int {
+		return a.NextAttemptAt.Compare(b.NextAttemptAt)
+	})
+
+	deliveries, err := page(slices.Values(due), limit, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []db.ListDueWebhookDeliveriesRow{}
+	for _, delivery := range deliveries {
+		webhook, ok := tx.data.webhooks.get(delivery.WebhookID)
+		if !ok {
+			continue
+		}
+		rows = append(rows, db.ListDueWebhookDeliveriesRow{WebhookDelivery: delivery, Webhook: webhook})
+	}
+	return rows, nil
+}
+
+func (tx *tx) ClaimWebhookDeliveries(arg db.ClaimWebhookDeliveriesParams) error {
+	var lockedUntil *time.Time
+	if arg.LockedUntil != nil {
+		rounded := arg.LockedUntil.Round(time.Microsecond)
+		lockedUntil = &rounded
+	}
+
+	for _, id := range arg.Ids {
+		delivery, ok := tx.data.deliveries.get(id)
+		if !ok {
+			continue
+		}
+		delivery.LockedUntil = lockedUntil
+		tx.data.deliveries.update(tx, id, delivery)
+	}
+	return nil
+}
+
+// RecordWebhookAttempt records the attempt of the worker holding the claim arg.LockedUntil and drops the claim
+// returns 0 if the delivery isn't claimed by it any more, like locked_until = NULL in postgres a nil claim never matches
+func (tx *tx) RecordWebhookAttempt(arg db.RecordWebhookAttemptParams) (int64, error) {
+	delivery, ok := tx.data.deliveries.get(arg.ID)
+	if !ok || delivery.LockedUntil == nil || arg.LockedUntil == nil ||
+		!delivery.LockedUntil.Equal(arg.LockedUntil.Round(time.Microsecond)) {
+		return 0, nil
+	}
+
+	delivery.Status = arg.Status
+	delivery.ResponseStatus = arg.ResponseStatus
+	delivery.LastError = arg.LastError
+	delivery.NextAttemptAt = arg.NextAttemptAt.Round(time.Microsecond)
+	delivery.Attempts++
+	delivery.LockedUntil = nil
+	delivery.UpdatedAt = tx.now
+	tx.data.deliveries.update(tx, arg.ID, delivery)
+	return 1, nil
+}
+
+// ListWebhookDeliveries returns the deliveries of a webhook, newest first
+func (tx *tx) ListWebhookDeliveries(arg db.ListWebhookDeliveriesParams) ([]db.WebhookDelivery, error) {
+	return page(filter(tx.data.deliveries.scanBackward(), func(delivery db.WebhookDelivery) bool {
+		return delivery.WebhookID == arg.WebhookID
+	}), arg.Limit, arg.Offset)
+}
+
+// withoutSecret returns a copy of the webhook that is safe to write to the audit log
+func withoutSecret(webhook db.Webhook) db.Webhook {
+	webhook.Secret = ""
+	return webhook
+}
+
+// CreateWebhookTx creates a webhook subscription and records it in the audit log
+func (store *Store) CreateWebhookTx(ctx context.Context, arg db.CreateWebhookParams) (db.Webhook, error) {
+	var webhook db.Webhook
+
+	err := store.execTx(ctx, func(tx *tx) error {
+		var err error
+		webhook, err = tx.CreateWebhook(arg)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, tx, db.AuditActionWebhookCreate, db.AuditResourceWebhook, webhook.ID, nil, withoutSecret(webhook))
+	})
+
+	return webhook, err
+}
+
+// UpdateWebhookTx updates a webhook subscription and records the before and after state in the audit log
+func (store *Store) UpdateWebhookTx(ctx context.Context, arg db.UpdateWebhookParams) (db.Webhook, error) {
+	var webhook db.Webhook
+
+	err := store.execTx(ctx, func(tx *tx) error {
+		before, err := tx.GetWebhook(arg.ID)
+		if err != nil {
+			return err
+		}
+
+		webhook, err = tx.UpdateWebhook(arg)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, tx, db.AuditActionWebhookUpdate, db.AuditResourceWebhook, webhook.ID, withoutSecret(before), withoutSecret(webhook))
+	})
+
+	return webhook, err
+}
+
+// DeleteWebhookTx deletes a webhook subscription together with its deliveries and records it in the audit log
+func (store *Store) DeleteWebhookTx(ctx context.Context, id int64) error {
+	return store.execTx(ctx, func(tx *tx) error {
+		before, err := tx.GetWebhook(id)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.DeleteWebhook(id); err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, tx, db.AuditActionWebhookDelete, db.AuditResourceWebhook, id, withoutSecret(before), nil)
+	})
+}
+
+// ProcessWebhookDeliveriesTx hands up to batchSize due deliveries to deliver and records the outcome of each attempt
+// like the SQL store the deliveries are claimed for db.WebhookClaimDuration, deliver runs without the lock of the
+// store and each attempt is recorded in a transaction of its own
+// returns the number of deliveries attempted
+func (store *Store) ProcessWebhookDeliveriesTx(
+	ctx context.Context,
+	batchSize int32,
+	deliver func(ctx context.Context, delivery db.WebhookDelivery, webhook db.Webhook) db.WebhookAttempt,
+) (int, error) {
+	lockedUntil := now().Add(db.WebhookClaimDuration)
+
+	var rows []db.ListDueWebhookDeliveriesRow
+	err := store.execTx(ctx, func(tx *tx) error {
+		var err error
+		rows, err = tx.ListDueWebhookDeliveries(batchSize)
+		if err != nil {
+			return err
+		}
+		return tx.ClaimWebhookDeliveries(db.ClaimWebhookDeliveriesParams{LockedUntil: &lockedUntil, Ids: deliveryIDs(rows)})
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// the attempts made are recorded even if the worker is stopping, the deliveries left are released
+	recordCtx := context.WithoutCancel(ctx)
+
+	attempted := 0
+	for i, row := range rows {
+		if ctx.Err() != nil {
+			return attempted, store.execTx(recordCtx, func(tx *tx) error {
+				return tx.ClaimWebhookDeliveries(db.ClaimWebhookDeliveriesParams{LockedUntil: nil, Ids: deliveryIDs(rows[i:])})
+			})
+		}
+
+		attempt := deliver(ctx, row.WebhookDelivery, row.Webhook)
+		var recorded int64
+		err := store.execTx(recordCtx, func(tx *tx) error {
+			var err error
+			recorded, err = tx.RecordWebhookAttempt(db.RecordWebhookAttemptParams{
+				ID:             row.WebhookDelivery.ID,
+				Status:         attempt.Status,
+				ResponseStatus: attempt.ResponseStatus,
+				LastError:      attempt.LastError,
+				NextAttemptAt:  attempt.NextAttemptAt,
+				LockedUntil:    &lockedUntil,
+			})
+			return err
+		})
+		if err != nil {
+			return attempted, err
+		}
+		if recorded == 0 {
+			metrics.WebhookClaimsLost.Inc()
+			slog.WarnContext(ctx, "webhook claim lost, the attempt was not recorded",
+				"delivery_id", row.WebhookDelivery.ID,
+				"webhook_id", row.Webhook.ID,
+				"status", attempt.Status,
+			)
+		}
+		attempted++
+	}
+
+	return attempted, nil
+}
+
+func deliveryIDs(rows []db.ListDueWebhookDeliveriesRow) []int64 {
+	ids := make([]int64, len(rows))
+	for i, row := range rows {
+		ids[i] = row.WebhookDelivery.ID
+	}
+	return ids
+}
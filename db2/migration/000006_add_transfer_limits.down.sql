@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS "transfers_from_account_id_created_at_idx";
+
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "limit_tier";
+
+DROP TABLE IF EXISTS "account_transfer_limits";
+
+DROP TABLE IF EXISTS "transfer_limit_tiers";
@@ -0,0 +1,35 @@
+CREATE TABLE "transfer_limit_tiers" (
+  "name" varchar PRIMARY KEY,
+  "max_single_amount" bigint NOT NULL,
+  "max_daily_amount" bigint NOT NULL,
+  "max_daily_count" int NOT NULL,
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "account_transfer_limits" (
+  "account_id" bigint PRIMARY KEY,
+  "max_single_amount" bigint,
+  "max_daily_amount" bigint,
+  "max_daily_count" int,
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+INSERT INTO "transfer_limit_tiers" ("name", "max_single_amount", "max_daily_amount", "max_daily_count")
+VALUES ('standard', 1000000, 5000000, 50);
+
+ALTER TABLE "accounts" ADD COLUMN "limit_tier" varchar NOT NULL DEFAULT 'standard';
+
+-- sums the outgoing transfers of an account since the start of the day
+CREATE INDEX ON "transfers" ("from_account_id", "created_at");
+
+COMMENT ON COLUMN "transfer_limit_tiers"."max_single_amount" IS 'largest amount of a single transfer, in minor units';
+
+COMMENT ON COLUMN "transfer_limit_tiers"."max_daily_amount" IS 'largest sum of the outgoing transfers of a UTC day, in minor units';
+
+COMMENT ON COLUMN "transfer_limit_tiers"."max_daily_count" IS 'largest number of outgoing transfers in a UTC day';
+
+COMMENT ON TABLE "account_transfer_limits" IS 'overrides of the tier limits for one account, NULL keeps the limit of the tier';
+
+ALTER TABLE "accounts" ADD FOREIGN KEY ("limit_tier") REFERENCES "transfer_limit_tiers" ("name");
+
+ALTER TABLE "account_transfer_limits" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id") ON DELETE CASCADE;
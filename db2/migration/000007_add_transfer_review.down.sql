@@ -0,0 +1,15 @@
+DROP INDEX IF EXISTS "transfers_created_at_idx";
+
+ALTER TABLE "transfers" DROP CONSTRAINT IF EXISTS "transfers_status_check";
+
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "review_note";
+
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "reviewed_at";
+
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "reviewed_by";
+
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "screening_reason";
+
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "screening_rule";
+
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "status";
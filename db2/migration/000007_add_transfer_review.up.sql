@@ -0,0 +1,19 @@
+ALTER TABLE "transfers" ADD COLUMN "status" varchar NOT NULL DEFAULT 'completed';
+
+ALTER TABLE "transfers" ADD COLUMN "screening_rule" varchar NOT NULL DEFAULT '';
+
+ALTER TABLE "transfers" ADD COLUMN "screening_reason" varchar NOT NULL DEFAULT '';
+
+ALTER TABLE "transfers" ADD COLUMN "reviewed_by" varchar NOT NULL DEFAULT '';
+
+ALTER TABLE "transfers" ADD COLUMN "reviewed_at" timestamptz;
+
+ALTER TABLE "transfers" ADD COLUMN "review_note" varchar NOT NULL DEFAULT '';
+
+ALTER TABLE "transfers" ADD CONSTRAINT "transfers_status_check" CHECK ("status" IN ('completed', 'pending_review', 'rejected'));
+
+CREATE INDEX ON "transfers" ("created_at") WHERE "status" = 'pending_review';
+
+COMMENT ON COLUMN "transfers"."status" IS 'completed, pending_review or rejected, money only moved for completed transfers';
+
+COMMENT ON COLUMN "transfers"."screening_rule" IS 'fraud rule that sent the transfer to review';
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS "transfers_id_idx";
+
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "aml_analyzed_at";
+
+DROP TABLE IF EXISTS "compliance_alerts";
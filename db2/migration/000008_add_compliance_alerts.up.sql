@@ -0,0 +1,43 @@
+CREATE TABLE "compliance_alerts" (
+  "id" bigserial PRIMARY KEY,
+  "kind" varchar NOT NULL,
+  "account_id" bigint NOT NULL,
+  "currency" varchar NOT NULL,
+  "total_amount" bigint NOT NULL,
+  "transfer_ids" bigint[] NOT NULL,
+  "status" varchar NOT NULL DEFAULT 'open',
+  "assignee" varchar NOT NULL DEFAULT '',
+  "resolution" varchar NOT NULL DEFAULT '',
+  "resolution_note" varchar NOT NULL DEFAULT '',
+  "resolved_by" varchar NOT NULL DEFAULT '',
+  "resolved_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "transfers" ADD COLUMN "aml_analyzed_at" timestamptz;
+
+ALTER TABLE "compliance_alerts" ADD CONSTRAINT "compliance_alerts_kind_check" CHECK ("kind" IN ('large_transaction', 'structuring'));
+
+ALTER TABLE "compliance_alerts" ADD CONSTRAINT "compliance_alerts_status_check" CHECK ("status" IN ('open', 'assigned', 'resolved'));
+
+-- new structuring evidence is added to the unresolved alert of the account rather than raising another one
+CREATE UNIQUE INDEX "compliance_alerts_open_structuring_idx" ON "compliance_alerts" ("account_id")
+WHERE "kind" = 'structuring' AND "status" <> 'resolved';
+
+CREATE INDEX ON "compliance_alerts" ("status", "created_at");
+
+-- the work queue of the AML analyzer
+CREATE INDEX ON "transfers" ("id") WHERE "aml_analyzed_at" IS NULL AND "status" = 'completed';
+
+COMMENT ON COLUMN "compliance_alerts"."kind" IS 'large_transaction or structuring';
+
+COMMENT ON COLUMN "compliance_alerts"."total_amount" IS 'sum of the evidence transfers, in minor units of currency';
+
+COMMENT ON COLUMN "compliance_alerts"."transfer_ids" IS 'the transfers that raised the alert, its evidence';
+
+COMMENT ON COLUMN "compliance_alerts"."status" IS 'open, assigned or resolved';
+
+COMMENT ON COLUMN "transfers"."aml_analyzed_at" IS 'when the AML analyzer looked at the transfer, NULL until then';
+
+ALTER TABLE "compliance_alerts" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
@@ -0,0 +1,11 @@
+COMMENT ON COLUMN "accounts"."currency" IS NULL;
+
+ALTER TABLE "compliance_alerts" DROP CONSTRAINT IF EXISTS "compliance_alerts_currency_format";
+
+ALTER TABLE "accounts" DROP CONSTRAINT IF EXISTS "accounts_currency_format";
+
+ALTER TABLE "transfers" DROP CONSTRAINT IF EXISTS "transfers_distinct_accounts";
+
+ALTER TABLE "transfers" DROP CONSTRAINT IF EXISTS "transfers_amount_positive";
+
+ALTER TABLE "transfers" DROP CONSTRAINT IF EXISTS "transfers_created_at_not_null";
@@ -0,0 +1,17 @@
+-- transfers inserted before the default existed, or with an explicit NULL, get the time of the migration
+UPDATE "transfers" SET "created_at" = now() WHERE "created_at" IS NULL;
+
+-- migrate runs each file in a single transaction, so the constraints are only added here, NOT VALID:
+-- new rows are checked from now on and the existing ones are left to 000010, which validates them
+-- the CHECK on created_at stands in for NOT NULL, which 000010 sets without scanning the table
+ALTER TABLE "transfers" ADD CONSTRAINT "transfers_created_at_not_null" CHECK ("created_at" IS NOT NULL) NOT VALID;
+
+ALTER TABLE "transfers" ADD CONSTRAINT "transfers_amount_positive" CHECK ("amount" > 0) NOT VALID;
+
+ALTER TABLE "transfers" ADD CONSTRAINT "transfers_distinct_accounts" CHECK ("from_account_id" <> "to_account_id") NOT VALID;
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_currency_format" CHECK ("currency" ~ '^[A-Z]{3}$') NOT VALID;
+
+ALTER TABLE "compliance_alerts" ADD CONSTRAINT "compliance_alerts_currency_format" CHECK ("currency" ~ '^[A-Z]{3}$') NOT VALID;
+
+COMMENT ON COLUMN "accounts"."currency" IS 'ISO 4217 code, three upper case letters';
@@ -0,0 +1,4 @@
+-- a validated constraint can't go back to NOT VALID, only the NOT NULL of created_at is reverted
+ALTER TABLE "transfers" ALTER COLUMN "created_at" DROP NOT NULL;
+
+ALTER TABLE "transfers" ADD CONSTRAINT "transfers_created_at_not_null" CHECK ("created_at" IS NOT NULL) NOT VALID;
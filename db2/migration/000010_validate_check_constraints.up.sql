@@ -0,0 +1,16 @@
+-- VALIDATE only takes a SHARE UPDATE EXCLUSIVE lock: the existing rows are scanned while writes go on
+ALTER TABLE "transfers" VALIDATE CONSTRAINT "transfers_created_at_not_null";
+
+ALTER TABLE "transfers" VALIDATE CONSTRAINT "transfers_amount_positive";
+
+ALTER TABLE "transfers" VALIDATE CONSTRAINT "transfers_distinct_accounts";
+
+ALTER TABLE "accounts" VALIDATE CONSTRAINT "accounts_currency_format";
+
+ALTER TABLE "compliance_alerts" VALIDATE CONSTRAINT "compliance_alerts_currency_format";
+
+-- SET NOT NULL takes an ACCESS EXCLUSIVE lock, but the validated CHECK proves the column has no NULL
+-- so the table isn't scanned again and the lock is only held until the end of the migration
+ALTER TABLE "transfers" ALTER COLUMN "created_at" SET NOT NULL;
+
+ALTER TABLE "transfers" DROP CONSTRAINT "transfers_created_at_not_null";
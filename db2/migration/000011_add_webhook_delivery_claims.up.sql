@@ -0,0 +1,3 @@
+ALTER TABLE "webhook_deliveries" ADD COLUMN "locked_until" timestamptz;
+
+COMMENT ON COLUMN "webhook_deliveries"."locked_until" IS 'claim of the worker sending the delivery, other workers skip it until then';
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS "transfers_from_account_id_completed_at_idx";
@@ -0,0 +1,3 @@
+-- the daily limits sum the transfers of a sender by the time they completed, approved transfers included
+CREATE INDEX "transfers_from_account_id_completed_at_idx" ON "transfers" ("from_account_id", (COALESCE("reviewed_at", "created_at")))
+WHERE "status" = 'completed';
@@ -0,0 +1,23 @@
+-- the transfers of the equity accounts and their entries go, the balances they funded stay
+-- the AML alerts of an equity account would keep it from being deleted, they go with it
+DELETE FROM "compliance_alerts" a
+USING "accounts" q
+WHERE q."kind" = 'equity' AND a."account_id" = q."id";
+
+DELETE FROM "entries" e
+USING "transfers" t, "accounts" q
+WHERE e."transfer_id" = t."id"
+  AND q."kind" = 'equity'
+  AND q."id" IN (t."from_account_id", t."to_account_id");
+
+DELETE FROM "transfers" t
+USING "accounts" q
+WHERE q."kind" = 'equity' AND q."id" IN (t."from_account_id", t."to_account_id");
+
+DELETE FROM "accounts" WHERE "kind" = 'equity';
+
+ALTER TABLE "entries" DROP COLUMN IF EXISTS "transfer_id";
+
+DROP INDEX IF EXISTS "accounts_equity_currency_idx";
+
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "kind";
@@ -0,0 +1,52 @@
+ALTER TABLE "accounts" ADD COLUMN "kind" varchar NOT NULL DEFAULT 'customer';
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_kind_check" CHECK ("kind" IN ('customer', 'equity'));
+
+-- one equity account per currency, CreateAccountTx creates it with the first opening balance in the currency
+CREATE UNIQUE INDEX "accounts_equity_currency_idx" ON "accounts" ("currency") WHERE "kind" = 'equity';
+
+COMMENT ON COLUMN "accounts"."kind" IS 'customer, or equity: the bank side of the opening balances, its balance is minus the money put in';
+
+-- every entry points at the transfer it writes, the entries written before this migration have none
+ALTER TABLE "entries" ADD COLUMN "transfer_id" bigint;
+
+ALTER TABLE "entries" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "entries" ("transfer_id");
+
+COMMENT ON COLUMN "entries"."transfer_id" IS 'the transfer the entry is one side of, null for the entries older than the column';
+
+-- the opening balances were only recorded by the audit events, they become transfers from the equity accounts:
+-- the part of a balance its entries don't explain is the opening balance of the account
+CREATE TEMPORARY TABLE "opening_balances" AS
+SELECT a."id" AS "account_id", a."currency", a."created_at", a."balance" - COALESCE(SUM(e."amount"), 0) AS "amount"
+FROM "accounts" a
+LEFT JOIN "entries" e ON e."account_id" = a."id"
+GROUP BY a."id"
+HAVING a."balance" <> COALESCE(SUM(e."amount"), 0);
+
+INSERT INTO "accounts" ("owner", "balance", "currency", "kind", "created_at")
+SELECT 'equity', -SUM("amount"), "currency", 'equity', MIN("created_at")
+FROM "opening_balances"
+GROUP BY "currency";
+
+-- a balance below its entries, only possible after an edit by hand, is paid back to equity
+-- the analyzer skips the transfers, they move no customer money
+WITH "openings" AS (
+  INSERT INTO "transfers" ("from_account_id", "to_account_id", "amount", "created_at", "aml_analyzed_at")
+  SELECT
+    CASE WHEN o."amount" > 0 THEN q."id" ELSE o."account_id" END,
+    CASE WHEN o."amount" > 0 THEN o."account_id" ELSE q."id" END,
+    abs(o."amount"),
+    o."created_at",
+    now()
+  FROM "opening_balances" o
+  JOIN "accounts" q ON q."kind" = 'equity' AND q."currency" = o."currency"
+  RETURNING "id", "from_account_id", "to_account_id", "amount", "created_at"
+)
+INSERT INTO "entries" ("account_id", "amount", "created_at", "transfer_id")
+SELECT "from_account_id", -"amount", "created_at", "id" FROM "openings"
+UNION ALL
+SELECT "to_account_id", "amount", "created_at", "id" FROM "openings";
+
+DROP TABLE "opening_balances";
@@ -0,0 +1,149 @@
+// Package migration embeds the schema migrations in the binary,
+// so a deploy doesn't need the migrate CLI or a copy of this directory
+package migration
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/pgx/v5" // registers the pgx5:// database driver
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// files holds every NNNNNN_name.up.sql and NNNNNN_name.down.sql of this directory
+//
+//go:embed *.sql
+var files embed.FS
+
+// lockTimeout is how long a command waits for the advisory lock of another instance,
+// e.g. a replica that started at the same time and is still applying the migrations
+const lockTimeout = 5 * time.Minute
+
+// New returns a migrate instance that applies the embedded migrations to the database at source,
+// a postgresql:// URL such as config.DBSource.
+// golang-migrate holds a postgres advisory lock while a command runs,
+// so instances that migrate at the same time run one after the other instead of racing
+func New(source string) (*migrate.Migrate, error) {
+	databaseURL, err := url.Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse database source: %w", err)
+	}
+	databaseURL.Scheme = "pgx5" // the driver connects with postgres:// but registers under its own scheme
+
+	sourceDriver, err := iofs.New(files, ".")
+	if err != nil {
+		return nil, fmt.Errorf("cannot read embedded migrations: %w", err)
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", sourceDriver, databaseURL.String())
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to database: %w", err)
+	}
+	m.LockTimeout = lockTimeout
+	return m, nil
+}
+
+// Up applies the pending migrations, a database that is already up to date isn't an error
+func Up(m *migrate.Migrate) error {
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
+
+// Run runs a migrate subcommand and reports to out:
+//
+//	up [N]     applies all pending migrations, or the next N
+//	down [N]   reverts the last N migrations, 1 by default
+//	version    prints the current version
+//	force V    sets the version without running anything, to recover from a dirty migration
+func Run(m *migrate.Migrate, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("missing command, expected up, down, version or force")
+	}
+	command, args := args[0], args[1:]
+
+	switch command {
+	case "up":
+		n, err := optionalCount(args, 0)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			err = Up(m)
+		} else {
+			err = m.Steps(n)
+		}
+		if err != nil {
+			return err
+		}
+	case "down":
+		// reverting everything drops all the data, so it takes an explicit count
+		n, err := optionalCount(args, 1)
+		if err != nil {
+			return err
+		}
+		if err := m.Steps(-n); err != nil {
+			return err
+		}
+	case "version":
+		if len(args) != 0 {
+			return errors.New("version takes no arguments")
+		}
+	case "force":
+		if len(args) != 1 {
+			return errors.New("force takes the version to set")
+		}
+		version, err := strconv.Atoi(args[0])
+		if err != nil || version < -1 {
+			return fmt.Errorf("invalid version %q", args[0])
+		}
+		if err := m.Force(version); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown command %q, expected up, down, version or force", command)
+	}
+
+	return printVersion(m, out)
+}
+
+// optionalCount parses the optional step count of up and down
+func optionalCount(args []string, defaultCount int) (int, error) {
+	switch len(args) {
+	case 0:
+		return defaultCount, nil
+	case 1:
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid number of migrations %q", args[0])
+		}
+		return n, nil
+	default:
+		return 0, errors.New("too many arguments")
+	}
+}
+
+func printVersion(m *migrate.Migrate, out io.Writer) error {
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		_, err = fmt.Fprintln(out, "no migration applied")
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if dirty {
+		_, err = fmt.Fprintf(out, "version %d (dirty, fix the schema then run force)\n", version)
+		return err
+	}
+	_, err = fmt.Fprintf(out, "version %d\n", version)
+	return err
+}
@@ -0,0 +1,64 @@
+package migration
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	sourceDriver, err := iofs.New(files, ".")
+	require.NoError(t, err)
+	defer sourceDriver.Close()
+
+	version, err := sourceDriver.First()
+	require.NoError(t, err)
+	require.Equal(t, uint(1), version)
+
+	// every migration can be reverted, and the versions follow each other
+	for {
+		up, _, err := sourceDriver.ReadUp(version)
+		require.NoError(t, err, "version %d has no up migration", version)
+		require.NoError(t, up.Close())
+		down, _, err := sourceDriver.ReadDown(version)
+		require.NoError(t, err, "version %d has no down migration", version)
+		require.NoError(t, down.Close())
+
+		next, err := sourceDriver.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		require.NoError(t, err)
+		require.Equal(t, version+1, next)
+		version = next
+	}
+}
+
+func TestRunInvalidArguments(t *testing.T) {
+	testCases := []struct {
+		name string
+		args []string
+		err  string
+	}{
+		{name: "NoCommand", args: nil, err: "missing command"},
+		{name: "UnknownCommand", args: []string{"sideways"}, err: `unknown command "sideways"`},
+		{name: "UpNotANumber", args: []string{"up", "all"}, err: `invalid number of migrations "all"`},
+		{name: "DownZero", args: []string{"down", "0"}, err: `invalid number of migrations "0"`},
+		{name: "DownTooManyArguments", args: []string{"down", "1", "2"}, err: "too many arguments"},
+		{name: "VersionWithArgument", args: []string{"version", "1"}, err: "version takes no arguments"},
+		{name: "ForceWithoutVersion", args: []string{"force"}, err: "force takes the version to set"},
+		{name: "ForceInvalidVersion", args: []string{"force", "-2"}, err: `invalid version "-2"`},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			// the arguments are checked before the database is touched
+			err := Run(nil, tc.args, nil)
+			require.ErrorContains(t, err, tc.err)
+		})
+	}
+}
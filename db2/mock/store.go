@@ -56,6 +56,80 @@ func (mr *MockStoreMockRecorder) AddAccountBalance(ctx, arg any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddAccountBalance", reflect.TypeOf((*MockStore)(nil).AddAccountBalance), ctx, arg)
 }
 
+// AnalyzeTransfersTx mocks base method.
+func (m *MockStore) AnalyzeTransfersTx(ctx context.Context, batchSize int32, rules db.AMLRules) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AnalyzeTransfersTx", ctx, batchSize, rules)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AnalyzeTransfersTx indicates an expected call of AnalyzeTransfersTx.
+func (mr *MockStoreMockRecorder) AnalyzeTransfersTx(ctx, batchSize, rules any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AnalyzeTransfersTx", reflect.TypeOf((*MockStore)(nil).AnalyzeTransfersTx), ctx, batchSize, rules)
+}
+
+// ApproveTransferTx mocks base method.
+func (m *MockStore) ApproveTransferTx(ctx context.Context, arg db.ReviewTransferTxParams) (db.TransferTxResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApproveTransferTx", ctx, arg)
+	ret0, _ := ret[0].(db.TransferTxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApproveTransferTx indicates an expected call of ApproveTransferTx.
+func (mr *MockStoreMockRecorder) ApproveTransferTx(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApproveTransferTx", reflect.TypeOf((*MockStore)(nil).ApproveTransferTx), ctx, arg)
+}
+
+// AssignComplianceAlert mocks base method.
+func (m *MockStore) AssignComplianceAlert(ctx context.Context, arg db.AssignComplianceAlertParams) (db.ComplianceAlert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AssignComplianceAlert", ctx, arg)
+	ret0, _ := ret[0].(db.ComplianceAlert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AssignComplianceAlert indicates an expected call of AssignComplianceAlert.
+func (mr *MockStoreMockRecorder) AssignComplianceAlert(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AssignComplianceAlert", reflect.TypeOf((*MockStore)(nil).AssignComplianceAlert), ctx, arg)
+}
+
+// AssignComplianceAlertTx mocks base method.
+func (m *MockStore) AssignComplianceAlertTx(ctx context.Context, arg db.AssignComplianceAlertParams) (db.ComplianceAlert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AssignComplianceAlertTx", ctx, arg)
+	ret0, _ := ret[0].(db.ComplianceAlert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AssignComplianceAlertTx indicates an expected call of AssignComplianceAlertTx.
+func (mr *MockStoreMockRecorder) AssignComplianceAlertTx(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AssignComplianceAlertTx", reflect.TypeOf((*MockStore)(nil).AssignComplianceAlertTx), ctx, arg)
+}
+
+// ClaimWebhookDeliveries mocks base method.
+func (m *MockStore) ClaimWebhookDeliveries(ctx context.Context, arg db.ClaimWebhookDeliveriesParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimWebhookDeliveries", ctx, arg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ClaimWebhookDeliveries indicates an expected call of ClaimWebhookDeliveries.
+func (mr *MockStoreMockRecorder) ClaimWebhookDeliveries(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimWebhookDeliveries", reflect.TypeOf((*MockStore)(nil).ClaimWebhookDeliveries), ctx, arg)
+}
+
 // CreateAccount mocks base method.
 func (m *MockStore) CreateAccount(ctx context.Context, arg db.CreateAccountParams) (db.Account, error) {
 	m.ctrl.T.Helper()
@@ -71,6 +145,51 @@ func (mr *MockStoreMockRecorder) CreateAccount(ctx, arg any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccount", reflect.TypeOf((*MockStore)(nil).CreateAccount), ctx, arg)
 }
 
+// CreateAccountTx mocks base method.
+func (m *MockStore) CreateAccountTx(ctx context.Context, arg db.CreateAccountParams) (db.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAccountTx", ctx, arg)
+	ret0, _ := ret[0].(db.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAccountTx indicates an expected call of CreateAccountTx.
+func (mr *MockStoreMockRecorder) CreateAccountTx(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccountTx", reflect.TypeOf((*MockStore)(nil).CreateAccountTx), ctx, arg)
+}
+
+// CreateAuditEvent mocks base method.
+func (m *MockStore) CreateAuditEvent(ctx context.Context, arg db.CreateAuditEventParams) (db.AuditEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAuditEvent", ctx, arg)
+	ret0, _ := ret[0].(db.AuditEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAuditEvent indicates an expected call of CreateAuditEvent.
+func (mr *MockStoreMockRecorder) CreateAuditEvent(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAuditEvent", reflect.TypeOf((*MockStore)(nil).CreateAuditEvent), ctx, arg)
+}
+
+// CreateComplianceAlert mocks base method.
+func (m *MockStore) CreateComplianceAlert(ctx context.Context, arg db.CreateComplianceAlertParams) (db.ComplianceAlert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateComplianceAlert", ctx, arg)
+	ret0, _ := ret[0].(db.ComplianceAlert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateComplianceAlert indicates an expected call of CreateComplianceAlert.
+func (mr *MockStoreMockRecorder) CreateComplianceAlert(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateComplianceAlert", reflect.TypeOf((*MockStore)(nil).CreateComplianceAlert), ctx, arg)
+}
+
 // CreateEntry mocks base method.
 func (m *MockStore) CreateEntry(ctx context.Context, arg db.CreateEntryParams) (db.Entry, error) {
 	m.ctrl.T.Helper()
@@ -86,6 +205,65 @@ func (mr *MockStoreMockRecorder) CreateEntry(ctx, arg any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEntry", reflect.TypeOf((*MockStore)(nil).CreateEntry), ctx, arg)
 }
 
+// CreateEquityAccount mocks base method.
+func (m *MockStore) CreateEquityAccount(ctx context.Context, currency string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateEquityAccount", ctx, currency)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateEquityAccount indicates an expected call of CreateEquityAccount.
+func (mr *MockStoreMockRecorder) CreateEquityAccount(ctx, currency any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEquityAccount", reflect.TypeOf((*MockStore)(nil).CreateEquityAccount), ctx, currency)
+}
+
+// CreateOpeningTransfer mocks base method.
+func (m *MockStore) CreateOpeningTransfer(ctx context.Context, arg db.CreateOpeningTransferParams) (db.Transfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOpeningTransfer", ctx, arg)
+	ret0, _ := ret[0].(db.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateOpeningTransfer indicates an expected call of CreateOpeningTransfer.
+func (mr *MockStoreMockRecorder) CreateOpeningTransfer(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOpeningTransfer", reflect.TypeOf((*MockStore)(nil).CreateOpeningTransfer), ctx, arg)
+}
+
+// CreateOutboxEvent mocks base method.
+func (m *MockStore) CreateOutboxEvent(ctx context.Context, arg db.CreateOutboxEventParams) (db.Outbox, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOutboxEvent", ctx, arg)
+	ret0, _ := ret[0].(db.Outbox)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateOutboxEvent indicates an expected call of CreateOutboxEvent.
+func (mr *MockStoreMockRecorder) CreateOutboxEvent(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOutboxEvent", reflect.TypeOf((*MockStore)(nil).CreateOutboxEvent), ctx, arg)
+}
+
+// CreatePendingTransfer mocks base method.
+func (m *MockStore) CreatePendingTransfer(ctx context.Context, arg db.CreatePendingTransferParams) (db.Transfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePendingTransfer", ctx, arg)
+	ret0, _ := ret[0].(db.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePendingTransfer indicates an expected call of CreatePendingTransfer.
+func (mr *MockStoreMockRecorder) CreatePendingTransfer(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePendingTransfer", reflect.TypeOf((*MockStore)(nil).CreatePendingTransfer), ctx, arg)
+}
+
 // CreateTransfer mocks base method.
 func (m *MockStore) CreateTransfer(ctx context.Context, arg db.CreateTransferParams) (db.Transfer, error) {
 	m.ctrl.T.Helper()
@@ -101,6 +279,51 @@ func (mr *MockStoreMockRecorder) CreateTransfer(ctx, arg any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTransfer", reflect.TypeOf((*MockStore)(nil).CreateTransfer), ctx, arg)
 }
 
+// CreateWebhook mocks base method.
+func (m *MockStore) CreateWebhook(ctx context.Context, arg db.CreateWebhookParams) (db.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWebhook", ctx, arg)
+	ret0, _ := ret[0].(db.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateWebhook indicates an expected call of CreateWebhook.
+func (mr *MockStoreMockRecorder) CreateWebhook(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWebhook", reflect.TypeOf((*MockStore)(nil).CreateWebhook), ctx, arg)
+}
+
+// CreateWebhookDeliveries mocks base method.
+func (m *MockStore) CreateWebhookDeliveries(ctx context.Context, arg db.CreateWebhookDeliveriesParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWebhookDeliveries", ctx, arg)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateWebhookDeliveries indicates an expected call of CreateWebhookDeliveries.
+func (mr *MockStoreMockRecorder) CreateWebhookDeliveries(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWebhookDeliveries", reflect.TypeOf((*MockStore)(nil).CreateWebhookDeliveries), ctx, arg)
+}
+
+// CreateWebhookTx mocks base method.
+func (m *MockStore) CreateWebhookTx(ctx context.Context, arg db.CreateWebhookParams) (db.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWebhookTx", ctx, arg)
+	ret0, _ := ret[0].(db.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateWebhookTx indicates an expected call of CreateWebhookTx.
+func (mr *MockStoreMockRecorder) CreateWebhookTx(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWebhookTx", reflect.TypeOf((*MockStore)(nil).CreateWebhookTx), ctx, arg)
+}
+
 // DeleteAccount mocks base method.
 func (m *MockStore) DeleteAccount(ctx context.Context, id int64) error {
 	m.ctrl.T.Helper()
@@ -115,6 +338,48 @@ func (mr *MockStoreMockRecorder) DeleteAccount(ctx, id any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAccount", reflect.TypeOf((*MockStore)(nil).DeleteAccount), ctx, id)
 }
 
+// DeleteAccountTransferLimitOverride mocks base method.
+func (m *MockStore) DeleteAccountTransferLimitOverride(ctx context.Context, accountID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAccountTransferLimitOverride", ctx, accountID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAccountTransferLimitOverride indicates an expected call of DeleteAccountTransferLimitOverride.
+func (mr *MockStoreMockRecorder) DeleteAccountTransferLimitOverride(ctx, accountID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAccountTransferLimitOverride", reflect.TypeOf((*MockStore)(nil).DeleteAccountTransferLimitOverride), ctx, accountID)
+}
+
+// DeleteWebhook mocks base method.
+func (m *MockStore) DeleteWebhook(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteWebhook", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteWebhook indicates an expected call of DeleteWebhook.
+func (mr *MockStoreMockRecorder) DeleteWebhook(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWebhook", reflect.TypeOf((*MockStore)(nil).DeleteWebhook), ctx, id)
+}
+
+// DeleteWebhookTx mocks base method.
+func (m *MockStore) DeleteWebhookTx(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteWebhookTx", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteWebhookTx indicates an expected call of DeleteWebhookTx.
+func (mr *MockStoreMockRecorder) DeleteWebhookTx(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWebhookTx", reflect.TypeOf((*MockStore)(nil).DeleteWebhookTx), ctx, id)
+}
+
 // GetAccount mocks base method.
 func (m *MockStore) GetAccount(ctx context.Context, id int64) (db.Account, error) {
 	m.ctrl.T.Helper()
@@ -145,6 +410,96 @@ func (mr *MockStoreMockRecorder) GetAccountForUpdate(ctx, id any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountForUpdate", reflect.TypeOf((*MockStore)(nil).GetAccountForUpdate), ctx, id)
 }
 
+// GetAccountTransferLimitOverride mocks base method.
+func (m *MockStore) GetAccountTransferLimitOverride(ctx context.Context, accountID int64) (db.AccountTransferLimit, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccountTransferLimitOverride", ctx, accountID)
+	ret0, _ := ret[0].(db.AccountTransferLimit)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccountTransferLimitOverride indicates an expected call of GetAccountTransferLimitOverride.
+func (mr *MockStoreMockRecorder) GetAccountTransferLimitOverride(ctx, accountID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountTransferLimitOverride", reflect.TypeOf((*MockStore)(nil).GetAccountTransferLimitOverride), ctx, accountID)
+}
+
+// GetAuditEvent mocks base method.
+func (m *MockStore) GetAuditEvent(ctx context.Context, id int64) (db.AuditEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAuditEvent", ctx, id)
+	ret0, _ := ret[0].(db.AuditEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAuditEvent indicates an expected call of GetAuditEvent.
+func (mr *MockStoreMockRecorder) GetAuditEvent(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuditEvent", reflect.TypeOf((*MockStore)(nil).GetAuditEvent), ctx, id)
+}
+
+// GetComplianceAlert mocks base method.
+func (m *MockStore) GetComplianceAlert(ctx context.Context, id int64) (db.ComplianceAlert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetComplianceAlert", ctx, id)
+	ret0, _ := ret[0].(db.ComplianceAlert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetComplianceAlert indicates an expected call of GetComplianceAlert.
+func (mr *MockStoreMockRecorder) GetComplianceAlert(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetComplianceAlert", reflect.TypeOf((*MockStore)(nil).GetComplianceAlert), ctx, id)
+}
+
+// GetComplianceAlertForUpdate mocks base method.
+func (m *MockStore) GetComplianceAlertForUpdate(ctx context.Context, id int64) (db.ComplianceAlert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetComplianceAlertForUpdate", ctx, id)
+	ret0, _ := ret[0].(db.ComplianceAlert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetComplianceAlertForUpdate indicates an expected call of GetComplianceAlertForUpdate.
+func (mr *MockStoreMockRecorder) GetComplianceAlertForUpdate(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetComplianceAlertForUpdate", reflect.TypeOf((*MockStore)(nil).GetComplianceAlertForUpdate), ctx, id)
+}
+
+// GetDailyOutgoingTransfers mocks base method.
+func (m *MockStore) GetDailyOutgoingTransfers(ctx context.Context, arg db.GetDailyOutgoingTransfersParams) (db.GetDailyOutgoingTransfersRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDailyOutgoingTransfers", ctx, arg)
+	ret0, _ := ret[0].(db.GetDailyOutgoingTransfersRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDailyOutgoingTransfers indicates an expected call of GetDailyOutgoingTransfers.
+func (mr *MockStoreMockRecorder) GetDailyOutgoingTransfers(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDailyOutgoingTransfers", reflect.TypeOf((*MockStore)(nil).GetDailyOutgoingTransfers), ctx, arg)
+}
+
+// GetEffectiveTransferLimits mocks base method.
+func (m *MockStore) GetEffectiveTransferLimits(ctx context.Context, id int64) (db.GetEffectiveTransferLimitsRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEffectiveTransferLimits", ctx, id)
+	ret0, _ := ret[0].(db.GetEffectiveTransferLimitsRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEffectiveTransferLimits indicates an expected call of GetEffectiveTransferLimits.
+func (mr *MockStoreMockRecorder) GetEffectiveTransferLimits(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEffectiveTransferLimits", reflect.TypeOf((*MockStore)(nil).GetEffectiveTransferLimits), ctx, id)
+}
+
 // GetEntry mocks base method.
 func (m *MockStore) GetEntry(ctx context.Context, id int64) (db.Entry, error) {
 	m.ctrl.T.Helper()
@@ -160,6 +515,21 @@ func (mr *MockStoreMockRecorder) GetEntry(ctx, id any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEntry", reflect.TypeOf((*MockStore)(nil).GetEntry), ctx, id)
 }
 
+// GetEquityAccount mocks base method.
+func (m *MockStore) GetEquityAccount(ctx context.Context, currency string) (db.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEquityAccount", ctx, currency)
+	ret0, _ := ret[0].(db.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEquityAccount indicates an expected call of GetEquityAccount.
+func (mr *MockStoreMockRecorder) GetEquityAccount(ctx, currency any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEquityAccount", reflect.TypeOf((*MockStore)(nil).GetEquityAccount), ctx, currency)
+}
+
 // GetTransfer mocks base method.
 func (m *MockStore) GetTransfer(ctx context.Context, id int64) (db.Transfer, error) {
 	m.ctrl.T.Helper()
@@ -175,6 +545,66 @@ func (mr *MockStoreMockRecorder) GetTransfer(ctx, id any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransfer", reflect.TypeOf((*MockStore)(nil).GetTransfer), ctx, id)
 }
 
+// GetTransferForUpdate mocks base method.
+func (m *MockStore) GetTransferForUpdate(ctx context.Context, id int64) (db.Transfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransferForUpdate", ctx, id)
+	ret0, _ := ret[0].(db.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTransferForUpdate indicates an expected call of GetTransferForUpdate.
+func (mr *MockStoreMockRecorder) GetTransferForUpdate(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransferForUpdate", reflect.TypeOf((*MockStore)(nil).GetTransferForUpdate), ctx, id)
+}
+
+// GetTransferLimitTier mocks base method.
+func (m *MockStore) GetTransferLimitTier(ctx context.Context, name string) (db.TransferLimitTier, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransferLimitTier", ctx, name)
+	ret0, _ := ret[0].(db.TransferLimitTier)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTransferLimitTier indicates an expected call of GetTransferLimitTier.
+func (mr *MockStoreMockRecorder) GetTransferLimitTier(ctx, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransferLimitTier", reflect.TypeOf((*MockStore)(nil).GetTransferLimitTier), ctx, name)
+}
+
+// GetWebhook mocks base method.
+func (m *MockStore) GetWebhook(ctx context.Context, id int64) (db.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWebhook", ctx, id)
+	ret0, _ := ret[0].(db.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWebhook indicates an expected call of GetWebhook.
+func (mr *MockStoreMockRecorder) GetWebhook(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebhook", reflect.TypeOf((*MockStore)(nil).GetWebhook), ctx, id)
+}
+
+// HasCompletedTransfer mocks base method.
+func (m *MockStore) HasCompletedTransfer(ctx context.Context, arg db.HasCompletedTransferParams) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HasCompletedTransfer", ctx, arg)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HasCompletedTransfer indicates an expected call of HasCompletedTransfer.
+func (mr *MockStoreMockRecorder) HasCompletedTransfer(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasCompletedTransfer", reflect.TypeOf((*MockStore)(nil).HasCompletedTransfer), ctx, arg)
+}
+
 // ListAccounts mocks base method.
 func (m *MockStore) ListAccounts(ctx context.Context, arg db.ListAccountsParams) ([]db.Account, error) {
 	m.ctrl.T.Helper()
@@ -190,6 +620,51 @@ func (mr *MockStoreMockRecorder) ListAccounts(ctx, arg any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccounts", reflect.TypeOf((*MockStore)(nil).ListAccounts), ctx, arg)
 }
 
+// ListAuditEvents mocks base method.
+func (m *MockStore) ListAuditEvents(ctx context.Context, arg db.ListAuditEventsParams) ([]db.AuditEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAuditEvents", ctx, arg)
+	ret0, _ := ret[0].([]db.AuditEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAuditEvents indicates an expected call of ListAuditEvents.
+func (mr *MockStoreMockRecorder) ListAuditEvents(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAuditEvents", reflect.TypeOf((*MockStore)(nil).ListAuditEvents), ctx, arg)
+}
+
+// ListComplianceAlerts mocks base method.
+func (m *MockStore) ListComplianceAlerts(ctx context.Context, arg db.ListComplianceAlertsParams) ([]db.ComplianceAlert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListComplianceAlerts", ctx, arg)
+	ret0, _ := ret[0].([]db.ComplianceAlert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListComplianceAlerts indicates an expected call of ListComplianceAlerts.
+func (mr *MockStoreMockRecorder) ListComplianceAlerts(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListComplianceAlerts", reflect.TypeOf((*MockStore)(nil).ListComplianceAlerts), ctx, arg)
+}
+
+// ListDueWebhookDeliveries mocks base method.
+func (m *MockStore) ListDueWebhookDeliveries(ctx context.Context, limit int32) ([]db.ListDueWebhookDeliveriesRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDueWebhookDeliveries", ctx, limit)
+	ret0, _ := ret[0].([]db.ListDueWebhookDeliveriesRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDueWebhookDeliveries indicates an expected call of ListDueWebhookDeliveries.
+func (mr *MockStoreMockRecorder) ListDueWebhookDeliveries(ctx, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDueWebhookDeliveries", reflect.TypeOf((*MockStore)(nil).ListDueWebhookDeliveries), ctx, limit)
+}
+
 // ListEntries mocks base method.
 func (m *MockStore) ListEntries(ctx context.Context, arg db.ListEntriesParams) ([]db.Entry, error) {
 	m.ctrl.T.Helper()
@@ -205,6 +680,51 @@ func (mr *MockStoreMockRecorder) ListEntries(ctx, arg any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListEntries", reflect.TypeOf((*MockStore)(nil).ListEntries), ctx, arg)
 }
 
+// ListPendingTransfers mocks base method.
+func (m *MockStore) ListPendingTransfers(ctx context.Context, arg db.ListPendingTransfersParams) ([]db.Transfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPendingTransfers", ctx, arg)
+	ret0, _ := ret[0].([]db.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPendingTransfers indicates an expected call of ListPendingTransfers.
+func (mr *MockStoreMockRecorder) ListPendingTransfers(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPendingTransfers", reflect.TypeOf((*MockStore)(nil).ListPendingTransfers), ctx, arg)
+}
+
+// ListRecentOutgoingTransfers mocks base method.
+func (m *MockStore) ListRecentOutgoingTransfers(ctx context.Context, arg db.ListRecentOutgoingTransfersParams) ([]db.Transfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRecentOutgoingTransfers", ctx, arg)
+	ret0, _ := ret[0].([]db.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRecentOutgoingTransfers indicates an expected call of ListRecentOutgoingTransfers.
+func (mr *MockStoreMockRecorder) ListRecentOutgoingTransfers(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRecentOutgoingTransfers", reflect.TypeOf((*MockStore)(nil).ListRecentOutgoingTransfers), ctx, arg)
+}
+
+// ListTransferLimitTiers mocks base method.
+func (m *MockStore) ListTransferLimitTiers(ctx context.Context) ([]db.TransferLimitTier, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTransferLimitTiers", ctx)
+	ret0, _ := ret[0].([]db.TransferLimitTier)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTransferLimitTiers indicates an expected call of ListTransferLimitTiers.
+func (mr *MockStoreMockRecorder) ListTransferLimitTiers(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTransferLimitTiers", reflect.TypeOf((*MockStore)(nil).ListTransferLimitTiers), ctx)
+}
+
 // ListTransfers mocks base method.
 func (m *MockStore) ListTransfers(ctx context.Context, arg db.ListTransfersParams) ([]db.Transfer, error) {
 	m.ctrl.T.Helper()
@@ -220,6 +740,257 @@ func (mr *MockStoreMockRecorder) ListTransfers(ctx, arg any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTransfers", reflect.TypeOf((*MockStore)(nil).ListTransfers), ctx, arg)
 }
 
+// ListTransfersInAmountRange mocks base method.
+func (m *MockStore) ListTransfersInAmountRange(ctx context.Context, arg db.ListTransfersInAmountRangeParams) ([]db.ListTransfersInAmountRangeRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTransfersInAmountRange", ctx, arg)
+	ret0, _ := ret[0].([]db.ListTransfersInAmountRangeRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTransfersInAmountRange indicates an expected call of ListTransfersInAmountRange.
+func (mr *MockStoreMockRecorder) ListTransfersInAmountRange(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTransfersInAmountRange", reflect.TypeOf((*MockStore)(nil).ListTransfersInAmountRange), ctx, arg)
+}
+
+// ListUnanalyzedTransfers mocks base method.
+func (m *MockStore) ListUnanalyzedTransfers(ctx context.Context, limit int32) ([]db.ListUnanalyzedTransfersRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUnanalyzedTransfers", ctx, limit)
+	ret0, _ := ret[0].([]db.ListUnanalyzedTransfersRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUnanalyzedTransfers indicates an expected call of ListUnanalyzedTransfers.
+func (mr *MockStoreMockRecorder) ListUnanalyzedTransfers(ctx, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUnanalyzedTransfers", reflect.TypeOf((*MockStore)(nil).ListUnanalyzedTransfers), ctx, limit)
+}
+
+// ListUnpublishedOutboxEvents mocks base method.
+func (m *MockStore) ListUnpublishedOutboxEvents(ctx context.Context, arg db.ListUnpublishedOutboxEventsParams) ([]db.Outbox, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUnpublishedOutboxEvents", ctx, arg)
+	ret0, _ := ret[0].([]db.Outbox)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUnpublishedOutboxEvents indicates an expected call of ListUnpublishedOutboxEvents.
+func (mr *MockStoreMockRecorder) ListUnpublishedOutboxEvents(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUnpublishedOutboxEvents", reflect.TypeOf((*MockStore)(nil).ListUnpublishedOutboxEvents), ctx, arg)
+}
+
+// ListWebhookDeliveries mocks base method.
+func (m *MockStore) ListWebhookDeliveries(ctx context.Context, arg db.ListWebhookDeliveriesParams) ([]db.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWebhookDeliveries", ctx, arg)
+	ret0, _ := ret[0].([]db.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWebhookDeliveries indicates an expected call of ListWebhookDeliveries.
+func (mr *MockStoreMockRecorder) ListWebhookDeliveries(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWebhookDeliveries", reflect.TypeOf((*MockStore)(nil).ListWebhookDeliveries), ctx, arg)
+}
+
+// ListWebhooks mocks base method.
+func (m *MockStore) ListWebhooks(ctx context.Context, arg db.ListWebhooksParams) ([]db.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWebhooks", ctx, arg)
+	ret0, _ := ret[0].([]db.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWebhooks indicates an expected call of ListWebhooks.
+func (mr *MockStoreMockRecorder) ListWebhooks(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWebhooks", reflect.TypeOf((*MockStore)(nil).ListWebhooks), ctx, arg)
+}
+
+// MarkOutboxEventFailed mocks base method.
+func (m *MockStore) MarkOutboxEventFailed(ctx context.Context, arg db.MarkOutboxEventFailedParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkOutboxEventFailed", ctx, arg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkOutboxEventFailed indicates an expected call of MarkOutboxEventFailed.
+func (mr *MockStoreMockRecorder) MarkOutboxEventFailed(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkOutboxEventFailed", reflect.TypeOf((*MockStore)(nil).MarkOutboxEventFailed), ctx, arg)
+}
+
+// MarkOutboxEventPublished mocks base method.
+func (m *MockStore) MarkOutboxEventPublished(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkOutboxEventPublished", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkOutboxEventPublished indicates an expected call of MarkOutboxEventPublished.
+func (mr *MockStoreMockRecorder) MarkOutboxEventPublished(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkOutboxEventPublished", reflect.TypeOf((*MockStore)(nil).MarkOutboxEventPublished), ctx, id)
+}
+
+// MarkTransferAnalyzed mocks base method.
+func (m *MockStore) MarkTransferAnalyzed(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkTransferAnalyzed", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkTransferAnalyzed indicates an expected call of MarkTransferAnalyzed.
+func (mr *MockStoreMockRecorder) MarkTransferAnalyzed(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkTransferAnalyzed", reflect.TypeOf((*MockStore)(nil).MarkTransferAnalyzed), ctx, id)
+}
+
+// NotifyAccountEvent mocks base method.
+func (m *MockStore) NotifyAccountEvent(ctx context.Context, payload string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NotifyAccountEvent", ctx, payload)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// NotifyAccountEvent indicates an expected call of NotifyAccountEvent.
+func (mr *MockStoreMockRecorder) NotifyAccountEvent(ctx, payload any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NotifyAccountEvent", reflect.TypeOf((*MockStore)(nil).NotifyAccountEvent), ctx, payload)
+}
+
+// ProcessWebhookDeliveriesTx mocks base method.
+func (m *MockStore) ProcessWebhookDeliveriesTx(ctx context.Context, batchSize int32, deliver func(context.Context, db.WebhookDelivery, db.Webhook) db.WebhookAttempt) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProcessWebhookDeliveriesTx", ctx, batchSize, deliver)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ProcessWebhookDeliveriesTx indicates an expected call of ProcessWebhookDeliveriesTx.
+func (mr *MockStoreMockRecorder) ProcessWebhookDeliveriesTx(ctx, batchSize, deliver any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessWebhookDeliveriesTx", reflect.TypeOf((*MockStore)(nil).ProcessWebhookDeliveriesTx), ctx, batchSize, deliver)
+}
+
+// ReconcileAccounts mocks base method.
+func (m *MockStore) ReconcileAccounts(ctx context.Context) ([]db.ReconcileAccountsRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReconcileAccounts", ctx)
+	ret0, _ := ret[0].([]db.ReconcileAccountsRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReconcileAccounts indicates an expected call of ReconcileAccounts.
+func (mr *MockStoreMockRecorder) ReconcileAccounts(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReconcileAccounts", reflect.TypeOf((*MockStore)(nil).ReconcileAccounts), ctx)
+}
+
+// RecordWebhookAttempt mocks base method.
+func (m *MockStore) RecordWebhookAttempt(ctx context.Context, arg db.RecordWebhookAttemptParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordWebhookAttempt", ctx, arg)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RecordWebhookAttempt indicates an expected call of RecordWebhookAttempt.
+func (mr *MockStoreMockRecorder) RecordWebhookAttempt(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordWebhookAttempt", reflect.TypeOf((*MockStore)(nil).RecordWebhookAttempt), ctx, arg)
+}
+
+// RejectTransferTx mocks base method.
+func (m *MockStore) RejectTransferTx(ctx context.Context, arg db.ReviewTransferTxParams) (db.Transfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RejectTransferTx", ctx, arg)
+	ret0, _ := ret[0].(db.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RejectTransferTx indicates an expected call of RejectTransferTx.
+func (mr *MockStoreMockRecorder) RejectTransferTx(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RejectTransferTx", reflect.TypeOf((*MockStore)(nil).RejectTransferTx), ctx, arg)
+}
+
+// RelayOutboxTx mocks base method.
+func (m *MockStore) RelayOutboxTx(ctx context.Context, batchSize, maxAttempts int32, publish func(context.Context, db.Outbox) error) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RelayOutboxTx", ctx, batchSize, maxAttempts, publish)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RelayOutboxTx indicates an expected call of RelayOutboxTx.
+func (mr *MockStoreMockRecorder) RelayOutboxTx(ctx, batchSize, maxAttempts, publish any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RelayOutboxTx", reflect.TypeOf((*MockStore)(nil).RelayOutboxTx), ctx, batchSize, maxAttempts, publish)
+}
+
+// ResolveComplianceAlert mocks base method.
+func (m *MockStore) ResolveComplianceAlert(ctx context.Context, arg db.ResolveComplianceAlertParams) (db.ComplianceAlert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveComplianceAlert", ctx, arg)
+	ret0, _ := ret[0].(db.ComplianceAlert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResolveComplianceAlert indicates an expected call of ResolveComplianceAlert.
+func (mr *MockStoreMockRecorder) ResolveComplianceAlert(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveComplianceAlert", reflect.TypeOf((*MockStore)(nil).ResolveComplianceAlert), ctx, arg)
+}
+
+// ResolveComplianceAlertTx mocks base method.
+func (m *MockStore) ResolveComplianceAlertTx(ctx context.Context, arg db.ResolveComplianceAlertTxParams) (db.ComplianceAlert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveComplianceAlertTx", ctx, arg)
+	ret0, _ := ret[0].(db.ComplianceAlert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResolveComplianceAlertTx indicates an expected call of ResolveComplianceAlertTx.
+func (mr *MockStoreMockRecorder) ResolveComplianceAlertTx(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveComplianceAlertTx", reflect.TypeOf((*MockStore)(nil).ResolveComplianceAlertTx), ctx, arg)
+}
+
+// ReviewTransfer mocks base method.
+func (m *MockStore) ReviewTransfer(ctx context.Context, arg db.ReviewTransferParams) (db.Transfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReviewTransfer", ctx, arg)
+	ret0, _ := ret[0].(db.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReviewTransfer indicates an expected call of ReviewTransfer.
+func (mr *MockStoreMockRecorder) ReviewTransfer(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReviewTransfer", reflect.TypeOf((*MockStore)(nil).ReviewTransfer), ctx, arg)
+}
+
 // TransferTx mocks base method.
 func (m *MockStore) TransferTx(ctx context.Context, arg db.TransferTxParams) (db.TransferTxResult, error) {
 	m.ctrl.T.Helper()
@@ -249,3 +1020,153 @@ func (mr *MockStoreMockRecorder) UpdateAccount(ctx, arg any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAccount", reflect.TypeOf((*MockStore)(nil).UpdateAccount), ctx, arg)
 }
+
+// UpdateAccountLimitTier mocks base method.
+func (m *MockStore) UpdateAccountLimitTier(ctx context.Context, arg db.UpdateAccountLimitTierParams) (db.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAccountLimitTier", ctx, arg)
+	ret0, _ := ret[0].(db.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateAccountLimitTier indicates an expected call of UpdateAccountLimitTier.
+func (mr *MockStoreMockRecorder) UpdateAccountLimitTier(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAccountLimitTier", reflect.TypeOf((*MockStore)(nil).UpdateAccountLimitTier), ctx, arg)
+}
+
+// UpdateAccountStatus mocks base method.
+func (m *MockStore) UpdateAccountStatus(ctx context.Context, arg db.UpdateAccountStatusParams) (db.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAccountStatus", ctx, arg)
+	ret0, _ := ret[0].(db.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateAccountStatus indicates an expected call of UpdateAccountStatus.
+func (mr *MockStoreMockRecorder) UpdateAccountStatus(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAccountStatus", reflect.TypeOf((*MockStore)(nil).UpdateAccountStatus), ctx, arg)
+}
+
+// UpdateAccountStatusTx mocks base method.
+func (m *MockStore) UpdateAccountStatusTx(ctx context.Context, arg db.UpdateAccountStatusParams) (db.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAccountStatusTx", ctx, arg)
+	ret0, _ := ret[0].(db.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateAccountStatusTx indicates an expected call of UpdateAccountStatusTx.
+func (mr *MockStoreMockRecorder) UpdateAccountStatusTx(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAccountStatusTx", reflect.TypeOf((*MockStore)(nil).UpdateAccountStatusTx), ctx, arg)
+}
+
+// UpdateAccountTransferLimitsTx mocks base method.
+func (m *MockStore) UpdateAccountTransferLimitsTx(ctx context.Context, arg db.UpdateAccountTransferLimitsParams) (db.GetEffectiveTransferLimitsRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAccountTransferLimitsTx", ctx, arg)
+	ret0, _ := ret[0].(db.GetEffectiveTransferLimitsRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateAccountTransferLimitsTx indicates an expected call of UpdateAccountTransferLimitsTx.
+func (mr *MockStoreMockRecorder) UpdateAccountTransferLimitsTx(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAccountTransferLimitsTx", reflect.TypeOf((*MockStore)(nil).UpdateAccountTransferLimitsTx), ctx, arg)
+}
+
+// UpdateWebhook mocks base method.
+func (m *MockStore) UpdateWebhook(ctx context.Context, arg db.UpdateWebhookParams) (db.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateWebhook", ctx, arg)
+	ret0, _ := ret[0].(db.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateWebhook indicates an expected call of UpdateWebhook.
+func (mr *MockStoreMockRecorder) UpdateWebhook(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateWebhook", reflect.TypeOf((*MockStore)(nil).UpdateWebhook), ctx, arg)
+}
+
+// UpdateWebhookTx mocks base method.
+func (m *MockStore) UpdateWebhookTx(ctx context.Context, arg db.UpdateWebhookParams) (db.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateWebhookTx", ctx, arg)
+	ret0, _ := ret[0].(db.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateWebhookTx indicates an expected call of UpdateWebhookTx.
+func (mr *MockStoreMockRecorder) UpdateWebhookTx(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateWebhookTx", reflect.TypeOf((*MockStore)(nil).UpdateWebhookTx), ctx, arg)
+}
+
+// UpsertAccountTransferLimitOverride mocks base method.
+func (m *MockStore) UpsertAccountTransferLimitOverride(ctx context.Context, arg db.UpsertAccountTransferLimitOverrideParams) (db.AccountTransferLimit, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertAccountTransferLimitOverride", ctx, arg)
+	ret0, _ := ret[0].(db.AccountTransferLimit)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpsertAccountTransferLimitOverride indicates an expected call of UpsertAccountTransferLimitOverride.
+func (mr *MockStoreMockRecorder) UpsertAccountTransferLimitOverride(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertAccountTransferLimitOverride", reflect.TypeOf((*MockStore)(nil).UpsertAccountTransferLimitOverride), ctx, arg)
+}
+
+// UpsertStructuringAlert mocks base method.
+func (m *MockStore) UpsertStructuringAlert(ctx context.Context, arg db.UpsertStructuringAlertParams) (db.ComplianceAlert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertStructuringAlert", ctx, arg)
+	ret0, _ := ret[0].(db.ComplianceAlert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpsertStructuringAlert indicates an expected call of UpsertStructuringAlert.
+func (mr *MockStoreMockRecorder) UpsertStructuringAlert(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertStructuringAlert", reflect.TypeOf((*MockStore)(nil).UpsertStructuringAlert), ctx, arg)
+}
+
+// UpsertTransferLimitTier mocks base method.
+func (m *MockStore) UpsertTransferLimitTier(ctx context.Context, arg db.UpsertTransferLimitTierParams) (db.TransferLimitTier, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertTransferLimitTier", ctx, arg)
+	ret0, _ := ret[0].(db.TransferLimitTier)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpsertTransferLimitTier indicates an expected call of UpsertTransferLimitTier.
+func (mr *MockStoreMockRecorder) UpsertTransferLimitTier(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertTransferLimitTier", reflect.TypeOf((*MockStore)(nil).UpsertTransferLimitTier), ctx, arg)
+}
+
+// UpsertTransferLimitTierTx mocks base method.
+func (m *MockStore) UpsertTransferLimitTierTx(ctx context.Context, arg db.UpsertTransferLimitTierParams) (db.TransferLimitTier, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertTransferLimitTierTx", ctx, arg)
+	ret0, _ := ret[0].(db.TransferLimitTier)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpsertTransferLimitTierTx indicates an expected call of UpsertTransferLimitTierTx.
+func (mr *MockStoreMockRecorder) UpsertTransferLimitTierTx(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertTransferLimitTierTx", reflect.TypeOf((*MockStore)(nil).UpsertTransferLimitTierTx), ctx, arg)
+}
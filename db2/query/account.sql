@@ -28,7 +28,8 @@ SELECT * FROM accounts WHERE id = $1 LIMIT 1 FOR NO KEY UPDATE;
 
 
 -- name: ListAccounts :many
-SELECT * FROM accounts ORDER BY id LIMIT $1 OFFSET $2;
+-- the equity accounts are the bank's side of the ledger, customers don't see them
+SELECT * FROM accounts WHERE kind = 'customer' ORDER BY id LIMIT $1 OFFSET $2;
 -- LIMIT $1 enable pagination so that we only display certain number of rows
 
 -- name: UpdateAccount :one
@@ -42,5 +43,25 @@ UPDATE accounts SET balance = balance + sqlc.arg(amount)
 WHERE id = sqlc.arg(id)
 RETURNING *;
 
+-- name: UpdateAccountStatus :one
+-- frozen accounts can't send or receive transfers, see TransferTx
+UPDATE accounts SET status = sqlc.arg(status)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
 -- name: DeleteAccount :exec
-DELETE FROM accounts WHERE id = $1;
\ No newline at end of file
+DELETE FROM accounts WHERE id = $1;
+-- name: GetEquityAccount :one
+-- the equity account of a currency pays the opening balances of the accounts in the currency, see CreateAccountTx
+SELECT * FROM accounts WHERE kind = 'equity' AND currency = $1 LIMIT 1;
+
+-- name: CreateEquityAccount :exec
+-- does nothing when a concurrent transaction created it first, GetEquityAccount finds it afterwards
+INSERT INTO accounts (
+  owner,
+  balance,
+  currency,
+  kind
+) VALUES (
+  'equity', 0, $1, 'equity'
+) ON CONFLICT (currency) WHERE kind = 'equity' DO NOTHING;
@@ -0,0 +1,29 @@
+-- name: CreateAuditEvent :one
+INSERT INTO audit_events (
+  actor,
+  action,
+  resource_type,
+  resource_id,
+  before,
+  after,
+  request_id,
+  client_ip
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8
+) RETURNING *;
+
+-- name: GetAuditEvent :one
+SELECT * FROM audit_events
+WHERE id = $1 LIMIT 1;
+
+-- name: ListAuditEvents :many
+-- every filter is optional, passing NULL skips it
+SELECT * FROM audit_events
+WHERE
+    (sqlc.narg(actor)::varchar IS NULL OR actor = sqlc.narg(actor)) AND
+    (sqlc.narg(action)::varchar IS NULL OR action = sqlc.narg(action)) AND
+    (sqlc.narg(resource_type)::varchar IS NULL OR resource_type = sqlc.narg(resource_type)) AND
+    (sqlc.narg(resource_id)::varchar IS NULL OR resource_id = sqlc.narg(resource_id))
+ORDER BY id DESC
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
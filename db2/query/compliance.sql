@@ -0,0 +1,100 @@
+-- name: ListUnanalyzedTransfers :many
+-- the completed transfers the AML analyzer didn't look at yet, with the currency of the sender
+-- SKIP LOCKED lets several analyzers run side by side, each one takes a different batch
+SELECT
+  transfers.id,
+  transfers.from_account_id,
+  transfers.amount,
+  transfers.created_at,
+  accounts.currency
+FROM transfers
+JOIN accounts ON accounts.id = transfers.from_account_id
+WHERE transfers.aml_analyzed_at IS NULL AND transfers.status = 'completed'
+ORDER BY transfers.id
+LIMIT $1
+FOR UPDATE OF transfers SKIP LOCKED;
+
+-- name: MarkTransferAnalyzed :exec
+UPDATE transfers SET aml_analyzed_at = now()
+WHERE id = $1;
+
+-- name: ListTransfersInAmountRange :many
+-- the completed transfers of the account between two times with min_amount <= amount < max_amount
+SELECT id, amount FROM transfers
+WHERE from_account_id = sqlc.arg(account_id)
+  AND status = 'completed'
+  AND created_at BETWEEN sqlc.arg(since)::timestamptz AND sqlc.arg(until)::timestamptz
+  AND amount >= sqlc.arg(min_amount)::bigint
+  AND amount < sqlc.arg(max_amount)::bigint
+ORDER BY id;
+
+-- name: CreateComplianceAlert :one
+INSERT INTO compliance_alerts (
+  kind,
+  account_id,
+  currency,
+  total_amount,
+  transfer_ids
+) VALUES (
+  $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: UpsertStructuringAlert :one
+-- raises a structuring alert for the account, or adds the transfers to its unresolved one
+INSERT INTO compliance_alerts (
+  kind,
+  account_id,
+  currency,
+  total_amount,
+  transfer_ids
+) VALUES (
+  'structuring', sqlc.arg(account_id), sqlc.arg(currency), sqlc.arg(total_amount), sqlc.arg(transfer_ids)::bigint[]
+)
+ON CONFLICT (account_id) WHERE kind = 'structuring' AND status <> 'resolved' DO UPDATE SET
+  transfer_ids = ARRAY(
+    SELECT DISTINCT unnest(compliance_alerts.transfer_ids || EXCLUDED.transfer_ids) ORDER BY 1
+  ),
+  total_amount = (
+    SELECT COALESCE(SUM(transfers.amount), 0) FROM transfers
+    WHERE transfers.id = ANY(compliance_alerts.transfer_ids || EXCLUDED.transfer_ids)
+  ),
+  updated_at = now()
+RETURNING *;
+
+-- name: GetComplianceAlert :one
+SELECT * FROM compliance_alerts
+WHERE id = $1 LIMIT 1;
+
+-- name: GetComplianceAlertForUpdate :one
+SELECT * FROM compliance_alerts
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: ListComplianceAlerts :many
+-- every filter is optional, NULL means "don't filter on it", newest alert first
+SELECT * FROM compliance_alerts
+WHERE (sqlc.narg(status)::varchar IS NULL OR status = sqlc.narg(status))
+  AND (sqlc.narg(kind)::varchar IS NULL OR kind = sqlc.narg(kind))
+  AND (sqlc.narg(assignee)::varchar IS NULL OR assignee = sqlc.narg(assignee))
+ORDER BY id DESC
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+-- name: AssignComplianceAlert :one
+UPDATE compliance_alerts SET
+  assignee = sqlc.arg(assignee),
+  status = 'assigned',
+  updated_at = now()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: ResolveComplianceAlert :one
+UPDATE compliance_alerts SET
+  status = 'resolved',
+  resolution = sqlc.arg(resolution),
+  resolution_note = sqlc.arg(resolution_note),
+  resolved_by = sqlc.arg(resolved_by),
+  resolved_at = now(),
+  updated_at = now()
+WHERE id = sqlc.arg(id)
+RETURNING *;
@@ -1,9 +1,10 @@
 -- name: CreateEntry :one
 INSERT INTO entries (
   account_id,
-  amount
+  amount,
+  transfer_id
 ) VALUES (
-  $1, $2
+  sqlc.arg(account_id), sqlc.arg(amount), sqlc.narg(transfer_id)
 ) RETURNING *;
 
 -- name: GetEntry :one
@@ -0,0 +1,3 @@
+-- name: NotifyAccountEvent :exec
+-- the notification is only delivered to listeners when the transaction commits
+SELECT pg_notify('account_events', sqlc.arg(payload)::text);
@@ -0,0 +1,26 @@
+-- name: CreateOutboxEvent :one
+INSERT INTO outbox (
+  event_type,
+  aggregate_type,
+  aggregate_id,
+  payload
+) VALUES (
+  $1, $2, $3, $4
+) RETURNING *;
+
+-- name: ListUnpublishedOutboxEvents :many
+-- SKIP LOCKED lets several relays run side by side, each one takes a different batch
+-- the events that failed max_attempts times are dead-lettered: they stay unpublished, with their last error, for inspection
+SELECT * FROM outbox
+WHERE published_at IS NULL AND attempts < sqlc.arg(max_attempts)
+ORDER BY id
+LIMIT sqlc.arg('limit')
+FOR UPDATE SKIP LOCKED;
+
+-- name: MarkOutboxEventPublished :exec
+UPDATE outbox SET published_at = now(), attempts = attempts + 1, last_error = ''
+WHERE id = $1;
+
+-- name: MarkOutboxEventFailed :exec
+UPDATE outbox SET attempts = attempts + 1, last_error = sqlc.arg(last_error)
+WHERE id = sqlc.arg(id);
@@ -7,6 +7,18 @@ INSERT INTO transfers (
   $1, $2, $3
 ) RETURNING *;
 
+-- name: CreateOpeningTransfer :one
+-- the opening balance of an account, paid by the equity account of its currency
+-- the AML analyzer skips it, no customer sent the money
+INSERT INTO transfers (
+  from_account_id,
+  to_account_id,
+  amount,
+  aml_analyzed_at
+) VALUES (
+  $1, $2, $3, now()
+) RETURNING *;
+
 -- name: GetTransfer :one
 SELECT * FROM transfers
 WHERE id = $1 LIMIT 1;
@@ -18,4 +30,55 @@ WHERE
     to_account_id = $2
 ORDER BY id
 LIMIT $3
-OFFSET $4;
\ No newline at end of file
+OFFSET $4;
+
+-- name: CreatePendingTransfer :one
+-- a transfer sent to review by the fraud screening, no entries are written and no money moves until it is approved
+INSERT INTO transfers (
+  from_account_id,
+  to_account_id,
+  amount,
+  status,
+  screening_rule,
+  screening_reason
+) VALUES (
+  $1, $2, $3, 'pending_review', $4, $5
+) RETURNING *;
+
+-- name: GetTransferForUpdate :one
+-- locks the transfer, so that two tellers can't review it at the same time
+SELECT * FROM transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: ListPendingTransfers :many
+-- the review queue, oldest transfer first
+SELECT * FROM transfers
+WHERE status = 'pending_review'
+ORDER BY created_at, id
+LIMIT $1
+OFFSET $2;
+
+-- name: ReviewTransfer :one
+UPDATE transfers SET
+  status = sqlc.arg(status),
+  reviewed_by = sqlc.arg(reviewed_by),
+  review_note = sqlc.arg(review_note),
+  reviewed_at = now()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: ListRecentOutgoingTransfers :many
+-- the screening history of an account, the rejected transfers left out
+SELECT * FROM transfers
+WHERE from_account_id = sqlc.arg(account_id)
+  AND status <> 'rejected'
+  AND created_at >= sqlc.arg(since)::timestamptz
+ORDER BY created_at DESC, id DESC;
+
+-- name: HasCompletedTransfer :one
+-- whether the sender already paid the receiver
+SELECT EXISTS (
+  SELECT 1 FROM transfers
+  WHERE from_account_id = $1 AND to_account_id = $2 AND status = 'completed'
+);
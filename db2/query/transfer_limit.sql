@@ -0,0 +1,78 @@
+-- name: GetTransferLimitTier :one
+SELECT * FROM transfer_limit_tiers
+WHERE name = $1 LIMIT 1;
+
+-- name: ListTransferLimitTiers :many
+SELECT * FROM transfer_limit_tiers
+ORDER BY name;
+
+-- name: UpsertTransferLimitTier :one
+INSERT INTO transfer_limit_tiers (
+  name,
+  max_single_amount,
+  max_daily_amount,
+  max_daily_count
+) VALUES (
+  $1, $2, $3, $4
+)
+ON CONFLICT (name) DO UPDATE SET
+  max_single_amount = EXCLUDED.max_single_amount,
+  max_daily_amount = EXCLUDED.max_daily_amount,
+  max_daily_count = EXCLUDED.max_daily_count,
+  updated_at = now()
+RETURNING *;
+
+-- name: GetAccountTransferLimitOverride :one
+SELECT * FROM account_transfer_limits
+WHERE account_id = $1 LIMIT 1;
+
+-- name: UpsertAccountTransferLimitOverride :one
+-- a NULL column keeps the limit of the tier
+INSERT INTO account_transfer_limits (
+  account_id,
+  max_single_amount,
+  max_daily_amount,
+  max_daily_count
+) VALUES (
+  $1, $2, $3, $4
+)
+ON CONFLICT (account_id) DO UPDATE SET
+  max_single_amount = EXCLUDED.max_single_amount,
+  max_daily_amount = EXCLUDED.max_daily_amount,
+  max_daily_count = EXCLUDED.max_daily_count,
+  updated_at = now()
+RETURNING *;
+
+-- name: DeleteAccountTransferLimitOverride :exec
+DELETE FROM account_transfer_limits WHERE account_id = $1;
+
+-- name: UpdateAccountLimitTier :one
+UPDATE accounts SET limit_tier = sqlc.arg(limit_tier)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: GetEffectiveTransferLimits :one
+-- the limits of the tier of the account, replaced by the overrides of the account where it has some
+SELECT
+  accounts.id AS account_id,
+  accounts.limit_tier AS tier,
+  COALESCE(overrides.max_single_amount, tiers.max_single_amount)::bigint AS max_single_amount,
+  COALESCE(overrides.max_daily_amount, tiers.max_daily_amount)::bigint AS max_daily_amount,
+  COALESCE(overrides.max_daily_count, tiers.max_daily_count)::int AS max_daily_count
+FROM accounts
+JOIN transfer_limit_tiers AS tiers ON tiers.name = accounts.limit_tier
+LEFT JOIN account_transfer_limits AS overrides ON overrides.account_id = accounts.id
+WHERE accounts.id = $1;
+
+-- name: GetDailyOutgoingTransfers :one
+-- the transfers sent by the account that were completed on the UTC day of sqlc.arg(day), the transfers of the
+-- current transaction included. A transfer completes when it is created, or when it is approved after a review:
+-- a pending transfer counts on the day it was approved, whatever the day it was made
+SELECT
+  COALESCE(SUM(amount), 0)::bigint AS total_amount,
+  COUNT(*)::int AS total_count
+FROM transfers
+WHERE from_account_id = sqlc.arg(from_account_id)
+  AND status = 'completed'
+  AND COALESCE(reviewed_at, created_at) >= date_trunc('day', sqlc.arg(day)::timestamptz AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'
+  AND COALESCE(reviewed_at, created_at) < (date_trunc('day', sqlc.arg(day)::timestamptz AT TIME ZONE 'UTC') + interval '1 day') AT TIME ZONE 'UTC';
@@ -0,0 +1,76 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (
+  url,
+  event_types,
+  secret
+) VALUES (
+  $1, $2, $3
+) RETURNING *;
+
+-- name: GetWebhook :one
+SELECT * FROM webhooks
+WHERE id = $1 LIMIT 1;
+
+-- name: ListWebhooks :many
+SELECT * FROM webhooks
+ORDER BY id
+LIMIT $1
+OFFSET $2;
+
+-- name: UpdateWebhook :one
+UPDATE webhooks SET url = $2, event_types = $3, active = $4
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteWebhook :exec
+DELETE FROM webhooks WHERE id = $1;
+
+-- name: CreateWebhookDeliveries :execrows
+-- one delivery per active webhook subscribed to the event type
+-- the unique (webhook_id, event_id) index makes it safe to call again for an event that was already fanned out
+INSERT INTO webhook_deliveries (
+  webhook_id,
+  event_id,
+  event_type,
+  payload
+)
+SELECT webhooks.id, sqlc.arg(event_id)::bigint, sqlc.arg(event_type)::varchar, sqlc.arg(payload)::jsonb
+FROM webhooks
+WHERE webhooks.active AND sqlc.arg(event_type)::varchar = ANY(webhooks.event_types)
+ON CONFLICT (webhook_id, event_id) DO NOTHING;
+
+-- name: ListDueWebhookDeliveries :many
+-- SKIP LOCKED lets several workers run side by side, like the outbox relay
+-- the deliveries claimed by a worker are skipped until their claim runs out
+SELECT sqlc.embed(webhook_deliveries), sqlc.embed(webhooks)
+FROM webhook_deliveries
+JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id
+WHERE webhook_deliveries.status = 'pending' AND webhook_deliveries.next_attempt_at <= now()
+  AND (webhook_deliveries.locked_until IS NULL OR webhook_deliveries.locked_until <= now())
+ORDER BY webhook_deliveries.next_attempt_at
+LIMIT $1
+FOR UPDATE OF webhook_deliveries SKIP LOCKED;
+
+-- name: ClaimWebhookDeliveries :exec
+UPDATE webhook_deliveries SET locked_until = sqlc.arg(locked_until)
+WHERE id = ANY(sqlc.arg(ids)::bigint[]);
+
+-- name: RecordWebhookAttempt :execrows
+-- only the worker holding the claim records its attempt, and the claim is dropped
+-- nothing is updated once the claim ran out and another worker took the delivery
+UPDATE webhook_deliveries SET
+  status = $2,
+  response_status = $3,
+  last_error = $4,
+  next_attempt_at = $5,
+  attempts = attempts + 1,
+  locked_until = NULL,
+  updated_at = now()
+WHERE id = $1 AND locked_until = sqlc.arg(locked_until);
+
+-- name: ListWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE webhook_id = $1
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3;
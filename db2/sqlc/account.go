@@ -0,0 +1,46 @@
+package db
+
+import "context"
+
+const AuditActionAccountStatusUpdate = "account.status.update"
+
+// UpdateAccountStatusTx freezes or unfreezes an account and records the change in the audit log,
+// frozen accounts can't send or receive transfers, see checkTransferAccounts
+// setting the status the account already has changes nothing and records nothing
+// returns ErrNotFound if the account doesn't exist and ErrEquityAccount for an equity account
+func (store *SQLStore) UpdateAccountStatusTx(ctx context.Context, arg UpdateAccountStatusParams) (Account, error) {
+	ctx, span := startSpan(ctx, "UpdateAccountStatusTx")
+	defer span.End()
+
+	var account Account
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		before, err := q.GetAccountForUpdate(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+		// a frozen equity account would block every opening balance in its currency
+		if before.Kind == AccountKindEquity {
+			return newAccountError(ErrEquityAccount, before.ID)
+		}
+		if before.Status == arg.Status {
+			account = before
+			return nil
+		}
+
+		account, err = q.UpdateAccountStatus(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		err = recordAudit(ctx, q, AuditActionAccountStatusUpdate, AuditResourceAccount, account.ID, before, account)
+		if err != nil {
+			return err
+		}
+
+		event := AccountStatusChangedEvent{Account: account, PreviousStatus: before.Status}
+		return enqueueEvent(ctx, q, EventAccountStatusChanged, AuditResourceAccount, account.ID, event)
+	})
+
+	return account, err
+}
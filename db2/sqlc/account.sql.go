@@ -13,7 +13,7 @@ const addAccountBalance = `-- name: AddAccountBalance :one
 
 UPDATE accounts SET balance = balance + $1 
 WHERE id = $2
-RETURNING id, owner, balance, currency, created_at
+RETURNING id, owner, balance, currency, created_at, status, limit_tier, kind
 `
 
 type AddAccountBalanceParams struct {
@@ -24,7 +24,7 @@ type AddAccountBalanceParams struct {
 // Here UpdateAccount is the name of the function in generated go code :one means return one row
 // sqlc.arg(amount) allows use to use the amount variable in generated go code, because balance doesn't make sense
 func (q *Queries) AddAccountBalance(ctx context.Context, arg AddAccountBalanceParams) (Account, error) {
-	row := q.db.QueryRowContext(ctx, addAccountBalance, arg.Amount, arg.ID)
+	row := q.db.QueryRow(ctx, addAccountBalance, arg.Amount, arg.ID)
 	var i Account
 	err := row.Scan(
 		&i.ID,
@@ -32,6 +32,9 @@ func (q *Queries) AddAccountBalance(ctx context.Context, arg AddAccountBalancePa
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.Status,
+		&i.LimitTier,
+		&i.Kind,
 	)
 	return i, err
 }
@@ -44,7 +47,7 @@ INSERT INTO accounts (
 ) VALUES (
   $1, $2, $3
 )
-RETURNING id, owner, balance, currency, created_at
+RETURNING id, owner, balance, currency, created_at, status, limit_tier, kind
 `
 
 type CreateAccountParams struct {
@@ -54,7 +57,7 @@ type CreateAccountParams struct {
 }
 
 func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error) {
-	row := q.db.QueryRowContext(ctx, createAccount, arg.Owner, arg.Balance, arg.Currency)
+	row := q.db.QueryRow(ctx, createAccount, arg.Owner, arg.Balance, arg.Currency)
 	var i Account
 	err := row.Scan(
 		&i.ID,
@@ -62,22 +65,42 @@ func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (A
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.Status,
+		&i.LimitTier,
+		&i.Kind,
 	)
 	return i, err
 }
 
+const createEquityAccount = `-- name: CreateEquityAccount :exec
+INSERT INTO accounts (
+  owner,
+  balance,
+  currency,
+  kind
+) VALUES (
+  'equity', 0, $1, 'equity'
+) ON CONFLICT (currency) WHERE kind = 'equity' DO NOTHING
+`
+
+// does nothing when a concurrent transaction created it first, GetEquityAccount finds it afterwards
+func (q *Queries) CreateEquityAccount(ctx context.Context, currency string) error {
+	_, err := q.db.Exec(ctx, createEquityAccount, currency)
+	return err
+}
+
 const deleteAccount = `-- name: DeleteAccount :exec
 DELETE FROM accounts WHERE id = $1
 `
 
 func (q *Queries) DeleteAccount(ctx context.Context, id int64) error {
-	_, err := q.db.ExecContext(ctx, deleteAccount, id)
+	_, err := q.db.Exec(ctx, deleteAccount, id)
 	return err
 }
 
 const getAccount = `-- name: GetAccount :one
 
-SELECT id, owner, balance, currency, created_at FROM accounts WHERE id = $1 LIMIT 1
+SELECT id, owner, balance, currency, created_at, status, limit_tier, kind FROM accounts WHERE id = $1 LIMIT 1
 `
 
 // the * means return all the columns
@@ -85,7 +108,7 @@ SELECT id, owner, balance, currency, created_at FROM accounts WHERE id = $1 LIMI
 // when we read the row, we need to lock it from begin to the commit of the transaction
 // otherwise, the other transaction will get incorrect info because the previous transaction is not completed yet
 func (q *Queries) GetAccount(ctx context.Context, id int64) (Account, error) {
-	row := q.db.QueryRowContext(ctx, getAccount, id)
+	row := q.db.QueryRow(ctx, getAccount, id)
 	var i Account
 	err := row.Scan(
 		&i.ID,
@@ -93,6 +116,9 @@ func (q *Queries) GetAccount(ctx context.Context, id int64) (Account, error) {
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.Status,
+		&i.LimitTier,
+		&i.Kind,
 	)
 	return i, err
 }
@@ -100,7 +126,7 @@ func (q *Queries) GetAccount(ctx context.Context, id int64) (Account, error) {
 const getAccountForUpdate = `-- name: GetAccountForUpdate :one
 
 
-SELECT id, owner, balance, currency, created_at FROM accounts WHERE id = $1 LIMIT 1 FOR NO KEY UPDATE
+SELECT id, owner, balance, currency, created_at, status, limit_tier, kind FROM accounts WHERE id = $1 LIMIT 1 FOR NO KEY UPDATE
 `
 
 // Here GetAccount is the name of the function in generated go code :one means one row
@@ -112,7 +138,7 @@ SELECT id, owner, balance, currency, created_at FROM accounts WHERE id = $1 LIMI
 // a better way is to use FOR NO KEY UPDATE
 // this only create weaker lock while allow INSERT, while still block modify key column and DELETE
 func (q *Queries) GetAccountForUpdate(ctx context.Context, id int64) (Account, error) {
-	row := q.db.QueryRowContext(ctx, getAccountForUpdate, id)
+	row := q.db.QueryRow(ctx, getAccountForUpdate, id)
 	var i Account
 	err := row.Scan(
 		&i.ID,
@@ -120,12 +146,36 @@ func (q *Queries) GetAccountForUpdate(ctx context.Context, id int64) (Account, e
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.Status,
+		&i.LimitTier,
+		&i.Kind,
+	)
+	return i, err
+}
+
+const getEquityAccount = `-- name: GetEquityAccount :one
+SELECT id, owner, balance, currency, created_at, status, limit_tier, kind FROM accounts WHERE kind = 'equity' AND currency = $1 LIMIT 1
+`
+
+// the equity account of a currency pays the opening balances of the accounts in the currency, see CreateAccountTx
+func (q *Queries) GetEquityAccount(ctx context.Context, currency string) (Account, error) {
+	row := q.db.QueryRow(ctx, getEquityAccount, currency)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.Status,
+		&i.LimitTier,
+		&i.Kind,
 	)
 	return i, err
 }
 
 const listAccounts = `-- name: ListAccounts :many
-SELECT id, owner, balance, currency, created_at FROM accounts ORDER BY id LIMIT $1 OFFSET $2
+SELECT id, owner, balance, currency, created_at, status, limit_tier, kind FROM accounts WHERE kind = 'customer' ORDER BY id LIMIT $1 OFFSET $2
 `
 
 type ListAccountsParams struct {
@@ -133,8 +183,9 @@ type ListAccountsParams struct {
 	Offset int32 `json:"offset"`
 }
 
+// the equity accounts are the bank's side of the ledger, customers don't see them
 func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error) {
-	rows, err := q.db.QueryContext(ctx, listAccounts, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, listAccounts, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -148,14 +199,14 @@ func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]A
 			&i.Balance,
 			&i.Currency,
 			&i.CreatedAt,
+			&i.Status,
+			&i.LimitTier,
+			&i.Kind,
 		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -165,7 +216,7 @@ func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]A
 const updateAccount = `-- name: UpdateAccount :one
 
 UPDATE accounts SET balance = $2 WHERE id = $1
-RETURNING id, owner, balance, currency, created_at
+RETURNING id, owner, balance, currency, created_at, status, limit_tier, kind
 `
 
 type UpdateAccountParams struct {
@@ -175,7 +226,35 @@ type UpdateAccountParams struct {
 
 // LIMIT $1 enable pagination so that we only display certain number of rows
 func (q *Queries) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error) {
-	row := q.db.QueryRowContext(ctx, updateAccount, arg.ID, arg.Balance)
+	row := q.db.QueryRow(ctx, updateAccount, arg.ID, arg.Balance)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.Status,
+		&i.LimitTier,
+		&i.Kind,
+	)
+	return i, err
+}
+
+const updateAccountStatus = `-- name: UpdateAccountStatus :one
+UPDATE accounts SET status = $1
+WHERE id = $2
+RETURNING id, owner, balance, currency, created_at, status, limit_tier, kind
+`
+
+type UpdateAccountStatusParams struct {
+	Status string `json:"status"`
+	ID     int64  `json:"id"`
+}
+
+// frozen accounts can't send or receive transfers, see TransferTx
+func (q *Queries) UpdateAccountStatus(ctx context.Context, arg UpdateAccountStatusParams) (Account, error) {
+	row := q.db.QueryRow(ctx, updateAccountStatus, arg.Status, arg.ID)
 	var i Account
 	err := row.Scan(
 		&i.ID,
@@ -183,6 +262,9 @@ func (q *Queries) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (A
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.Status,
+		&i.LimitTier,
+		&i.Kind,
 	)
 	return i, err
 }
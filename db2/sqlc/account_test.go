@@ -2,6 +2,8 @@ package db
 
 import (
 	"context"
+	"encoding/json"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -32,10 +34,21 @@ func createRandomAccount(t *testing.T) Account {
 	return account
 }
 
+// deleteAllAccounts empties the ledger, child records first due to foreign key constraints.
+// Compliance alerts don't cascade with their account, and the equity accounts go too:
+// nothing references them once the transfers are gone
+func deleteAllAccounts(t *testing.T) {
+	for _, table := range []string{"compliance_alerts", "entries", "transfers", "accounts"} {
+		_, err := testDB.Exec(context.Background(), "DELETE FROM "+table)
+		require.NoError(t, err)
+	}
+}
+
 func verifyNoAccountExists(t *testing.T) {
-	// Count all accounts using the stored database connection
+	// Count the customer accounts using the stored database connection,
+	// the equity accounts are created on demand by the stores and aren't owned by a test
 	var count int
-	err := testDB.QueryRow("SELECT COUNT(*) FROM accounts").Scan(&count)
+	err := testDB.QueryRow(context.Background(), "SELECT COUNT(*) FROM accounts WHERE kind = 'customer'").Scan(&count)
 	require.NoError(t, err)
 	require.Equal(t, 0, count)
 	t.Logf("Total accounts in database: %d", count)
@@ -132,13 +145,40 @@ func TestListAccounts(t *testing.T) {
 	}
 
 	// Clean up all accounts from the database (including any from previous test runs)
-	// Must delete child records first due to foreign key constraints
-	_, err = testDB.Exec("DELETE FROM entries")
-	require.NoError(t, err)
-	_, err = testDB.Exec("DELETE FROM transfers")
+	deleteAllAccounts(t)
+
+	verifyNoAccountExists(t)
+}
+
+func TestUpdateAccountStatusTx(t *testing.T) {
+	store := NewStore(testDB)
+	ctx := context.Background()
+
+	account := createRandomAccount(t)
+	require.Equal(t, AccountStatusActive, account.Status)
+
+	frozen, err := store.UpdateAccountStatusTx(ctx, UpdateAccountStatusParams{ID: account.ID, Status: AccountStatusFrozen})
 	require.NoError(t, err)
-	_, err = testDB.Exec("DELETE FROM accounts")
+	require.Equal(t, AccountStatusFrozen, frozen.Status)
+	require.Equal(t, account.Balance, frozen.Balance)
+
+	// freezing twice changes nothing
+	_, err = store.UpdateAccountStatusTx(ctx, UpdateAccountStatusParams{ID: account.ID, Status: AccountStatusFrozen})
 	require.NoError(t, err)
 
-	verifyNoAccountExists(t)
+	events, err := testQueries.ListAuditEvents(ctx, ListAuditEventsParams{
+		Action:     ptr(AuditActionAccountStatusUpdate),
+		ResourceID: ptr(strconv.FormatInt(account.ID, 10)),
+		Limit:      5,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	var before, after Account
+	require.NoError(t, json.Unmarshal(events[0].Before, &before))
+	require.NoError(t, json.Unmarshal(events[0].After, &after))
+	require.Equal(t, AccountStatusActive, before.Status)
+	require.Equal(t, AccountStatusFrozen, after.Status)
+
+	_, err = store.UpdateAccountStatusTx(ctx, UpdateAccountStatusParams{ID: account.ID + 1000000, Status: AccountStatusFrozen})
+	require.ErrorIs(t, err, ErrNotFound)
 }
@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+)
+
+// audit actions, stored in the action column of audit_events
+const (
+	AuditActionAccountCreate  = "account.create"
+	AuditActionTransferCreate = "transfer.create"
+)
+
+// audit resource types, stored in the resource_type column of audit_events
+const (
+	AuditResourceAccount  = "account"
+	AuditResourceTransfer = "transfer"
+)
+
+// AuditInfo describes who triggered a state change
+// it travels in the context from the API layer down to the transaction that writes the audit event
+type AuditInfo struct {
+	Actor     string
+	RequestID string
+	ClientIP  string
+}
+
+// DefaultAuditActor is recorded when a change is made without any caller information in the context
+const DefaultAuditActor = "system"
+
+type auditInfoKey struct{}
+
+// WithAuditInfo returns a copy of ctx carrying the audit info of the caller
+func WithAuditInfo(ctx context.Context, info AuditInfo) context.Context {
+	return context.WithValue(ctx, auditInfoKey{}, info)
+}
+
+// AuditInfoFromContext returns the audit info stored in ctx
+// the actor falls back to DefaultAuditActor when nothing was stored
+func AuditInfoFromContext(ctx context.Context) AuditInfo {
+	info, _ := ctx.Value(auditInfoKey{}).(AuditInfo)
+	if info.Actor == "" {
+		info.Actor = DefaultAuditActor
+	}
+	return info
+}
+
+// recordAudit writes one audit event with q, so it must be called with the Queries of the transaction
+// that makes the change: the event is committed or rolled back together with it
+// before and after are marshalled to JSON, nil is stored as JSON null
+func recordAudit(
+	ctx context.Context,
+	q *Queries,
+	action string,
+	resourceType string,
+	resourceID int64,
+	before any,
+	after any,
+) error {
+	return recordAuditByKey(ctx, q, action, resourceType, strconv.FormatInt(resourceID, 10), before, after)
+}
+
+// recordAuditByKey is recordAudit for resources identified by a string, e.g. limit tiers by their name
+func recordAuditByKey(
+	ctx context.Context,
+	q *Queries,
+	action string,
+	resourceType string,
+	resourceID string,
+	before any,
+	after any,
+) error {
+	beforeJSON, err := json.Marshal(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := json.Marshal(after)
+	if err != nil {
+		return err
+	}
+
+	info := AuditInfoFromContext(ctx)
+	_, err = q.CreateAuditEvent(ctx, CreateAuditEventParams{
+		Actor:        info.Actor,
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Before:       beforeJSON,
+		After:        afterJSON,
+		RequestID:    info.RequestID,
+		ClientIp:     info.ClientIP,
+	})
+	return err
+}
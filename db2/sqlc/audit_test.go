@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simple-bank/utils"
+)
+
+func TestCreateAccountTxRecordsAudit(t *testing.T) {
+	store := NewStore(testDB)
+
+	info := AuditInfo{Actor: "tester", RequestID: utils.RandomString(12), ClientIP: "127.0.0.1"}
+	ctx := WithAuditInfo(context.Background(), info)
+
+	// without opening balance, the account has no transfer and can be deleted at the end
+	account, err := store.CreateAccountTx(ctx, CreateAccountParams{
+		Owner:    utils.RandomOwner(),
+		Currency: utils.RandomCurrency(),
+	})
+	require.NoError(t, err)
+	require.NotZero(t, account.ID)
+
+	events, err := testQueries.ListAuditEvents(context.Background(), ListAuditEventsParams{
+		ResourceType: ptr(AuditResourceAccount),
+		ResourceID:   ptr(strconv.FormatInt(account.ID, 10)),
+		Limit:        5,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+
+	event := events[0]
+	require.Equal(t, info.Actor, event.Actor)
+	require.Equal(t, info.RequestID, event.RequestID)
+	require.Equal(t, info.ClientIP, event.ClientIp)
+	require.Equal(t, AuditActionAccountCreate, event.Action)
+	require.JSONEq(t, "null", string(event.Before))
+
+	var after Account
+	require.NoError(t, json.Unmarshal(event.After, &after))
+	require.Equal(t, account.ID, after.ID)
+	require.Equal(t, account.Balance, after.Balance)
+
+	_, err = testDB.Exec(context.Background(), "DELETE FROM audit_events")
+	require.NoError(t, err)
+	err = testQueries.DeleteAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+}
+
+func TestTransferTxRollbackLeavesNoAudit(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createRandomAccount(t)
+	before := countAuditEvents(t)
+
+	// the receiving account doesn't exist, so the foreign key fails and the whole transaction is rolled back
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account.ID,
+		ToAccountID:   account.ID + 1000000,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrForeignKeyViolation)
+	require.Equal(t, before, countAuditEvents(t))
+
+	err = testQueries.DeleteAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+}
+
+func countAuditEvents(t *testing.T) int {
+	var count int
+	err := testDB.QueryRow(context.Background(), "SELECT COUNT(*) FROM audit_events").Scan(&count)
+	require.NoError(t, err)
+	return count
+}
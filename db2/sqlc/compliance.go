@@ -0,0 +1,203 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// compliance alert kinds, stored in the kind column of compliance_alerts
+const (
+	AlertKindLargeTransaction = "large_transaction" // a single transfer at or above the reporting threshold
+	AlertKindStructuring      = "structuring"       // several transfers just below the threshold, split to stay under it
+)
+
+// compliance alert statuses, stored in the status column of compliance_alerts
+const (
+	AlertStatusOpen     = "open"
+	AlertStatusAssigned = "assigned"
+	AlertStatusResolved = "resolved"
+)
+
+const (
+	AuditActionAlertAssign  = "compliance_alert.assign"
+	AuditActionAlertResolve = "compliance_alert.resolve"
+
+	AuditResourceComplianceAlert = "compliance_alert"
+)
+
+// AMLRules are the anti money laundering rules applied by AnalyzeTransfersTx
+type AMLRules struct {
+	// Thresholds is the reporting threshold per currency, in minor units
+	// transfers in a currency without a threshold are marked analyzed without raising anything
+	Thresholds map[string]int64
+	// StructuringFloor is the percentage of the threshold from which an amount is "just below" it
+	StructuringFloor int64
+	// StructuringWindow is how far back the transfers just below the threshold are counted
+	StructuringWindow time.Duration
+	// StructuringMinCount is the number of such transfers within the window that raises a structuring alert
+	StructuringMinCount int
+}
+
+// AnalyzeTransfersTx applies rules to up to batchSize completed transfers not analyzed yet, oldest first,
+// raises the compliance alerts they call for and marks them analyzed
+// the rows stay locked until the transaction ends, so concurrent analyzers never look at the same transfer
+// returns the number of transfers analyzed
+func (store *SQLStore) AnalyzeTransfersTx(ctx context.Context, batchSize int32, rules AMLRules) (int, error) {
+	ctx, span := startSpan(ctx, "AnalyzeTransfersTx")
+	defer span.End()
+
+	analyzed := 0
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		analyzed = 0 // execTx may run this again after a serialization failure
+
+		transfers, err := q.ListUnanalyzedTransfers(ctx, batchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, transfer := range transfers {
+			if err := analyzeTransfer(ctx, q, transfer, rules); err != nil {
+				return err
+			}
+			if err := q.MarkTransferAnalyzed(ctx, transfer.ID); err != nil {
+				return err
+			}
+			analyzed++
+		}
+		return nil
+	})
+
+	return analyzed, err
+}
+
+// analyzeTransfer raises the alerts called for by a single transfer
+func analyzeTransfer(ctx context.Context, q *Queries, transfer ListUnanalyzedTransfersRow, rules AMLRules) error {
+	threshold, ok := rules.Thresholds[transfer.Currency]
+	if !ok {
+		return nil
+	}
+
+	if transfer.Amount >= threshold {
+		_, err := q.CreateComplianceAlert(ctx, CreateComplianceAlertParams{
+			Kind:        AlertKindLargeTransaction,
+			AccountID:   transfer.FromAccountID,
+			Currency:    transfer.Currency,
+			TotalAmount: transfer.Amount,
+			TransferIds: []int64{transfer.ID},
+		})
+		return err
+	}
+
+	floor := threshold * rules.StructuringFloor / 100
+	if rules.StructuringMinCount <= 0 || transfer.Amount < floor {
+		return nil
+	}
+
+	// the window ends at the transfer, so analyzing a backlog late gives the same alerts as analyzing it live
+	until := transfer.CreatedAt
+	nearby, err := q.ListTransfersInAmountRange(ctx, ListTransfersInAmountRangeParams{
+		AccountID: transfer.FromAccountID,
+		Since:     until.Add(-rules.StructuringWindow),
+		Until:     until,
+		MinAmount: floor,
+		MaxAmount: threshold,
+	})
+	if err != nil {
+		return err
+	}
+	if len(nearby) < rules.StructuringMinCount {
+		return nil
+	}
+
+	ids := make([]int64, len(nearby))
+	var total int64
+	for i, row := range nearby {
+		ids[i] = row.ID
+		total += row.Amount
+	}
+
+	_, err = q.UpsertStructuringAlert(ctx, UpsertStructuringAlertParams{
+		AccountID:   transfer.FromAccountID,
+		Currency:    transfer.Currency,
+		TotalAmount: total,
+		TransferIds: ids,
+	})
+	return err
+}
+
+// AssignComplianceAlertTx hands an alert over to an investigator
+// returns ErrNotFound if the alert doesn't exist and ErrAlertResolved if it was already resolved
+func (store *SQLStore) AssignComplianceAlertTx(ctx context.Context, arg AssignComplianceAlertParams) (ComplianceAlert, error) {
+	ctx, span := startSpan(ctx, "AssignComplianceAlertTx")
+	defer span.End()
+
+	var alert ComplianceAlert
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		before, err := lockUnresolvedAlert(ctx, q, arg.ID)
+		if err != nil {
+			return err
+		}
+
+		alert, err = q.AssignComplianceAlert(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, AuditActionAlertAssign, AuditResourceComplianceAlert, arg.ID, before, alert)
+	})
+
+	return alert, err
+}
+
+// ResolveComplianceAlertTxParams holds the outcome of an investigation, the investigator is the actor of the
+// audit info in the context
+type ResolveComplianceAlertTxParams struct {
+	ID         int64  `json:"id"`
+	Resolution string `json:"resolution"`
+	Note       string `json:"note"`
+}
+
+// ResolveComplianceAlertTx closes an alert
+// returns ErrNotFound if the alert doesn't exist and ErrAlertResolved if it was already resolved
+func (store *SQLStore) ResolveComplianceAlertTx(ctx context.Context, arg ResolveComplianceAlertTxParams) (ComplianceAlert, error) {
+	ctx, span := startSpan(ctx, "ResolveComplianceAlertTx")
+	defer span.End()
+
+	var alert ComplianceAlert
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		before, err := lockUnresolvedAlert(ctx, q, arg.ID)
+		if err != nil {
+			return err
+		}
+
+		alert, err = q.ResolveComplianceAlert(ctx, ResolveComplianceAlertParams{
+			ID:             arg.ID,
+			Resolution:     arg.Resolution,
+			ResolutionNote: arg.Note,
+			ResolvedBy:     AuditInfoFromContext(ctx).Actor,
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, AuditActionAlertResolve, AuditResourceComplianceAlert, arg.ID, before, alert)
+	})
+
+	return alert, err
+}
+
+// lockUnresolvedAlert locks the alert until the end of the transaction and checks that it isn't resolved
+func lockUnresolvedAlert(ctx context.Context, q *Queries, id int64) (ComplianceAlert, error) {
+	alert, err := q.GetComplianceAlertForUpdate(ctx, id)
+	if err != nil {
+		return ComplianceAlert{}, err
+	}
+
+	if alert.Status == AlertStatusResolved {
+		return ComplianceAlert{}, &Error{Kind: ErrAlertResolved, Details: map[string]any{"alert_id": id}}
+	}
+	return alert, nil
+}
@@ -0,0 +1,424 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: compliance.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const assignComplianceAlert = `-- name: AssignComplianceAlert :one
+UPDATE compliance_alerts SET
+  assignee = $1,
+  status = 'assigned',
+  updated_at = now()
+WHERE id = $2
+RETURNING id, kind, account_id, currency, total_amount, transfer_ids, status, assignee, resolution, resolution_note, resolved_by, resolved_at, created_at, updated_at
+`
+
+type AssignComplianceAlertParams struct {
+	Assignee string `json:"assignee"`
+	ID       int64  `json:"id"`
+}
+
+func (q *Queries) AssignComplianceAlert(ctx context.Context, arg AssignComplianceAlertParams) (ComplianceAlert, error) {
+	row := q.db.QueryRow(ctx, assignComplianceAlert, arg.Assignee, arg.ID)
+	var i ComplianceAlert
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.AccountID,
+		&i.Currency,
+		&i.TotalAmount,
+		&i.TransferIds,
+		&i.Status,
+		&i.Assignee,
+		&i.Resolution,
+		&i.ResolutionNote,
+		&i.ResolvedBy,
+		&i.ResolvedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createComplianceAlert = `-- name: CreateComplianceAlert :one
+INSERT INTO compliance_alerts (
+  kind,
+  account_id,
+  currency,
+  total_amount,
+  transfer_ids
+) VALUES (
+  $1, $2, $3, $4, $5
+) RETURNING id, kind, account_id, currency, total_amount, transfer_ids, status, assignee, resolution, resolution_note, resolved_by, resolved_at, created_at, updated_at
+`
+
+type CreateComplianceAlertParams struct {
+	Kind        string  `json:"kind"`
+	AccountID   int64   `json:"account_id"`
+	Currency    string  `json:"currency"`
+	TotalAmount int64   `json:"total_amount"`
+	TransferIds []int64 `json:"transfer_ids"`
+}
+
+func (q *Queries) CreateComplianceAlert(ctx context.Context, arg CreateComplianceAlertParams) (ComplianceAlert, error) {
+	row := q.db.QueryRow(ctx, createComplianceAlert,
+		arg.Kind,
+		arg.AccountID,
+		arg.Currency,
+		arg.TotalAmount,
+		arg.TransferIds,
+	)
+	var i ComplianceAlert
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.AccountID,
+		&i.Currency,
+		&i.TotalAmount,
+		&i.TransferIds,
+		&i.Status,
+		&i.Assignee,
+		&i.Resolution,
+		&i.ResolutionNote,
+		&i.ResolvedBy,
+		&i.ResolvedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getComplianceAlert = `-- name: GetComplianceAlert :one
+SELECT id, kind, account_id, currency, total_amount, transfer_ids, status, assignee, resolution, resolution_note, resolved_by, resolved_at, created_at, updated_at FROM compliance_alerts
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetComplianceAlert(ctx context.Context, id int64) (ComplianceAlert, error) {
+	row := q.db.QueryRow(ctx, getComplianceAlert, id)
+	var i ComplianceAlert
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.AccountID,
+		&i.Currency,
+		&i.TotalAmount,
+		&i.TransferIds,
+		&i.Status,
+		&i.Assignee,
+		&i.Resolution,
+		&i.ResolutionNote,
+		&i.ResolvedBy,
+		&i.ResolvedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getComplianceAlertForUpdate = `-- name: GetComplianceAlertForUpdate :one
+SELECT id, kind, account_id, currency, total_amount, transfer_ids, status, assignee, resolution, resolution_note, resolved_by, resolved_at, created_at, updated_at FROM compliance_alerts
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetComplianceAlertForUpdate(ctx context.Context, id int64) (ComplianceAlert, error) {
+	row := q.db.QueryRow(ctx, getComplianceAlertForUpdate, id)
+	var i ComplianceAlert
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.AccountID,
+		&i.Currency,
+		&i.TotalAmount,
+		&i.TransferIds,
+		&i.Status,
+		&i.Assignee,
+		&i.Resolution,
+		&i.ResolutionNote,
+		&i.ResolvedBy,
+		&i.ResolvedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const listComplianceAlerts = `-- name: ListComplianceAlerts :many
+SELECT id, kind, account_id, currency, total_amount, transfer_ids, status, assignee, resolution, resolution_note, resolved_by, resolved_at, created_at, updated_at FROM compliance_alerts
+WHERE ($1::varchar IS NULL OR status = $1)
+  AND ($2::varchar IS NULL OR kind = $2)
+  AND ($3::varchar IS NULL OR assignee = $3)
+ORDER BY id DESC
+LIMIT $5
+OFFSET $4
+`
+
+type ListComplianceAlertsParams struct {
+	Status   *string `json:"status"`
+	Kind     *string `json:"kind"`
+	Assignee *string `json:"assignee"`
+	Offset   int32   `json:"offset"`
+	Limit    int32   `json:"limit"`
+}
+
+// every filter is optional, NULL means "don't filter on it", newest alert first
+func (q *Queries) ListComplianceAlerts(ctx context.Context, arg ListComplianceAlertsParams) ([]ComplianceAlert, error) {
+	rows, err := q.db.Query(ctx, listComplianceAlerts,
+		arg.Status,
+		arg.Kind,
+		arg.Assignee,
+		arg.Offset,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ComplianceAlert{}
+	for rows.Next() {
+		var i ComplianceAlert
+		if err := rows.Scan(
+			&i.ID,
+			&i.Kind,
+			&i.AccountID,
+			&i.Currency,
+			&i.TotalAmount,
+			&i.TransferIds,
+			&i.Status,
+			&i.Assignee,
+			&i.Resolution,
+			&i.ResolutionNote,
+			&i.ResolvedBy,
+			&i.ResolvedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransfersInAmountRange = `-- name: ListTransfersInAmountRange :many
+SELECT id, amount FROM transfers
+WHERE from_account_id = $1
+  AND status = 'completed'
+  AND created_at BETWEEN $2::timestamptz AND $3::timestamptz
+  AND amount >= $4::bigint
+  AND amount < $5::bigint
+ORDER BY id
+`
+
+type ListTransfersInAmountRangeParams struct {
+	AccountID int64     `json:"account_id"`
+	Since     time.Time `json:"since"`
+	Until     time.Time `json:"until"`
+	MinAmount int64     `json:"min_amount"`
+	MaxAmount int64     `json:"max_amount"`
+}
+
+type ListTransfersInAmountRangeRow struct {
+	ID     int64 `json:"id"`
+	Amount int64 `json:"amount"`
+}
+
+// the completed transfers of the account between two times with min_amount <= amount < max_amount
+func (q *Queries) ListTransfersInAmountRange(ctx context.Context, arg ListTransfersInAmountRangeParams) ([]ListTransfersInAmountRangeRow, error) {
+	rows, err := q.db.Query(ctx, listTransfersInAmountRange,
+		arg.AccountID,
+		arg.Since,
+		arg.Until,
+		arg.MinAmount,
+		arg.MaxAmount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTransfersInAmountRangeRow{}
+	for rows.Next() {
+		var i ListTransfersInAmountRangeRow
+		if err := rows.Scan(&i.ID, &i.Amount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUnanalyzedTransfers = `-- name: ListUnanalyzedTransfers :many
+SELECT
+  transfers.id,
+  transfers.from_account_id,
+  transfers.amount,
+  transfers.created_at,
+  accounts.currency
+FROM transfers
+JOIN accounts ON accounts.id = transfers.from_account_id
+WHERE transfers.aml_analyzed_at IS NULL AND transfers.status = 'completed'
+ORDER BY transfers.id
+LIMIT $1
+FOR UPDATE OF transfers SKIP LOCKED
+`
+
+type ListUnanalyzedTransfersRow struct {
+	ID            int64     `json:"id"`
+	FromAccountID int64     `json:"from_account_id"`
+	Amount        int64     `json:"amount"`
+	CreatedAt     time.Time `json:"created_at"`
+	Currency      string    `json:"currency"`
+}
+
+// the completed transfers the AML analyzer didn't look at yet, with the currency of the sender
+// SKIP LOCKED lets several analyzers run side by side, each one takes a different batch
+func (q *Queries) ListUnanalyzedTransfers(ctx context.Context, limit int32) ([]ListUnanalyzedTransfersRow, error) {
+	rows, err := q.db.Query(ctx, listUnanalyzedTransfers, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListUnanalyzedTransfersRow{}
+	for rows.Next() {
+		var i ListUnanalyzedTransfersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.Currency,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markTransferAnalyzed = `-- name: MarkTransferAnalyzed :exec
+UPDATE transfers SET aml_analyzed_at = now()
+WHERE id = $1
+`
+
+func (q *Queries) MarkTransferAnalyzed(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, markTransferAnalyzed, id)
+	return err
+}
+
+const resolveComplianceAlert = `-- name: ResolveComplianceAlert :one
+UPDATE compliance_alerts SET
+  status = 'resolved',
+  resolution = $1,
+  resolution_note = $2,
+  resolved_by = $3,
+  resolved_at = now(),
+  updated_at = now()
+WHERE id = $4
+RETURNING id, kind, account_id, currency, total_amount, transfer_ids, status, assignee, resolution, resolution_note, resolved_by, resolved_at, created_at, updated_at
+`
+
+type ResolveComplianceAlertParams struct {
+	Resolution     string `json:"resolution"`
+	ResolutionNote string `json:"resolution_note"`
+	ResolvedBy     string `json:"resolved_by"`
+	ID             int64  `json:"id"`
+}
+
+func (q *Queries) ResolveComplianceAlert(ctx context.Context, arg ResolveComplianceAlertParams) (ComplianceAlert, error) {
+	row := q.db.QueryRow(ctx, resolveComplianceAlert,
+		arg.Resolution,
+		arg.ResolutionNote,
+		arg.ResolvedBy,
+		arg.ID,
+	)
+	var i ComplianceAlert
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.AccountID,
+		&i.Currency,
+		&i.TotalAmount,
+		&i.TransferIds,
+		&i.Status,
+		&i.Assignee,
+		&i.Resolution,
+		&i.ResolutionNote,
+		&i.ResolvedBy,
+		&i.ResolvedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const upsertStructuringAlert = `-- name: UpsertStructuringAlert :one
+INSERT INTO compliance_alerts (
+  kind,
+  account_id,
+  currency,
+  total_amount,
+  transfer_ids
+) VALUES (
+  'structuring', $1, $2, $3, $4::bigint[]
+)
+ON CONFLICT (account_id) WHERE kind = 'structuring' AND status <> 'resolved' DO UPDATE SET
+  transfer_ids = ARRAY(
+    SELECT DISTINCT unnest(compliance_alerts.transfer_ids || EXCLUDED.transfer_ids) ORDER BY 1
+  ),
+  total_amount = (
+    SELECT COALESCE(SUM(transfers.amount), 0) FROM transfers
+    WHERE transfers.id = ANY(compliance_alerts.transfer_ids || EXCLUDED.transfer_ids)
+  ),
+  updated_at = now()
+RETURNING id, kind, account_id, currency, total_amount, transfer_ids, status, assignee, resolution, resolution_note, resolved_by, resolved_at, created_at, updated_at
+`
+
+type UpsertStructuringAlertParams struct {
+	AccountID   int64   `json:"account_id"`
+	Currency    string  `json:"currency"`
+	TotalAmount int64   `json:"total_amount"`
+	TransferIds []int64 `json:"transfer_ids"`
+}
+
+// raises a structuring alert for the account, or adds the transfers to its unresolved one
+func (q *Queries) UpsertStructuringAlert(ctx context.Context, arg UpsertStructuringAlertParams) (ComplianceAlert, error) {
+	row := q.db.QueryRow(ctx, upsertStructuringAlert,
+		arg.AccountID,
+		arg.Currency,
+		arg.TotalAmount,
+		arg.TransferIds,
+	)
+	var i ComplianceAlert
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.AccountID,
+		&i.Currency,
+		&i.TotalAmount,
+		&i.TransferIds,
+		&i.Status,
+		&i.Assignee,
+		&i.Resolution,
+		&i.ResolutionNote,
+		&i.ResolvedBy,
+		&i.ResolvedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
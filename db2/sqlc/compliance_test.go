@@ -0,0 +1,172 @@
+package db
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// analyzeAll runs the analyzer until every transfer of the test database is analyzed
+func analyzeAll(t *testing.T, store Store, rules AMLRules) {
+	for {
+		n, err := store.AnalyzeTransfersTx(context.Background(), 100, rules)
+		require.NoError(t, err)
+		if n == 0 {
+			return
+		}
+	}
+}
+
+// accountAlerts returns the alerts of the account, newest first
+func accountAlerts(t *testing.T, accountID int64) []ComplianceAlert {
+	alerts, err := testQueries.ListComplianceAlerts(context.Background(), ListComplianceAlertsParams{
+		Limit: 1000,
+	})
+	require.NoError(t, err)
+
+	var result []ComplianceAlert
+	for _, alert := range alerts {
+		if alert.AccountID == accountID {
+			result = append(result, alert)
+		}
+	}
+	return result
+}
+
+func TestAnalyzeTransfersTx(t *testing.T) {
+	store := NewStore(testDB)
+	ctx := WithAuditInfo(context.Background(), AuditInfo{Actor: "officer"})
+
+	account1 := createFundedAccount(t, 10000)
+	account2 := createRandomAccount(t)
+	rules := AMLRules{
+		Thresholds:          map[string]int64{account1.Currency: 1000},
+		StructuringFloor:    90,
+		StructuringWindow:   time.Hour,
+		StructuringMinCount: 3,
+	}
+	transfer := func(amount int64) int64 {
+		result, err := store.TransferTx(ctx, TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        amount,
+		})
+		require.NoError(t, err)
+		return result.Transfer.ID
+	}
+
+	large := transfer(1000)
+	transfer(100) // far below the threshold, not evidence of anything
+	near1 := transfer(950)
+	near2 := transfer(900)
+	analyzeAll(t, store, rules)
+
+	// two transfers just below the threshold aren't enough for structuring
+	alerts := accountAlerts(t, account1.ID)
+	require.Len(t, alerts, 1)
+	require.Equal(t, AlertKindLargeTransaction, alerts[0].Kind)
+	require.Equal(t, []int64{large}, alerts[0].TransferIds)
+	require.Equal(t, int64(1000), alerts[0].TotalAmount)
+	require.Equal(t, AlertStatusOpen, alerts[0].Status)
+
+	// the third raises the alert, the fourth is added to it
+	near3 := transfer(990)
+	analyzeAll(t, store, rules)
+	near4 := transfer(999)
+	analyzeAll(t, store, rules)
+
+	alerts = accountAlerts(t, account1.ID)
+	require.Len(t, alerts, 2)
+	structuring := alerts[0]
+	require.Equal(t, AlertKindStructuring, structuring.Kind)
+	require.Equal(t, []int64{near1, near2, near3, near4}, structuring.TransferIds)
+	require.Equal(t, int64(950+900+990+999), structuring.TotalAmount)
+
+	// analyzing again raises nothing new
+	analyzeAll(t, store, rules)
+	require.Len(t, accountAlerts(t, account1.ID), 2)
+
+	// once resolved, new evidence raises a new alert
+	_, err := store.ResolveComplianceAlertTx(ctx, ResolveComplianceAlertTxParams{
+		ID:         structuring.ID,
+		Resolution: "false_positive",
+		Note:       "weekly rent",
+	})
+	require.NoError(t, err)
+
+	transfer(920)
+	analyzeAll(t, store, rules)
+	alerts = accountAlerts(t, account1.ID)
+	require.Len(t, alerts, 3)
+	require.Equal(t, AlertKindStructuring, alerts[0].Kind)
+	require.Equal(t, AlertStatusOpen, alerts[0].Status)
+	require.Len(t, alerts[0].TransferIds, 5)
+}
+
+func TestAnalyzeTransfersTxUnknownCurrency(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createFundedAccount(t, 10000)
+	account2 := createRandomAccount(t)
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        5000,
+	})
+	require.NoError(t, err)
+
+	// no threshold for any currency: the transfer is marked analyzed without an alert
+	analyzeAll(t, store, AMLRules{Thresholds: map[string]int64{}})
+	require.Empty(t, accountAlerts(t, account1.ID))
+}
+
+func TestComplianceAlertWorkflow(t *testing.T) {
+	store := NewStore(testDB)
+	ctx := WithAuditInfo(context.Background(), AuditInfo{Actor: "officer"})
+
+	account := createRandomAccount(t)
+	alert, err := testQueries.CreateComplianceAlert(context.Background(), CreateComplianceAlertParams{
+		Kind:        AlertKindLargeTransaction,
+		AccountID:   account.ID,
+		Currency:    account.Currency,
+		TotalAmount: 5000,
+		TransferIds: []int64{1},
+	})
+	require.NoError(t, err)
+
+	assigned, err := store.AssignComplianceAlertTx(ctx, AssignComplianceAlertParams{ID: alert.ID, Assignee: "jdoe"})
+	require.NoError(t, err)
+	require.Equal(t, AlertStatusAssigned, assigned.Status)
+	require.Equal(t, "jdoe", assigned.Assignee)
+
+	resolved, err := store.ResolveComplianceAlertTx(ctx, ResolveComplianceAlertTxParams{
+		ID:         alert.ID,
+		Resolution: "reported",
+		Note:       "SAR filed",
+	})
+	require.NoError(t, err)
+	require.Equal(t, AlertStatusResolved, resolved.Status)
+	require.Equal(t, "officer", resolved.ResolvedBy)
+	require.NotNil(t, resolved.ResolvedAt)
+
+	// a resolved alert can't be assigned or resolved again
+	_, err = store.AssignComplianceAlertTx(ctx, AssignComplianceAlertParams{ID: alert.ID, Assignee: "other"})
+	require.ErrorIs(t, err, ErrAlertResolved)
+	_, err = store.ResolveComplianceAlertTx(ctx, ResolveComplianceAlertTxParams{ID: alert.ID, Resolution: "reported"})
+	require.ErrorIs(t, err, ErrAlertResolved)
+
+	_, err = store.AssignComplianceAlertTx(ctx, AssignComplianceAlertParams{ID: alert.ID + 1000000, Assignee: "jdoe"})
+	require.ErrorIs(t, err, ErrNotFound)
+
+	// both changes are audited
+	events, err := testQueries.ListAuditEvents(context.Background(), ListAuditEventsParams{
+		ResourceType: ptr(AuditResourceComplianceAlert),
+		ResourceID:   ptr(strconv.FormatInt(alert.ID, 10)),
+		Limit:        10,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simple-bank/utils"
+)
+
+// requireCheckViolation asserts that err comes from the constraint named constraint
+func requireCheckViolation(t *testing.T, err error, constraint string) {
+	var pgErr *pgconn.PgError
+	require.ErrorAs(t, err, &pgErr)
+	require.Equal(t, CheckViolation, pgErr.Code)
+	require.Equal(t, constraint, pgErr.ConstraintName)
+}
+
+func TestTransferConstraints(t *testing.T) {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+
+	testCases := []struct {
+		name       string
+		arg        CreateTransferParams
+		constraint string
+	}{
+		{
+			name:       "ZeroAmount",
+			arg:        CreateTransferParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 0},
+			constraint: "transfers_amount_positive",
+		},
+		{
+			name:       "NegativeAmount",
+			arg:        CreateTransferParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: -10},
+			constraint: "transfers_amount_positive",
+		},
+		{
+			name:       "SameAccount",
+			arg:        CreateTransferParams{FromAccountID: account1.ID, ToAccountID: account1.ID, Amount: 10},
+			constraint: "transfers_distinct_accounts",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := testQueries.CreateTransfer(context.Background(), tc.arg)
+			requireCheckViolation(t, err, tc.constraint)
+		})
+	}
+
+	// created_at can't be left out any more
+	_, err := testDB.Exec(context.Background(),
+		"INSERT INTO transfers (from_account_id, to_account_id, amount, created_at) VALUES ($1, $2, 10, NULL)",
+		account1.ID, account2.ID)
+	var pgErr *pgconn.PgError
+	require.ErrorAs(t, err, &pgErr)
+	require.Equal(t, NotNullViolation, pgErr.Code)
+	require.Equal(t, "created_at", pgErr.ColumnName)
+
+	transfer, err := testQueries.CreateTransfer(context.Background(), CreateTransferParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+	require.False(t, transfer.CreatedAt.IsZero())
+}
+
+func TestAccountCurrencyConstraint(t *testing.T) {
+	for _, currency := range []string{"usd", "US", "USDT", "U$D", ""} {
+		t.Run(currency, func(t *testing.T) {
+			_, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+				Owner:    utils.RandomOwner(),
+				Balance:  utils.RandomMoney(),
+				Currency: currency,
+			})
+			requireCheckViolation(t, err, "accounts_currency_format")
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SQLSTATE codes of the constraint violations mapped by ClassifyError
+const (
+	UniqueViolation     = "23505"
+	ForeignKeyViolation = "23503"
+	CheckViolation      = "23514"
+	NotNullViolation    = "23502"
+)
+
+// account statuses, stored in the status column of accounts
+const (
+	AccountStatusActive = "active"
+	AccountStatusFrozen = "frozen"
+)
+
+// account kinds, stored in the kind column of accounts
+const (
+	AccountKindCustomer = "customer"
+	AccountKindEquity   = "equity" // one per currency, pays the opening balances of the accounts in the currency
+)
+
+// the kinds of database errors callers can act upon, test for them with errors.Is
+var (
+	ErrNotFound            = errors.New("record not found")
+	ErrUniqueViolation     = errors.New("record already exists")
+	ErrForeignKeyViolation = errors.New("referenced record does not exist")
+	ErrCheckViolation      = errors.New("value violates a constraint") // a CHECK or NOT NULL constraint rejected the row
+	ErrInsufficientFunds   = errors.New("insufficient funds")
+	ErrAccountFrozen       = errors.New("account is frozen")
+	ErrEquityAccount       = errors.New("account is an equity account") // only CreateAccountTx moves the money of equity accounts
+	ErrLimitExceeded       = errors.New("transfer limit exceeded")
+	ErrTransferDenied      = errors.New("transfer denied")
+	ErrTransferNotPending  = errors.New("transfer is not pending review")
+	ErrAlertResolved       = errors.New("compliance alert is already resolved")
+)
+
+// Error is a database error classified into one of the Err* kinds
+// errors.Is matches both the kind and the original error, so err == pgx.ErrNoRows style checks keep working
+// through errors.Is(err, pgx.ErrNoRows)
+type Error struct {
+	Kind    error          // one of the Err* kinds
+	Details map[string]any // safe to show to clients, e.g. the violated constraint or the account involved
+	Err     error          // the original error, nil for errors raised by the store itself
+}
+
+func (e *Error) Error() string {
+	return e.Kind.Error()
+}
+
+// Is reports whether target is the kind of the error
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// Unwrap returns the original error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// newAccountError returns an error of the given kind about accountID
+func newAccountError(kind error, accountID int64) *Error {
+	return &Error{Kind: kind, Details: map[string]any{"account_id": accountID}}
+}
+
+// ClassifyError turns pgx.ErrNoRows and postgres constraint violations into an *Error
+// other errors, and errors that are already classified, are returned unchanged
+func ClassifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var dbErr *Error
+	if errors.As(err, &dbErr) {
+		return err
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &Error{Kind: ErrNotFound, Err: err}
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case UniqueViolation:
+			return &Error{Kind: ErrUniqueViolation, Details: constraintDetails(pgErr), Err: err}
+		case ForeignKeyViolation:
+			return &Error{Kind: ErrForeignKeyViolation, Details: constraintDetails(pgErr), Err: err}
+		case CheckViolation, NotNullViolation:
+			return &Error{Kind: ErrCheckViolation, Details: constraintDetails(pgErr), Err: err}
+		}
+	}
+
+	return err
+}
+
+// constraintDetails only keeps the constraint and column names, the message and detail of a postgres error may
+// contain row values. A NOT NULL violation has no constraint name, only the column
+func constraintDetails(pgErr *pgconn.PgError) map[string]any {
+	details := map[string]any{}
+	if pgErr.ConstraintName != "" {
+		details["constraint"] = pgErr.ConstraintName
+	}
+	if pgErr.ColumnName != "" {
+		details["column"] = pgErr.ColumnName
+	}
+	if len(details) == 0 {
+		return nil
+	}
+	return details
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyError(t *testing.T) {
+	testCases := []struct {
+		name    string
+		err     error
+		kind    error
+		details map[string]any
+	}{
+		{
+			name: "NoRows",
+			err:  fmt.Errorf("get account: %w", pgx.ErrNoRows),
+			kind: ErrNotFound,
+		},
+		{
+			name:    "UniqueViolation",
+			err:     &pgconn.PgError{Code: UniqueViolation, ConstraintName: "owner_currency_key", Detail: "Key (owner)=(alice) already exists."},
+			kind:    ErrUniqueViolation,
+			details: map[string]any{"constraint": "owner_currency_key"},
+		},
+		{
+			name:    "ForeignKeyViolation",
+			err:     fmt.Errorf("commit: %w", &pgconn.PgError{Code: ForeignKeyViolation, ConstraintName: "entries_account_id_fkey"}),
+			kind:    ErrForeignKeyViolation,
+			details: map[string]any{"constraint": "entries_account_id_fkey"},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			err := ClassifyError(tc.err)
+			require.ErrorIs(t, err, tc.kind)
+			require.ErrorIs(t, err, tc.err) // the original error is still reachable, e.g. for logs
+
+			var dbErr *Error
+			require.ErrorAs(t, err, &dbErr)
+			require.Equal(t, tc.details, dbErr.Details)
+		})
+	}
+}
+
+func TestClassifyErrorUnchanged(t *testing.T) {
+	require.NoError(t, ClassifyError(nil))
+
+	err := errors.New("connection refused")
+	require.Equal(t, err, ClassifyError(err))
+
+	err = &pgconn.PgError{Code: SerializationFailure}
+	require.Equal(t, err, ClassifyError(err))
+
+	classified := newAccountError(ErrAccountFrozen, 1)
+	require.Equal(t, error(classified), ClassifyError(classified))
+}
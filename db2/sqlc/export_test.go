@@ -0,0 +1,4 @@
+package db
+
+// CreateScratchDatabase is createScratchDatabase for the external test package
+var CreateScratchDatabase = createScratchDatabase
@@ -0,0 +1,75 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: ledger.sql
+
+package db
+
+import (
+	"context"
+)
+
+const reconcileAccounts = `-- name: ReconcileAccounts :many
+WITH entry_totals AS (
+  SELECT account_id, SUM(amount)::bigint AS total
+  FROM entries
+  GROUP BY account_id
+), transfer_totals AS (
+  SELECT account_id, SUM(amount)::bigint AS total
+  FROM (
+    SELECT to_account_id AS account_id, amount FROM transfers WHERE status = 'completed'
+    UNION ALL
+    SELECT from_account_id AS account_id, -amount FROM transfers WHERE status = 'completed'
+  ) AS moves
+  GROUP BY account_id
+)
+SELECT
+  a.id,
+  a.currency,
+  a.balance,
+  COALESCE(e.total, 0)::bigint AS entry_total,
+  COALESCE(t.total, 0)::bigint AS transfer_total
+FROM accounts a
+LEFT JOIN entry_totals e ON e.account_id = a.id
+LEFT JOIN transfer_totals t ON t.account_id = a.id
+WHERE COALESCE(e.total, 0) <> COALESCE(t.total, 0)
+   OR a.balance <> COALESCE(e.total, 0)
+ORDER BY a.id
+`
+
+type ReconcileAccountsRow struct {
+	ID            int64  `json:"id"`
+	Currency      string `json:"currency"`
+	Balance       int64  `json:"balance"`
+	EntryTotal    int64  `json:"entry_total"`
+	TransferTotal int64  `json:"transfer_total"`
+}
+
+// lists the accounts whose ledger doesn't add up, an empty result means the books are consistent:
+// the entries of an account must add up to its completed transfers, and to its balance: the opening balance
+// is a transfer from the equity account of the currency, so every change of a balance has its entry
+func (q *Queries) ReconcileAccounts(ctx context.Context) ([]ReconcileAccountsRow, error) {
+	rows, err := q.db.Query(ctx, reconcileAccounts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ReconcileAccountsRow{}
+	for rows.Next() {
+		var i ReconcileAccountsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Currency,
+			&i.Balance,
+			&i.EntryTotal,
+			&i.TransferTotal,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
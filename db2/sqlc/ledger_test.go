@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simple-bank/utils"
+)
+
+// findReconcileRow returns the row of accountID in the result of ReconcileAccounts
+func findReconcileRow(t *testing.T, accountID int64) (ReconcileAccountsRow, bool) {
+	rows, err := testQueries.ReconcileAccounts(context.Background())
+	require.NoError(t, err)
+
+	for _, row := range rows {
+		if row.ID == accountID {
+			return row, true
+		}
+	}
+	return ReconcileAccountsRow{}, false
+}
+
+func TestReconcileAccounts(t *testing.T) {
+	store := NewStore(testDB)
+	ctx := context.Background()
+
+	// the opening balances are transfers from the equity account, the ledger explains them
+	account1, err := store.CreateAccountTx(ctx, CreateAccountParams{Owner: utils.RandomOwner(), Balance: 1000, Currency: "USD"})
+	require.NoError(t, err)
+	account2, err := store.CreateAccountTx(ctx, CreateAccountParams{Owner: utils.RandomOwner(), Balance: 0, Currency: "USD"})
+	require.NoError(t, err)
+
+	_, err = store.TransferTx(ctx, TransferTxParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 300})
+	require.NoError(t, err)
+
+	_, found := findReconcileRow(t, account1.ID)
+	require.False(t, found)
+	_, found = findReconcileRow(t, account2.ID)
+	require.False(t, found)
+
+	// a balance changed behind the back of the ledger shows up
+	_, err = testQueries.AddAccountBalance(ctx, AddAccountBalanceParams{ID: account2.ID, Amount: 50})
+	require.NoError(t, err)
+
+	row, found := findReconcileRow(t, account2.ID)
+	require.True(t, found)
+	require.Equal(t, int64(350), row.Balance)
+	require.Equal(t, int64(300), row.EntryTotal)
+	require.Equal(t, int64(300), row.TransferTotal)
+
+	// so does an entry without its transfer
+	_, err = testQueries.CreateEntry(ctx, CreateEntryParams{AccountID: account1.ID, Amount: -10})
+	require.NoError(t, err)
+
+	row, found = findReconcileRow(t, account1.ID)
+	require.True(t, found)
+	require.Equal(t, int64(-310), row.EntryTotal)
+	require.Equal(t, int64(-300), row.TransferTotal)
+}
@@ -1,36 +1,35 @@
 package db
 
 import (
-	"database/sql"
+	"context"
 	"log"
 	"os"
 	"testing"
 
-	_ "github.com/lib/pq" // PostgreSQL driver,
-	// _ is blank identifier to avoid import error if not used
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/techschool/simple-bank/utils"
 )
 
 
 // this is a global variable that will be used by all unit tests
 var testQueries *Queries
-var testDB *sql.DB // Store the database connection for raw SQL queries
+var testDB *pgxpool.Pool // Store the database connection for raw SQL queries
 
 // entrance point for all unit tests
 func TestMain(m *testing.M) {
-	config, err := utils.LoadConfig("../../")
+	config, err := utils.LoadConfig("../../", nil)
 	if err != nil {
 		log.Fatal("Cannot load config:", err)
 	}
 
-	conn, err := sql.Open(config.DBDriver, config.DBSource)
+	conn, err := pgxpool.New(context.Background(), config.DBSource)
 	if err != nil {
 		log.Fatal("Cannot connect to database:", err)
 	}
 	defer conn.Close()
 
 	// Test the connection
-	if err = conn.Ping(); err != nil {
+	if err = conn.Ping(context.Background()); err != nil {
 		log.Fatal("Cannot ping database:", err)
 	}
 
@@ -39,3 +38,8 @@ func TestMain(m *testing.M) {
 
 	os.Exit(m.Run())
 }
+
+// ptr returns a pointer to v, for the optional parameters of the queries
+func ptr[T any](v T) *T {
+	return &v
+}
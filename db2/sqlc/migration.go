@@ -0,0 +1,5 @@
+package db
+
+// SchemaVersion is the version of the latest migration in db2/migration, bump it together with every new migration
+// the server reports not ready while the database is behind it
+const SchemaVersion = 13
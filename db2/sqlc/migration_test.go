@@ -0,0 +1,165 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simple-bank/db2/migration"
+	"github.com/techschool/simple-bank/utils"
+)
+
+func TestSchemaVersionMatchesMigrations(t *testing.T) {
+	files, err := os.ReadDir("../migration")
+	require.NoError(t, err)
+
+	latest := 0
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".sql") {
+			continue // the package embedding the migrations
+		}
+		prefix, _, found := strings.Cut(file.Name(), "_")
+		require.True(t, found, file.Name())
+
+		version, err := strconv.Atoi(prefix)
+		require.NoError(t, err, file.Name())
+		latest = max(latest, version)
+	}
+
+	require.Equal(t, latest, SchemaVersion, "update SchemaVersion after adding a migration")
+}
+
+// createScratchDatabase creates an empty database next to the test database and returns its URL,
+// so the migrations can go down without dropping the tables the other tests use,
+// and the store conformance suite runs on tables nothing else writes to
+func createScratchDatabase(t *testing.T) string {
+	name := fmt.Sprintf("simple_bank_scratch_%d", utils.RandomInt(1, 1000000))
+	_, err := testDB.Exec(context.Background(), "CREATE DATABASE "+name)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, err := testDB.Exec(context.Background(), "DROP DATABASE IF EXISTS "+name+" WITH (FORCE)")
+		require.NoError(t, err)
+	})
+
+	source, err := url.Parse(testDB.Config().ConnString())
+	require.NoError(t, err)
+	source.Path = "/" + name
+	return source.String()
+}
+
+func TestMigrationsUpDown(t *testing.T) {
+	m, err := migration.New(createScratchDatabase(t))
+	require.NoError(t, err)
+	defer m.Close()
+
+	requireVersion := func(expected uint) {
+		version, dirty, err := m.Version()
+		require.NoError(t, err)
+		require.False(t, dirty)
+		require.Equal(t, expected, version)
+	}
+
+	require.NoError(t, migration.Up(m))
+	requireVersion(SchemaVersion)
+	require.NoError(t, migration.Up(m)) // nothing left to apply
+
+	// every down migration reverts its up migration, so each step can be applied again
+	for version := uint(SchemaVersion); version > 1; version-- {
+		require.NoError(t, m.Steps(-1))
+		requireVersion(version - 1)
+		require.NoError(t, m.Steps(1))
+		requireVersion(version)
+		require.NoError(t, m.Steps(-1))
+	}
+
+	// the first down migration leaves an empty schema
+	require.NoError(t, m.Steps(-1))
+	_, _, err = m.Version()
+	require.ErrorIs(t, err, migrate.ErrNilVersion)
+
+	require.NoError(t, migration.Up(m))
+	requireVersion(SchemaVersion)
+}
+
+// the balances funded before 000013 get their opening balance as a transfer from the equity account of the currency
+func TestMigrateOpeningBalances(t *testing.T) {
+	databaseURL := createScratchDatabase(t)
+	m, err := migration.New(databaseURL)
+	require.NoError(t, err)
+	defer m.Close()
+	require.NoError(t, m.Migrate(12))
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, databaseURL)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	// sender opened with 100 and sent 30 to receiver, which opened with 20; empty never had money
+	createAccount := func(balance int64, currency string) int64 {
+		var id int64
+		err := pool.QueryRow(ctx, "INSERT INTO accounts (owner, balance, currency) VALUES ($1, $2, $3) RETURNING id",
+			utils.RandomOwner(), balance, currency).Scan(&id)
+		require.NoError(t, err)
+		return id
+	}
+	sender := createAccount(70, "USD")
+	receiver := createAccount(50, "USD")
+	createAccount(0, "USD")
+	createAccount(5, "EUR")
+
+	_, err = pool.Exec(ctx, "INSERT INTO transfers (from_account_id, to_account_id, amount) VALUES ($1, $2, 30)", sender, receiver)
+	require.NoError(t, err)
+	_, err = pool.Exec(ctx, "INSERT INTO entries (account_id, amount) VALUES ($1, -30), ($2, 30)", sender, receiver)
+	require.NoError(t, err)
+
+	require.NoError(t, m.Steps(1))
+	q := New(pool)
+
+	mismatches, err := q.ReconcileAccounts(ctx)
+	require.NoError(t, err)
+	require.Empty(t, mismatches)
+
+	equity, err := q.GetEquityAccount(ctx, "USD")
+	require.NoError(t, err)
+	require.Equal(t, int64(-120), equity.Balance)
+	equity, err = q.GetEquityAccount(ctx, "EUR")
+	require.NoError(t, err)
+	require.Equal(t, int64(-5), equity.Balance)
+
+	count := func(table string) int {
+		var n int
+		require.NoError(t, pool.QueryRow(ctx, "SELECT count(*) FROM "+table).Scan(&n))
+		return n
+	}
+	require.Equal(t, 4, count("transfers"))
+	require.Equal(t, 8, count("entries"))
+
+	var unlinked int
+	require.NoError(t, pool.QueryRow(ctx, "SELECT count(*) FROM entries WHERE transfer_id IS NULL").Scan(&unlinked))
+	require.Equal(t, 2, unlinked) // the entries written before the migration
+
+	// an entry looking like the opening of receiver, but not written by it, and an alert on the equity account
+	usd, err := q.GetEquityAccount(ctx, "USD")
+	require.NoError(t, err)
+	_, err = pool.Exec(ctx, `INSERT INTO entries (account_id, amount, created_at)
+		SELECT to_account_id, amount, created_at FROM transfers WHERE to_account_id = $1 AND from_account_id = $2`,
+		receiver, usd.ID)
+	require.NoError(t, err)
+	_, err = pool.Exec(ctx, `INSERT INTO compliance_alerts (kind, account_id, currency, total_amount, transfer_ids)
+		VALUES ('large_transaction', $1, 'USD', 20, '{}')`, usd.ID)
+	require.NoError(t, err)
+
+	// going down removes the opening transfers, their entries and the equity accounts, the balances stay
+	require.NoError(t, m.Steps(-1))
+	require.Equal(t, 1, count("transfers"))
+	require.Equal(t, 3, count("entries"))
+	require.Equal(t, 4, count("accounts"))
+	require.Zero(t, count("compliance_alerts"))
+}
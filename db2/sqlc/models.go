@@ -5,16 +5,67 @@
 package db
 
 import (
-	"database/sql"
+	"encoding/json"
 	"time"
 )
 
 type Account struct {
-	ID        int64     `json:"id"`
-	Owner     string    `json:"owner"`
-	Balance   int64     `json:"balance"`
+	ID      int64  `json:"id"`
+	Owner   string `json:"owner"`
+	Balance int64  `json:"balance"`
+	// ISO 4217 code, three upper case letters
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
+	// active or frozen, money can only move in and out of active accounts
+	Status    string `json:"status"`
+	LimitTier string `json:"limit_tier"`
+	// customer, or equity: the bank side of the opening balances, its balance is minus the money put in
+	Kind string `json:"kind"`
+}
+
+// overrides of the tier limits for one account, NULL keeps the limit of the tier
+type AccountTransferLimit struct {
+	AccountID       int64     `json:"account_id"`
+	MaxSingleAmount *int64    `json:"max_single_amount"`
+	MaxDailyAmount  *int64    `json:"max_daily_amount"`
+	MaxDailyCount   *int32    `json:"max_daily_count"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type AuditEvent struct {
+	ID           int64  `json:"id"`
+	Actor        string `json:"actor"`
+	Action       string `json:"action"`
+	ResourceType string `json:"resource_type"`
+	ResourceID   string `json:"resource_id"`
+	// state of the resource before the change, null on create
+	Before json.RawMessage `json:"before"`
+	// state of the resource after the change, null on delete
+	After     json.RawMessage `json:"after"`
+	RequestID string          `json:"request_id"`
+	ClientIp  string          `json:"client_ip"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type ComplianceAlert struct {
+	ID int64 `json:"id"`
+	// large_transaction or structuring
+	Kind      string `json:"kind"`
+	AccountID int64  `json:"account_id"`
+	Currency  string `json:"currency"`
+	// sum of the evidence transfers, in minor units of currency
+	TotalAmount int64 `json:"total_amount"`
+	// the transfers that raised the alert, its evidence
+	TransferIds []int64 `json:"transfer_ids"`
+	// open, assigned or resolved
+	Status         string     `json:"status"`
+	Assignee       string     `json:"assignee"`
+	Resolution     string     `json:"resolution"`
+	ResolutionNote string     `json:"resolution_note"`
+	ResolvedBy     string     `json:"resolved_by"`
+	ResolvedAt     *time.Time `json:"resolved_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
 }
 
 type Entry struct {
@@ -23,6 +74,21 @@ type Entry struct {
 	// can be negative or positive
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// the transfer the entry is one side of, null for the entries older than the column
+	TransferID *int64 `json:"transfer_id"`
+}
+
+type Outbox struct {
+	ID            int64           `json:"id"`
+	EventType     string          `json:"event_type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	Payload       json.RawMessage `json:"payload"`
+	Attempts      int32           `json:"attempts"`
+	LastError     string          `json:"last_error"`
+	CreatedAt     time.Time       `json:"created_at"`
+	// null until the relay has handed the event to the publisher
+	PublishedAt *time.Time `json:"published_at"`
 }
 
 type Transfer struct {
@@ -30,6 +96,56 @@ type Transfer struct {
 	FromAccountID int64 `json:"from_account_id"`
 	ToAccountID   int64 `json:"to_account_id"`
 	// must be positive
-	Amount    int64        `json:"amount"`
-	CreatedAt sql.NullTime `json:"created_at"`
+	Amount    int64     `json:"amount"`
+	CreatedAt time.Time `json:"created_at"`
+	// completed, pending_review or rejected, money only moved for completed transfers
+	Status string `json:"status"`
+	// fraud rule that sent the transfer to review
+	ScreeningRule   string     `json:"screening_rule"`
+	ScreeningReason string     `json:"screening_reason"`
+	ReviewedBy      string     `json:"reviewed_by"`
+	ReviewedAt      *time.Time `json:"reviewed_at"`
+	ReviewNote      string     `json:"review_note"`
+	// when the AML analyzer looked at the transfer, NULL until then
+	AmlAnalyzedAt *time.Time `json:"aml_analyzed_at"`
+}
+
+type TransferLimitTier struct {
+	Name string `json:"name"`
+	// largest amount of a single transfer, in minor units
+	MaxSingleAmount int64 `json:"max_single_amount"`
+	// largest sum of the outgoing transfers of a UTC day, in minor units
+	MaxDailyAmount int64 `json:"max_daily_amount"`
+	// largest number of outgoing transfers in a UTC day
+	MaxDailyCount int32     `json:"max_daily_count"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type Webhook struct {
+	ID         int64    `json:"id"`
+	Url        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	// key of the HMAC-SHA256 signature sent with every delivery
+	Secret    string    `json:"secret"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID        int64 `json:"id"`
+	WebhookID int64 `json:"webhook_id"`
+	// id of the outbox event being delivered
+	EventID   int64           `json:"event_id"`
+	EventType string          `json:"event_type"`
+	Payload   json.RawMessage `json:"payload"`
+	// pending, succeeded or dead
+	Status         string    `json:"status"`
+	Attempts       int32     `json:"attempts"`
+	ResponseStatus int32     `json:"response_status"`
+	LastError      string    `json:"last_error"`
+	NextAttemptAt  time.Time `json:"next_attempt_at"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	// claim of the worker sending the delivery, other workers skip it until then
+	LockedUntil *time.Time `json:"locked_until"`
 }
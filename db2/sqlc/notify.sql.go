@@ -0,0 +1,20 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: notify.sql
+
+package db
+
+import (
+	"context"
+)
+
+const notifyAccountEvent = `-- name: NotifyAccountEvent :exec
+SELECT pg_notify('account_events', $1::text)
+`
+
+// the notification is only delivered to listeners when the transaction commits
+func (q *Queries) NotifyAccountEvent(ctx context.Context, payload string) error {
+	_, err := q.db.Exec(ctx, notifyAccountEvent, payload)
+	return err
+}
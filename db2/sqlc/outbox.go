@@ -0,0 +1,186 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// domain event types, stored in the event_type column of the outbox
+const (
+	EventAccountCreated       = "AccountCreated"
+	EventTransferCompleted    = "TransferCompleted"
+	EventBalanceChanged       = "BalanceChanged"
+	EventAccountStatusChanged = "AccountStatusChanged"
+)
+
+// AccountCreatedEvent is the payload of an AccountCreated event
+type AccountCreatedEvent struct {
+	Account Account `json:"account"`
+}
+
+// AccountStatusChangedEvent is the payload of an AccountStatusChanged event, emitted when an account is frozen or unfrozen
+type AccountStatusChangedEvent struct {
+	Account        Account `json:"account"` // the account with its new status
+	PreviousStatus string  `json:"previous_status"`
+}
+
+// TransferCompletedEvent is the payload of a TransferCompleted event
+type TransferCompletedEvent struct {
+	Transfer  Transfer `json:"transfer"`
+	FromEntry Entry    `json:"from_entry"`
+	ToEntry   Entry    `json:"to_entry"`
+}
+
+// BalanceChangedEvent is the payload of a BalanceChanged event, one is emitted per account touched by a transfer
+// it is also sent on the AccountEventsChannel notification channel for live streaming
+type BalanceChangedEvent struct {
+	AccountID int64     `json:"account_id"`
+	EntryID   int64     `json:"entry_id"`
+	Amount    int64     `json:"amount"`  // the change, negative for money going out
+	Balance   int64     `json:"balance"` // the balance after the change
+	Currency  string    `json:"currency"`
+	CreatedAt time.Time `json:"created_at"` // when the entry was created
+}
+
+// AccountEventsChannel is the postgres LISTEN/NOTIFY channel carrying BalanceChangedEvent payloads
+// it must match the channel used by the NotifyAccountEvent query
+const AccountEventsChannel = "account_events"
+
+// enqueueEvent writes a domain event to the outbox with q
+// like recordAudit it must be called with the Queries of the transaction making the change,
+// so the event exists if and only if the change is committed
+func enqueueEvent(
+	ctx context.Context,
+	q *Queries,
+	eventType string,
+	aggregateType string,
+	aggregateID int64,
+	payload any,
+) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.CreateOutboxEvent(ctx, CreateOutboxEventParams{
+		EventType:     eventType,
+		AggregateType: aggregateType,
+		AggregateID:   strconv.FormatInt(aggregateID, 10),
+		Payload:       data,
+	})
+	return err
+}
+
+// enqueueTransferEvents writes the TransferCompleted event and one BalanceChanged event per account,
+// and notifies listeners of the balance changes
+func enqueueTransferEvents(ctx context.Context, q *Queries, result TransferTxResult) error {
+	err := enqueueEvent(ctx, q, EventTransferCompleted, AuditResourceTransfer, result.Transfer.ID, TransferCompletedEvent{
+		Transfer:  result.Transfer,
+		FromEntry: result.FromEntry,
+		ToEntry:   result.ToEntry,
+	})
+	if err != nil {
+		return err
+	}
+
+	changes := []struct {
+		account Account
+		entry   Entry
+	}{
+		{result.FromAccount, result.FromEntry},
+		{result.ToAccount, result.ToEntry},
+	}
+	for _, change := range changes {
+		event := BalanceChangedEvent{
+			AccountID: change.account.ID,
+			EntryID:   change.entry.ID,
+			Amount:    change.entry.Amount,
+			Balance:   change.account.Balance,
+			Currency:  change.account.Currency,
+			CreatedAt: change.entry.CreatedAt,
+		}
+		err = enqueueEvent(ctx, q, EventBalanceChanged, AuditResourceAccount, change.account.ID, event)
+		if err != nil {
+			return err
+		}
+
+		// the outbox guarantees delivery to downstream systems but is relayed by a single replica,
+		// the notification reaches every replica right after the commit, which is what live streams need
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if err := q.NotifyAccountEvent(ctx, string(payload)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsOutboxEventDead reports whether event is dead-lettered when the publish it was just handed to fails:
+// it has then failed maxAttempts times and the relays leave it alone
+func IsOutboxEventDead(event Outbox, maxAttempts int32) bool {
+	return event.Attempts+1 >= maxAttempts
+}
+
+// RelayOutboxTx hands up to batchSize unpublished outbox events to publish, oldest first
+// the rows stay locked until the transaction ends, so concurrent relays never publish the same batch
+// a successful publish marks the event as published; the first failure is recorded on the event and
+// stops the batch, so events are never published out of order. The failed event is retried on the next call,
+// until it has failed maxAttempts times: it is then dead-lettered and the batch goes on without it,
+// one event the publisher always rejects must not hold back the ones behind it forever.
+// If the commit fails after a successful publish, the event is published again later,
+// which means publishers may see an event more than once (at-least-once delivery)
+// returns the number of events published, and the publish error if the batch was stopped by one
+func (store *SQLStore) RelayOutboxTx(
+	ctx context.Context,
+	batchSize int32,
+	maxAttempts int32,
+	publish func(ctx context.Context, event Outbox) error,
+) (int, error) {
+	ctx, span := startSpan(ctx, "RelayOutboxTx")
+	defer span.End()
+
+	published := 0
+	var publishErr error
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		published, publishErr = 0, nil // execTx may run this again after a serialization failure
+
+		events, err := q.ListUnpublishedOutboxEvents(ctx, ListUnpublishedOutboxEventsParams{
+			MaxAttempts: maxAttempts,
+			Limit:       batchSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			if publishErr = publish(ctx, event); publishErr != nil {
+				err := q.MarkOutboxEventFailed(ctx, MarkOutboxEventFailedParams{
+					ID:        event.ID,
+					LastError: publishErr.Error(),
+				})
+				if err != nil || !IsOutboxEventDead(event, maxAttempts) {
+					// returning nil commits the events published so far along with the failure
+					return err
+				}
+				publishErr = nil // dead-lettered, the next events go ahead
+				continue
+			}
+
+			if err := q.MarkOutboxEventPublished(ctx, event.ID); err != nil {
+				return err
+			}
+			published++
+		}
+		return nil
+	})
+	if err != nil {
+		return published, err
+	}
+
+	return published, publishErr
+}
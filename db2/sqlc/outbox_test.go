@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferTxWritesOutbox(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createFundedAccount(t, 100)
+	account2 := createRandomAccount(t)
+
+	// publish whatever other tests left behind, so only the events of this transfer remain
+	_, err := store.RelayOutboxTx(context.Background(), 10000, 3, func(ctx context.Context, event Outbox) error {
+		return nil
+	})
+	require.NoError(t, err)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	var published []Outbox
+	n, err := store.RelayOutboxTx(context.Background(), 10, 3, func(ctx context.Context, event Outbox) error {
+		published = append(published, event)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.Len(t, published, 3)
+
+	require.Equal(t, EventTransferCompleted, published[0].EventType)
+	require.Equal(t, strconv.FormatInt(result.Transfer.ID, 10), published[0].AggregateID)
+	var completed TransferCompletedEvent
+	require.NoError(t, json.Unmarshal(published[0].Payload, &completed))
+	require.Equal(t, result.Transfer.ID, completed.Transfer.ID)
+
+	for _, event := range published[1:] {
+		require.Equal(t, EventBalanceChanged, event.EventType)
+		var changed BalanceChangedEvent
+		require.NoError(t, json.Unmarshal(event.Payload, &changed))
+		if changed.AccountID == account1.ID {
+			require.Equal(t, int64(-10), changed.Amount)
+			require.Equal(t, result.FromAccount.Balance, changed.Balance)
+		} else {
+			require.Equal(t, account2.ID, changed.AccountID)
+			require.Equal(t, int64(10), changed.Amount)
+			require.Equal(t, result.ToAccount.Balance, changed.Balance)
+		}
+	}
+
+	// everything has been published, a second relay finds nothing
+	n, err = store.RelayOutboxTx(context.Background(), 10, 3, func(ctx context.Context, event Outbox) error {
+		return nil
+	})
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	deleteAllAccounts(t)
+}
@@ -12,10 +12,28 @@ type Querier interface {
 	// Here UpdateAccount is the name of the function in generated go code :one means return one row
 	// sqlc.arg(amount) allows use to use the amount variable in generated go code, because balance doesn't make sense
 	AddAccountBalance(ctx context.Context, arg AddAccountBalanceParams) (Account, error)
+	AssignComplianceAlert(ctx context.Context, arg AssignComplianceAlertParams) (ComplianceAlert, error)
+	ClaimWebhookDeliveries(ctx context.Context, arg ClaimWebhookDeliveriesParams) error
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) (AuditEvent, error)
+	CreateComplianceAlert(ctx context.Context, arg CreateComplianceAlertParams) (ComplianceAlert, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	// does nothing when a concurrent transaction created it first, GetEquityAccount finds it afterwards
+	CreateEquityAccount(ctx context.Context, currency string) error
+	// the opening balance of an account, paid by the equity account of its currency
+	// the AML analyzer skips it, no customer sent the money
+	CreateOpeningTransfer(ctx context.Context, arg CreateOpeningTransferParams) (Transfer, error)
+	CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) (Outbox, error)
+	// a transfer sent to review by the fraud screening, no entries are written and no money moves until it is approved
+	CreatePendingTransfer(ctx context.Context, arg CreatePendingTransferParams) (Transfer, error)
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error)
+	CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error)
+	// one delivery per active webhook subscribed to the event type
+	// the unique (webhook_id, event_id) index makes it safe to call again for an event that was already fanned out
+	CreateWebhookDeliveries(ctx context.Context, arg CreateWebhookDeliveriesParams) (int64, error)
 	DeleteAccount(ctx context.Context, id int64) error
+	DeleteAccountTransferLimitOverride(ctx context.Context, accountID int64) error
+	DeleteWebhook(ctx context.Context, id int64) error
 	// the * means return all the columns
 	// There was a bug here during concurrent transactions:
 	// when we read the row, we need to lock it from begin to the commit of the transaction
@@ -30,13 +48,77 @@ type Querier interface {
 	// a better way is to use FOR NO KEY UPDATE
 	// this only create weaker lock while allow INSERT, while still block modify key column and DELETE
 	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
+	GetAccountTransferLimitOverride(ctx context.Context, accountID int64) (AccountTransferLimit, error)
+	GetAuditEvent(ctx context.Context, id int64) (AuditEvent, error)
+	GetComplianceAlert(ctx context.Context, id int64) (ComplianceAlert, error)
+	GetComplianceAlertForUpdate(ctx context.Context, id int64) (ComplianceAlert, error)
+	// the transfers sent by the account that were completed on the UTC day of sqlc.arg(day), the transfers of the
+	// current transaction included. A transfer completes when it is created, or when it is approved after a review:
+	// a pending transfer counts on the day it was approved, whatever the day it was made
+	GetDailyOutgoingTransfers(ctx context.Context, arg GetDailyOutgoingTransfersParams) (GetDailyOutgoingTransfersRow, error)
+	// the limits of the tier of the account, replaced by the overrides of the account where it has some
+	GetEffectiveTransferLimits(ctx context.Context, id int64) (GetEffectiveTransferLimitsRow, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	// the equity account of a currency pays the opening balances of the accounts in the currency, see CreateAccountTx
+	GetEquityAccount(ctx context.Context, currency string) (Account, error)
 	GetTransfer(ctx context.Context, id int64) (Transfer, error)
+	// locks the transfer, so that two tellers can't review it at the same time
+	GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error)
+	GetTransferLimitTier(ctx context.Context, name string) (TransferLimitTier, error)
+	GetWebhook(ctx context.Context, id int64) (Webhook, error)
+	// whether the sender already paid the receiver
+	HasCompletedTransfer(ctx context.Context, arg HasCompletedTransferParams) (bool, error)
+	// the equity accounts are the bank's side of the ledger, customers don't see them
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
+	// every filter is optional, passing NULL skips it
+	ListAuditEvents(ctx context.Context, arg ListAuditEventsParams) ([]AuditEvent, error)
+	// every filter is optional, NULL means "don't filter on it", newest alert first
+	ListComplianceAlerts(ctx context.Context, arg ListComplianceAlertsParams) ([]ComplianceAlert, error)
+	// SKIP LOCKED lets several workers run side by side, like the outbox relay
+	// the deliveries claimed by a worker are skipped until their claim runs out
+	ListDueWebhookDeliveries(ctx context.Context, limit int32) ([]ListDueWebhookDeliveriesRow, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
+	// the review queue, oldest transfer first
+	ListPendingTransfers(ctx context.Context, arg ListPendingTransfersParams) ([]Transfer, error)
+	// the screening history of an account, the rejected transfers left out
+	ListRecentOutgoingTransfers(ctx context.Context, arg ListRecentOutgoingTransfersParams) ([]Transfer, error)
+	ListTransferLimitTiers(ctx context.Context) ([]TransferLimitTier, error)
 	ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error)
+	// the completed transfers of the account between two times with min_amount <= amount < max_amount
+	ListTransfersInAmountRange(ctx context.Context, arg ListTransfersInAmountRangeParams) ([]ListTransfersInAmountRangeRow, error)
+	// the completed transfers the AML analyzer didn't look at yet, with the currency of the sender
+	// SKIP LOCKED lets several analyzers run side by side, each one takes a different batch
+	ListUnanalyzedTransfers(ctx context.Context, limit int32) ([]ListUnanalyzedTransfersRow, error)
+	// SKIP LOCKED lets several relays run side by side, each one takes a different batch
+	// the events that failed max_attempts times are dead-lettered: they stay unpublished, with their last error, for inspection
+	ListUnpublishedOutboxEvents(ctx context.Context, arg ListUnpublishedOutboxEventsParams) ([]Outbox, error)
+	ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	ListWebhooks(ctx context.Context, arg ListWebhooksParams) ([]Webhook, error)
+	MarkOutboxEventFailed(ctx context.Context, arg MarkOutboxEventFailedParams) error
+	MarkOutboxEventPublished(ctx context.Context, id int64) error
+	MarkTransferAnalyzed(ctx context.Context, id int64) error
+	// the notification is only delivered to listeners when the transaction commits
+	NotifyAccountEvent(ctx context.Context, payload string) error
+	// lists the accounts whose ledger doesn't add up, an empty result means the books are consistent:
+	// the entries of an account must add up to its completed transfers, and to its balance: the opening balance
+	// is a transfer from the equity account of the currency, so every change of a balance has its entry
+	ReconcileAccounts(ctx context.Context) ([]ReconcileAccountsRow, error)
+	// only the worker holding the claim records its attempt, and the claim is dropped
+	// nothing is updated once the claim ran out and another worker took the delivery
+	RecordWebhookAttempt(ctx context.Context, arg RecordWebhookAttemptParams) (int64, error)
+	ResolveComplianceAlert(ctx context.Context, arg ResolveComplianceAlertParams) (ComplianceAlert, error)
+	ReviewTransfer(ctx context.Context, arg ReviewTransferParams) (Transfer, error)
 	// LIMIT $1 enable pagination so that we only display certain number of rows
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
+	UpdateAccountLimitTier(ctx context.Context, arg UpdateAccountLimitTierParams) (Account, error)
+	// frozen accounts can't send or receive transfers, see TransferTx
+	UpdateAccountStatus(ctx context.Context, arg UpdateAccountStatusParams) (Account, error)
+	UpdateWebhook(ctx context.Context, arg UpdateWebhookParams) (Webhook, error)
+	// a NULL column keeps the limit of the tier
+	UpsertAccountTransferLimitOverride(ctx context.Context, arg UpsertAccountTransferLimitOverrideParams) (AccountTransferLimit, error)
+	// raises a structuring alert for the account, or adds the transfers to its unresolved one
+	UpsertStructuringAlert(ctx context.Context, arg UpsertStructuringAlertParams) (ComplianceAlert, error)
+	UpsertTransferLimitTier(ctx context.Context, arg UpsertTransferLimitTierParams) (TransferLimitTier, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// WithReadReplica routes the read-only queries GetAccount, ListAccounts, ListEntries and ListTransfers to replica
+// every other query, and every transaction, still runs on the primary
+// a replica lags behind the primary: a caller that must see its own recent writes uses WithReadYourWrites
+func WithReadReplica(replica *pgxpool.Pool) StoreOption {
+	return func(store *SQLStore) {
+		store.replica = New(traceDBTX(replica))
+	}
+}
+
+type readYourWritesKey struct{}
+
+// WithReadYourWrites returns a copy of ctx whose queries all run on the primary, even those routed to the replica,
+// e.g. to read an account right after creating it, or to check an account before writing to it
+func WithReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readYourWritesKey{}, true)
+}
+
+// readQueries returns the Queries to run a read-only query with: the replica unless there is none or ctx asks
+// for the primary
+func (store *SQLStore) readQueries(ctx context.Context) *Queries {
+	if store.replica == nil {
+		return store.Queries
+	}
+	if primary, _ := ctx.Value(readYourWritesKey{}).(bool); primary {
+		return store.Queries
+	}
+	return store.replica
+}
+
+// the read-only queries served by the replica, they shadow the methods of the embedded Queries
+
+func (store *SQLStore) GetAccount(ctx context.Context, id int64) (Account, error) {
+	return store.readQueries(ctx).GetAccount(ctx, id)
+}
+
+func (store *SQLStore) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error) {
+	return store.readQueries(ctx).ListAccounts(ctx, arg)
+}
+
+func (store *SQLStore) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error) {
+	return store.readQueries(ctx).ListEntries(ctx, arg)
+}
+
+func (store *SQLStore) ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error) {
+	return store.readQueries(ctx).ListTransfers(ctx, arg)
+}
@@ -0,0 +1,26 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadReplicaRouting(t *testing.T) {
+	// the test database stands in for the replica, what matters is which Queries each call goes through
+	store := NewStore(testDB, WithReadReplica(testDB)).(*SQLStore)
+	ctx := context.Background()
+
+	require.Same(t, store.replica, store.readQueries(ctx))
+	require.Same(t, store.Queries, store.readQueries(WithReadYourWrites(ctx)))
+
+	account := createRandomAccount(t)
+	got, err := store.GetAccount(ctx, account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.ID, got.ID)
+
+	// without a replica every read goes to the primary
+	primaryOnly := NewStore(testDB).(*SQLStore)
+	require.Same(t, primaryOnly.Queries, primaryOnly.readQueries(ctx))
+}
@@ -2,13 +2,36 @@ package db
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/techschool/simple-bank/fraud"
+	"github.com/techschool/simple-bank/metrics"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // store provides all functions to execute db queries and transactions
 type Store interface {
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	CreateAccountTx(ctx context.Context, arg CreateAccountParams) (Account, error)
+	UpdateAccountStatusTx(ctx context.Context, arg UpdateAccountStatusParams) (Account, error)
+	RelayOutboxTx(ctx context.Context, batchSize int32, maxAttempts int32, publish func(ctx context.Context, event Outbox) error) (int, error)
+	CreateWebhookTx(ctx context.Context, arg CreateWebhookParams) (Webhook, error)
+	UpdateWebhookTx(ctx context.Context, arg UpdateWebhookParams) (Webhook, error)
+	DeleteWebhookTx(ctx context.Context, id int64) error
+	ProcessWebhookDeliveriesTx(ctx context.Context, batchSize int32, deliver func(ctx context.Context, delivery WebhookDelivery, webhook Webhook) WebhookAttempt) (int, error)
+	UpsertTransferLimitTierTx(ctx context.Context, arg UpsertTransferLimitTierParams) (TransferLimitTier, error)
+	UpdateAccountTransferLimitsTx(ctx context.Context, arg UpdateAccountTransferLimitsParams) (GetEffectiveTransferLimitsRow, error)
+	ApproveTransferTx(ctx context.Context, arg ReviewTransferTxParams) (TransferTxResult, error)
+	RejectTransferTx(ctx context.Context, arg ReviewTransferTxParams) (Transfer, error)
+	AnalyzeTransfersTx(ctx context.Context, batchSize int32, rules AMLRules) (int, error)
+	AssignComplianceAlertTx(ctx context.Context, arg AssignComplianceAlertParams) (ComplianceAlert, error)
+	ResolveComplianceAlertTx(ctx context.Context, arg ResolveComplianceAlertTxParams) (ComplianceAlert, error)
 	Querier
 }
 
@@ -16,40 +39,109 @@ type Store interface {
 type SQLStore struct {
 	*Queries // Shares the same instance — all Store methods use the same Queries due to pointer
 	// Enable direct access, can execute queries like this: store.GetAccount(ctx, 1)
-	db *sql.DB
+	db       *pgxpool.Pool
+	retry    RetryPolicy    // how execTx retries serialization failures and deadlocks
+	screener fraud.Screener // screens every transfer, nil allows them all
+	replica  *Queries       // serves the read-only queries listed in WithReadReplica, nil sends them to db
 }
 
-// NewStore creates a new Store with the given database connection
-// parameter db is the database connection
+// StoreOption configures a store created by NewStore
+type StoreOption func(*SQLStore)
+
+// WithScreener makes TransferTx screen every transfer with screener
+func WithScreener(screener fraud.Screener) StoreOption {
+	return func(store *SQLStore) {
+		store.screener = screener
+	}
+}
+
+// NewStore creates a new Store with the given database connection pool
+// parameter db is the connection pool of the primary database
+// parameter opts are optional settings such as WithScreener or WithReadReplica
 // returns a new Store instance
-func NewStore(db *sql.DB) Store {
-	return &SQLStore{
+func NewStore(db *pgxpool.Pool, opts ...StoreOption) Store {
+	store := &SQLStore{
 		db:      db,
-		Queries: New(db),
+		Queries: New(traceDBTX(db)),
+		retry:   DefaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		opt(store)
 	}
+	return store
 }
 
 // execTx executes a function within a database transaction
-// parameter ctx is the context
+// parameter ctx is the context, the transaction uses the TxOptions set with WithTxOptions
 // parameter fn is CALLBACK function that will be executed within the transaction
-// returns error if the transaction fails
+// if the transaction fails with a serialization failure or a deadlock, it is rolled back and fn runs again
+// in a new transaction, up to the attempts of the retry policy, so fn must not keep state between calls
+// returns error if the transaction fails, classified with ClassifyError
 func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
-	tx, err := store.db.BeginTx(ctx, nil) // nil means use the default transaction options
+	start := time.Now()
+	err := store.execTxWithRetry(ctx, fn)
+
+	outcome := txOutcomeCommitted
+	if err != nil {
+		outcome = txOutcomeFailed
+	}
+	metrics.TxDuration.WithLabelValues(outcome).Observe(time.Since(start).Seconds())
+
+	err = ClassifyError(err)
+	recordSpanError(trace.SpanFromContext(ctx), err)
+	return err
+}
+
+// execTxWithRetry runs fn in a transaction until it commits, fails with a non retryable error or runs out of attempts
+func (store *SQLStore) execTxWithRetry(ctx context.Context, fn func(*Queries) error) error {
+	opts := txOptionsFromContext(ctx)
+
+	for attempt := 1; ; attempt++ {
+		err := store.runTx(ctx, opts, fn)
+
+		code, retryable := retryableCode(err)
+		if !retryable {
+			return err
+		}
+		if attempt >= store.retry.MaxAttempts {
+			metrics.TxRetriesExhausted.Inc()
+			return err
+		}
+
+		metrics.TxRetries.WithLabelValues(code).Inc()
+		trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(
+			attribute.Int("attempt", attempt),
+			attribute.String("sqlstate", code),
+		))
+		if sleepErr := sleepContext(ctx, store.retry.delay(attempt)); sleepErr != nil {
+			return err // the caller gave up, report the database error rather than the cancellation
+		}
+	}
+}
+
+// runTx makes a single attempt at running fn within a transaction
+func (store *SQLStore) runTx(ctx context.Context, opts TxOptions, fn func(*Queries) error) error {
+	txOptions := pgx.TxOptions{IsoLevel: opts.Isolation}
+	if opts.ReadOnly {
+		txOptions.AccessMode = pgx.ReadOnly
+	}
+
+	tx, err := store.db.BeginTx(ctx, txOptions)
 	if err != nil {
 		return err
 	}
 
-	q := New(tx) // creates a new Queries instance with the transaction
+	q := New(traceDBTX(tx)) // creates a new Queries instance with the transaction, its statements are traced
 	// unlike line 18 where a db connection is passed, here a tx is passed
 	// it works because New() accepts DBTX type
 	err = fn(q)     // execute the callback function passing the new Queries instance
 	if err != nil { // if callback function execution fails, rollback the transaction
-		if rbErr := tx.Rollback(); rbErr != nil {
-			return fmt.Errorf("transactionErr: %v, rollbackErr: %v", err, rbErr)
+		if rbErr := tx.Rollback(ctx); rbErr != nil {
+			return fmt.Errorf("transactionErr: %w, rollbackErr: %v", err, rbErr)
 		}
 		return err // rollback successful, return the original error
 	}
-	return tx.Commit() // everything in the transaction succeeded, commit the transaction
+	return tx.Commit(ctx) // everything in the transaction succeeded, commit the transaction
 }
 
 // TransferTxParams contains the input parameters of the transfer transaction
@@ -75,14 +167,58 @@ var txKey = struct{}{}
 
 // TransferTx performs a money transfer from one account to another
 // it creates a transfer record, add account entries, and update accounts balance within a single database transaction
+// it fails with ErrEquityAccount if either account is an equity account, ErrAccountFrozen if either account isn't active, ErrInsufficientFunds if the sender's balance
+// would go below zero, and ErrLimitExceeded if the transfer is over one of the sender's transfer limits
+// the fraud screener of the store may deny it with ErrTransferDenied, or send it to review: the transfer is then
+// recorded as pending_review and no money moves
 // parameter ctx is the context
 // parameter arg is the transfer request
 // returns error if the transfer fails
 func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
+	ctx, span := startSpan(ctx, "TransferTx")
+	defer span.End()
+	span.SetAttributes(
+		attribute.Int64("transfer.from_account_id", arg.FromAccountID),
+		attribute.Int64("transfer.to_account_id", arg.ToAccountID),
+	)
+
 	var result TransferTxResult
 
 	// the second parameter is a callback function that will be executed within the transaction
 	err := store.execTx(ctx, func(q *Queries) error {
+		result = TransferTxResult{} // execTx may run this again after a serialization failure
+
+		verdict, err := store.screenTransfer(ctx, q, arg)
+		if err != nil {
+			return err
+		}
+
+		switch verdict.Decision {
+		case fraud.Deny:
+			// the rule stays out of the error, telling it to the client would help getting around it
+			slog.WarnContext(ctx, "transfer denied by fraud screening",
+				"from_account_id", arg.FromAccountID,
+				"to_account_id", arg.ToAccountID,
+				"rule", verdict.Rule,
+				"reason", verdict.Reason,
+			)
+			return newAccountError(ErrTransferDenied, arg.FromAccountID)
+
+		case fraud.Review:
+			// no money moves until a teller approves the transfer, see ApproveTransferTx
+			result.Transfer, err = q.CreatePendingTransfer(ctx, CreatePendingTransferParams{
+				FromAccountID:   arg.FromAccountID,
+				ToAccountID:     arg.ToAccountID,
+				Amount:          arg.Amount,
+				ScreeningRule:   verdict.Rule,
+				ScreeningReason: verdict.Reason,
+			})
+			if err != nil {
+				return err
+			}
+			return recordAudit(ctx, q, AuditActionTransferCreate, AuditResourceTransfer, result.Transfer.ID, nil, result)
+		}
+
 		var ctError error // declare err here to avoid shadowing the err in the outer scope
 		// the next line assign the result of the CreateTransfer to the result.Transfer variable
 		// defined in the outer scope
@@ -96,46 +232,192 @@ func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (Tr
 			return ctError
 		}
 
-		// now add the account entries
-		var feError error
-		result.FromEntry, feError = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.FromAccountID,
-			Amount:    -arg.Amount,
-		})
-		if feError != nil {
-			return feError // the transaction will be rolled back if this error occurs
+		if err := completeTransfer(ctx, q, &result); err != nil {
+			return err
 		}
 
-		var teError error
-		result.ToEntry, teError = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountID,
-			Amount:    arg.Amount,
-		})
-		if teError != nil {
-			return teError // the transaction will be rolled back if this error occurs
+		// the audit event and the outbox events are part of the same transaction, so a rolled back transfer leaves no trace
+		if err := recordAudit(ctx, q, AuditActionTransferCreate, AuditResourceTransfer, result.Transfer.ID, nil, result); err != nil {
+			return err
 		}
+		return enqueueTransferEvents(ctx, q, result)
+	}) // this block does the job of creating the transfer record
 
-		// steps for updating sender account balance
-		if arg.FromAccountID < arg.ToAccountID { // step to avoid deadlock: to fix case where both concurrenttransaction try to update the same account
-			var trError error
-			result.FromAccount, result.ToAccount, trError = addMoney(ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.Amount)
-			if trError != nil {
-				return trError // the transaction will be rolled back if this error occurs
-			}
-		} else { // update receiver account balance first
-			// steps for updating receiver account balance
-			var trError error
-			result.ToAccount, result.FromAccount, trError = addMoney(ctx, q, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
-			if trError != nil {
-				return trError // the transaction will be rolled back if this error occurs
+	if err == nil && result.Transfer.Status == TransferStatusCompleted {
+		metrics.ObserveTransferCompleted(result.FromAccount.Currency, result.Transfer.Amount)
+	}
+	return result, err
+}
+
+// completeTransfer writes the entries of result.Transfer and moves the money between the two accounts
+// it fails, and the caller must roll back, if the accounts or the limits of the sender don't allow the transfer
+func completeTransfer(ctx context.Context, q *Queries, result *TransferTxResult) error {
+	transfer := result.Transfer
+
+	// now add the account entries
+	var feError error
+	result.FromEntry, feError = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:  transfer.FromAccountID,
+		Amount:     -transfer.Amount,
+		TransferID: &transfer.ID,
+	})
+	if feError != nil {
+		return feError // the transaction will be rolled back if this error occurs
+	}
+
+	var teError error
+	result.ToEntry, teError = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:  transfer.ToAccountID,
+		Amount:     transfer.Amount,
+		TransferID: &transfer.ID,
+	})
+	if teError != nil {
+		return teError // the transaction will be rolled back if this error occurs
+	}
+
+	// steps for updating sender account balance
+	if transfer.FromAccountID < transfer.ToAccountID { // step to avoid deadlock: to fix case where both concurrenttransaction try to update the same account
+		var trError error
+		result.FromAccount, result.ToAccount, trError = addMoney(ctx, q, transfer.FromAccountID, -transfer.Amount, transfer.ToAccountID, transfer.Amount)
+		if trError != nil {
+			return trError // the transaction will be rolled back if this error occurs
+		}
+	} else { // update receiver account balance first
+		// steps for updating receiver account balance
+		var trError error
+		result.ToAccount, result.FromAccount, trError = addMoney(ctx, q, transfer.ToAccountID, transfer.Amount, transfer.FromAccountID, -transfer.Amount)
+		if trError != nil {
+			return trError // the transaction will be rolled back if this error occurs
+		}
+	}
+
+	// the accounts are locked by the balance updates, so these checks can't race with another transfer
+	// returning an error rolls the whole transfer back
+	if err := checkTransferAccounts(*result); err != nil {
+		return err
+	}
+	return checkTransferLimits(ctx, q, transfer)
+}
+
+// CreateAccountTx creates a new account, records an audit event and an AccountCreated event for it
+// within a single database transaction
+// a non zero arg.Balance is paid by the equity account of the currency, see openAccount, so that the opening
+// balance is in the ledger like any later change of the balance
+// parameter ctx is the context, it may carry the AuditInfo of the caller
+// parameter arg is the account to create
+// returns the created account
+func (store *SQLStore) CreateAccountTx(ctx context.Context, arg CreateAccountParams) (Account, error) {
+	ctx, span := startSpan(ctx, "CreateAccountTx")
+	defer span.End()
+
+	var account Account
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		account, err = q.CreateAccount(ctx, CreateAccountParams{Owner: arg.Owner, Currency: arg.Currency})
+		if err != nil {
+			return err
+		}
+
+		var opening TransferTxResult
+		if arg.Balance != 0 {
+			opening, err = openAccount(ctx, q, account, arg.Balance)
+			if err != nil {
+				return err
 			}
+			account = opening.ToAccount
 		}
-		return nil
-	}) // this block does the job of creating the transfer record
 
+		err = recordAudit(ctx, q, AuditActionAccountCreate, AuditResourceAccount, account.ID, nil, account)
+		if err != nil {
+			return err
+		}
+
+		err = enqueueEvent(ctx, q, EventAccountCreated, AuditResourceAccount, account.ID, AccountCreatedEvent{Account: account})
+		if err != nil || arg.Balance == 0 {
+			return err
+		}
+
+		err = recordAudit(ctx, q, AuditActionTransferCreate, AuditResourceTransfer, opening.Transfer.ID, nil, opening)
+		if err != nil {
+			return err
+		}
+		return enqueueTransferEvents(ctx, q, opening)
+	})
+
+	return account, err
+}
+
+// openAccount pays the opening balance of a new account from the equity account of its currency
+// the checks of TransferTx don't apply: the equity account has no money of its own, its balance goes below zero
+func openAccount(ctx context.Context, q *Queries, account Account, balance int64) (TransferTxResult, error) {
+	equity, err := equityAccount(ctx, q, account.Currency)
+	if err != nil {
+		return TransferTxResult{}, err
+	}
+
+	var result TransferTxResult
+	result.Transfer, err = q.CreateOpeningTransfer(ctx, CreateOpeningTransferParams{
+		FromAccountID: equity.ID,
+		ToAccountID:   account.ID,
+		Amount:        balance,
+	})
+	if err != nil {
+		return TransferTxResult{}, err
+	}
+
+	result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:  equity.ID,
+		Amount:     -balance,
+		TransferID: &result.Transfer.ID,
+	})
+	if err != nil {
+		return TransferTxResult{}, err
+	}
+	result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:  account.ID,
+		Amount:     balance,
+		TransferID: &result.Transfer.ID,
+	})
+	if err != nil {
+		return TransferTxResult{}, err
+	}
+
+	// no other transaction can lock the new account, so locking it before the equity account can't deadlock
+	result.ToAccount, result.FromAccount, err = addMoney(ctx, q, account.ID, balance, equity.ID, -balance)
 	return result, err
 }
 
+// equityAccount returns the equity account of currency, it is created with the first opening balance in the currency
+func equityAccount(ctx context.Context, q *Queries, currency string) (Account, error) {
+	equity, err := q.GetEquityAccount(ctx, currency)
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return equity, err
+	}
+
+	if err := q.CreateEquityAccount(ctx, currency); err != nil {
+		return Account{}, err
+	}
+	return q.GetEquityAccount(ctx, currency)
+}
+
+// checkTransferAccounts makes sure neither account is an equity account, both are active and the sender didn't go below zero
+func checkTransferAccounts(result TransferTxResult) error {
+	for _, account := range []Account{result.FromAccount, result.ToAccount} {
+		if account.Kind == AccountKindEquity {
+			return newAccountError(ErrEquityAccount, account.ID)
+		}
+		if account.Status != AccountStatusActive {
+			return newAccountError(ErrAccountFrozen, account.ID)
+		}
+	}
+
+	if result.FromAccount.Balance < 0 {
+		return newAccountError(ErrInsufficientFunds, result.FromAccount.ID)
+	}
+	return nil
+}
+
 func addMoney(
 	ctx context.Context,
 	q *Queries,
@@ -0,0 +1,34 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simple-bank/db2/migration"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/db2/storetest"
+	"github.com/techschool/simple-bank/fraud"
+)
+
+// the suite lives in its own package, which imports db, so it runs from this external test package
+// TestMain of package db has already checked that the database is up.
+// The suite leaves its accounts, transfers and alerts behind, it runs in a database of its own,
+// migrated for the run and dropped with everything in it afterwards
+func TestStoreConformance(t *testing.T) {
+	source := db.CreateScratchDatabase(t)
+
+	m, err := migration.New(source)
+	require.NoError(t, err)
+	require.NoError(t, migration.Up(m))
+	m.Close()
+
+	pool, err := pgxpool.New(context.Background(), source)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	storetest.Run(t, func(t *testing.T, screener fraud.Screener) db.Store {
+		return db.NewStore(pool, db.WithScreener(screener))
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
+	"github.com/jackc/pgx/v5"
 	"github.com/stretchr/testify/require"
 )
 
@@ -11,7 +13,8 @@ func TestTransferTx(t *testing.T) {
 	store := NewStore(testDB) // create a new store instance
 
 	// Arrange: Prepare test data
-	account1 := createRandomAccount(t)
+	// account1 needs enough money for all the transfers, overdrafts are rejected
+	account1 := createFundedAccount(t, 1000)
 	account2 := createRandomAccount(t)
 	// fmt.Println(">> before:", account1.Balance, account2.Balance)
 	// run n concurrent transfer transactions
@@ -118,13 +121,7 @@ func TestTransferTx(t *testing.T) {
 	require.Equal(t, account2.Balance + int64(n) * amount, updatedAccount2.Balance)
 
 	// Clean up all accounts from the database (including any from previous test runs)
-	// Must delete child records first due to foreign key constraints
-	_, err = testDB.Exec("DELETE FROM entries")
-	require.NoError(t, err)
-	_, err = testDB.Exec("DELETE FROM transfers")
-	require.NoError(t, err)
-	_, err = testDB.Exec("DELETE FROM accounts")
-	require.NoError(t, err)
+	deleteAllAccounts(t)
 
 	verifyNoAccountExists(t)
 
@@ -135,8 +132,9 @@ func TestTransferTxDeadlock(t *testing.T) {
 	store := NewStore(testDB) // create a new store instance
 
 	// Arrange: Prepare test data
-	account1 := createRandomAccount(t)
-	account2 := createRandomAccount(t)
+	// both accounts send money, so both need enough to never go below zero whatever the order of the transfers
+	account1 := createFundedAccount(t, 1000)
+	account2 := createFundedAccount(t, 1000)
 	fmt.Println(">> before:", account1.Balance, account2.Balance)
 	// run n concurrent transfer transactions
 	n := 10 // run 10 concurrent transactions
@@ -185,12 +183,115 @@ func TestTransferTxDeadlock(t *testing.T) {
 	require.Equal(t, account2.Balance, updatedAccount2.Balance)
 
 	// Clean up all accounts from the database (including any from previous test runs)
-	// Must delete child records first due to foreign key constraints
-	_, err = testDB.Exec("DELETE FROM entries")
+	deleteAllAccounts(t)
+	verifyNoAccountExists(t);
+}
+// under serializable isolation, concurrent transfers between the same accounts fail with serialization errors
+// or deadlocks, execTx must retry them until every transfer goes through
+func TestTransferTxSerializableRetries(t *testing.T) {
+	store := NewStore(testDB).(*SQLStore)
+	store.retry = RetryPolicy{MaxAttempts: 50, BaseDelay: time.Millisecond, MaxDelay: 50 * time.Millisecond}
+
+	account1 := createFundedAccount(t, 1000)
+	account2 := createFundedAccount(t, 1000)
+
+	n := 40
+	amount := int64(10)
+	ctx := WithTxOptions(context.Background(), TxOptions{Isolation: pgx.Serializable})
+
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		fromAccountID := account1.ID
+		toAccountID := account2.ID
+		if i%2 == 1 { // half of the transfers go in the opposite direction
+			fromAccountID = account2.ID
+			toAccountID = account1.ID
+		}
+
+		go func() {
+			_, err := store.TransferTx(ctx, TransferTxParams{
+				FromAccountID: fromAccountID,
+				ToAccountID:   toAccountID,
+				Amount:        amount,
+			})
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	// as many transfers went each way, the balances are unchanged
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	updatedAccount2, err := testQueries.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+	require.Equal(t, account2.Balance, updatedAccount2.Balance)
+
+	deleteAllAccounts(t)
+}
+
+// createFundedAccount creates a random account holding at least balance
+func createFundedAccount(t *testing.T, balance int64) Account {
+	account := createRandomAccount(t)
+
+	account, err := testQueries.AddAccountBalance(context.Background(), AddAccountBalanceParams{
+		ID:     account.ID,
+		Amount: balance,
+	})
+	require.NoError(t, err)
+	return account
+}
+
+func TestTransferTxInsufficientFunds(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        account1.Balance + 1,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	// the transaction is rolled back, neither balance changed
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+
+	updatedAccount2, err := testQueries.GetAccount(context.Background(), account2.ID)
 	require.NoError(t, err)
-	_, err = testDB.Exec("DELETE FROM transfers")
+	require.Equal(t, account2.Balance, updatedAccount2.Balance)
+}
+
+func TestTransferTxAccountFrozen(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createFundedAccount(t, 100)
+	account2 := createRandomAccount(t)
+
+	_, err := testQueries.UpdateAccountStatus(context.Background(), UpdateAccountStatusParams{
+		ID:     account2.ID,
+		Status: AccountStatusFrozen,
+	})
 	require.NoError(t, err)
-	_, err = testDB.Exec("DELETE FROM accounts")
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	var dbErr *Error
+	require.ErrorAs(t, err, &dbErr)
+	require.Equal(t, account2.ID, dbErr.Details["account_id"])
+
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
 	require.NoError(t, err)
-	verifyNoAccountExists(t);
-}
\ No newline at end of file
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+}
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the spans created by this package
+const tracerName = "github.com/techschool/simple-bank/db2/sqlc"
+
+// attributes of the statement spans, following the OpenTelemetry database conventions
+var (
+	dbSystemAttribute = attribute.String("db.system", "postgresql")
+)
+
+// tracer returns the tracer of the package
+// it is looked up on every call, so a provider installed after the store was created is still used
+func tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// startSpan starts a span named name as a child of the span in ctx
+// the Tx methods start one before execTx, so that the statements of the transaction become its children
+// and execTx records the outcome on it
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return tracer().Start(ctx, name)
+}
+
+// recordSpanError marks span as failed with err, if any
+func recordSpanError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// endSpan records err on span and ends it
+func endSpan(span trace.Span, err error) {
+	recordSpanError(span, err)
+	span.End()
+}
+
+// tracedDBTX wraps a DBTX so that every statement gets its own span, named after the sqlc query
+type tracedDBTX struct {
+	db DBTX
+}
+
+// traceDBTX returns db wrapped in a tracedDBTX
+func traceDBTX(db DBTX) DBTX {
+	return tracedDBTX{db: db}
+}
+
+func (t tracedDBTX) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	ctx, span := startStatementSpan(ctx, query)
+	tag, err := t.db.Exec(ctx, query, args...)
+	endSpan(span, err)
+	return tag, err
+}
+
+func (t tracedDBTX) Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+	ctx, span := startStatementSpan(ctx, query)
+	rows, err := t.db.Query(ctx, query, args...)
+	endSpan(span, err) // the span covers the query, not the iteration over the rows done by the caller
+	return rows, err
+}
+
+func (t tracedDBTX) QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row {
+	ctx, span := startStatementSpan(ctx, query)
+	return tracedRow{row: t.db.QueryRow(ctx, query, args...), span: span}
+}
+
+// tracedRow ends the span of a QueryRow statement once the row is scanned, pgx only reports errors then
+type tracedRow struct {
+	row  pgx.Row
+	span trace.Span
+}
+
+func (r tracedRow) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		r.span.End() // not finding a row is an answer, not a failure of the statement
+		return err
+	}
+	endSpan(r.span, err)
+	return err
+}
+
+// startStatementSpan starts the span of one statement
+// the arguments are never recorded, they contain account ids, amounts and webhook secrets
+func startStatementSpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	return tracer().Start(ctx, queryName(query),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(dbSystemAttribute, attribute.String("db.statement", query)),
+	)
+}
+
+// queryName extracts the name of a sqlc query from the comment sqlc puts in front of it,
+// e.g. "-- name: GetAccount :one" gives "GetAccount"
+func queryName(query string) string {
+	const prefix = "-- name: "
+	if !strings.HasPrefix(query, prefix) {
+		return "query"
+	}
+
+	name, _, _ := strings.Cut(strings.TrimPrefix(query, prefix), " ")
+	return name
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestQueryName(t *testing.T) {
+	require.Equal(t, "GetAccount", queryName(getAccount))
+	require.Equal(t, "AddAccountBalance", queryName(addAccountBalance))
+	require.Equal(t, "query", queryName("SELECT 1"))
+}
+
+func TestTransferTxSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	store := NewStore(testDB)
+	account1 := createFundedAccount(t, 100)
+	account2 := createRandomAccount(t)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	spans := exporter.GetSpans()
+	var parent tracetest.SpanStub
+	for _, span := range spans {
+		if span.Name == "TransferTx" {
+			parent = span
+		}
+	}
+	require.Equal(t, "TransferTx", parent.Name)
+
+	// every statement of the transaction is a child of the TransferTx span
+	children := map[string]int{}
+	for _, span := range spans {
+		if span.Parent.SpanID() == parent.SpanContext.SpanID() {
+			children[span.Name]++
+		}
+	}
+	require.Equal(t, 1, children["CreateTransfer"])
+	require.Equal(t, 2, children["CreateEntry"])
+	require.Equal(t, 2, children["AddAccountBalance"])
+	require.Equal(t, 1, children["CreateAuditEvent"])
+}
@@ -7,8 +7,96 @@ package db
 
 import (
 	"context"
+	"time"
 )
 
+const createOpeningTransfer = `-- name: CreateOpeningTransfer :one
+INSERT INTO transfers (
+  from_account_id,
+  to_account_id,
+  amount,
+  aml_analyzed_at
+) VALUES (
+  $1, $2, $3, now()
+) RETURNING id, from_account_id, to_account_id, amount, created_at, status, screening_rule, screening_reason, reviewed_by, reviewed_at, review_note, aml_analyzed_at
+`
+
+type CreateOpeningTransferParams struct {
+	FromAccountID int64 `json:"from_account_id"`
+	ToAccountID   int64 `json:"to_account_id"`
+	Amount        int64 `json:"amount"`
+}
+
+// the opening balance of an account, paid by the equity account of its currency
+// the AML analyzer skips it, no customer sent the money
+func (q *Queries) CreateOpeningTransfer(ctx context.Context, arg CreateOpeningTransferParams) (Transfer, error) {
+	row := q.db.QueryRow(ctx, createOpeningTransfer, arg.FromAccountID, arg.ToAccountID, arg.Amount)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.Status,
+		&i.ScreeningRule,
+		&i.ScreeningReason,
+		&i.ReviewedBy,
+		&i.ReviewedAt,
+		&i.ReviewNote,
+		&i.AmlAnalyzedAt,
+	)
+	return i, err
+}
+
+const createPendingTransfer = `-- name: CreatePendingTransfer :one
+INSERT INTO transfers (
+  from_account_id,
+  to_account_id,
+  amount,
+  status,
+  screening_rule,
+  screening_reason
+) VALUES (
+  $1, $2, $3, 'pending_review', $4, $5
+) RETURNING id, from_account_id, to_account_id, amount, created_at, status, screening_rule, screening_reason, reviewed_by, reviewed_at, review_note, aml_analyzed_at
+`
+
+type CreatePendingTransferParams struct {
+	FromAccountID   int64  `json:"from_account_id"`
+	ToAccountID     int64  `json:"to_account_id"`
+	Amount          int64  `json:"amount"`
+	ScreeningRule   string `json:"screening_rule"`
+	ScreeningReason string `json:"screening_reason"`
+}
+
+// a transfer sent to review by the fraud screening, no entries are written and no money moves until it is approved
+func (q *Queries) CreatePendingTransfer(ctx context.Context, arg CreatePendingTransferParams) (Transfer, error) {
+	row := q.db.QueryRow(ctx, createPendingTransfer,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.ScreeningRule,
+		arg.ScreeningReason,
+	)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.Status,
+		&i.ScreeningRule,
+		&i.ScreeningReason,
+		&i.ReviewedBy,
+		&i.ReviewedAt,
+		&i.ReviewNote,
+		&i.AmlAnalyzedAt,
+	)
+	return i, err
+}
+
 const createTransfer = `-- name: CreateTransfer :one
 INSERT INTO transfers (
   from_account_id,
@@ -16,7 +104,7 @@ INSERT INTO transfers (
   amount
 ) VALUES (
   $1, $2, $3
-) RETURNING id, from_account_id, to_account_id, amount, created_at
+) RETURNING id, from_account_id, to_account_id, amount, created_at, status, screening_rule, screening_reason, reviewed_by, reviewed_at, review_note, aml_analyzed_at
 `
 
 type CreateTransferParams struct {
@@ -26,7 +114,7 @@ type CreateTransferParams struct {
 }
 
 func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
-	row := q.db.QueryRowContext(ctx, createTransfer, arg.FromAccountID, arg.ToAccountID, arg.Amount)
+	row := q.db.QueryRow(ctx, createTransfer, arg.FromAccountID, arg.ToAccountID, arg.Amount)
 	var i Transfer
 	err := row.Scan(
 		&i.ID,
@@ -34,17 +122,24 @@ func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams)
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.Status,
+		&i.ScreeningRule,
+		&i.ScreeningReason,
+		&i.ReviewedBy,
+		&i.ReviewedAt,
+		&i.ReviewNote,
+		&i.AmlAnalyzedAt,
 	)
 	return i, err
 }
 
 const getTransfer = `-- name: GetTransfer :one
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, status, screening_rule, screening_reason, reviewed_by, reviewed_at, review_note, aml_analyzed_at FROM transfers
 WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
-	row := q.db.QueryRowContext(ctx, getTransfer, id)
+	row := q.db.QueryRow(ctx, getTransfer, id)
 	var i Transfer
 	err := row.Scan(
 		&i.ID,
@@ -52,12 +147,160 @@ func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.Status,
+		&i.ScreeningRule,
+		&i.ScreeningReason,
+		&i.ReviewedBy,
+		&i.ReviewedAt,
+		&i.ReviewNote,
+		&i.AmlAnalyzedAt,
 	)
 	return i, err
 }
 
+const getTransferForUpdate = `-- name: GetTransferForUpdate :one
+SELECT id, from_account_id, to_account_id, amount, created_at, status, screening_rule, screening_reason, reviewed_by, reviewed_at, review_note, aml_analyzed_at FROM transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+// locks the transfer, so that two tellers can't review it at the same time
+func (q *Queries) GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error) {
+	row := q.db.QueryRow(ctx, getTransferForUpdate, id)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.Status,
+		&i.ScreeningRule,
+		&i.ScreeningReason,
+		&i.ReviewedBy,
+		&i.ReviewedAt,
+		&i.ReviewNote,
+		&i.AmlAnalyzedAt,
+	)
+	return i, err
+}
+
+const hasCompletedTransfer = `-- name: HasCompletedTransfer :one
+SELECT EXISTS (
+  SELECT 1 FROM transfers
+  WHERE from_account_id = $1 AND to_account_id = $2 AND status = 'completed'
+)
+`
+
+type HasCompletedTransferParams struct {
+	FromAccountID int64 `json:"from_account_id"`
+	ToAccountID   int64 `json:"to_account_id"`
+}
+
+// whether the sender already paid the receiver
+func (q *Queries) HasCompletedTransfer(ctx context.Context, arg HasCompletedTransferParams) (bool, error) {
+	row := q.db.QueryRow(ctx, hasCompletedTransfer, arg.FromAccountID, arg.ToAccountID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const listPendingTransfers = `-- name: ListPendingTransfers :many
+SELECT id, from_account_id, to_account_id, amount, created_at, status, screening_rule, screening_reason, reviewed_by, reviewed_at, review_note, aml_analyzed_at FROM transfers
+WHERE status = 'pending_review'
+ORDER BY created_at, id
+LIMIT $1
+OFFSET $2
+`
+
+type ListPendingTransfersParams struct {
+	Limit  int32 `json:"limit"`
+	Offset int32 `json:"offset"`
+}
+
+// the review queue, oldest transfer first
+func (q *Queries) ListPendingTransfers(ctx context.Context, arg ListPendingTransfersParams) ([]Transfer, error) {
+	rows, err := q.db.Query(ctx, listPendingTransfers, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transfer{}
+	for rows.Next() {
+		var i Transfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.Status,
+			&i.ScreeningRule,
+			&i.ScreeningReason,
+			&i.ReviewedBy,
+			&i.ReviewedAt,
+			&i.ReviewNote,
+			&i.AmlAnalyzedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRecentOutgoingTransfers = `-- name: ListRecentOutgoingTransfers :many
+SELECT id, from_account_id, to_account_id, amount, created_at, status, screening_rule, screening_reason, reviewed_by, reviewed_at, review_note, aml_analyzed_at FROM transfers
+WHERE from_account_id = $1
+  AND status <> 'rejected'
+  AND created_at >= $2::timestamptz
+ORDER BY created_at DESC, id DESC
+`
+
+type ListRecentOutgoingTransfersParams struct {
+	AccountID int64     `json:"account_id"`
+	Since     time.Time `json:"since"`
+}
+
+// the screening history of an account, the rejected transfers left out
+func (q *Queries) ListRecentOutgoingTransfers(ctx context.Context, arg ListRecentOutgoingTransfersParams) ([]Transfer, error) {
+	rows, err := q.db.Query(ctx, listRecentOutgoingTransfers, arg.AccountID, arg.Since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transfer{}
+	for rows.Next() {
+		var i Transfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.Status,
+			&i.ScreeningRule,
+			&i.ScreeningReason,
+			&i.ReviewedBy,
+			&i.ReviewedAt,
+			&i.ReviewNote,
+			&i.AmlAnalyzedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listTransfers = `-- name: ListTransfers :many
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, status, screening_rule, screening_reason, reviewed_by, reviewed_at, review_note, aml_analyzed_at FROM transfers
 WHERE 
     from_account_id = $1 OR
     to_account_id = $2
@@ -74,7 +317,7 @@ type ListTransfersParams struct {
 }
 
 func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error) {
-	rows, err := q.db.QueryContext(ctx, listTransfers,
+	rows, err := q.db.Query(ctx, listTransfers,
 		arg.FromAccountID,
 		arg.ToAccountID,
 		arg.Limit,
@@ -93,16 +336,62 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 			&i.ToAccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.Status,
+			&i.ScreeningRule,
+			&i.ScreeningReason,
+			&i.ReviewedBy,
+			&i.ReviewedAt,
+			&i.ReviewNote,
+			&i.AmlAnalyzedAt,
 		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 	return items, nil
 }
+
+const reviewTransfer = `-- name: ReviewTransfer :one
+UPDATE transfers SET
+  status = $1,
+  reviewed_by = $2,
+  review_note = $3,
+  reviewed_at = now()
+WHERE id = $4
+RETURNING id, from_account_id, to_account_id, amount, created_at, status, screening_rule, screening_reason, reviewed_by, reviewed_at, review_note, aml_analyzed_at
+`
+
+type ReviewTransferParams struct {
+	Status     string `json:"status"`
+	ReviewedBy string `json:"reviewed_by"`
+	ReviewNote string `json:"review_note"`
+	ID         int64  `json:"id"`
+}
+
+func (q *Queries) ReviewTransfer(ctx context.Context, arg ReviewTransferParams) (Transfer, error) {
+	row := q.db.QueryRow(ctx, reviewTransfer,
+		arg.Status,
+		arg.ReviewedBy,
+		arg.ReviewNote,
+		arg.ID,
+	)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.Status,
+		&i.ScreeningRule,
+		&i.ScreeningReason,
+		&i.ReviewedBy,
+		&i.ReviewedAt,
+		&i.ReviewNote,
+		&i.AmlAnalyzedAt,
+	)
+	return i, err
+}
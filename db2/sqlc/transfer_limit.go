@@ -0,0 +1,172 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// the limits a transfer can exceed, reported in the "limit" detail of ErrLimitExceeded
+const (
+	LimitSingleAmount = "single_amount"
+	LimitDailyAmount  = "daily_amount"
+	LimitDailyCount   = "daily_count"
+)
+
+// DefaultLimitTier is the tier of new accounts, created by the migration
+const DefaultLimitTier = "standard"
+
+const (
+	AuditActionLimitTierUpdate     = "limit_tier.update"
+	AuditActionAccountLimitsUpdate = "account.limits.update"
+
+	AuditResourceLimitTier = "limit_tier"
+)
+
+// checkTransferLimits fails with ErrLimitExceeded if the transfer, already written with q as completed, takes the
+// sender over one of its limits
+// it must run after the balance update of the sender: the row lock serialises the transfers of the account, so
+// the daily totals include every transfer committed before this one
+// the transfer counts on the day it completed: an approved transfer on the day of its approval, not of its creation
+func checkTransferLimits(ctx context.Context, q *Queries, transfer Transfer) error {
+	limits, err := q.GetEffectiveTransferLimits(ctx, transfer.FromAccountID)
+	if err != nil {
+		return err
+	}
+
+	daily, err := q.GetDailyOutgoingTransfers(ctx, GetDailyOutgoingTransfersParams{
+		FromAccountID: transfer.FromAccountID,
+		Day:           transfer.CompletedAt(),
+	})
+	if err != nil {
+		return err
+	}
+
+	remainingAmount := max(0, limits.MaxDailyAmount-(daily.TotalAmount-transfer.Amount))
+	remainingCount := max(0, limits.MaxDailyCount-(daily.TotalCount-1))
+
+	var exceeded string
+	switch {
+	case transfer.Amount > limits.MaxSingleAmount:
+		exceeded = LimitSingleAmount
+	case daily.TotalCount > limits.MaxDailyCount:
+		exceeded = LimitDailyCount
+	case daily.TotalAmount > limits.MaxDailyAmount:
+		exceeded = LimitDailyAmount
+	default:
+		return nil
+	}
+
+	// the allowance left to the client, the transfer itself not counted since it is rolled back
+	return &Error{Kind: ErrLimitExceeded, Details: map[string]any{
+		"account_id":             transfer.FromAccountID,
+		"limit":                  exceeded,
+		"max_single_amount":      limits.MaxSingleAmount,
+		"remaining_daily_amount": remainingAmount,
+		"remaining_daily_count":  remainingCount,
+	}}
+}
+
+// UpsertTransferLimitTierTx creates or replaces a limit tier and records the change in the audit log
+func (store *SQLStore) UpsertTransferLimitTierTx(ctx context.Context, arg UpsertTransferLimitTierParams) (TransferLimitTier, error) {
+	ctx, span := startSpan(ctx, "UpsertTransferLimitTierTx")
+	defer span.End()
+
+	var tier TransferLimitTier
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var before any // nil for a new tier
+		previous, err := q.GetTransferLimitTier(ctx, arg.Name)
+		switch {
+		case err == nil:
+			before = previous
+		case !errors.Is(err, pgx.ErrNoRows):
+			return err
+		}
+
+		tier, err = q.UpsertTransferLimitTier(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		return recordAuditByKey(ctx, q, AuditActionLimitTierUpdate, AuditResourceLimitTier, tier.Name, before, tier)
+	})
+
+	return tier, err
+}
+
+// UpdateAccountTransferLimitsParams sets the tier of an account and its own limits
+// a nil limit uses the one of the tier
+type UpdateAccountTransferLimitsParams struct {
+	AccountID       int64  `json:"account_id"`
+	Tier            string `json:"tier"`
+	MaxSingleAmount *int64 `json:"max_single_amount"`
+	MaxDailyAmount  *int64 `json:"max_daily_amount"`
+	MaxDailyCount   *int32 `json:"max_daily_count"`
+}
+
+// accountLimits is the state of the limits of an account recorded in the audit log
+type accountLimits struct {
+	Tier      string                `json:"tier"`
+	Overrides *AccountTransferLimit `json:"overrides"`
+}
+
+// UpdateAccountTransferLimitsTx changes the tier and the limits of an account, records the change in the audit log
+// and returns the limits now in effect
+// returns ErrNotFound if the account doesn't exist and ErrForeignKeyViolation if the tier doesn't
+func (store *SQLStore) UpdateAccountTransferLimitsTx(
+	ctx context.Context,
+	arg UpdateAccountTransferLimitsParams,
+) (GetEffectiveTransferLimitsRow, error) {
+	ctx, span := startSpan(ctx, "UpdateAccountTransferLimitsTx")
+	defer span.End()
+
+	var limits GetEffectiveTransferLimitsRow
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		// locks the account, so concurrent updates of its limits are recorded one after the other
+		account, err := q.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		before := accountLimits{Tier: account.LimitTier}
+		previous, err := q.GetAccountTransferLimitOverride(ctx, arg.AccountID)
+		switch {
+		case err == nil:
+			before.Overrides = &previous
+		case !errors.Is(err, pgx.ErrNoRows):
+			return err
+		}
+
+		if _, err := q.UpdateAccountLimitTier(ctx, UpdateAccountLimitTierParams{ID: arg.AccountID, LimitTier: arg.Tier}); err != nil {
+			return err
+		}
+
+		after := accountLimits{Tier: arg.Tier}
+		if arg.MaxSingleAmount != nil || arg.MaxDailyAmount != nil || arg.MaxDailyCount != nil {
+			overrides, err := q.UpsertAccountTransferLimitOverride(ctx, UpsertAccountTransferLimitOverrideParams{
+				AccountID:       arg.AccountID,
+				MaxSingleAmount: arg.MaxSingleAmount,
+				MaxDailyAmount:  arg.MaxDailyAmount,
+				MaxDailyCount:   arg.MaxDailyCount,
+			})
+			if err != nil {
+				return err
+			}
+			after.Overrides = &overrides
+		} else if err := q.DeleteAccountTransferLimitOverride(ctx, arg.AccountID); err != nil {
+			return err
+		}
+
+		limits, err = q.GetEffectiveTransferLimits(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, AuditActionAccountLimitsUpdate, AuditResourceAccount, arg.AccountID, before, after)
+	})
+
+	return limits, err
+}
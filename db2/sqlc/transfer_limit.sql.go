@@ -0,0 +1,265 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: transfer_limit.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const deleteAccountTransferLimitOverride = `-- name: DeleteAccountTransferLimitOverride :exec
+DELETE FROM account_transfer_limits WHERE account_id = $1
+`
+
+func (q *Queries) DeleteAccountTransferLimitOverride(ctx context.Context, accountID int64) error {
+	_, err := q.db.Exec(ctx, deleteAccountTransferLimitOverride, accountID)
+	return err
+}
+
+const getAccountTransferLimitOverride = `-- name: GetAccountTransferLimitOverride :one
+SELECT account_id, max_single_amount, max_daily_amount, max_daily_count, updated_at FROM account_transfer_limits
+WHERE account_id = $1 LIMIT 1
+`
+
+func (q *Queries) GetAccountTransferLimitOverride(ctx context.Context, accountID int64) (AccountTransferLimit, error) {
+	row := q.db.QueryRow(ctx, getAccountTransferLimitOverride, accountID)
+	var i AccountTransferLimit
+	err := row.Scan(
+		&i.AccountID,
+		&i.MaxSingleAmount,
+		&i.MaxDailyAmount,
+		&i.MaxDailyCount,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getDailyOutgoingTransfers = `-- name: GetDailyOutgoingTransfers :one
+SELECT
+  COALESCE(SUM(amount), 0)::bigint AS total_amount,
+  COUNT(*)::int AS total_count
+FROM transfers
+WHERE from_account_id = $1
+  AND status = 'completed'
+  AND COALESCE(reviewed_at, created_at) >= date_trunc('day', $2::timestamptz AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'
+  AND COALESCE(reviewed_at, created_at) < (date_trunc('day', $2::timestamptz AT TIME ZONE 'UTC') + interval '1 day') AT TIME ZONE 'UTC'
+`
+
+type GetDailyOutgoingTransfersParams struct {
+	FromAccountID int64     `json:"from_account_id"`
+	Day           time.Time `json:"day"`
+}
+
+type GetDailyOutgoingTransfersRow struct {
+	TotalAmount int64 `json:"total_amount"`
+	TotalCount  int32 `json:"total_count"`
+}
+
+// the transfers sent by the account that were completed on the UTC day of sqlc.arg(day), the transfers of the
+// current transaction included. A transfer completes when it is created, or when it is approved after a review:
+// a pending transfer counts on the day it was approved, whatever the day it was made
+func (q *Queries) GetDailyOutgoingTransfers(ctx context.Context, arg GetDailyOutgoingTransfersParams) (GetDailyOutgoingTransfersRow, error) {
+	row := q.db.QueryRow(ctx, getDailyOutgoingTransfers, arg.FromAccountID, arg.Day)
+	var i GetDailyOutgoingTransfersRow
+	err := row.Scan(&i.TotalAmount, &i.TotalCount)
+	return i, err
+}
+
+const getEffectiveTransferLimits = `-- name: GetEffectiveTransferLimits :one
+SELECT
+  accounts.id AS account_id,
+  accounts.limit_tier AS tier,
+  COALESCE(overrides.max_single_amount, tiers.max_single_amount)::bigint AS max_single_amount,
+  COALESCE(overrides.max_daily_amount, tiers.max_daily_amount)::bigint AS max_daily_amount,
+  COALESCE(overrides.max_daily_count, tiers.max_daily_count)::int AS max_daily_count
+FROM accounts
+JOIN transfer_limit_tiers AS tiers ON tiers.name = accounts.limit_tier
+LEFT JOIN account_transfer_limits AS overrides ON overrides.account_id = accounts.id
+WHERE accounts.id = $1
+`
+
+type GetEffectiveTransferLimitsRow struct {
+	AccountID       int64  `json:"account_id"`
+	Tier            string `json:"tier"`
+	MaxSingleAmount int64  `json:"max_single_amount"`
+	MaxDailyAmount  int64  `json:"max_daily_amount"`
+	MaxDailyCount   int32  `json:"max_daily_count"`
+}
+
+// the limits of the tier of the account, replaced by the overrides of the account where it has some
+func (q *Queries) GetEffectiveTransferLimits(ctx context.Context, id int64) (GetEffectiveTransferLimitsRow, error) {
+	row := q.db.QueryRow(ctx, getEffectiveTransferLimits, id)
+	var i GetEffectiveTransferLimitsRow
+	err := row.Scan(
+		&i.AccountID,
+		&i.Tier,
+		&i.MaxSingleAmount,
+		&i.MaxDailyAmount,
+		&i.MaxDailyCount,
+	)
+	return i, err
+}
+
+const getTransferLimitTier = `-- name: GetTransferLimitTier :one
+SELECT name, max_single_amount, max_daily_amount, max_daily_count, updated_at FROM transfer_limit_tiers
+WHERE name = $1 LIMIT 1
+`
+
+func (q *Queries) GetTransferLimitTier(ctx context.Context, name string) (TransferLimitTier, error) {
+	row := q.db.QueryRow(ctx, getTransferLimitTier, name)
+	var i TransferLimitTier
+	err := row.Scan(
+		&i.Name,
+		&i.MaxSingleAmount,
+		&i.MaxDailyAmount,
+		&i.MaxDailyCount,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const listTransferLimitTiers = `-- name: ListTransferLimitTiers :many
+SELECT name, max_single_amount, max_daily_amount, max_daily_count, updated_at FROM transfer_limit_tiers
+ORDER BY name
+`
+
+func (q *Queries) ListTransferLimitTiers(ctx context.Context) ([]TransferLimitTier, error) {
+	rows, err := q.db.Query(ctx, listTransferLimitTiers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []TransferLimitTier{}
+	for rows.Next() {
+		var i TransferLimitTier
+		if err := rows.Scan(
+			&i.Name,
+			&i.MaxSingleAmount,
+			&i.MaxDailyAmount,
+			&i.MaxDailyCount,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateAccountLimitTier = `-- name: UpdateAccountLimitTier :one
+UPDATE accounts SET limit_tier = $1
+WHERE id = $2
+RETURNING id, owner, balance, currency, created_at, status, limit_tier, kind
+`
+
+type UpdateAccountLimitTierParams struct {
+	LimitTier string `json:"limit_tier"`
+	ID        int64  `json:"id"`
+}
+
+func (q *Queries) UpdateAccountLimitTier(ctx context.Context, arg UpdateAccountLimitTierParams) (Account, error) {
+	row := q.db.QueryRow(ctx, updateAccountLimitTier, arg.LimitTier, arg.ID)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.Status,
+		&i.LimitTier,
+		&i.Kind,
+	)
+	return i, err
+}
+
+const upsertAccountTransferLimitOverride = `-- name: UpsertAccountTransferLimitOverride :one
+INSERT INTO account_transfer_limits (
+  account_id,
+  max_single_amount,
+  max_daily_amount,
+  max_daily_count
+) VALUES (
+  $1, $2, $3, $4
+)
+ON CONFLICT (account_id) DO UPDATE SET
+  max_single_amount = EXCLUDED.max_single_amount,
+  max_daily_amount = EXCLUDED.max_daily_amount,
+  max_daily_count = EXCLUDED.max_daily_count,
+  updated_at = now()
+RETURNING account_id, max_single_amount, max_daily_amount, max_daily_count, updated_at
+`
+
+type UpsertAccountTransferLimitOverrideParams struct {
+	AccountID       int64  `json:"account_id"`
+	MaxSingleAmount *int64 `json:"max_single_amount"`
+	MaxDailyAmount  *int64 `json:"max_daily_amount"`
+	MaxDailyCount   *int32 `json:"max_daily_count"`
+}
+
+// a NULL column keeps the limit of the tier
+func (q *Queries) UpsertAccountTransferLimitOverride(ctx context.Context, arg UpsertAccountTransferLimitOverrideParams) (AccountTransferLimit, error) {
+	row := q.db.QueryRow(ctx, upsertAccountTransferLimitOverride,
+		arg.AccountID,
+		arg.MaxSingleAmount,
+		arg.MaxDailyAmount,
+		arg.MaxDailyCount,
+	)
+	var i AccountTransferLimit
+	err := row.Scan(
+		&i.AccountID,
+		&i.MaxSingleAmount,
+		&i.MaxDailyAmount,
+		&i.MaxDailyCount,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const upsertTransferLimitTier = `-- name: UpsertTransferLimitTier :one
+INSERT INTO transfer_limit_tiers (
+  name,
+  max_single_amount,
+  max_daily_amount,
+  max_daily_count
+) VALUES (
+  $1, $2, $3, $4
+)
+ON CONFLICT (name) DO UPDATE SET
+  max_single_amount = EXCLUDED.max_single_amount,
+  max_daily_amount = EXCLUDED.max_daily_amount,
+  max_daily_count = EXCLUDED.max_daily_count,
+  updated_at = now()
+RETURNING name, max_single_amount, max_daily_amount, max_daily_count, updated_at
+`
+
+type UpsertTransferLimitTierParams struct {
+	Name            string `json:"name"`
+	MaxSingleAmount int64  `json:"max_single_amount"`
+	MaxDailyAmount  int64  `json:"max_daily_amount"`
+	MaxDailyCount   int32  `json:"max_daily_count"`
+}
+
+func (q *Queries) UpsertTransferLimitTier(ctx context.Context, arg UpsertTransferLimitTierParams) (TransferLimitTier, error) {
+	row := q.db.QueryRow(ctx, upsertTransferLimitTier,
+		arg.Name,
+		arg.MaxSingleAmount,
+		arg.MaxDailyAmount,
+		arg.MaxDailyCount,
+	)
+	var i TransferLimitTier
+	err := row.Scan(
+		&i.Name,
+		&i.MaxSingleAmount,
+		&i.MaxDailyAmount,
+		&i.MaxDailyCount,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
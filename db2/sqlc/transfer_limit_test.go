@@ -0,0 +1,156 @@
+package db
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// setAccountLimits gives the account its own limits
+func setAccountLimits(t *testing.T, accountID int64, maxSingle int64, maxDaily int64, maxCount int32) {
+	store := NewStore(testDB)
+
+	_, err := store.UpdateAccountTransferLimitsTx(context.Background(), UpdateAccountTransferLimitsParams{
+		AccountID:       accountID,
+		Tier:            DefaultLimitTier,
+		MaxSingleAmount: ptr(maxSingle),
+		MaxDailyAmount:  ptr(maxDaily),
+		MaxDailyCount:   ptr(maxCount),
+	})
+	require.NoError(t, err)
+}
+
+func TestTransferTxLimits(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createFundedAccount(t, 1000)
+	account2 := createRandomAccount(t)
+	setAccountLimits(t, account1.ID, 50, 100, 3)
+
+	transfer := func(amount int64) error {
+		_, err := store.TransferTx(context.Background(), TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        amount,
+		})
+		return err
+	}
+
+	requireLimit := func(err error, limit string, remainingAmount int64, remainingCount int32) {
+		require.ErrorIs(t, err, ErrLimitExceeded)
+		var dbErr *Error
+		require.ErrorAs(t, err, &dbErr)
+		require.Equal(t, limit, dbErr.Details["limit"])
+		require.Equal(t, remainingAmount, dbErr.Details["remaining_daily_amount"])
+		require.Equal(t, remainingCount, dbErr.Details["remaining_daily_count"])
+	}
+
+	requireLimit(transfer(51), LimitSingleAmount, 100, 3)
+
+	require.NoError(t, transfer(50))
+	require.NoError(t, transfer(40))
+	requireLimit(transfer(20), LimitDailyAmount, 10, 1)
+
+	require.NoError(t, transfer(10))
+	requireLimit(transfer(1), LimitDailyCount, 0, 0)
+
+	// the rejected transfers were rolled back
+	daily, err := testQueries.GetDailyOutgoingTransfers(context.Background(), GetDailyOutgoingTransfersParams{FromAccountID: account1.ID, Day: time.Now()})
+	require.NoError(t, err)
+	require.Equal(t, int64(100), daily.TotalAmount)
+	require.Equal(t, int32(3), daily.TotalCount)
+}
+
+func TestTransferTxLimitsConcurrent(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createFundedAccount(t, 1000)
+	account2 := createRandomAccount(t)
+	setAccountLimits(t, account1.ID, 10, 50, 100)
+
+	// the balance update locks the sender, so exactly 5 of the transfers fit in the daily amount
+	n := 10
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.TransferTx(context.Background(), TransferTxParams{
+				FromAccountID: account1.ID,
+				ToAccountID:   account2.ID,
+				Amount:        10,
+			})
+			errs <- err
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err == nil {
+			succeeded++
+			continue
+		}
+		require.ErrorIs(t, err, ErrLimitExceeded)
+	}
+	require.Equal(t, 5, succeeded)
+}
+
+func TestUpdateAccountTransferLimitsTx(t *testing.T) {
+	store := NewStore(testDB)
+	ctx := WithAuditInfo(context.Background(), AuditInfo{Actor: "admin"})
+
+	account := createRandomAccount(t)
+	require.Equal(t, DefaultLimitTier, account.LimitTier)
+
+	tierName := "test" + strconv.FormatInt(account.ID, 10)
+	tier, err := store.UpsertTransferLimitTierTx(ctx, UpsertTransferLimitTierParams{
+		Name:            tierName,
+		MaxSingleAmount: 100,
+		MaxDailyAmount:  1000,
+		MaxDailyCount:   10,
+	})
+	require.NoError(t, err)
+
+	// the tier applies, except for the limit the account overrides
+	limits, err := store.UpdateAccountTransferLimitsTx(ctx, UpdateAccountTransferLimitsParams{
+		AccountID:     account.ID,
+		Tier:          tier.Name,
+		MaxDailyCount: ptr(int32(3)),
+	})
+	require.NoError(t, err)
+	require.Equal(t, tier.Name, limits.Tier)
+	require.Equal(t, tier.MaxSingleAmount, limits.MaxSingleAmount)
+	require.Equal(t, tier.MaxDailyAmount, limits.MaxDailyAmount)
+	require.Equal(t, int32(3), limits.MaxDailyCount)
+
+	// without overrides the tier applies entirely
+	limits, err = store.UpdateAccountTransferLimitsTx(ctx, UpdateAccountTransferLimitsParams{
+		AccountID: account.ID,
+		Tier:      tier.Name,
+	})
+	require.NoError(t, err)
+	require.Equal(t, tier.MaxDailyCount, limits.MaxDailyCount)
+
+	events, err := testQueries.ListAuditEvents(context.Background(), ListAuditEventsParams{
+		Action:     ptr(AuditActionAccountLimitsUpdate),
+		ResourceID: ptr(strconv.FormatInt(account.ID, 10)),
+		Limit:      5,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, "admin", events[0].Actor)
+
+	_, err = store.UpdateAccountTransferLimitsTx(ctx, UpdateAccountTransferLimitsParams{
+		AccountID: account.ID,
+		Tier:      "unknown" + tierName,
+	})
+	require.ErrorIs(t, err, ErrForeignKeyViolation)
+
+	_, err = store.UpdateAccountTransferLimitsTx(ctx, UpdateAccountTransferLimitsParams{
+		AccountID: account.ID + 1000000,
+		Tier:      DefaultLimitTier,
+	})
+	require.ErrorIs(t, err, ErrNotFound)
+}
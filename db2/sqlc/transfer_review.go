@@ -0,0 +1,199 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/techschool/simple-bank/fraud"
+	"github.com/techschool/simple-bank/metrics"
+)
+
+// transfer statuses, stored in the status column of transfers
+const (
+	TransferStatusCompleted     = "completed"
+	TransferStatusPendingReview = "pending_review" // flagged by the fraud screening, waiting for a teller
+	TransferStatusRejected      = "rejected"
+)
+
+const (
+	AuditActionTransferApprove = "transfer.approve"
+	AuditActionTransferReject  = "transfer.reject"
+)
+
+// CompletedAt returns when the money of a completed transfer moved: when it was approved if it was held for review,
+// when it was created otherwise
+func (transfer Transfer) CompletedAt() time.Time {
+	if transfer.ReviewedAt != nil {
+		return *transfer.ReviewedAt
+	}
+	return transfer.CreatedAt
+}
+
+// screenTransfer asks the screener of the store about the transfer, with the history of the sender read with q
+func (store *SQLStore) screenTransfer(ctx context.Context, q *Queries, arg TransferTxParams) (fraud.Verdict, error) {
+	if store.screener == nil {
+		return fraud.Verdict{Decision: fraud.Allow}, nil
+	}
+
+	// at read committed the history is read without any lock, a burst of concurrent transfers would all see the
+	// same history and get through the velocity rules together. Locking the accounts first queues the transfers of
+	// the sender, each one reads the history once the previous one has committed
+	if err := lockTransferAccounts(ctx, q, arg); err != nil {
+		return fraud.Verdict{}, err
+	}
+
+	now := time.Now()
+	recent, err := q.ListRecentOutgoingTransfers(ctx, ListRecentOutgoingTransfersParams{
+		AccountID: arg.FromAccountID,
+		Since:     now.Add(-fraud.HistoryWindow),
+	})
+	if err != nil {
+		return fraud.Verdict{}, err
+	}
+
+	knownPayee, err := q.HasCompletedTransfer(ctx, HasCompletedTransferParams{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+	})
+	if err != nil {
+		return fraud.Verdict{}, err
+	}
+
+	history := fraud.History{KnownPayee: knownPayee}
+	for _, transfer := range recent {
+		history.Recent = append(history.Recent, fraud.PastTransfer{
+			ToAccountID: transfer.ToAccountID,
+			Amount:      transfer.Amount,
+			CreatedAt:   transfer.CreatedAt,
+		})
+	}
+
+	info := AuditInfoFromContext(ctx)
+	return store.screener.Screen(ctx, fraud.Input{
+		Transfer: fraud.Transfer{
+			FromAccountID: arg.FromAccountID,
+			ToAccountID:   arg.ToAccountID,
+			Amount:        arg.Amount,
+		},
+		History: history,
+		Caller: fraud.Caller{
+			Actor:     info.Actor,
+			RequestID: info.RequestID,
+			ClientIP:  info.ClientIP,
+		},
+		Now: now,
+	})
+}
+
+// ReviewTransferTxParams identifies the reviewed transfer, the teller is the actor of the audit info in the context
+type ReviewTransferTxParams struct {
+	TransferID int64  `json:"transfer_id"`
+	Note       string `json:"note"`
+}
+
+// ApproveTransferTx completes a transfer waiting for review: its entries are written and the money moves
+// the checks of TransferTx apply as if it was made now, if one fails the transfer stays pending
+// returns ErrNotFound if the transfer doesn't exist and ErrTransferNotPending if it was already reviewed
+func (store *SQLStore) ApproveTransferTx(ctx context.Context, arg ReviewTransferTxParams) (TransferTxResult, error) {
+	ctx, span := startSpan(ctx, "ApproveTransferTx")
+	defer span.End()
+
+	var result TransferTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		result = TransferTxResult{}
+
+		before, err := lockPendingTransfer(ctx, q, arg.TransferID)
+		if err != nil {
+			return err
+		}
+
+		result.Transfer, err = q.ReviewTransfer(ctx, ReviewTransferParams{
+			ID:         arg.TransferID,
+			Status:     TransferStatusCompleted,
+			ReviewedBy: AuditInfoFromContext(ctx).Actor,
+			ReviewNote: arg.Note,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := completeTransfer(ctx, q, &result); err != nil {
+			return err
+		}
+
+		if err := recordAudit(ctx, q, AuditActionTransferApprove, AuditResourceTransfer, arg.TransferID, before, result); err != nil {
+			return err
+		}
+		return enqueueTransferEvents(ctx, q, result)
+	})
+
+	if err == nil && result.Transfer.Status == TransferStatusCompleted {
+		metrics.ObserveTransferCompleted(result.FromAccount.Currency, result.Transfer.Amount)
+	}
+	return result, err
+}
+
+// RejectTransferTx refuses a transfer waiting for review, no money ever moved for it
+// returns ErrNotFound if the transfer doesn't exist and ErrTransferNotPending if it was already reviewed
+func (store *SQLStore) RejectTransferTx(ctx context.Context, arg ReviewTransferTxParams) (Transfer, error) {
+	ctx, span := startSpan(ctx, "RejectTransferTx")
+	defer span.End()
+
+	var transfer Transfer
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		before, err := lockPendingTransfer(ctx, q, arg.TransferID)
+		if err != nil {
+			return err
+		}
+
+		transfer, err = q.ReviewTransfer(ctx, ReviewTransferParams{
+			ID:         arg.TransferID,
+			Status:     TransferStatusRejected,
+			ReviewedBy: AuditInfoFromContext(ctx).Actor,
+			ReviewNote: arg.Note,
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, AuditActionTransferReject, AuditResourceTransfer, arg.TransferID, before, transfer)
+	})
+
+	return transfer, err
+}
+
+// lockPendingTransfer locks the transfer until the end of the transaction and checks that it waits for review
+func lockPendingTransfer(ctx context.Context, q *Queries, id int64) (Transfer, error) {
+	transfer, err := q.GetTransferForUpdate(ctx, id)
+	if err != nil {
+		return Transfer{}, err
+	}
+
+	if transfer.Status != TransferStatusPendingReview {
+		return Transfer{}, &Error{Kind: ErrTransferNotPending, Details: map[string]any{
+			"transfer_id": id,
+			"status":      transfer.Status,
+		}}
+	}
+	return transfer, nil
+}
+
+// lockTransferAccounts locks both accounts of the transfer, in the order of their ids like completeTransfer,
+// so that two transfers going opposite ways can't deadlock
+// a missing account isn't locked, the transfer then fails on its foreign key like without a screener
+func lockTransferAccounts(ctx context.Context, q *Queries, arg TransferTxParams) error {
+	ids := []int64{arg.FromAccountID, arg.ToAccountID}
+	if ids[0] > ids[1] {
+		ids[0], ids[1] = ids[1], ids[0]
+	}
+	for _, id := range ids {
+		if _, err := q.GetAccountForUpdate(ctx, id); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+	}
+	return nil
+}
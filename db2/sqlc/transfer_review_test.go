@@ -0,0 +1,187 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simple-bank/fraud"
+)
+
+// verdictScreener returns the same verdict for every transfer and keeps the last input
+type verdictScreener struct {
+	verdict fraud.Verdict
+	input   fraud.Input
+}
+
+func (screener *verdictScreener) Screen(ctx context.Context, input fraud.Input) (fraud.Verdict, error) {
+	screener.input = input
+	return screener.verdict, nil
+}
+
+func TestTransferTxScreening(t *testing.T) {
+	screener := &verdictScreener{verdict: fraud.Verdict{Decision: fraud.Allow}}
+	store := NewStore(testDB, WithScreener(screener))
+	ctx := WithAuditInfo(context.Background(), AuditInfo{Actor: "tester", ClientIP: "127.0.0.1"})
+
+	account1 := createFundedAccount(t, 1000)
+	account2 := createRandomAccount(t)
+	arg := TransferTxParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 10}
+
+	// allowed: the screener sees the caller and a payee the sender never paid
+	result, err := store.TransferTx(ctx, arg)
+	require.NoError(t, err)
+	require.Equal(t, TransferStatusCompleted, result.Transfer.Status)
+	require.Equal(t, "tester", screener.input.Caller.Actor)
+	require.False(t, screener.input.History.KnownPayee)
+	require.Empty(t, screener.input.History.Recent)
+
+	// review: the transfer is recorded but no money moves
+	screener.verdict = fraud.Verdict{Decision: fraud.Review, Rule: "test_rule", Reason: "testing"}
+	result, err = store.TransferTx(ctx, arg)
+	require.NoError(t, err)
+	require.Equal(t, TransferStatusPendingReview, result.Transfer.Status)
+	require.Equal(t, "test_rule", result.Transfer.ScreeningRule)
+	require.True(t, screener.input.History.KnownPayee)
+	require.Len(t, screener.input.History.Recent, 1)
+
+	sender, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-10, sender.Balance)
+
+	// deny: nothing is recorded
+	screener.verdict = fraud.Verdict{Decision: fraud.Deny, Rule: "test_rule"}
+	_, err = store.TransferTx(ctx, arg)
+	require.ErrorIs(t, err, ErrTransferDenied)
+
+	recent, err := testQueries.ListRecentOutgoingTransfers(context.Background(), ListRecentOutgoingTransfersParams{
+		AccountID: account1.ID,
+		Since:     result.Transfer.CreatedAt.Add(-fraud.HistoryWindow),
+	})
+	require.NoError(t, err)
+	require.Len(t, recent, 2)
+}
+
+func TestReviewTransferTx(t *testing.T) {
+	screener := &verdictScreener{verdict: fraud.Verdict{Decision: fraud.Review, Rule: "test_rule"}}
+	store := NewStore(testDB, WithScreener(screener))
+	ctx := WithAuditInfo(context.Background(), AuditInfo{Actor: "teller"})
+
+	account1 := createFundedAccount(t, 1000)
+	account2 := createRandomAccount(t)
+	arg := TransferTxParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 10}
+
+	pending1, err := store.TransferTx(ctx, arg)
+	require.NoError(t, err)
+	pending2, err := store.TransferTx(ctx, arg)
+	require.NoError(t, err)
+
+	// approving moves the money
+	result, err := store.ApproveTransferTx(ctx, ReviewTransferTxParams{TransferID: pending1.Transfer.ID, Note: "ok"})
+	require.NoError(t, err)
+	require.Equal(t, TransferStatusCompleted, result.Transfer.Status)
+	require.Equal(t, "teller", result.Transfer.ReviewedBy)
+	require.NotNil(t, result.Transfer.ReviewedAt)
+	require.Equal(t, account1.Balance-10, result.FromAccount.Balance)
+	require.Equal(t, account2.Balance+10, result.ToAccount.Balance)
+	require.Equal(t, int64(-10), result.FromEntry.Amount)
+
+	// rejecting doesn't
+	rejected, err := store.RejectTransferTx(ctx, ReviewTransferTxParams{TransferID: pending2.Transfer.ID, Note: "fraud"})
+	require.NoError(t, err)
+	require.Equal(t, TransferStatusRejected, rejected.Status)
+	require.Equal(t, "fraud", rejected.ReviewNote)
+
+	sender, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-10, sender.Balance)
+
+	// a transfer is only reviewed once
+	_, err = store.ApproveTransferTx(ctx, ReviewTransferTxParams{TransferID: pending2.Transfer.ID})
+	require.ErrorIs(t, err, ErrTransferNotPending)
+	_, err = store.RejectTransferTx(ctx, ReviewTransferTxParams{TransferID: pending1.Transfer.ID, Note: "too late"})
+	require.ErrorIs(t, err, ErrTransferNotPending)
+
+	_, err = store.ApproveTransferTx(ctx, ReviewTransferTxParams{TransferID: pending2.Transfer.ID + 1000000})
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestApproveTransferTxChecksBalance(t *testing.T) {
+	screener := &verdictScreener{verdict: fraud.Verdict{Decision: fraud.Review, Rule: "test_rule"}}
+	store := NewStore(testDB, WithScreener(screener))
+
+	account1 := createFundedAccount(t, 100)
+	account2 := createRandomAccount(t)
+
+	// held for review even though the sender can't afford it, the balance is checked when the money moves
+	pending, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        account1.Balance + 1,
+	})
+	require.NoError(t, err)
+
+	_, err = store.ApproveTransferTx(context.Background(), ReviewTransferTxParams{TransferID: pending.Transfer.ID})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	transfer, err := testQueries.GetTransfer(context.Background(), pending.Transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, TransferStatusPendingReview, transfer.Status)
+}
+
+// a transfer held for review counts towards the daily limits of the day it is approved, not of the day it was made
+func TestApproveTransferTxFromPreviousDay(t *testing.T) {
+	screener := &verdictScreener{verdict: fraud.Verdict{Decision: fraud.Review, Rule: "test_rule"}}
+	store := NewStore(testDB, WithScreener(screener))
+
+	account1 := createFundedAccount(t, 1000)
+	account2 := createRandomAccount(t)
+	setAccountLimits(t, account1.ID, 100, 100, 3)
+	transfer := func(amount int64) (TransferTxResult, error) {
+		return store.TransferTx(context.Background(), TransferTxParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: amount})
+	}
+
+	early, err := transfer(60)
+	require.NoError(t, err)
+	late, err := transfer(40)
+	require.NoError(t, err)
+	_, err = testDB.Exec(context.Background(),
+		"UPDATE transfers SET created_at = created_at - interval '1 day' WHERE id = ANY($1)",
+		[]int64{early.Transfer.ID, late.Transfer.ID})
+	require.NoError(t, err)
+
+	screener.verdict = fraud.Verdict{Decision: fraud.Allow}
+	_, err = transfer(50)
+	require.NoError(t, err)
+
+	// 50 today plus the 60 approved now is over the daily amount, the transfer stays pending
+	_, err = store.ApproveTransferTx(context.Background(), ReviewTransferTxParams{TransferID: early.Transfer.ID})
+	require.ErrorIs(t, err, ErrLimitExceeded)
+	var dbErr *Error
+	require.ErrorAs(t, err, &dbErr)
+	require.Equal(t, LimitDailyAmount, dbErr.Details["limit"])
+	require.Equal(t, int64(50), dbErr.Details["remaining_daily_amount"])
+	require.Equal(t, int32(2), dbErr.Details["remaining_daily_count"])
+
+	pending, err := testQueries.GetTransfer(context.Background(), early.Transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, TransferStatusPendingReview, pending.Status)
+
+	// the 40 fits and is counted today
+	result, err := store.ApproveTransferTx(context.Background(), ReviewTransferTxParams{TransferID: late.Transfer.ID})
+	require.NoError(t, err)
+	require.Equal(t, *result.Transfer.ReviewedAt, result.Transfer.CompletedAt())
+
+	daily, err := testQueries.GetDailyOutgoingTransfers(context.Background(), GetDailyOutgoingTransfersParams{FromAccountID: account1.ID, Day: time.Now()})
+	require.NoError(t, err)
+	require.Equal(t, int64(90), daily.TotalAmount)
+	require.Equal(t, int32(2), daily.TotalCount)
+
+	yesterday, err := testQueries.GetDailyOutgoingTransfers(context.Background(), GetDailyOutgoingTransfersParams{
+		FromAccountID: account1.ID,
+		Day:           late.Transfer.CreatedAt.Add(-24 * time.Hour),
+	})
+	require.NoError(t, err)
+	require.Zero(t, yesterday.TotalCount)
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SQLSTATE codes after which a transaction can simply be run again
+const (
+	SerializationFailure = "40001"
+	DeadlockDetected     = "40P01"
+)
+
+// TxOptions controls how a transaction is opened
+type TxOptions struct {
+	Isolation pgx.TxIsoLevel // empty uses the database default, read committed for postgres
+	ReadOnly  bool
+}
+
+type txOptionsKey struct{}
+
+// WithTxOptions returns a copy of ctx asking the transactions started with it to use opts
+// e.g. store.TransferTx(db.WithTxOptions(ctx, db.TxOptions{Isolation: pgx.Serializable}), arg)
+func WithTxOptions(ctx context.Context, opts TxOptions) context.Context {
+	return context.WithValue(ctx, txOptionsKey{}, opts)
+}
+
+// txOptionsFromContext returns the options stored in ctx, the zero value uses the database defaults
+func txOptionsFromContext(ctx context.Context) TxOptions {
+	opts, _ := ctx.Value(txOptionsKey{}).(TxOptions)
+	return opts
+}
+
+// RetryPolicy bounds how often and how fast execTx retries a transaction that failed with a retryable error
+type RetryPolicy struct {
+	MaxAttempts int           // total number of attempts, 1 disables retries
+	BaseDelay   time.Duration // the delay before retry n is drawn from [0, BaseDelay * 2^(n-1)]
+	MaxDelay    time.Duration // upper bound of a single delay
+}
+
+// DefaultRetryPolicy is the policy of the stores created by NewStore
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   10 * time.Millisecond,
+	MaxDelay:    500 * time.Millisecond,
+}
+
+// delay returns the randomised wait before the given retry (1 for the first retry)
+// the full jitter spreads out transactions that failed together, so they don't collide again
+func (policy RetryPolicy) delay(retry int) time.Duration {
+	ceiling := policy.BaseDelay
+	for i := 1; i < retry && ceiling < policy.MaxDelay; i++ {
+		ceiling *= 2
+	}
+	if ceiling > policy.MaxDelay {
+		ceiling = policy.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// outcomes of a transaction in the db_tx_duration_seconds metric
+const (
+	txOutcomeCommitted = "committed"
+	txOutcomeFailed    = "failed"
+)
+
+// retryableCode returns the SQLSTATE of err if running the transaction again may succeed
+func retryableCode(err error) (string, bool) {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return "", false
+	}
+
+	code := pgErr.Code
+	return code, code == SerializationFailure || code == DeadlockDetected
+}
+
+// sleepContext waits for d, or less if ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
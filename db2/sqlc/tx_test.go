@@ -0,0 +1,41 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryableCode(t *testing.T) {
+	code, ok := retryableCode(&pgconn.PgError{Code: SerializationFailure})
+	require.True(t, ok)
+	require.Equal(t, SerializationFailure, code)
+
+	// execTx wraps the error when the rollback fails too
+	_, ok = retryableCode(fmt.Errorf("transactionErr: %w, rollbackErr: boom", &pgconn.PgError{Code: DeadlockDetected}))
+	require.True(t, ok)
+
+	_, ok = retryableCode(&pgconn.PgError{Code: "23505"}) // unique violation
+	require.False(t, ok)
+
+	_, ok = retryableCode(errors.New("connection refused"))
+	require.False(t, ok)
+
+	_, ok = retryableCode(nil)
+	require.False(t, ok)
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: 10 * time.Millisecond, MaxDelay: 40 * time.Millisecond}
+
+	for i := 0; i < 100; i++ {
+		require.LessOrEqual(t, policy.delay(1), 10*time.Millisecond)
+		require.LessOrEqual(t, policy.delay(2), 20*time.Millisecond)
+		require.LessOrEqual(t, policy.delay(10), 40*time.Millisecond)
+		require.GreaterOrEqual(t, policy.delay(3), time.Duration(0))
+	}
+}
@@ -0,0 +1,189 @@
+package db
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/techschool/simple-bank/metrics"
+)
+
+// webhook delivery statuses, stored in the status column of webhook_deliveries
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryDead      = "dead" // retries are exhausted, the delivery is kept for inspection
+)
+
+const (
+	AuditActionWebhookCreate = "webhook.create"
+	AuditActionWebhookUpdate = "webhook.update"
+	AuditActionWebhookDelete = "webhook.delete"
+
+	AuditResourceWebhook = "webhook"
+)
+
+// WebhookClaimDuration is how long the deliveries handed to a worker are skipped by the other workers
+// it must outlast a whole batch of attempts, a worker that dies lets the deliveries be sent again after it
+const WebhookClaimDuration = 10 * time.Minute
+
+// WebhookAttempt is the outcome of one delivery attempt
+type WebhookAttempt struct {
+	Status         string    // one of the WebhookDelivery* statuses
+	ResponseStatus int32     // HTTP status returned by the receiver, 0 if there was no response
+	LastError      string    // empty on success
+	NextAttemptAt  time.Time // only meaningful while the status is pending
+}
+
+// withoutSecret returns a copy of the webhook that is safe to write to the audit log
+func (webhook Webhook) withoutSecret() Webhook {
+	webhook.Secret = ""
+	return webhook
+}
+
+// CreateWebhookTx creates a webhook subscription and records it in the audit log within a single database transaction
+func (store *SQLStore) CreateWebhookTx(ctx context.Context, arg CreateWebhookParams) (Webhook, error) {
+	ctx, span := startSpan(ctx, "CreateWebhookTx")
+	defer span.End()
+
+	var webhook Webhook
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		webhook, err = q.CreateWebhook(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, AuditActionWebhookCreate, AuditResourceWebhook, webhook.ID, nil, webhook.withoutSecret())
+	})
+
+	return webhook, err
+}
+
+// UpdateWebhookTx updates a webhook subscription and records the before and after state in the audit log
+// returns ErrNotFound if the webhook doesn't exist
+func (store *SQLStore) UpdateWebhookTx(ctx context.Context, arg UpdateWebhookParams) (Webhook, error) {
+	ctx, span := startSpan(ctx, "UpdateWebhookTx")
+	defer span.End()
+
+	var webhook Webhook
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		before, err := q.GetWebhook(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+
+		webhook, err = q.UpdateWebhook(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, AuditActionWebhookUpdate, AuditResourceWebhook, webhook.ID, before.withoutSecret(), webhook.withoutSecret())
+	})
+
+	return webhook, err
+}
+
+// DeleteWebhookTx deletes a webhook subscription together with its deliveries and records it in the audit log
+// returns ErrNotFound if the webhook doesn't exist
+func (store *SQLStore) DeleteWebhookTx(ctx context.Context, id int64) error {
+	ctx, span := startSpan(ctx, "DeleteWebhookTx")
+	defer span.End()
+
+	return store.execTx(ctx, func(q *Queries) error {
+		before, err := q.GetWebhook(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := q.DeleteWebhook(ctx, id); err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, AuditActionWebhookDelete, AuditResourceWebhook, id, before.withoutSecret(), nil)
+	})
+}
+
+// ProcessWebhookDeliveriesTx hands up to batchSize due deliveries to deliver and records the outcome of each attempt
+// the deliveries are claimed for WebhookClaimDuration in a first short transaction, so concurrent workers never
+// send the same delivery, and deliver runs outside of any transaction: a slow receiver holds no lock and no connection.
+// Each attempt is then recorded in a transaction of its own. An attempt is dropped if the claim ran out before it
+// was recorded, the worker that took the delivery over records its own; the loss is logged and counted in
+// metrics.WebhookClaimsLost, a receiver slower than WebhookClaimDuration shows up there
+// returns the number of deliveries attempted
+func (store *SQLStore) ProcessWebhookDeliveriesTx(
+	ctx context.Context,
+	batchSize int32,
+	deliver func(ctx context.Context, delivery WebhookDelivery, webhook Webhook) WebhookAttempt,
+) (int, error) {
+	ctx, span := startSpan(ctx, "ProcessWebhookDeliveriesTx")
+	defer span.End()
+
+	// compared for equality when the attempt is recorded, postgres keeps microseconds
+	lockedUntil := time.Now().Add(WebhookClaimDuration).Truncate(time.Microsecond)
+
+	var rows []ListDueWebhookDeliveriesRow
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		rows, err = q.ListDueWebhookDeliveries(ctx, batchSize)
+		if err != nil {
+			return err
+		}
+		return q.ClaimWebhookDeliveries(ctx, ClaimWebhookDeliveriesParams{LockedUntil: &lockedUntil, Ids: deliveryIDs(rows)})
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// the attempts made are recorded even if the worker is stopping, the deliveries left are released so that
+	// they don't wait for the claim to run out
+	recordCtx := context.WithoutCancel(ctx)
+
+	attempted := 0
+	for i, row := range rows {
+		if ctx.Err() != nil {
+			return attempted, store.execTx(recordCtx, func(q *Queries) error {
+				return q.ClaimWebhookDeliveries(recordCtx, ClaimWebhookDeliveriesParams{LockedUntil: nil, Ids: deliveryIDs(rows[i:])})
+			})
+		}
+
+		attempt := deliver(ctx, row.WebhookDelivery, row.Webhook)
+		var recorded int64
+		err := store.execTx(recordCtx, func(q *Queries) error {
+			var err error
+			recorded, err = q.RecordWebhookAttempt(recordCtx, RecordWebhookAttemptParams{
+				ID:             row.WebhookDelivery.ID,
+				Status:         attempt.Status,
+				ResponseStatus: attempt.ResponseStatus,
+				LastError:      attempt.LastError,
+				NextAttemptAt:  attempt.NextAttemptAt,
+				LockedUntil:    &lockedUntil,
+			})
+			return err
+		})
+		if err != nil {
+			return attempted, err
+		}
+		if recorded == 0 {
+			metrics.WebhookClaimsLost.Inc()
+			slog.WarnContext(ctx, "webhook claim lost, the attempt was not recorded",
+				"delivery_id", row.WebhookDelivery.ID,
+				"webhook_id", row.Webhook.ID,
+				"status", attempt.Status,
+			)
+		}
+		attempted++
+	}
+
+	return attempted, nil
+}
+
+func deliveryIDs(rows []ListDueWebhookDeliveriesRow) []int64 {
+	ids := make([]int64, len(rows))
+	for i, row := range rows {
+		ids[i] = row.WebhookDelivery.ID
+	}
+	return ids
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simple-bank/utils"
+)
+
+func createRandomWebhook(t *testing.T, eventTypes ...string) Webhook {
+	store := NewStore(testDB)
+
+	webhook, err := store.CreateWebhookTx(context.Background(), CreateWebhookParams{
+		Url:        "https://example.com/" + utils.RandomString(6),
+		EventTypes: eventTypes,
+		Secret:     utils.RandomString(32),
+	})
+	require.NoError(t, err)
+	require.NotZero(t, webhook.ID)
+	require.True(t, webhook.Active)
+	require.Equal(t, eventTypes, webhook.EventTypes)
+
+	return webhook
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	store := NewStore(testDB)
+
+	subscribed := createRandomWebhook(t, EventTransferCompleted)
+	other := createRandomWebhook(t, EventAccountCreated)
+
+	arg := CreateWebhookDeliveriesParams{
+		EventID:   utils.RandomInt(1, 1000000),
+		EventType: EventTransferCompleted,
+		Payload:   json.RawMessage(`{"transfer":{"id":1}}`),
+	}
+	n, err := testQueries.CreateWebhookDeliveries(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	// the relay may publish the same event twice, it must not queue a second delivery
+	n, err = testQueries.CreateWebhookDeliveries(context.Background(), arg)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	next := time.Now().Add(time.Minute)
+	var attempted []WebhookDelivery
+	_, err = store.ProcessWebhookDeliveriesTx(context.Background(), 100, func(ctx context.Context, delivery WebhookDelivery, webhook Webhook) WebhookAttempt {
+		attempted = append(attempted, delivery)
+		require.Equal(t, delivery.WebhookID, webhook.ID)
+		return WebhookAttempt{Status: WebhookDeliveryPending, ResponseStatus: 500, LastError: "boom", NextAttemptAt: next}
+	})
+	require.NoError(t, err)
+
+	var found bool
+	for _, delivery := range attempted {
+		require.NotEqual(t, other.ID, delivery.WebhookID)
+		if delivery.WebhookID == subscribed.ID {
+			found = true
+		}
+	}
+	require.True(t, found)
+
+	deliveries, err := testQueries.ListWebhookDeliveries(context.Background(), ListWebhookDeliveriesParams{
+		WebhookID: subscribed.ID,
+		Limit:     5,
+	})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, int32(1), deliveries[0].Attempts)
+	require.Equal(t, "boom", deliveries[0].LastError)
+	require.WithinDuration(t, next, deliveries[0].NextAttemptAt, time.Second)
+
+	// the retry isn't due yet
+	n2, err := store.ProcessWebhookDeliveriesTx(context.Background(), 100, func(ctx context.Context, delivery WebhookDelivery, webhook Webhook) WebhookAttempt {
+		require.NotEqual(t, subscribed.ID, delivery.WebhookID)
+		return WebhookAttempt{Status: WebhookDeliverySucceeded, NextAttemptAt: time.Now()}
+	})
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, n2, 0)
+
+	// deleting the webhook removes its deliveries too
+	require.NoError(t, store.DeleteWebhookTx(context.Background(), subscribed.ID))
+	require.NoError(t, store.DeleteWebhookTx(context.Background(), other.ID))
+	deliveries, err = testQueries.ListWebhookDeliveries(context.Background(), ListWebhookDeliveriesParams{
+		WebhookID: subscribed.ID,
+		Limit:     5,
+	})
+	require.NoError(t, err)
+	require.Empty(t, deliveries)
+}
@@ -0,0 +1,265 @@
+package storetest
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/utils"
+)
+
+func testAccounts(t *testing.T, newStore NewStore) {
+	store := newStore(t, nil)
+	ctx := context.Background()
+
+	arg := db.CreateAccountParams{Owner: utils.RandomOwner(), Balance: 100, Currency: "USD"}
+	account, err := store.CreateAccountTx(testContext(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, account.ID)
+	require.Equal(t, arg.Owner, account.Owner)
+	require.Equal(t, arg.Balance, account.Balance)
+	require.Equal(t, arg.Currency, account.Currency)
+	require.Equal(t, db.AccountStatusActive, account.Status)
+	require.Equal(t, db.DefaultLimitTier, account.LimitTier)
+	requireRecent(t, account.CreatedAt)
+	require.Equal(t, account, getAccount(t, store, account.ID))
+
+	// the creation is audited
+	events := auditEvents(t, store, db.AuditResourceAccount, strconv.FormatInt(account.ID, 10))
+	require.Len(t, events, 1)
+	require.Equal(t, testActor, events[0].Actor)
+	require.Equal(t, db.AuditActionAccountCreate, events[0].Action)
+	require.Equal(t, "storetest-request", events[0].RequestID)
+	require.JSONEq(t, "null", string(events[0].Before))
+	require.Equal(t, account.ID, unmarshal[db.Account](t, events[0].After).ID)
+
+	// the opening balance is a transfer from the equity account of the currency, the ledger alone explains it
+	require.Equal(t, db.AccountKindCustomer, account.Kind)
+	equity, err := store.GetEquityAccount(ctx, "USD")
+	require.NoError(t, err)
+	require.Equal(t, db.AccountKindEquity, equity.Kind)
+
+	transfers, err := store.ListTransfers(ctx, db.ListTransfersParams{FromAccountID: account.ID, ToAccountID: account.ID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	require.Equal(t, equity.ID, transfers[0].FromAccountID)
+	require.Equal(t, arg.Balance, transfers[0].Amount)
+	require.Equal(t, db.TransferStatusCompleted, transfers[0].Status)
+	require.NotNil(t, transfers[0].AmlAnalyzedAt) // no customer sent the money
+
+	entries, err := store.ListEntries(ctx, db.ListEntriesParams{AccountID: account.ID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, arg.Balance, entries[0].Amount)
+	require.Empty(t, mismatches(t, store, account.ID, equity.ID))
+
+	// the next opening balance in the currency is paid by the same equity account
+	createAccount(t, store, "USD", 40)
+	require.Equal(t, equity.Balance-40, getAccount(t, store, equity.ID).Balance)
+
+	_, err = store.CreateAccountTx(testContext(), db.CreateAccountParams{Owner: utils.RandomOwner(), Balance: -1, Currency: "USD"})
+	requireKind(t, err, db.ErrCheckViolation, map[string]any{"constraint": "transfers_amount_positive"})
+
+	// the queries return the errors of pgx, unclassified
+	_, err = store.GetAccount(ctx, missingID)
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+	require.NotErrorIs(t, err, db.ErrNotFound)
+
+	_, err = store.AddAccountBalance(ctx, db.AddAccountBalanceParams{ID: missingID, Amount: 10})
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+
+	_, err = store.CreateAccount(ctx, db.CreateAccountParams{Owner: utils.RandomOwner(), Currency: "usd"})
+	requirePgError(t, err, db.CheckViolation, "accounts_currency_format")
+
+	_, err = store.ListAccounts(ctx, db.ListAccountsParams{Limit: -1})
+	requirePgError(t, err, "2201W", "")
+
+	// an account without opening balance has no entry, and a list without rows is empty, not nil
+	second := createAccount(t, store, "EUR", 0)
+	entries, err = store.ListEntries(ctx, db.ListEntriesParams{AccountID: second.ID, Limit: 10})
+	require.NoError(t, err)
+	require.NotNil(t, entries)
+	require.Empty(t, entries)
+
+	// pagination follows the ids
+	accounts, err := store.ListAccounts(ctx, db.ListAccountsParams{Limit: 2, Offset: 0})
+	require.NoError(t, err)
+	require.Len(t, accounts, 2)
+	require.Less(t, accounts[0].ID, accounts[1].ID)
+	require.Less(t, account.ID, second.ID)
+}
+
+// the equity accounts only pay opening balances: customers don't see them and no transfer or status change touches them
+func testEquityAccounts(t *testing.T, newStore NewStore) {
+	store := newStore(t, nil)
+	ctx := context.Background()
+	account := createAccount(t, store, "USD", 100)
+	equity, err := store.GetEquityAccount(ctx, "USD")
+	require.NoError(t, err)
+
+	for offset := int32(0); ; offset += 1000 {
+		accounts, err := store.ListAccounts(ctx, db.ListAccountsParams{Limit: 1000, Offset: offset})
+		require.NoError(t, err)
+		for _, listed := range accounts {
+			require.Equal(t, db.AccountKindCustomer, listed.Kind)
+		}
+		if len(accounts) < 1000 {
+			break
+		}
+	}
+
+	_, err = store.TransferTx(testContext(), db.TransferTxParams{FromAccountID: equity.ID, ToAccountID: account.ID, Amount: 10})
+	requireKind(t, err, db.ErrEquityAccount, map[string]any{"account_id": equity.ID})
+	_, err = store.TransferTx(testContext(), db.TransferTxParams{FromAccountID: account.ID, ToAccountID: equity.ID, Amount: 10})
+	requireKind(t, err, db.ErrEquityAccount, map[string]any{"account_id": equity.ID})
+	require.Equal(t, int64(100), getAccount(t, store, account.ID).Balance)
+
+	_, err = store.UpdateAccountStatusTx(testContext(), db.UpdateAccountStatusParams{ID: equity.ID, Status: db.AccountStatusFrozen})
+	requireKind(t, err, db.ErrEquityAccount, map[string]any{"account_id": equity.ID})
+	require.Equal(t, db.AccountStatusActive, getAccount(t, store, equity.ID).Status)
+}
+
+func testAccountStatus(t *testing.T, newStore NewStore) {
+	store := newStore(t, nil)
+	account := createAccount(t, store, "USD", 0)
+	resourceID := strconv.FormatInt(account.ID, 10)
+
+	webhook, err := store.CreateWebhookTx(testContext(), db.CreateWebhookParams{
+		Url:        "https://example.com/" + utils.RandomString(6),
+		EventTypes: []string{db.EventAccountStatusChanged},
+		Secret:     utils.RandomString(32),
+	})
+	require.NoError(t, err)
+
+	frozen, err := store.UpdateAccountStatusTx(testContext(), db.UpdateAccountStatusParams{ID: account.ID, Status: db.AccountStatusFrozen})
+	require.NoError(t, err)
+	require.Equal(t, db.AccountStatusFrozen, frozen.Status)
+	require.Equal(t, frozen, getAccount(t, store, account.ID))
+	require.Len(t, auditEvents(t, store, db.AuditResourceAccount, resourceID), 2)
+
+	// the same status again changes nothing and records nothing
+	_, err = store.UpdateAccountStatusTx(testContext(), db.UpdateAccountStatusParams{ID: account.ID, Status: db.AccountStatusFrozen})
+	require.NoError(t, err)
+	events := auditEvents(t, store, db.AuditResourceAccount, resourceID)
+	require.Len(t, events, 2)
+	require.Equal(t, db.AuditActionAccountStatusUpdate, events[0].Action)
+	require.Equal(t, db.AccountStatusActive, unmarshal[db.Account](t, events[0].Before).Status)
+
+	// one event for the change, none for the update that changed nothing, and the subscribed webhook
+	// gets a delivery of it once the relay queued them like the webhook dispatcher does
+	queue := func(ctx context.Context, event db.Outbox) error {
+		_, err := store.CreateWebhookDeliveries(ctx, db.CreateWebhookDeliveriesParams{
+			EventID:   event.ID,
+			EventType: event.EventType,
+			Payload:   event.Payload,
+		})
+		return err
+	}
+	outbox := relayAll(t, store, queue, aggregate{db.AuditResourceAccount, account.ID})
+	require.Len(t, outbox, 2)
+	require.Equal(t, db.EventAccountCreated, outbox[0].EventType)
+	require.Equal(t, db.EventAccountStatusChanged, outbox[1].EventType)
+	statusChanged := db.AccountStatusChangedEvent{Account: frozen, PreviousStatus: db.AccountStatusActive}
+	requireJSON(t, statusChanged, outbox[1].Payload)
+
+	deliveries, err := store.ListWebhookDeliveries(context.Background(), db.ListWebhookDeliveriesParams{WebhookID: webhook.ID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, outbox[1].ID, deliveries[0].EventID)
+	require.Equal(t, db.EventAccountStatusChanged, deliveries[0].EventType)
+	require.Equal(t, db.WebhookDeliveryPending, deliveries[0].Status)
+	requireJSON(t, statusChanged, deliveries[0].Payload)
+
+	// the transactions classify their errors
+	_, err = store.UpdateAccountStatusTx(testContext(), db.UpdateAccountStatusParams{ID: missingID, Status: db.AccountStatusFrozen})
+	requireKind(t, err, db.ErrNotFound, nil)
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+
+	_, err = store.UpdateAccountStatusTx(testContext(), db.UpdateAccountStatusParams{ID: account.ID, Status: "closed"})
+	requireKind(t, err, db.ErrCheckViolation, map[string]any{"constraint": "accounts_status_check"})
+	requirePgError(t, err, db.CheckViolation, "accounts_status_check")
+	require.Equal(t, db.AccountStatusFrozen, getAccount(t, store, account.ID).Status)
+}
+
+func testTransferLimits(t *testing.T, newStore NewStore) {
+	store := newStore(t, nil)
+	ctx := testContext()
+
+	tiers, err := store.ListTransferLimitTiers(ctx)
+	require.NoError(t, err)
+	require.Contains(t, tierNames(tiers), db.DefaultLimitTier)
+
+	name := "storetest_" + utils.RandomString(8)
+	tier, err := store.UpsertTransferLimitTierTx(ctx, db.UpsertTransferLimitTierParams{
+		Name:            name,
+		MaxSingleAmount: 100,
+		MaxDailyAmount:  500,
+		MaxDailyCount:   5,
+	})
+	require.NoError(t, err)
+	require.Equal(t, name, tier.Name)
+	requireRecent(t, tier.UpdatedAt)
+
+	got, err := store.GetTransferLimitTier(ctx, name)
+	require.NoError(t, err)
+	require.Equal(t, tier, got)
+
+	tiers, err = store.ListTransferLimitTiers(ctx)
+	require.NoError(t, err)
+	require.IsIncreasing(t, tierNames(tiers))
+	require.Contains(t, tierNames(tiers), name)
+
+	// replacing the tier audits the previous limits
+	_, err = store.UpsertTransferLimitTierTx(ctx, db.UpsertTransferLimitTierParams{
+		Name:            name,
+		MaxSingleAmount: 200,
+		MaxDailyAmount:  500,
+		MaxDailyCount:   5,
+	})
+	require.NoError(t, err)
+	events := auditEvents(t, store, db.AuditResourceLimitTier, name)
+	require.Len(t, events, 2)
+	require.Equal(t, int64(100), unmarshal[db.TransferLimitTier](t, events[0].Before).MaxSingleAmount)
+
+	// the overrides of the account replace the limits of its tier
+	account := createAccount(t, store, "USD", 100)
+	maxDailyCount := int32(2)
+	limits, err := store.UpdateAccountTransferLimitsTx(ctx, db.UpdateAccountTransferLimitsParams{
+		AccountID:     account.ID,
+		Tier:          name,
+		MaxDailyCount: &maxDailyCount,
+	})
+	require.NoError(t, err)
+	require.Equal(t, db.GetEffectiveTransferLimitsRow{
+		AccountID:       account.ID,
+		Tier:            name,
+		MaxSingleAmount: 200,
+		MaxDailyAmount:  500,
+		MaxDailyCount:   2,
+	}, limits)
+	require.Equal(t, name, getAccount(t, store, account.ID).LimitTier)
+
+	// no override left, the tier applies again
+	limits, err = store.UpdateAccountTransferLimitsTx(ctx, db.UpdateAccountTransferLimitsParams{AccountID: account.ID, Tier: name})
+	require.NoError(t, err)
+	require.Equal(t, int32(5), limits.MaxDailyCount)
+	_, err = store.GetAccountTransferLimitOverride(ctx, account.ID)
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+
+	_, err = store.UpdateAccountTransferLimitsTx(ctx, db.UpdateAccountTransferLimitsParams{AccountID: account.ID, Tier: "storetest_missing"})
+	requireKind(t, err, db.ErrForeignKeyViolation, map[string]any{"constraint": "accounts_limit_tier_fkey"})
+
+	_, err = store.UpdateAccountTransferLimitsTx(ctx, db.UpdateAccountTransferLimitsParams{AccountID: missingID, Tier: name})
+	requireKind(t, err, db.ErrNotFound, nil)
+}
+
+func tierNames(tiers []db.TransferLimitTier) []string {
+	names := make([]string, len(tiers))
+	for i, tier := range tiers {
+		names[i] = tier.Name
+	}
+	return names
+}
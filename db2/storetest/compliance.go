@@ -0,0 +1,146 @@
+package storetest
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+)
+
+// amlCurrency is the currency of the accounts of the compliance tests, the ISO 4217 code reserved for testing
+// the rules only have a threshold for it, so the transfers of the other tests never raise an alert
+const amlCurrency = "XTS"
+
+// analyzeAll runs the analyzer until every transfer of the store is analyzed
+func analyzeAll(t *testing.T, store db.Store, rules db.AMLRules) {
+	for {
+		n, err := store.AnalyzeTransfersTx(context.Background(), 100, rules)
+		require.NoError(t, err)
+		if n == 0 {
+			return
+		}
+	}
+}
+
+// accountAlerts returns the alerts of the account, newest first
+func accountAlerts(t *testing.T, store db.Store, accountID int64) []db.ComplianceAlert {
+	alerts, err := store.ListComplianceAlerts(context.Background(), db.ListComplianceAlertsParams{Limit: 1000})
+	require.NoError(t, err)
+
+	result := []db.ComplianceAlert{}
+	for _, alert := range alerts {
+		if alert.AccountID == accountID {
+			result = append(result, alert)
+		}
+	}
+	return result
+}
+
+func testCompliance(t *testing.T, newStore NewStore) {
+	store := newStore(t, nil)
+	ctx := testContext()
+
+	from := createAccount(t, store, amlCurrency, 10000)
+	to := createAccount(t, store, amlCurrency, 0)
+	rules := db.AMLRules{
+		Thresholds:          map[string]int64{amlCurrency: 1000},
+		StructuringFloor:    90,
+		StructuringWindow:   time.Hour,
+		StructuringMinCount: 2,
+	}
+	transfer := func(amount int64) int64 {
+		result, err := store.TransferTx(ctx, db.TransferTxParams{FromAccountID: from.ID, ToAccountID: to.ID, Amount: amount})
+		require.NoError(t, err)
+		return result.Transfer.ID
+	}
+
+	large := transfer(1500)
+	transfer(500) // far below the threshold, not evidence of anything
+	near1 := transfer(950)
+	near2 := transfer(960)
+	analyzeAll(t, store, rules)
+
+	alerts := accountAlerts(t, store, from.ID)
+	require.Len(t, alerts, 2)
+
+	structuring := alerts[0]
+	require.Equal(t, db.AlertKindStructuring, structuring.Kind)
+	require.Equal(t, amlCurrency, structuring.Currency)
+	require.Equal(t, []int64{near1, near2}, structuring.TransferIds)
+	require.Equal(t, int64(950+960), structuring.TotalAmount)
+	require.Equal(t, db.AlertStatusOpen, structuring.Status)
+	requireRecent(t, structuring.CreatedAt)
+
+	require.Equal(t, db.AlertKindLargeTransaction, alerts[1].Kind)
+	require.Equal(t, []int64{large}, alerts[1].TransferIds)
+	require.Equal(t, int64(1500), alerts[1].TotalAmount)
+
+	// new evidence is added to the unresolved alert
+	near3 := transfer(970)
+	analyzeAll(t, store, rules)
+	alerts = accountAlerts(t, store, from.ID)
+	require.Len(t, alerts, 2)
+	require.Equal(t, structuring.ID, alerts[0].ID)
+	require.Equal(t, []int64{near1, near2, near3}, alerts[0].TransferIds)
+	require.Equal(t, int64(950+960+970), alerts[0].TotalAmount)
+
+	// a second open structuring alert of the account violates the unique index
+	_, err := store.CreateComplianceAlert(context.Background(), db.CreateComplianceAlertParams{
+		Kind:        db.AlertKindStructuring,
+		AccountID:   from.ID,
+		Currency:    amlCurrency,
+		TotalAmount: 1,
+		TransferIds: []int64{near1},
+	})
+	requirePgError(t, err, db.UniqueViolation, "compliance_alerts_open_structuring_idx")
+
+	// the workflow of an investigation
+	assigned, err := store.AssignComplianceAlertTx(ctx, db.AssignComplianceAlertParams{ID: structuring.ID, Assignee: "jdoe"})
+	require.NoError(t, err)
+	require.Equal(t, db.AlertStatusAssigned, assigned.Status)
+	require.Equal(t, "jdoe", assigned.Assignee)
+
+	resolved, err := store.ResolveComplianceAlertTx(ctx, db.ResolveComplianceAlertTxParams{
+		ID:         structuring.ID,
+		Resolution: "false_positive",
+		Note:       "weekly rent",
+	})
+	require.NoError(t, err)
+	require.Equal(t, db.AlertStatusResolved, resolved.Status)
+	require.Equal(t, "false_positive", resolved.Resolution)
+	require.Equal(t, "weekly rent", resolved.ResolutionNote)
+	require.Equal(t, testActor, resolved.ResolvedBy)
+	require.NotNil(t, resolved.ResolvedAt)
+
+	alert, err := store.GetComplianceAlert(context.Background(), structuring.ID)
+	require.NoError(t, err)
+	require.Equal(t, resolved, alert)
+
+	_, err = store.ResolveComplianceAlertTx(ctx, db.ResolveComplianceAlertTxParams{ID: structuring.ID, Resolution: "reported"})
+	requireKind(t, err, db.ErrAlertResolved, map[string]any{"alert_id": structuring.ID})
+	_, err = store.AssignComplianceAlertTx(ctx, db.AssignComplianceAlertParams{ID: structuring.ID, Assignee: "other"})
+	requireKind(t, err, db.ErrAlertResolved, nil)
+
+	events := auditEvents(t, store, db.AuditResourceComplianceAlert, strconv.FormatInt(structuring.ID, 10))
+	require.Len(t, events, 2)
+	require.Equal(t, db.AuditActionAlertResolve, events[0].Action)
+	require.Equal(t, db.AlertStatusAssigned, unmarshal[db.ComplianceAlert](t, events[0].Before).Status)
+
+	// once resolved, new evidence raises a new alert
+	transfer(980)
+	analyzeAll(t, store, rules)
+	alerts = accountAlerts(t, store, from.ID)
+	require.Len(t, alerts, 3)
+	require.Equal(t, db.AlertKindStructuring, alerts[0].Kind)
+	require.Equal(t, db.AlertStatusOpen, alerts[0].Status)
+	require.Len(t, alerts[0].TransferIds, 4)
+
+	_, err = store.GetComplianceAlert(context.Background(), missingID)
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+	_, err = store.AssignComplianceAlertTx(ctx, db.AssignComplianceAlertParams{ID: missingID, Assignee: "jdoe"})
+	requireKind(t, err, db.ErrNotFound, nil)
+}
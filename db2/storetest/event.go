@@ -0,0 +1,378 @@
+package storetest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/metrics"
+	"github.com/techschool/simple-bank/utils"
+)
+
+// aggregate identifies the aggregate of an outbox event
+type aggregate struct {
+	kind string
+	id   int64
+}
+
+// outboxMaxAttempts is the number of failed publishes after which the relays of the suite dead-letter an event
+const outboxMaxAttempts = 3
+
+// relayAll relays the outbox until it is empty and returns the events published about the given aggregates
+func relayAll(t *testing.T, store db.Store, publish func(ctx context.Context, event db.Outbox) error, aggregates ...aggregate) []db.Outbox {
+	var events []db.Outbox
+	for {
+		n, err := store.RelayOutboxTx(context.Background(), 100, outboxMaxAttempts, func(ctx context.Context, event db.Outbox) error {
+			if publish != nil {
+				if err := publish(ctx, event); err != nil {
+					return err
+				}
+			}
+			for _, aggregate := range aggregates {
+				if event.AggregateType == aggregate.kind && event.AggregateID == strconv.FormatInt(aggregate.id, 10) {
+					events = append(events, event)
+				}
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		if n == 0 {
+			return events
+		}
+	}
+}
+
+func testOutbox(t *testing.T, newStore NewStore) {
+	store := newStore(t, nil)
+
+	from := createAccount(t, store, "USD", 100)
+	to := createAccount(t, store, "USD", 0)
+	result, err := store.TransferTx(testContext(), db.TransferTxParams{FromAccountID: from.ID, ToAccountID: to.ID, Amount: 30})
+	require.NoError(t, err)
+
+	// the publisher may use the store, e.g. the webhook dispatcher queues its deliveries
+	publish := func(ctx context.Context, event db.Outbox) error {
+		_, err := store.GetAccount(ctx, from.ID)
+		return err
+	}
+	events := relayAll(t, store, publish,
+		aggregate{db.AuditResourceAccount, from.ID},
+		aggregate{db.AuditResourceAccount, to.ID},
+		aggregate{db.AuditResourceTransfer, result.Transfer.ID},
+	)
+
+	var types []string
+	for _, event := range events {
+		types = append(types, event.EventType)
+		require.Nil(t, event.PublishedAt) // handed over before being marked published
+		requireRecent(t, event.CreatedAt)
+	}
+	// the events of a transfer are enqueued in order, after the ones of the accounts
+	// the opening balance of from changed its balance, the opening transfer is left out by the aggregates
+	require.Equal(t, []string{
+		db.EventAccountCreated,
+		db.EventBalanceChanged,
+		db.EventAccountCreated,
+		db.EventTransferCompleted,
+		db.EventBalanceChanged,
+		db.EventBalanceChanged,
+	}, types)
+
+	requireJSON(t, db.AccountCreatedEvent{Account: from}, events[0].Payload)
+	requireJSON(t, db.TransferCompletedEvent{
+		Transfer:  result.Transfer,
+		FromEntry: result.FromEntry,
+		ToEntry:   result.ToEntry,
+	}, events[3].Payload)
+	requireJSON(t, db.BalanceChangedEvent{
+		AccountID: from.ID,
+		EntryID:   result.FromEntry.ID,
+		Amount:    -30,
+		Balance:   70,
+		Currency:  "USD",
+		CreatedAt: result.FromEntry.CreatedAt,
+	}, events[4].Payload)
+
+	// a failed publish stops the batch and the event is retried on the next call
+	account := createAccount(t, store, "EUR", 0)
+	aggregateID := strconv.FormatInt(account.ID, 10)
+	errPublish := errors.New("broker unavailable")
+	rejectAccount := func(aggregateID string) func(ctx context.Context, event db.Outbox) error {
+		return func(ctx context.Context, event db.Outbox) error {
+			if event.AggregateType == db.AuditResourceAccount && event.AggregateID == aggregateID {
+				return errPublish
+			}
+			return nil
+		}
+	}
+	_, err = store.RelayOutboxTx(context.Background(), 100, outboxMaxAttempts, rejectAccount(aggregateID))
+	require.ErrorIs(t, err, errPublish)
+
+	unpublished, err := store.ListUnpublishedOutboxEvents(context.Background(), db.ListUnpublishedOutboxEventsParams{
+		MaxAttempts: outboxMaxAttempts,
+		Limit:       100,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, unpublished)
+	require.Equal(t, aggregateID, unpublished[0].AggregateID)
+	require.Equal(t, int32(1), unpublished[0].Attempts)
+	require.Equal(t, errPublish.Error(), unpublished[0].LastError)
+
+	events = relayAll(t, store, nil, aggregate{db.AuditResourceAccount, account.ID})
+	require.Len(t, events, 1)
+	require.Equal(t, int32(1), events[0].Attempts)
+
+	// an event the publisher always rejects is dead-lettered once it failed outboxMaxAttempts times,
+	// the events behind it are published from then on
+	dead := createAccount(t, store, "EUR", 0)
+	behind := createAccount(t, store, "EUR", 0)
+	reject := rejectAccount(strconv.FormatInt(dead.ID, 10))
+	for attempt := 1; attempt < outboxMaxAttempts; attempt++ {
+		n, err := store.RelayOutboxTx(context.Background(), 100, outboxMaxAttempts, reject)
+		require.ErrorIs(t, err, errPublish)
+		require.Zero(t, n)
+	}
+	events = relayAll(t, store, reject,
+		aggregate{db.AuditResourceAccount, dead.ID},
+		aggregate{db.AuditResourceAccount, behind.ID},
+	)
+	require.Len(t, events, 1)
+	require.Equal(t, strconv.FormatInt(behind.ID, 10), events[0].AggregateID)
+
+	// the dead event stays unpublished with its last error, the relays no longer hand it out
+	unpublished, err = store.ListUnpublishedOutboxEvents(context.Background(), db.ListUnpublishedOutboxEventsParams{
+		MaxAttempts: math.MaxInt32,
+		Limit:       100,
+	})
+	require.NoError(t, err)
+	var found bool
+	for _, event := range unpublished {
+		if event.AggregateType == db.AuditResourceAccount && event.AggregateID == strconv.FormatInt(dead.ID, 10) {
+			found = true
+			require.Equal(t, int32(outboxMaxAttempts), event.Attempts)
+			require.Equal(t, errPublish.Error(), event.LastError)
+		}
+	}
+	require.True(t, found)
+	n, err := store.RelayOutboxTx(context.Background(), 100, outboxMaxAttempts, reject)
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
+
+func testWebhooks(t *testing.T, newStore NewStore) {
+	store := newStore(t, nil)
+	ctx := testContext()
+
+	webhook, err := store.CreateWebhookTx(ctx, db.CreateWebhookParams{
+		Url:        "https://example.com/" + utils.RandomString(6),
+		EventTypes: []string{db.EventTransferCompleted},
+		Secret:     utils.RandomString(32),
+	})
+	require.NoError(t, err)
+	require.True(t, webhook.Active)
+	requireRecent(t, webhook.CreatedAt)
+
+	got, err := store.GetWebhook(context.Background(), webhook.ID)
+	require.NoError(t, err)
+	require.Equal(t, webhook, got)
+
+	// deliveries are only queued for the active webhooks subscribed to the event type
+	inactive, err := store.CreateWebhookTx(ctx, db.CreateWebhookParams{
+		Url:        "https://example.com/" + utils.RandomString(6),
+		EventTypes: []string{db.EventTransferCompleted},
+		Secret:     utils.RandomString(32),
+	})
+	require.NoError(t, err)
+	inactive, err = store.UpdateWebhookTx(ctx, db.UpdateWebhookParams{
+		ID:         inactive.ID,
+		Url:        inactive.Url,
+		EventTypes: inactive.EventTypes,
+		Active:     false,
+	})
+	require.NoError(t, err)
+	require.False(t, inactive.Active)
+
+	arg := db.CreateWebhookDeliveriesParams{
+		EventID:   utils.RandomInt(1, 1000000000),
+		EventType: db.EventTransferCompleted,
+		Payload:   json.RawMessage(`{"transfer":{"id":1}}`),
+	}
+	n, err := store.CreateWebhookDeliveries(context.Background(), arg)
+	require.NoError(t, err)
+	require.Positive(t, n)
+
+	// the relay may publish the same event twice, it must not queue a second delivery
+	n, err = store.CreateWebhookDeliveries(context.Background(), arg)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	// the first attempt fails, the retry isn't due before next
+	next := time.Now().Add(time.Hour)
+	attempts := processAll(t, store, webhook.ID, db.WebhookAttempt{
+		Status:         db.WebhookDeliveryPending,
+		ResponseStatus: 500,
+		LastError:      "boom",
+		NextAttemptAt:  next,
+	})
+	require.Equal(t, 1, attempts)
+
+	deliveries, err := store.ListWebhookDeliveries(context.Background(), db.ListWebhookDeliveriesParams{WebhookID: webhook.ID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	delivery := deliveries[0]
+	require.Equal(t, arg.EventID, delivery.EventID)
+	require.JSONEq(t, string(arg.Payload), string(delivery.Payload))
+	require.Equal(t, db.WebhookDeliveryPending, delivery.Status)
+	require.Equal(t, int32(1), delivery.Attempts)
+	require.Equal(t, int32(500), delivery.ResponseStatus)
+	require.Equal(t, "boom", delivery.LastError)
+	require.WithinDuration(t, next, delivery.NextAttemptAt, time.Second)
+	require.Zero(t, processAll(t, store, webhook.ID, db.WebhookAttempt{Status: db.WebhookDeliverySucceeded}))
+
+	deliveries, err = store.ListWebhookDeliveries(context.Background(), db.ListWebhookDeliveriesParams{WebhookID: inactive.ID, Limit: 10})
+	require.NoError(t, err)
+	require.Empty(t, deliveries)
+
+	// deleting the webhook deletes its deliveries
+	require.NoError(t, store.DeleteWebhookTx(ctx, webhook.ID))
+	_, err = store.GetWebhook(context.Background(), webhook.ID)
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+	deliveries, err = store.ListWebhookDeliveries(context.Background(), db.ListWebhookDeliveriesParams{WebhookID: webhook.ID, Limit: 10})
+	require.NoError(t, err)
+	require.Empty(t, deliveries)
+
+	requireKind(t, store.DeleteWebhookTx(ctx, webhook.ID), db.ErrNotFound, nil)
+
+	// the secret never reaches the audit log
+	events := auditEvents(t, store, db.AuditResourceWebhook, strconv.FormatInt(webhook.ID, 10))
+	require.Len(t, events, 2)
+	require.Equal(t, db.AuditActionWebhookDelete, events[0].Action)
+	require.JSONEq(t, "null", string(events[0].After))
+	before := unmarshal[db.Webhook](t, events[0].Before)
+	require.Equal(t, webhook.ID, before.ID)
+	require.Empty(t, before.Secret)
+	require.Empty(t, unmarshal[db.Webhook](t, events[1].After).Secret)
+}
+
+// deliver runs outside of any transaction and the deliveries it is given are claimed: another worker running
+// meanwhile skips them, and the ones left when the worker stops are released without being attempted
+func testWebhookClaims(t *testing.T, newStore NewStore) {
+	store := newStore(t, nil)
+
+	webhook, err := store.CreateWebhookTx(testContext(), db.CreateWebhookParams{
+		Url:        "https://example.com/" + utils.RandomString(6),
+		EventTypes: []string{db.EventTransferCompleted},
+		Secret:     utils.RandomString(32),
+	})
+	require.NoError(t, err)
+
+	eventID := utils.RandomInt(1, 1000000000)
+	for i := int64(0); i < 3; i++ {
+		_, err := store.CreateWebhookDeliveries(context.Background(), db.CreateWebhookDeliveriesParams{
+			EventID:   eventID + i,
+			EventType: db.EventTransferCompleted,
+			Payload:   json.RawMessage(`{}`),
+		})
+		require.NoError(t, err)
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+
+	attempts := 0
+	n, err := store.ProcessWebhookDeliveriesTx(ctx, 100, func(ctx context.Context, delivery db.WebhookDelivery, hook db.Webhook) db.WebhookAttempt {
+		if hook.ID != webhook.ID {
+			return db.WebhookAttempt{Status: db.WebhookDeliveryPending, NextAttemptAt: time.Now().Add(time.Hour)}
+		}
+		attempts++
+
+		// a second worker finds none of the claimed deliveries
+		require.Zero(t, processAll(t, store, webhook.ID, db.WebhookAttempt{Status: db.WebhookDeliverySucceeded}))
+
+		// the worker is stopped during the first attempt, the attempt is still recorded
+		stop()
+		return db.WebhookAttempt{Status: db.WebhookDeliveryPending, LastError: "stopped", NextAttemptAt: time.Now().Add(time.Hour)}
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, attempts)
+	require.GreaterOrEqual(t, n, 1)
+
+	deliveries, err := store.ListWebhookDeliveries(context.Background(), db.ListWebhookDeliveriesParams{WebhookID: webhook.ID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 3)
+
+	var attempted, released int
+	for _, delivery := range deliveries {
+		require.Nil(t, delivery.LockedUntil)
+		require.Equal(t, db.WebhookDeliveryPending, delivery.Status)
+		switch delivery.Attempts {
+		case 1:
+			attempted++
+			require.Equal(t, "stopped", delivery.LastError)
+		case 0:
+			released++
+		}
+	}
+	require.Equal(t, 1, attempted)
+	require.Equal(t, 2, released)
+
+	// the released deliveries are due right away
+	require.Equal(t, 2, processAll(t, store, webhook.ID, db.WebhookAttempt{Status: db.WebhookDeliverySucceeded}))
+
+	// a worker whose claim ran out and was taken over doesn't record its attempt, the loss is counted
+	_, err = store.CreateWebhookDeliveries(context.Background(), db.CreateWebhookDeliveriesParams{
+		EventID:   eventID + 3,
+		EventType: db.EventTransferCompleted,
+		Payload:   json.RawMessage(`{}`),
+	})
+	require.NoError(t, err)
+	lost := testutil.ToFloat64(metrics.WebhookClaimsLost)
+	takenOver := time.Now().Add(time.Hour)
+	_, err = store.ProcessWebhookDeliveriesTx(context.Background(), 100, func(ctx context.Context, delivery db.WebhookDelivery, hook db.Webhook) db.WebhookAttempt {
+		if hook.ID != webhook.ID {
+			return db.WebhookAttempt{Status: db.WebhookDeliveryPending, NextAttemptAt: time.Now().Add(time.Hour)}
+		}
+		err := store.ClaimWebhookDeliveries(ctx, db.ClaimWebhookDeliveriesParams{LockedUntil: &takenOver, Ids: []int64{delivery.ID}})
+		require.NoError(t, err)
+		return db.WebhookAttempt{Status: db.WebhookDeliverySucceeded}
+	})
+	require.NoError(t, err)
+	require.Equal(t, lost+1, testutil.ToFloat64(metrics.WebhookClaimsLost))
+
+	deliveries, err = store.ListWebhookDeliveries(context.Background(), db.ListWebhookDeliveriesParams{WebhookID: webhook.ID, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, eventID+3, deliveries[0].EventID)
+	require.Equal(t, db.WebhookDeliveryPending, deliveries[0].Status)
+	require.Zero(t, deliveries[0].Attempts)
+	require.NotNil(t, deliveries[0].LockedUntil)
+	require.WithinDuration(t, takenOver, *deliveries[0].LockedUntil, time.Second)
+}
+
+// processAll processes the due deliveries until none is left and returns the number of attempts made
+// for webhookID, which all end with attempt. The deliveries of other webhooks are postponed by an hour
+func processAll(t *testing.T, store db.Store, webhookID int64, attempt db.WebhookAttempt) int {
+	attempts := 0
+	for {
+		n, err := store.ProcessWebhookDeliveriesTx(context.Background(), 100, func(ctx context.Context, delivery db.WebhookDelivery, webhook db.Webhook) db.WebhookAttempt {
+			require.Equal(t, delivery.WebhookID, webhook.ID)
+			if webhook.ID != webhookID {
+				return db.WebhookAttempt{Status: db.WebhookDeliveryPending, NextAttemptAt: time.Now().Add(time.Hour)}
+			}
+			attempts++
+			return attempt
+		})
+		require.NoError(t, err)
+		if n == 0 {
+			return attempts
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// Package storetest is the suite every db.Store must pass. It runs against db.SQLStore and the store of package memdb,
+// so that code tested against the memory store behaves the same with postgres
+package storetest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/fraud"
+	"github.com/techschool/simple-bank/utils"
+)
+
+// NewStore returns the store under test, which screens the transfers with screener unless it is nil
+type NewStore func(t *testing.T, screener fraud.Screener) db.Store
+
+// Run runs the suite against the stores returned by newStore
+// the stores may hold data of other tests, the suite only looks at the rows it creates
+// and doesn't delete them: run it on a database of its own
+func Run(t *testing.T, newStore NewStore) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, newStore NewStore)
+	}{
+		{"Accounts", testAccounts},
+		{"EquityAccounts", testEquityAccounts},
+		{"AccountStatus", testAccountStatus},
+		{"TransferTx", testTransferTx},
+		{"TransferTxErrors", testTransferTxErrors},
+		{"ConcurrentTransfers", testConcurrentTransfers},
+		{"ConcurrentScreening", testConcurrentScreening},
+		{"TransferLimits", testTransferLimits},
+		{"TransferReview", testTransferReview},
+		{"TransferMetrics", testTransferMetrics},
+		{"Reconcile", testReconcile},
+		{"Outbox", testOutbox},
+		{"Webhooks", testWebhooks},
+		{"WebhookClaims", testWebhookClaims},
+		{"Compliance", testCompliance},
+		{"CancelledContext", testCancelledContext},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.test(t, newStore)
+		})
+	}
+}
+
+// testActor is the actor of the changes made by the suite
+const testActor = "storetest"
+
+// missingID is the id of a row that doesn't exist
+const missingID = math.MaxInt64
+
+// testContext returns a context whose changes are audited as made by testActor
+func testContext() context.Context {
+	return db.WithAuditInfo(context.Background(), db.AuditInfo{Actor: testActor, RequestID: "storetest-request", ClientIP: "127.0.0.1"})
+}
+
+// createAccount creates an account whose opening balance is paid by the equity account of the currency
+func createAccount(t *testing.T, store db.Store, currency string, balance int64) db.Account {
+	account, err := store.CreateAccountTx(testContext(), db.CreateAccountParams{
+		Owner:    utils.RandomOwner(),
+		Balance:  balance,
+		Currency: currency,
+	})
+	require.NoError(t, err)
+	return account
+}
+
+// getAccount returns the account as stored now
+func getAccount(t *testing.T, store db.Store, id int64) db.Account {
+	account, err := store.GetAccount(context.Background(), id)
+	require.NoError(t, err)
+	return account
+}
+
+// auditEvents returns the audit events of a resource, newest first
+func auditEvents(t *testing.T, store db.Store, resourceType string, resourceID string) []db.AuditEvent {
+	events, err := store.ListAuditEvents(context.Background(), db.ListAuditEventsParams{
+		ResourceType: &resourceType,
+		ResourceID:   &resourceID,
+		Limit:        100,
+	})
+	require.NoError(t, err)
+	return events
+}
+
+// requireKind checks that err is a *db.Error of the given kind, with the given details
+func requireKind(t *testing.T, err error, kind error, details map[string]any) {
+	t.Helper()
+	require.ErrorIs(t, err, kind)
+
+	var dbErr *db.Error
+	require.ErrorAs(t, err, &dbErr)
+	for key, value := range details {
+		require.EqualValues(t, value, dbErr.Details[key], key)
+	}
+}
+
+// requirePgError checks that err is the postgres error raised by a violation of constraint
+func requirePgError(t *testing.T, err error, code string, constraint string) {
+	t.Helper()
+	var pgErr *pgconn.PgError
+	require.True(t, errors.As(err, &pgErr), "%v is not a postgres error", err)
+	require.Equal(t, code, pgErr.Code)
+	require.Equal(t, constraint, pgErr.ConstraintName)
+}
+
+// requireRecent checks that a timestamp written by the store is the current time
+func requireRecent(t *testing.T, timestamp time.Time) {
+	t.Helper()
+	require.WithinDuration(t, time.Now(), timestamp, time.Minute)
+}
+
+// unmarshal decodes the JSON of an audit event or an outbox event
+func unmarshal[T any](t *testing.T, data json.RawMessage) T {
+	var value T
+	require.NoError(t, json.Unmarshal(data, &value))
+	return value
+}
+
+// requireJSON checks that data is the JSON of expected, decoding it instead would lose the time zones
+func requireJSON(t *testing.T, expected any, data json.RawMessage) {
+	t.Helper()
+	encoded, err := json.Marshal(expected)
+	require.NoError(t, err)
+	require.JSONEq(t, string(encoded), string(data))
+}
+
+// verdictScreener returns the same verdict for every transfer
+type verdictScreener struct {
+	verdict fraud.Verdict
+}
+
+func (screener *verdictScreener) Screen(ctx context.Context, input fraud.Input) (fraud.Verdict, error) {
+	return screener.verdict, nil
+}
@@ -0,0 +1,417 @@
+package storetest
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/fraud"
+	"github.com/techschool/simple-bank/metrics"
+	"github.com/techschool/simple-bank/utils"
+)
+
+func testTransferTx(t *testing.T, newStore NewStore) {
+	store := newStore(t, nil)
+	from := createAccount(t, store, "USD", 100)
+	to := createAccount(t, store, "USD", 20)
+
+	result, err := store.TransferTx(testContext(), db.TransferTxParams{FromAccountID: from.ID, ToAccountID: to.ID, Amount: 30})
+	require.NoError(t, err)
+
+	transfer := result.Transfer
+	require.NotZero(t, transfer.ID)
+	require.Equal(t, from.ID, transfer.FromAccountID)
+	require.Equal(t, to.ID, transfer.ToAccountID)
+	require.Equal(t, int64(30), transfer.Amount)
+	require.Equal(t, db.TransferStatusCompleted, transfer.Status)
+	requireRecent(t, transfer.CreatedAt)
+
+	require.Equal(t, from.ID, result.FromEntry.AccountID)
+	require.Equal(t, int64(-30), result.FromEntry.Amount)
+	require.Equal(t, to.ID, result.ToEntry.AccountID)
+	require.Equal(t, int64(30), result.ToEntry.Amount)
+	require.Equal(t, &transfer.ID, result.FromEntry.TransferID)
+	require.Equal(t, &transfer.ID, result.ToEntry.TransferID)
+
+	require.Equal(t, int64(70), result.FromAccount.Balance)
+	require.Equal(t, int64(50), result.ToAccount.Balance)
+	require.Equal(t, result.FromAccount, getAccount(t, store, from.ID))
+	require.Equal(t, result.ToAccount, getAccount(t, store, to.ID))
+
+	ctx := context.Background()
+	got, err := store.GetTransfer(ctx, transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, transfer, got)
+
+	entry, err := store.GetEntry(ctx, result.FromEntry.ID)
+	require.NoError(t, err)
+	require.Equal(t, result.FromEntry, entry)
+
+	// either side of the transfer lists it, after the transfer of its opening balance
+	for _, arg := range []db.ListTransfersParams{
+		{FromAccountID: from.ID, ToAccountID: from.ID, Limit: 10},
+		{FromAccountID: to.ID, ToAccountID: to.ID, Limit: 10},
+	} {
+		transfers, err := store.ListTransfers(ctx, arg)
+		require.NoError(t, err)
+		require.Len(t, transfers, 2)
+		require.Equal(t, transfer, transfers[1])
+	}
+
+	events := auditEvents(t, store, db.AuditResourceTransfer, strconv.FormatInt(transfer.ID, 10))
+	require.Len(t, events, 1)
+	require.Equal(t, db.AuditActionTransferCreate, events[0].Action)
+	requireJSON(t, result, events[0].After)
+}
+
+func testTransferTxErrors(t *testing.T, newStore NewStore) {
+	store := newStore(t, nil)
+	ctx := testContext()
+
+	from := createAccount(t, store, "USD", 100)
+	to := createAccount(t, store, "USD", 0)
+
+	transfer := func(fromID int64, toID int64, amount int64) error {
+		_, err := store.TransferTx(ctx, db.TransferTxParams{FromAccountID: fromID, ToAccountID: toID, Amount: amount})
+		return err
+	}
+
+	// the failed transfers are rolled back, only the opening balance is left
+	requireUntouched := func(t *testing.T) {
+		require.Equal(t, int64(100), getAccount(t, store, from.ID).Balance)
+		require.Equal(t, int64(0), getAccount(t, store, to.ID).Balance)
+
+		entries, err := store.ListEntries(context.Background(), db.ListEntriesParams{AccountID: from.ID, Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+
+		transfers, err := store.ListTransfers(context.Background(), db.ListTransfersParams{FromAccountID: from.ID, ToAccountID: from.ID, Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, transfers, 1)
+	}
+
+	t.Run("InsufficientFunds", func(t *testing.T) {
+		requireKind(t, transfer(from.ID, to.ID, 101), db.ErrInsufficientFunds, map[string]any{"account_id": from.ID})
+		requireUntouched(t)
+	})
+
+	t.Run("UnknownAccount", func(t *testing.T) {
+		err := transfer(from.ID, missingID, 10)
+		requireKind(t, err, db.ErrForeignKeyViolation, map[string]any{"constraint": "transfers_to_account_id_fkey"})
+		requireUntouched(t)
+	})
+
+	t.Run("Constraints", func(t *testing.T) {
+		for constraint, err := range map[string]error{
+			"transfers_amount_positive":   transfer(from.ID, to.ID, 0),
+			"transfers_distinct_accounts": transfer(from.ID, from.ID, 10),
+		} {
+			requireKind(t, err, db.ErrCheckViolation, map[string]any{"constraint": constraint})
+			requirePgError(t, err, db.CheckViolation, constraint)
+		}
+		requireUntouched(t)
+	})
+
+	t.Run("LimitExceeded", func(t *testing.T) {
+		tier := "storetest_" + utils.RandomString(8)
+		_, err := store.UpsertTransferLimitTierTx(ctx, db.UpsertTransferLimitTierParams{
+			Name:            tier,
+			MaxSingleAmount: 50,
+			MaxDailyAmount:  1000,
+			MaxDailyCount:   2,
+		})
+		require.NoError(t, err)
+
+		sender := createAccount(t, store, "USD", 1000)
+		receiver := createAccount(t, store, "USD", 0)
+		_, err = store.UpdateAccountTransferLimitsTx(ctx, db.UpdateAccountTransferLimitsParams{AccountID: sender.ID, Tier: tier})
+		require.NoError(t, err)
+
+		requireKind(t, transfer(sender.ID, receiver.ID, 60), db.ErrLimitExceeded, map[string]any{
+			"account_id":             sender.ID,
+			"limit":                  db.LimitSingleAmount,
+			"max_single_amount":      50,
+			"remaining_daily_amount": 1000,
+			"remaining_daily_count":  2,
+		})
+
+		require.NoError(t, transfer(sender.ID, receiver.ID, 10))
+		require.NoError(t, transfer(sender.ID, receiver.ID, 10))
+		requireKind(t, transfer(sender.ID, receiver.ID, 10), db.ErrLimitExceeded, map[string]any{
+			"limit":                  db.LimitDailyCount,
+			"remaining_daily_amount": 980,
+			"remaining_daily_count":  0,
+		})
+		require.Equal(t, int64(980), getAccount(t, store, sender.ID).Balance)
+	})
+
+	t.Run("AccountFrozen", func(t *testing.T) {
+		for _, frozen := range []db.Account{from, to} {
+			_, err := store.UpdateAccountStatusTx(ctx, db.UpdateAccountStatusParams{ID: frozen.ID, Status: db.AccountStatusFrozen})
+			require.NoError(t, err)
+
+			requireKind(t, transfer(from.ID, to.ID, 10), db.ErrAccountFrozen, map[string]any{"account_id": frozen.ID})
+			requireUntouched(t)
+
+			_, err = store.UpdateAccountStatusTx(ctx, db.UpdateAccountStatusParams{ID: frozen.ID, Status: db.AccountStatusActive})
+			require.NoError(t, err)
+		}
+	})
+}
+
+func testConcurrentTransfers(t *testing.T, newStore NewStore) {
+	store := newStore(t, nil)
+
+	t.Run("BothWays", func(t *testing.T) {
+		account1 := createAccount(t, store, "USD", 1000)
+		account2 := createAccount(t, store, "USD", 1000)
+
+		// as many transfers go each way, so the balances end up unchanged
+		errs := runConcurrently(10, func(i int) error {
+			from, to := account1.ID, account2.ID
+			if i%2 == 1 {
+				from, to = to, from
+			}
+			_, err := store.TransferTx(testContext(), db.TransferTxParams{FromAccountID: from, ToAccountID: to, Amount: 10})
+			return err
+		})
+		for _, err := range errs {
+			require.NoError(t, err)
+		}
+
+		require.Equal(t, int64(1000), getAccount(t, store, account1.ID).Balance)
+		require.Equal(t, int64(1000), getAccount(t, store, account2.ID).Balance)
+	})
+
+	t.Run("Overdraft", func(t *testing.T) {
+		from := createAccount(t, store, "USD", 50)
+		to := createAccount(t, store, "USD", 0)
+
+		// the balance covers half of the transfers, the other half must fail and leave nothing behind
+		errs := runConcurrently(10, func(i int) error {
+			_, err := store.TransferTx(testContext(), db.TransferTxParams{FromAccountID: from.ID, ToAccountID: to.ID, Amount: 10})
+			return err
+		})
+
+		succeeded := 0
+		for _, err := range errs {
+			if err == nil {
+				succeeded++
+				continue
+			}
+			requireKind(t, err, db.ErrInsufficientFunds, map[string]any{"account_id": from.ID})
+		}
+		require.Equal(t, 5, succeeded)
+		require.Equal(t, int64(0), getAccount(t, store, from.ID).Balance)
+		require.Equal(t, int64(50), getAccount(t, store, to.ID).Balance)
+
+		entries, err := store.ListEntries(context.Background(), db.ListEntriesParams{AccountID: from.ID, Limit: 20})
+		require.NoError(t, err)
+		require.Len(t, entries, 6) // with the opening balance
+	})
+}
+
+// testConcurrentScreening sends a burst of transfers at once: each one must be screened with the ones before it
+// in its history, or the whole burst gets through a velocity rule meant to stop it
+func testConcurrentScreening(t *testing.T, newStore NewStore) {
+	engine, err := fraud.ParseRules([]byte(`
+rules:
+  - name: burst
+    type: velocity
+    window: 10m
+    max_count: 3
+    action: review
+`))
+	require.NoError(t, err)
+	store := newStore(t, engine)
+
+	from := createAccount(t, store, "USD", 1000)
+	to := createAccount(t, store, "USD", 0)
+
+	statuses := make([]string, 10)
+	errs := runConcurrently(len(statuses), func(i int) error {
+		result, err := store.TransferTx(testContext(), db.TransferTxParams{FromAccountID: from.ID, ToAccountID: to.ID, Amount: 10})
+		statuses[i] = result.Transfer.Status
+		return err
+	})
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+
+	counts := map[string]int{}
+	for _, status := range statuses {
+		counts[status]++
+	}
+	require.Equal(t, map[string]int{db.TransferStatusCompleted: 3, db.TransferStatusPendingReview: 7}, counts)
+	require.Equal(t, int64(970), getAccount(t, store, from.ID).Balance)
+}
+
+// runConcurrently calls fn n times at once and returns the n errors
+func runConcurrently(n int, fn func(i int) error) []error {
+	errs := make([]error, n)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = fn(i)
+		}()
+	}
+	wg.Wait()
+
+	return errs
+}
+
+func testTransferReview(t *testing.T, newStore NewStore) {
+	screener := &verdictScreener{verdict: fraud.Verdict{Decision: fraud.Review, Rule: "storetest", Reason: "sent to review"}}
+	store := newStore(t, screener)
+	ctx := testContext()
+
+	from := createAccount(t, store, "USD", 100)
+	to := createAccount(t, store, "USD", 0)
+	send := func() (db.TransferTxResult, error) {
+		return store.TransferTx(ctx, db.TransferTxParams{FromAccountID: from.ID, ToAccountID: to.ID, Amount: 40})
+	}
+
+	// no money moves while the transfer waits for review
+	result, err := send()
+	require.NoError(t, err)
+	pending := result.Transfer
+	require.Equal(t, db.TransferStatusPendingReview, pending.Status)
+	require.Equal(t, "storetest", pending.ScreeningRule)
+	require.Equal(t, "sent to review", pending.ScreeningReason)
+	require.Zero(t, result.FromEntry.ID)
+	require.Equal(t, int64(100), getAccount(t, store, from.ID).Balance)
+
+	queue, err := store.ListPendingTransfers(context.Background(), db.ListPendingTransfersParams{Limit: 1000})
+	require.NoError(t, err)
+	require.Contains(t, queue, pending)
+
+	// the approval moves the money
+	approved, err := store.ApproveTransferTx(ctx, db.ReviewTransferTxParams{TransferID: pending.ID, Note: "known customer"})
+	require.NoError(t, err)
+	require.Equal(t, db.TransferStatusCompleted, approved.Transfer.Status)
+	require.Equal(t, testActor, approved.Transfer.ReviewedBy)
+	require.Equal(t, "known customer", approved.Transfer.ReviewNote)
+	require.NotNil(t, approved.Transfer.ReviewedAt)
+	require.Equal(t, int64(-40), approved.FromEntry.Amount)
+	require.Equal(t, int64(60), getAccount(t, store, from.ID).Balance)
+	require.Equal(t, int64(40), getAccount(t, store, to.ID).Balance)
+
+	_, err = store.ApproveTransferTx(ctx, db.ReviewTransferTxParams{TransferID: pending.ID})
+	requireKind(t, err, db.ErrTransferNotPending, map[string]any{"transfer_id": pending.ID, "status": db.TransferStatusCompleted})
+
+	// the rejection moves nothing
+	result, err = send()
+	require.NoError(t, err)
+	rejected, err := store.RejectTransferTx(ctx, db.ReviewTransferTxParams{TransferID: result.Transfer.ID, Note: "unknown payee"})
+	require.NoError(t, err)
+	require.Equal(t, db.TransferStatusRejected, rejected.Status)
+	require.Equal(t, int64(60), getAccount(t, store, from.ID).Balance)
+
+	events := auditEvents(t, store, db.AuditResourceTransfer, strconv.FormatInt(rejected.ID, 10))
+	require.Len(t, events, 2)
+	require.Equal(t, db.AuditActionTransferReject, events[0].Action)
+	require.Equal(t, db.TransferStatusPendingReview, unmarshal[db.Transfer](t, events[0].Before).Status)
+
+	_, err = store.RejectTransferTx(ctx, db.ReviewTransferTxParams{TransferID: missingID})
+	requireKind(t, err, db.ErrNotFound, nil)
+
+	// a denied transfer isn't written at all
+	screener.verdict = fraud.Verdict{Decision: fraud.Deny, Rule: "storetest", Reason: "denied"}
+	_, err = send()
+	requireKind(t, err, db.ErrTransferDenied, map[string]any{"account_id": from.ID})
+
+	transfers, err := store.ListTransfers(context.Background(), db.ListTransfersParams{FromAccountID: from.ID, ToAccountID: from.ID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, transfers, 3) // with the opening balance
+}
+
+func testTransferMetrics(t *testing.T, newStore NewStore) {
+	screener := &verdictScreener{verdict: fraud.Verdict{Decision: fraud.Allow}}
+	store := newStore(t, screener)
+	ctx := testContext()
+
+	from := createAccount(t, store, "CAD", 100)
+	to := createAccount(t, store, "CAD", 0)
+	send := func(amount int64) (db.TransferTxResult, error) {
+		return store.TransferTx(ctx, db.TransferTxParams{FromAccountID: from.ID, ToAccountID: to.ID, Amount: amount})
+	}
+
+	completed := metrics.TransfersCompleted.WithLabelValues("CAD")
+	volume := metrics.TransferVolume.WithLabelValues("CAD")
+	beforeCompleted := testutil.ToFloat64(completed)
+	beforeVolume := testutil.ToFloat64(volume)
+
+	_, err := send(30)
+	require.NoError(t, err)
+	_, err = send(1000)
+	requireKind(t, err, db.ErrInsufficientFunds, nil)
+
+	// a transfer held for review counts once it is approved
+	screener.verdict = fraud.Verdict{Decision: fraud.Review, Rule: "storetest"}
+	pending, err := send(40)
+	require.NoError(t, err)
+	require.Equal(t, beforeCompleted+1, testutil.ToFloat64(completed))
+
+	_, err = store.ApproveTransferTx(ctx, db.ReviewTransferTxParams{TransferID: pending.Transfer.ID})
+	require.NoError(t, err)
+	require.Equal(t, beforeCompleted+2, testutil.ToFloat64(completed))
+	require.Equal(t, beforeVolume+70, testutil.ToFloat64(volume))
+}
+
+func testReconcile(t *testing.T, newStore NewStore) {
+	store := newStore(t, nil)
+	ctx := context.Background()
+
+	from := createAccount(t, store, "USD", 100)
+	to := createAccount(t, store, "USD", 0)
+	_, err := store.TransferTx(testContext(), db.TransferTxParams{FromAccountID: from.ID, ToAccountID: to.ID, Amount: 30})
+	require.NoError(t, err)
+	require.Empty(t, mismatches(t, store, from.ID, to.ID))
+
+	// a balance changed without an entry no longer adds up
+	_, err = store.AddAccountBalance(ctx, db.AddAccountBalanceParams{ID: to.ID, Amount: 5})
+	require.NoError(t, err)
+
+	require.Equal(t, []db.ReconcileAccountsRow{{
+		ID:            to.ID,
+		Currency:      "USD",
+		Balance:       35,
+		EntryTotal:    30,
+		TransferTotal: 30,
+	}}, mismatches(t, store, from.ID, to.ID))
+}
+
+// mismatches returns the rows of ReconcileAccounts about the given accounts, the other accounts are left out
+func mismatches(t *testing.T, store db.Store, accountIDs ...int64) []db.ReconcileAccountsRow {
+	rows, err := store.ReconcileAccounts(context.Background())
+	require.NoError(t, err)
+
+	result := []db.ReconcileAccountsRow{}
+	for _, row := range rows {
+		if slices.Contains(accountIDs, row.ID) {
+			result = append(result, row)
+		}
+	}
+	return result
+}
+
+func testCancelledContext(t *testing.T, newStore NewStore) {
+	store := newStore(t, nil)
+	from := createAccount(t, store, "USD", 100)
+	to := createAccount(t, store, "USD", 0)
+
+	ctx, cancel := context.WithCancel(testContext())
+	cancel()
+
+	_, err := store.TransferTx(ctx, db.TransferTxParams{FromAccountID: from.ID, ToAccountID: to.ID, Amount: 10})
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, int64(100), getAccount(t, store, from.ID).Balance)
+}
@@ -0,0 +1,88 @@
+package events
+
+import (
+	"sync"
+
+	db "github.com/techschool/simple-bank/db2/sqlc"
+)
+
+// subscriptionBuffer is how many events a subscriber may lag behind before it is dropped
+const subscriptionBuffer = 64
+
+// Bus fans balance changes out to in-process subscribers, such as SSE and gRPC streams
+type Bus struct {
+	mu          sync.Mutex
+	subscribers map[int64]map[*Subscription]struct{} // keyed by account ID
+}
+
+// Subscription receives the balance changes of one account
+type Subscription struct {
+	bus       *Bus
+	accountID int64
+	events    chan db.BalanceChangedEvent
+	once      sync.Once
+}
+
+// NewBus creates a bus without subscribers
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[int64]map[*Subscription]struct{})}
+}
+
+// Subscribe starts receiving the balance changes of accountID
+// the caller must call Close when it is done with the subscription
+func (bus *Bus) Subscribe(accountID int64) *Subscription {
+	sub := &Subscription{
+		bus:       bus,
+		accountID: accountID,
+		events:    make(chan db.BalanceChangedEvent, subscriptionBuffer),
+	}
+
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	if bus.subscribers[accountID] == nil {
+		bus.subscribers[accountID] = make(map[*Subscription]struct{})
+	}
+	bus.subscribers[accountID][sub] = struct{}{}
+	return sub
+}
+
+// Publish sends the event to every subscriber of its account without blocking
+// a subscriber whose buffer is full is dropped: its channel is closed, so the client can reconnect and
+// re-read the account, instead of silently missing events
+func (bus *Bus) Publish(event db.BalanceChangedEvent) {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	for sub := range bus.subscribers[event.AccountID] {
+		select {
+		case sub.events <- event:
+		default:
+			bus.removeLocked(sub)
+		}
+	}
+}
+
+// Events returns the channel of balance changes, it is closed when the subscription ends
+func (sub *Subscription) Events() <-chan db.BalanceChangedEvent {
+	return sub.events
+}
+
+// Close ends the subscription, it is safe to call more than once
+func (sub *Subscription) Close() {
+	sub.bus.mu.Lock()
+	defer sub.bus.mu.Unlock()
+
+	sub.bus.removeLocked(sub)
+}
+
+// removeLocked must be called with bus.mu held
+func (bus *Bus) removeLocked(sub *Subscription) {
+	sub.once.Do(func() {
+		delete(bus.subscribers[sub.accountID], sub)
+		if len(bus.subscribers[sub.accountID]) == 0 {
+			delete(bus.subscribers, sub.accountID)
+		}
+		close(sub.events)
+	})
+}
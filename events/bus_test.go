@@ -0,0 +1,56 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+)
+
+func TestBusDeliversToAccountSubscribers(t *testing.T) {
+	bus := NewBus()
+
+	sub1 := bus.Subscribe(1)
+	defer sub1.Close()
+	sub2 := bus.Subscribe(2)
+	defer sub2.Close()
+
+	event := db.BalanceChangedEvent{AccountID: 1, EntryID: 10, Amount: -5, Balance: 95}
+	bus.Publish(event)
+
+	require.Equal(t, event, <-sub1.Events())
+	require.Empty(t, sub2.Events()) // another account's subscriber sees nothing
+}
+
+func TestBusDropsSlowSubscriber(t *testing.T) {
+	bus := NewBus()
+	sub := bus.Subscribe(1)
+
+	for i := 0; i <= subscriptionBuffer; i++ {
+		bus.Publish(db.BalanceChangedEvent{AccountID: 1, EntryID: int64(i)})
+	}
+
+	// the buffered events are still readable, then the channel is closed
+	for i := 0; i < subscriptionBuffer; i++ {
+		_, ok := <-sub.Events()
+		require.True(t, ok)
+	}
+	_, ok := <-sub.Events()
+	require.False(t, ok)
+
+	sub.Close() // closing a dropped subscription is harmless
+}
+
+func TestBusClose(t *testing.T) {
+	bus := NewBus()
+	sub := bus.Subscribe(1)
+	sub.Close()
+	sub.Close()
+
+	_, ok := <-sub.Events()
+	require.False(t, ok)
+
+	// publishing after the last subscriber left doesn't panic
+	bus.Publish(db.BalanceChangedEvent{AccountID: 1})
+	require.Empty(t, bus.subscribers)
+}
@@ -0,0 +1,108 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/health"
+)
+
+const (
+	listenerMinReconnect = 10 * time.Second
+	listenerMaxReconnect = time.Minute
+	// listenerPingInterval is how often an idle connection is checked, so a dead one is noticed and reopened
+	listenerPingInterval = 90 * time.Second
+)
+
+// Listener feeds a Bus with the balance changes that TransferTx notifies on db.AccountEventsChannel
+// every replica runs its own listener, so every replica's subscribers see every committed transfer
+type Listener struct {
+	dataSource string
+	bus        *Bus
+	heartbeat  *health.Heartbeat // beats whenever the connection is known to be up
+}
+
+// NewListener creates a listener connecting to the database at dataSource
+func NewListener(dataSource string, bus *Bus) *Listener {
+	return &Listener{dataSource: dataSource, bus: bus, heartbeat: health.NewHeartbeat(listenerPingInterval)}
+}
+
+// Heartbeat returns the heartbeat of the listener, for the readiness checks
+func (listener *Listener) Heartbeat() *health.Heartbeat {
+	return listener.heartbeat
+}
+
+// Run listens until ctx is cancelled, it always returns ctx.Err()
+// a failed connection is opened again after a delay doubling from listenerMinReconnect to listenerMaxReconnect;
+// notifications sent while the connection was down are lost, clients re-read the account when their stream ends
+func (listener *Listener) Run(ctx context.Context) error {
+	delay := listenerMinReconnect
+	for {
+		connected, err := listener.listen(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if connected {
+			delay = listenerMinReconnect // the connection worked for a while, this is a new outage
+		}
+		slog.WarnContext(ctx, "account events listener connection failed", "error", err, "retry_in", delay)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, listenerMaxReconnect)
+	}
+}
+
+// listen holds a single connection until it fails or ctx is cancelled
+// connected reports whether the LISTEN succeeded, the error is the one that ended the connection
+func (listener *Listener) listen(ctx context.Context) (connected bool, err error) {
+	conn, err := pgx.Connect(ctx, listener.dataSource)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{db.AccountEventsChannel}.Sanitize()); err != nil {
+		return false, err
+	}
+	listener.heartbeat.Beat()
+
+	for {
+		// the timeout wakes an idle listener up to check its connection, it leaves the connection usable
+		waitCtx, cancel := context.WithTimeout(ctx, listenerPingInterval)
+		notification, err := conn.WaitForNotification(waitCtx)
+		cancel()
+
+		switch {
+		case err == nil:
+			listener.handle(notification.Payload)
+		case ctx.Err() != nil:
+			return true, ctx.Err()
+		case errors.Is(err, context.DeadlineExceeded):
+			if err := conn.Ping(ctx); err != nil {
+				return true, err
+			}
+		default:
+			return true, err
+		}
+		listener.heartbeat.Beat()
+	}
+}
+
+// handle decodes one notification payload and publishes it on the bus
+func (listener *Listener) handle(payload string) {
+	var event db.BalanceChangedEvent
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		slog.Error("account events listener received an invalid payload", "error", err)
+		return
+	}
+	listener.bus.Publish(event)
+}
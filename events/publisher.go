@@ -0,0 +1,135 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	db "github.com/techschool/simple-bank/db2/sqlc"
+)
+
+// EventPublisher delivers outbox events to downstream systems
+// the relay may call Publish more than once for the same event, so consumers must be idempotent
+// (the event ID is stable and can be used to deduplicate)
+type EventPublisher interface {
+	Publish(ctx context.Context, event db.Outbox) error
+}
+
+// publisher kinds accepted by NewPublisher, matching the EVENT_PUBLISHER config value
+const (
+	PublisherMemory = "memory"
+	PublisherNDJSON = "ndjson"
+)
+
+// NewPublisher creates the publisher of the given kind
+// path is only used by the ndjson publisher, "-" writes to stdout
+func NewPublisher(kind string, path string) (EventPublisher, error) {
+	switch kind {
+	case PublisherMemory:
+		return NewMemoryPublisher(), nil
+	case PublisherNDJSON:
+		if path == "-" {
+			return NewNDJSONPublisher(os.Stdout), nil
+		}
+		return NewFilePublisher(path)
+	default:
+		return nil, fmt.Errorf("unknown event publisher %q", kind)
+	}
+}
+
+// MemoryPublisher keeps every published event in memory, it is meant for tests and local development
+type MemoryPublisher struct {
+	mu     sync.Mutex
+	events []db.Outbox
+}
+
+// NewMemoryPublisher creates an empty MemoryPublisher
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+// Publish stores the event
+func (publisher *MemoryPublisher) Publish(ctx context.Context, event db.Outbox) error {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	publisher.events = append(publisher.events, event)
+	return nil
+}
+
+// Events returns a copy of the events published so far, in publish order
+func (publisher *MemoryPublisher) Events() []db.Outbox {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	return append([]db.Outbox(nil), publisher.events...)
+}
+
+// NDJSONPublisher writes every event as one JSON object per line
+type NDJSONPublisher struct {
+	mu     sync.Mutex
+	out    io.Writer
+	closer io.Closer // nil when the publisher doesn't own out
+}
+
+// NewNDJSONPublisher creates a publisher writing to out, the caller keeps ownership of out
+func NewNDJSONPublisher(out io.Writer) *NDJSONPublisher {
+	return &NDJSONPublisher{out: out}
+}
+
+// NewFilePublisher creates a publisher appending to the file at path, creating it if needed
+// the file is closed by Close
+func NewFilePublisher(path string) (*NDJSONPublisher, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &NDJSONPublisher{out: file, closer: file}, nil
+}
+
+// Publish writes the event as a single line
+func (publisher *NDJSONPublisher) Publish(ctx context.Context, event db.Outbox) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	_, err = publisher.out.Write(line)
+	return err
+}
+
+// Close closes the underlying file if the publisher opened it
+func (publisher *NDJSONPublisher) Close() error {
+	if publisher.closer == nil {
+		return nil
+	}
+	return publisher.closer.Close()
+}
+
+// MultiPublisher publishes every event to several publishers in order
+// it stops at the first error, the relay then retries the event on all of them, so each publisher must be idempotent
+type MultiPublisher struct {
+	publishers []EventPublisher
+}
+
+// NewMultiPublisher creates a publisher fanning events out to publishers
+func NewMultiPublisher(publishers ...EventPublisher) *MultiPublisher {
+	return &MultiPublisher{publishers: publishers}
+}
+
+// Publish hands the event to every publisher
+func (multi *MultiPublisher) Publish(ctx context.Context, event db.Outbox) error {
+	for _, publisher := range multi.publishers {
+		if err := publisher.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
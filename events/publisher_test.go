@@ -0,0 +1,84 @@
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/utils"
+)
+
+func randomEvent() db.Outbox {
+	return db.Outbox{
+		ID:            utils.RandomInt(1, 1000),
+		EventType:     db.EventBalanceChanged,
+		AggregateType: db.AuditResourceAccount,
+		AggregateID:   utils.RandomString(4),
+		Payload:       json.RawMessage(`{"amount":10}`),
+	}
+}
+
+func TestMemoryPublisher(t *testing.T) {
+	publisher := NewMemoryPublisher()
+
+	event1 := randomEvent()
+	event2 := randomEvent()
+	require.NoError(t, publisher.Publish(context.Background(), event1))
+	require.NoError(t, publisher.Publish(context.Background(), event2))
+
+	require.Equal(t, []db.Outbox{event1, event2}, publisher.Events())
+}
+
+func TestNDJSONPublisher(t *testing.T) {
+	var buf bytes.Buffer
+	publisher := NewNDJSONPublisher(&buf)
+
+	event1 := randomEvent()
+	event2 := randomEvent()
+	require.NoError(t, publisher.Publish(context.Background(), event1))
+	require.NoError(t, publisher.Publish(context.Background(), event2))
+
+	// one event per line
+	scanner := bufio.NewScanner(&buf)
+	var got []db.Outbox
+	for scanner.Scan() {
+		var event db.Outbox
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		got = append(got, event)
+	}
+	require.NoError(t, scanner.Err())
+	require.Equal(t, []db.Outbox{event1, event2}, got)
+}
+
+func TestNewPublisher(t *testing.T) {
+	publisher, err := NewPublisher(PublisherMemory, "")
+	require.NoError(t, err)
+	require.IsType(t, &MemoryPublisher{}, publisher)
+
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	publisher, err = NewPublisher(PublisherNDJSON, path)
+	require.NoError(t, err)
+	require.NoError(t, publisher.Publish(context.Background(), randomEvent()))
+	require.NoError(t, publisher.(*NDJSONPublisher).Close())
+	require.FileExists(t, path)
+
+	_, err = NewPublisher("kafka", "")
+	require.Error(t, err)
+}
+
+func TestMultiPublisher(t *testing.T) {
+	publisher1 := NewMemoryPublisher()
+	publisher2 := NewMemoryPublisher()
+	multi := NewMultiPublisher(publisher1, publisher2)
+
+	event := randomEvent()
+	require.NoError(t, multi.Publish(context.Background(), event))
+
+	require.Equal(t, []db.Outbox{event}, publisher1.Events())
+	require.Equal(t, []db.Outbox{event}, publisher2.Events())
+}
@@ -0,0 +1,90 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/health"
+)
+
+// Relay moves events from the outbox table to an EventPublisher
+type Relay struct {
+	store       db.Store
+	publisher   EventPublisher
+	interval    time.Duration // how long to wait once the outbox is drained
+	batchSize   int32
+	maxAttempts int32             // failed publishes before an event is dead-lettered
+	heartbeat   *health.Heartbeat // beats after every batch that was published without error
+}
+
+// NewRelay creates a relay polling store every interval and publishing up to batchSize events at a time,
+// an event the publisher rejected maxAttempts times is dead-lettered
+func NewRelay(store db.Store, publisher EventPublisher, interval time.Duration, batchSize int32, maxAttempts int32) *Relay {
+	return &Relay{
+		store:       store,
+		publisher:   publisher,
+		interval:    interval,
+		batchSize:   batchSize,
+		maxAttempts: maxAttempts,
+		heartbeat:   health.NewHeartbeat(interval),
+	}
+}
+
+// Heartbeat returns the heartbeat of the relay, for the readiness checks
+func (relay *Relay) Heartbeat() *health.Heartbeat {
+	return relay.heartbeat
+}
+
+// Run publishes outbox events until ctx is cancelled, it always returns ctx.Err()
+// errors are logged and retried on the next tick: nothing is lost because events stay in the outbox until published
+func (relay *Relay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(relay.interval)
+	defer ticker.Stop()
+
+	for {
+		relay.drain(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain publishes batches until the outbox is empty or a batch fails
+func (relay *Relay) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		n, err := relay.RelayOnce(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "outbox relay failed", "error", err)
+			return
+		}
+		relay.heartbeat.Beat()
+		if n < int(relay.batchSize) {
+			return
+		}
+	}
+}
+
+// RelayOnce publishes a single batch and returns the number of events published
+func (relay *Relay) RelayOnce(ctx context.Context) (int, error) {
+	return relay.store.RelayOutboxTx(ctx, relay.batchSize, relay.maxAttempts, relay.publish)
+}
+
+// publish hands event to the publisher and logs it when the failure dead-letters it,
+// the store moves on without the event and only its row tells what happened
+func (relay *Relay) publish(ctx context.Context, event db.Outbox) error {
+	err := relay.publisher.Publish(ctx, event)
+	if err != nil && db.IsOutboxEventDead(event, relay.maxAttempts) {
+		slog.ErrorContext(ctx, "outbox event dead-lettered",
+			"id", event.ID,
+			"event_type", event.EventType,
+			"attempts", event.Attempts+1,
+			"error", err,
+		)
+	}
+	return err
+}
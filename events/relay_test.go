@@ -0,0 +1,137 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	memdb "github.com/techschool/simple-bank/db2/memory"
+	mockdb "github.com/techschool/simple-bank/db2/mock"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"go.uber.org/mock/gomock"
+)
+
+// stubOutbox makes RelayOutboxTx hand events to the publisher the way SQLStore does
+func stubOutbox(events []db.Outbox) func(ctx context.Context, batchSize int32, maxAttempts int32, publish func(context.Context, db.Outbox) error) (int, error) {
+	return func(ctx context.Context, batchSize int32, maxAttempts int32, publish func(context.Context, db.Outbox) error) (int, error) {
+		n := 0
+		for _, event := range events {
+			if err := publish(ctx, event); err != nil {
+				return n, err
+			}
+			n++
+		}
+		return n, nil
+	}
+}
+
+func TestRelayOnce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	events := []db.Outbox{randomEvent(), randomEvent()}
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		RelayOutboxTx(gomock.Any(), gomock.Eq(int32(10)), gomock.Eq(int32(3)), gomock.Any()).
+		Times(1).
+		DoAndReturn(stubOutbox(events))
+
+	publisher := NewMemoryPublisher()
+	relay := NewRelay(store, publisher, time.Second, 10, 3)
+
+	n, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, events, publisher.Events())
+}
+
+type failingPublisher struct{}
+
+func (failingPublisher) Publish(ctx context.Context, event db.Outbox) error {
+	return errors.New("broker unavailable")
+}
+
+func TestRelayOncePublishError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		RelayOutboxTx(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(stubOutbox([]db.Outbox{randomEvent()}))
+
+	relay := NewRelay(store, failingPublisher{}, time.Second, 10, 3)
+
+	n, err := relay.RelayOnce(context.Background())
+	require.Error(t, err)
+	require.Zero(t, n)
+}
+
+// rejectingPublisher rejects the events of one aggregate and publishes the others to a MemoryPublisher
+type rejectingPublisher struct {
+	*MemoryPublisher
+	aggregateID string
+}
+
+func (publisher rejectingPublisher) Publish(ctx context.Context, event db.Outbox) error {
+	if event.AggregateID == publisher.aggregateID {
+		return errors.New("event rejected")
+	}
+	return publisher.MemoryPublisher.Publish(ctx, event)
+}
+
+// an event the publisher always rejects holds back the outbox and the heartbeat until it is dead-lettered
+func TestRelayDeadLettersRejectedEvent(t *testing.T) {
+	store := memdb.NewStore()
+	ctx := context.Background()
+
+	rejected, err := store.CreateAccountTx(ctx, db.CreateAccountParams{Owner: "rejected", Currency: "USD"})
+	require.NoError(t, err)
+	behind, err := store.CreateAccountTx(ctx, db.CreateAccountParams{Owner: "behind", Currency: "USD"})
+	require.NoError(t, err)
+
+	publisher := rejectingPublisher{NewMemoryPublisher(), strconv.FormatInt(rejected.ID, 10)}
+	relay := NewRelay(store, publisher, time.Second, 10, 3)
+
+	for attempt := 1; attempt < 3; attempt++ {
+		relay.drain(ctx)
+		_, err := relay.Heartbeat().Check()(ctx)
+		require.Error(t, err)
+		require.Empty(t, publisher.Events())
+	}
+
+	relay.drain(ctx)
+	_, err = relay.Heartbeat().Check()(ctx)
+	require.NoError(t, err)
+	require.Len(t, publisher.Events(), 1)
+	require.Equal(t, strconv.FormatInt(behind.ID, 10), publisher.Events()[0].AggregateID)
+
+	// the dead event is left alone from then on
+	n, err := relay.RelayOnce(ctx)
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
+
+func TestRelayRunStopsOnCancel(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		RelayOutboxTx(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		MinTimes(1).
+		DoAndReturn(func(ctx context.Context, batchSize int32, maxAttempts int32, publish func(context.Context, db.Outbox) error) (int, error) {
+			cancel() // stop the relay after the first poll
+			return 0, nil
+		})
+
+	relay := NewRelay(store, NewMemoryPublisher(), time.Millisecond, 10, 3)
+	err := relay.Run(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+}
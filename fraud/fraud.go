@@ -0,0 +1,78 @@
+package fraud
+
+import (
+	"context"
+	"time"
+)
+
+// Decision is what happens to a screened transfer
+type Decision string
+
+const (
+	Allow  Decision = "allow"  // the transfer goes through
+	Review Decision = "review" // the transfer waits for a teller to approve or reject it, no money moves until then
+	Deny   Decision = "deny"   // the transfer is refused
+)
+
+// severity orders the decisions, the most severe verdict of the rules wins
+func (decision Decision) severity() int {
+	switch decision {
+	case Deny:
+		return 2
+	case Review:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// HistoryWindow is how far back the history given to a Screener goes
+const HistoryWindow = 24 * time.Hour
+
+// Transfer is the transfer being screened, amounts are in minor units
+type Transfer struct {
+	FromAccountID int64
+	ToAccountID   int64
+	Amount        int64
+}
+
+// PastTransfer is an earlier outgoing transfer of the sender, approved or waiting for review
+type PastTransfer struct {
+	ToAccountID int64
+	Amount      int64
+	CreatedAt   time.Time
+}
+
+// History describes what the sender did before this transfer
+type History struct {
+	Recent     []PastTransfer // outgoing transfers of the last HistoryWindow, newest first
+	KnownPayee bool           // the sender already completed a transfer to the receiver
+}
+
+// Caller describes who asked for the transfer
+type Caller struct {
+	Actor     string
+	RequestID string
+	ClientIP  string
+}
+
+// Input is everything a Screener knows about a transfer
+type Input struct {
+	Transfer Transfer
+	History  History
+	Caller   Caller
+	Now      time.Time
+}
+
+// Verdict is the outcome of screening a transfer
+type Verdict struct {
+	Decision Decision `json:"decision"`
+	Rule     string   `json:"rule,omitempty"`   // the rule behind a review or deny decision
+	Reason   string   `json:"reason,omitempty"` // why the rule matched, for tellers
+}
+
+// Screener decides whether a transfer may go through
+// TransferTx calls it before moving any money, within the transaction of the transfer
+type Screener interface {
+	Screen(ctx context.Context, input Input) (Verdict, error)
+}
@@ -0,0 +1,172 @@
+package fraud
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// rule types accepted in the rules file
+const (
+	RuleVelocity            = "velocity"
+	RuleNewPayeeLargeAmount = "new_payee_large_amount"
+	RuleRoundAmount         = "round_amount"
+)
+
+// RuleConfig is one rule of the rules file, the fields used depend on the type
+//
+//	rules:
+//	  - name: burst_of_transfers
+//	    type: velocity
+//	    window: 10m
+//	    max_count: 5
+//	    action: review
+type RuleConfig struct {
+	Name   string   `yaml:"name"`
+	Type   string   `yaml:"type"`
+	Action Decision `yaml:"action"` // review or deny
+
+	// velocity: more than MaxCount transfers, or more than MaxAmount in total, within Window, this transfer included
+	Window    time.Duration `yaml:"window"`
+	MaxCount  int           `yaml:"max_count"`
+	MaxAmount int64         `yaml:"max_amount"`
+
+	// new_payee_large_amount: at least MinAmount to a receiver the sender never paid before
+	// round_amount: at least MinAmount, and a multiple of Multiple
+	MinAmount int64 `yaml:"min_amount"`
+	Multiple  int64 `yaml:"multiple"`
+}
+
+// rulesFile is the layout of the rules file
+type rulesFile struct {
+	Rules []RuleConfig `yaml:"rules"`
+}
+
+// Engine is a Screener applying a list of rules, the most severe decision of the matching rules wins
+type Engine struct {
+	rules []RuleConfig
+}
+
+// LoadRules reads the rules file at path
+func LoadRules(path string) (*Engine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	engine, err := ParseRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return engine, nil
+}
+
+// ParseRules parses and validates the YAML content of a rules file
+func ParseRules(data []byte) (*Engine, error) {
+	var file rulesFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true) // a misspelled field would silently disable a check
+
+	// io.EOF: the file is empty
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid fraud rules: %w", err)
+	}
+
+	names := map[string]bool{}
+	for _, rule := range file.Rules {
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("invalid fraud rule %q: %w", rule.Name, err)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("invalid fraud rule %q: duplicate name", rule.Name)
+		}
+		names[rule.Name] = true
+	}
+
+	return &Engine{rules: file.Rules}, nil
+}
+
+func (rule RuleConfig) validate() error {
+	if rule.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if rule.Action != Review && rule.Action != Deny {
+		return fmt.Errorf("action must be %q or %q", Review, Deny)
+	}
+
+	switch rule.Type {
+	case RuleVelocity:
+		if rule.Window <= 0 || rule.Window > HistoryWindow {
+			return fmt.Errorf("window must be between 0 and %s", HistoryWindow)
+		}
+		if rule.MaxCount <= 0 && rule.MaxAmount <= 0 {
+			return fmt.Errorf("max_count or max_amount is required")
+		}
+	case RuleNewPayeeLargeAmount:
+		if rule.MinAmount <= 0 {
+			return fmt.Errorf("min_amount must be positive")
+		}
+	case RuleRoundAmount:
+		if rule.Multiple <= 0 {
+			return fmt.Errorf("multiple must be positive")
+		}
+	default:
+		return fmt.Errorf("unknown type %q", rule.Type)
+	}
+	return nil
+}
+
+// Screen applies every rule to the transfer
+func (engine *Engine) Screen(ctx context.Context, input Input) (Verdict, error) {
+	verdict := Verdict{Decision: Allow}
+
+	for _, rule := range engine.rules {
+		reason, matched := rule.match(input)
+		if matched && rule.Action.severity() > verdict.Decision.severity() {
+			verdict = Verdict{Decision: rule.Action, Rule: rule.Name, Reason: reason}
+		}
+	}
+	return verdict, nil
+}
+
+// match reports whether the rule matches the transfer and why
+func (rule RuleConfig) match(input Input) (string, bool) {
+	transfer := input.Transfer
+
+	switch rule.Type {
+	case RuleVelocity:
+		count, total := 1, transfer.Amount
+		for _, past := range input.History.Recent {
+			if input.Now.Sub(past.CreatedAt) > rule.Window {
+				break // newest first, the rest is older
+			}
+			count++
+			total += past.Amount
+		}
+
+		if rule.MaxCount > 0 && count > rule.MaxCount {
+			return fmt.Sprintf("%d transfers within %s", count, rule.Window), true
+		}
+		if rule.MaxAmount > 0 && total > rule.MaxAmount {
+			return fmt.Sprintf("%d sent within %s", total, rule.Window), true
+		}
+
+	case RuleNewPayeeLargeAmount:
+		if !input.History.KnownPayee && transfer.Amount >= rule.MinAmount {
+			return fmt.Sprintf("%d to a new payee", transfer.Amount), true
+		}
+
+	case RuleRoundAmount:
+		if transfer.Amount >= rule.MinAmount && transfer.Amount%rule.Multiple == 0 {
+			return fmt.Sprintf("round amount %d", transfer.Amount), true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,150 @@
+package fraud
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testRules = `
+rules:
+  - name: burst
+    type: velocity
+    window: 10m
+    max_count: 3
+    action: review
+  - name: volume
+    type: velocity
+    window: 1h
+    max_amount: 1000
+    action: deny
+  - name: first_payment
+    type: new_payee_large_amount
+    min_amount: 500
+    action: review
+  - name: round
+    type: round_amount
+    multiple: 100
+    min_amount: 200
+    action: review
+`
+
+func TestEngineScreen(t *testing.T) {
+	engine, err := ParseRules([]byte(testRules))
+	require.NoError(t, err)
+
+	now := time.Now()
+	past := func(amount int64, ago time.Duration) PastTransfer {
+		return PastTransfer{ToAccountID: 2, Amount: amount, CreatedAt: now.Add(-ago)}
+	}
+
+	testCases := []struct {
+		name     string
+		amount   int64
+		history  History
+		decision Decision
+		rule     string
+	}{
+		{
+			name:     "Allow",
+			amount:   123,
+			history:  History{KnownPayee: true},
+			decision: Allow,
+		},
+		{
+			name:     "Velocity",
+			amount:   1,
+			history:  History{KnownPayee: true, Recent: []PastTransfer{past(1, time.Minute), past(1, 2*time.Minute), past(1, 3*time.Minute)}},
+			decision: Review,
+			rule:     "burst",
+		},
+		{
+			name:     "VelocityOutsideWindow",
+			amount:   1,
+			history:  History{KnownPayee: true, Recent: []PastTransfer{past(1, time.Minute), past(1, 20*time.Minute), past(1, 30*time.Minute)}},
+			decision: Allow,
+		},
+		{
+			name:     "NewPayeeLargeAmount",
+			amount:   501,
+			history:  History{},
+			decision: Review,
+			rule:     "first_payment",
+		},
+		{
+			name:     "KnownPayeeLargeAmount",
+			amount:   501,
+			history:  History{KnownPayee: true},
+			decision: Allow,
+		},
+		{
+			name:     "RoundAmount",
+			amount:   300,
+			history:  History{KnownPayee: true},
+			decision: Review,
+			rule:     "round",
+		},
+		{
+			name:     "DenyWinsOverReview",
+			amount:   600, // round and new payee too
+			history:  History{Recent: []PastTransfer{past(500, 30*time.Minute)}},
+			decision: Deny,
+			rule:     "volume",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			verdict, err := engine.Screen(context.Background(), Input{
+				Transfer: Transfer{FromAccountID: 1, ToAccountID: 2, Amount: tc.amount},
+				History:  tc.history,
+				Now:      now,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tc.decision, verdict.Decision)
+			require.Equal(t, tc.rule, verdict.Rule)
+			if tc.decision != Allow {
+				require.NotEmpty(t, verdict.Reason)
+			}
+		})
+	}
+}
+
+func TestParseRulesInvalid(t *testing.T) {
+	testCases := map[string]string{
+		"UnknownType":    "rules: [{name: a, type: geo, action: review}]",
+		"UnknownField":   "rules: [{name: a, type: round_amount, multiple: 10, action: review, multipel: 3}]",
+		"MissingName":    "rules: [{type: round_amount, multiple: 10, action: review}]",
+		"BadAction":      "rules: [{name: a, type: round_amount, multiple: 10, action: block}]",
+		"WindowTooLong":  "rules: [{name: a, type: velocity, window: 48h, max_count: 3, action: review}]",
+		"VelocityNoMax":  "rules: [{name: a, type: velocity, window: 1h, action: review}]",
+		"DuplicateName":  "rules: [{name: a, type: round_amount, multiple: 10, action: review}, {name: a, type: round_amount, multiple: 5, action: deny}]",
+		"NotYAML":        "rules: [",
+		"NoMinAmount":    "rules: [{name: a, type: new_payee_large_amount, action: review}]",
+		"ZeroMultiple":   "rules: [{name: a, type: round_amount, action: review}]",
+		"BadWindowValue": "rules: [{name: a, type: velocity, window: soon, max_count: 1, action: review}]",
+	}
+
+	for name, rules := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseRules([]byte(rules))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestParseRulesEmpty(t *testing.T) {
+	engine, err := ParseRules(nil)
+	require.NoError(t, err)
+
+	verdict, err := engine.Screen(context.Background(), Input{Transfer: Transfer{Amount: 1000000}})
+	require.NoError(t, err)
+	require.Equal(t, Allow, verdict.Decision)
+}
+
+func TestLoadRulesShipped(t *testing.T) {
+	_, err := LoadRules("../fraud_rules.yaml")
+	require.NoError(t, err)
+}
@@ -0,0 +1,80 @@
+package gapi
+
+import (
+	"github.com/techschool/simple-bank/apperror"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// StreamAccountEvents sends a snapshot of the account, then every balance change until the client goes away
+// the caller must send an access token of the owner of the account
+func (server *Server) StreamAccountEvents(req *pb.StreamAccountEventsRequest, stream grpc.ServerStreamingServer[pb.AccountEvent]) error {
+	ctx := stream.Context()
+	payload, err := authorizeOwner(ctx, server.tokenMaker)
+	if err != nil {
+		return err
+	}
+
+	if req.GetAccountId() < 1 {
+		return apperror.New(apperror.CodeInvalidArgument, "account_id must be positive")
+	}
+
+	// the account is checked before subscribing, an unknown id or someone else's account must not hold a
+	// subscription of the bus
+	account, err := server.store.GetAccount(ctx, req.GetAccountId())
+	if err != nil {
+		return convertError(ctx, err)
+	}
+	if account.Owner != payload.Owner {
+		return apperror.New(apperror.CodePermissionDenied, "account doesn't belong to the authenticated user")
+	}
+
+	// subscribe before reading the snapshot, so that no change can slip in between the snapshot and the stream
+	// the snapshot comes from the primary, a lagging replica would miss changes already notified
+	sub := server.bus.Subscribe(req.GetAccountId())
+	defer sub.Close()
+
+	account, err = server.store.GetAccount(db.WithReadYourWrites(ctx), req.GetAccountId())
+	if err != nil {
+		return convertError(ctx, err)
+	}
+
+	snapshot := &pb.AccountEvent{
+		AccountId: account.ID,
+		Balance:   account.Balance,
+		Currency:  account.Currency,
+		CreatedAt: timestamppb.Now(),
+	}
+	if err := stream.Send(snapshot); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-server.shuttingDown:
+			return apperror.New(apperror.CodeUnavailable, "server is shutting down, reconnect to resume")
+		case event, ok := <-sub.Events():
+			if !ok {
+				return apperror.New(apperror.CodeUnavailable, "stream fell behind, reconnect to resume")
+			}
+			if err := stream.Send(convertBalanceChanged(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func convertBalanceChanged(event db.BalanceChangedEvent) *pb.AccountEvent {
+	return &pb.AccountEvent{
+		AccountId: event.AccountID,
+		EntryId:   event.EntryID,
+		Amount:    event.Amount,
+		Balance:   event.Balance,
+		Currency:  event.Currency,
+		CreatedAt: timestamppb.New(event.CreatedAt),
+	}
+}
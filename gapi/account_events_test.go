@@ -0,0 +1,185 @@
+package gapi
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/techschool/simple-bank/db2/mock"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/events"
+	"github.com/techschool/simple-bank/pb"
+	"github.com/techschool/simple-bank/ratelimit"
+	"github.com/techschool/simple-bank/token"
+	"github.com/techschool/simple-bank/utils"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// testConfig signs the access tokens of the servers of the account events tests
+var testConfig = utils.Config{TokenSymmetricKey: utils.RandomString(32)}
+
+// newTestServer returns a server of store and bus with testConfig
+func newTestServer(t *testing.T, config utils.Config, store db.Store, bus *events.Bus) *Server {
+	server, err := NewServer(config, store, bus)
+	require.NoError(t, err)
+	return server
+}
+
+// ownerContext returns a context whose calls carry an access token of owner, valid for duration
+func ownerContext(t *testing.T, ctx context.Context, owner string, duration time.Duration) context.Context {
+	maker, err := token.NewMaker(testConfig.TokenSymmetricKey)
+	require.NoError(t, err)
+	accessToken, _, err := maker.CreateToken(owner, duration)
+	require.NoError(t, err)
+	return metadata.AppendToOutgoingContext(ctx, authorizationMetadataKey, "Bearer "+accessToken)
+}
+
+// newTestClient serves server over an in-memory connection and returns a client for it
+func newTestClient(t *testing.T, server *Server) pb.SimpleBankClient {
+	return newRateLimitedTestClient(t, server, nil)
+}
+
+// newRateLimitedTestClient is newTestClient with the calls limited by limiter, like in main
+func newRateLimitedTestClient(t *testing.T, server *Server, limiter *ratelimit.Limiter) pb.SimpleBankClient {
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(UnaryLogger, UnaryRateLimiter(limiter)),
+		grpc.ChainStreamInterceptor(StreamLogger, StreamRateLimiter(limiter)),
+	)
+	pb.RegisterSimpleBankServer(grpcServer, server)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewSimpleBankClient(conn)
+}
+
+func TestStreamAccountEvents(t *testing.T) {
+	account := db.Account{
+		ID:       utils.RandomInt(1, 1000),
+		Owner:    utils.RandomOwner(),
+		Balance:  utils.RandomMoney(),
+		Currency: utils.RandomCurrency(),
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	// once before subscribing, once for the snapshot
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(2).Return(account, nil)
+
+	bus := events.NewBus()
+	client := newTestClient(t, newTestServer(t, testConfig, store, bus))
+
+	ctx, cancel := context.WithTimeout(ownerContext(t, context.Background(), account.Owner, time.Minute), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.StreamAccountEvents(ctx, &pb.StreamAccountEventsRequest{AccountId: account.ID})
+	require.NoError(t, err)
+
+	// the snapshot is sent after subscribing, so the change published next can't be missed
+	snapshot, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, account.ID, snapshot.GetAccountId())
+	require.Equal(t, account.Balance, snapshot.GetBalance())
+	require.Zero(t, snapshot.GetEntryId())
+
+	changed := db.BalanceChangedEvent{
+		AccountID: account.ID,
+		EntryID:   99,
+		Amount:    -10,
+		Balance:   account.Balance - 10,
+		Currency:  account.Currency,
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+	bus.Publish(changed)
+
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, changed.EntryID, event.GetEntryId())
+	require.Equal(t, changed.Amount, event.GetAmount())
+	require.Equal(t, changed.Balance, event.GetBalance())
+	require.Equal(t, changed.CreatedAt, event.GetCreatedAt().AsTime())
+}
+
+func TestStreamAccountEventsErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(int64(7))).Times(1).Return(db.Account{}, pgx.ErrNoRows)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(int64(8))).Times(1).Return(db.Account{}, pgx.ErrTxClosed)
+	// another owner's account is read once, the stream never subscribes
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(int64(10))).Times(1).Return(db.Account{ID: 10, Owner: "someone_else"}, nil)
+
+	client := newTestClient(t, newTestServer(t, testConfig, store, events.NewBus()))
+	disabled := newTestClient(t, newTestServer(t, utils.Config{}, store, events.NewBus()))
+	owner := ownerContext(t, context.Background(), utils.RandomOwner(), time.Minute)
+
+	testCases := []struct {
+		name      string
+		client    pb.SimpleBankClient
+		ctx       context.Context
+		accountID int64
+		code      codes.Code
+	}{
+		{name: "InvalidID", client: client, ctx: owner, accountID: 0, code: codes.InvalidArgument},
+		{name: "NotFound", client: client, ctx: owner, accountID: 7, code: codes.NotFound},
+		{name: "Internal", client: client, ctx: owner, accountID: 8, code: codes.Internal},
+		{name: "OtherOwner", client: client, ctx: owner, accountID: 10, code: codes.PermissionDenied},
+		// the token is checked before the account, which isn't read at all
+		{name: "NoToken", client: client, ctx: context.Background(), accountID: 9, code: codes.Unauthenticated},
+		{
+			name:      "WrongToken",
+			client:    client,
+			ctx:       metadata.AppendToOutgoingContext(context.Background(), authorizationMetadataKey, "Bearer wrong"),
+			accountID: 9,
+			code:      codes.Unauthenticated,
+		},
+		{
+			name:      "InvalidFormat",
+			client:    client,
+			ctx:       metadata.AppendToOutgoingContext(context.Background(), authorizationMetadataKey, "Token abc"),
+			accountID: 9,
+			code:      codes.Unauthenticated,
+		},
+		{
+			name:      "Expired",
+			client:    client,
+			ctx:       ownerContext(t, context.Background(), utils.RandomOwner(), -time.Minute),
+			accountID: 9,
+			code:      codes.Unauthenticated,
+		},
+		{name: "Disabled", client: disabled, ctx: owner, accountID: 9, code: codes.PermissionDenied},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			stream, err := tc.client.StreamAccountEvents(tc.ctx, &pb.StreamAccountEventsRequest{AccountId: tc.accountID})
+			require.NoError(t, err)
+
+			_, err = stream.Recv()
+			require.Equal(t, tc.code, status.Code(err))
+			require.NotContains(t, status.Convert(err).Message(), pgx.ErrTxClosed.Error())
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package gapi
+
+import (
+	"context"
+	"strings"
+
+	"github.com/techschool/simple-bank/apperror"
+	"github.com/techschool/simple-bank/token"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	authorizationMetadataKey = "authorization"
+	authorizationTypeBearer  = "bearer"
+)
+
+// authorizeOwner checks that the call carries the "authorization: Bearer <access token>" metadata and returns
+// the payload of the token, the gRPC counterpart of the owner middleware of the HTTP api
+// a nil maker, when no TOKEN_SYMMETRIC_KEY is configured, disables the owner methods entirely
+func authorizeOwner(ctx context.Context, maker *token.Maker) (token.Payload, error) {
+	if maker == nil {
+		return token.Payload{}, apperror.New(apperror.CodePermissionDenied, "owner methods are disabled")
+	}
+
+	bearer, err := bearerToken(ctx)
+	if err != nil {
+		return token.Payload{}, err
+	}
+
+	payload, err := maker.VerifyToken(bearer)
+	if err != nil {
+		return token.Payload{}, apperror.New(apperror.CodeUnauthenticated, err.Error())
+	}
+	return payload, nil
+}
+
+// bearerToken returns the token of the "authorization: Bearer <token>" metadata of the call
+func bearerToken(ctx context.Context) (string, error) {
+	var values []string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		values = md.Get(authorizationMetadataKey)
+	}
+	if len(values) != 1 {
+		return "", apperror.New(apperror.CodeUnauthenticated, "missing authorization metadata")
+	}
+
+	fields := strings.Fields(values[0])
+	if len(fields) != 2 || strings.ToLower(fields[0]) != authorizationTypeBearer {
+		return "", apperror.New(apperror.CodeUnauthenticated, "invalid authorization metadata format")
+	}
+	return fields[1], nil
+}
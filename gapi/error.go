@@ -0,0 +1,18 @@
+package gapi
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/techschool/simple-bank/apperror"
+)
+
+// convertError turns err into an *apperror.Error, which grpc-go sends to the client as its status
+// internal errors are logged and only reported with a generic message
+func convertError(ctx context.Context, err error) error {
+	appErr := apperror.From(err)
+	if appErr.Code == apperror.CodeInternal {
+		slog.ErrorContext(ctx, "gRPC request failed", "error", err)
+	}
+	return appErr
+}
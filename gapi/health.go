@@ -0,0 +1,35 @@
+package gapi
+
+import (
+	"context"
+	"time"
+
+	"github.com/techschool/simple-bank/health"
+	"github.com/techschool/simple-bank/pb"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// WatchHealth runs the readiness checks every interval and publishes the outcome on the grpc.health.v1.Health service,
+// both for the whole server ("") and for the SimpleBank service
+// when ctx is cancelled every service is reported NOT_SERVING, so clients move away before the server stops
+func WatchHealth(ctx context.Context, checker *health.Checker, healthServer *grpchealth.Server, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		status := healthpb.HealthCheckResponse_SERVING
+		if report := checker.Run(ctx); !report.OK() {
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		healthServer.SetServingStatus("", status)
+		healthServer.SetServingStatus(pb.SimpleBank_ServiceDesc.ServiceName, status)
+
+		select {
+		case <-ctx.Done():
+			healthServer.Shutdown()
+			return
+		case <-ticker.C:
+		}
+	}
+}
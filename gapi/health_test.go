@@ -0,0 +1,54 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simple-bank/health"
+	"github.com/techschool/simple-bank/pb"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestWatchHealth(t *testing.T) {
+	var failing atomic.Bool
+	checker := health.NewChecker(time.Second)
+	checker.Add("database", func(ctx context.Context) (string, error) {
+		if failing.Load() {
+			return "", errors.New("connection refused")
+		}
+		return "", nil
+	})
+
+	healthServer := grpchealth.NewServer()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		WatchHealth(ctx, checker, healthServer, 5*time.Millisecond)
+		close(done)
+	}()
+
+	requireStatus := func(service string, want healthpb.HealthCheckResponse_ServingStatus) {
+		require.Eventually(t, func() bool {
+			rsp, err := healthServer.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+			return err == nil && rsp.GetStatus() == want
+		}, time.Second, 5*time.Millisecond)
+	}
+
+	requireStatus("", healthpb.HealthCheckResponse_SERVING)
+	requireStatus(pb.SimpleBank_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+
+	failing.Store(true)
+	requireStatus(pb.SimpleBank_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+
+	// a shutting down server stops serving even though its checks pass
+	failing.Store(false)
+	requireStatus("", healthpb.HealthCheckResponse_SERVING)
+	cancel()
+	<-done
+	requireStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+}
@@ -0,0 +1,79 @@
+package gapi
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/techschool/simple-bank/db2/mock"
+	"github.com/techschool/simple-bank/events"
+	"github.com/techschool/simple-bank/logging"
+	"github.com/techschool/simple-bank/pb"
+	"github.com/techschool/simple-bank/utils"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestStreamLoggerRequestID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := newTestClient(t, newTestServer(t, utils.Config{}, mockdb.NewMockStore(ctrl), events.NewBus()))
+
+	testCases := []struct {
+		name          string
+		requestID     string
+		checkResponse func(t *testing.T, requestID string)
+	}{
+		{
+			name:      "FromClient",
+			requestID: "req-123",
+			checkResponse: func(t *testing.T, requestID string) {
+				require.Equal(t, "req-123", requestID)
+			},
+		},
+		{
+			name:      "Generated",
+			requestID: "",
+			checkResponse: func(t *testing.T, requestID string) {
+				require.Len(t, requestID, 32)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.requestID != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, requestIDMetadataKey, tc.requestID)
+			}
+
+			// a call without an access token ends the stream right away, the header is sent anyway
+			stream, err := client.StreamAccountEvents(ctx, &pb.StreamAccountEventsRequest{AccountId: 0})
+			require.NoError(t, err)
+			_, err = stream.Recv()
+			require.Error(t, err)
+
+			header, err := stream.Header()
+			require.NoError(t, err)
+			values := header.Get(requestIDMetadataKey)
+			require.Len(t, values, 1)
+			tc.checkResponse(t, values[0])
+		})
+	}
+}
+
+func TestUnaryLoggerRequestID(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(requestIDMetadataKey, "req-456"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/pb.SimpleBank/Test"}
+
+	var requestID string
+	_, err := UnaryLogger(ctx, nil, info, func(ctx context.Context, req any) (any, error) {
+		requestID = logging.RequestIDFromContext(ctx)
+		return nil, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "req-456", requestID)
+}
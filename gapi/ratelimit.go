@@ -0,0 +1,67 @@
+package gapi
+
+import (
+	"context"
+	"net"
+	"strconv"
+
+	"github.com/techschool/simple-bank/apperror"
+	"github.com/techschool/simple-bank/metrics"
+	"github.com/techschool/simple-bank/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// retryAfterMetadataKey tells a rate limited client how many seconds to wait, like the Retry-After header
+const retryAfterMetadataKey = "retry-after"
+
+// UnaryRateLimiter limits the unary calls of each client IP with the policy of the method
+func UnaryRateLimiter(limiter *ratelimit.Limiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := limitCall(ctx, limiter, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamRateLimiter limits the streams opened by each client IP with the policy of the method
+func StreamRateLimiter(limiter *ratelimit.Limiter) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := limitCall(stream.Context(), limiter, info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+// limitCall returns a rate_limited error when the bucket of the client IP for method is empty
+// the calls are limited by IP, the interceptors run before the methods check the caller's token
+func limitCall(ctx context.Context, limiter *ratelimit.Limiter, method string) error {
+	result := limiter.Allow(ctx, method, "ip:"+clientIP(ctx))
+	if result.Allowed {
+		return nil
+	}
+
+	metrics.RateLimited.WithLabelValues(method).Inc()
+
+	retryAfter := result.RetryAfterSeconds()
+	_ = grpc.SetHeader(ctx, metadata.Pairs(retryAfterMetadataKey, strconv.Itoa(retryAfter)))
+	return apperror.New(apperror.CodeRateLimited, "too many requests").
+		WithDetails(map[string]any{"retry_after_seconds": retryAfter})
+}
+
+// clientIP returns the IP of the peer, without its port
+func clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
@@ -0,0 +1,70 @@
+package gapi
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/techschool/simple-bank/db2/mock"
+	"github.com/techschool/simple-bank/events"
+	"github.com/techschool/simple-bank/pb"
+	"github.com/techschool/simple-bank/ratelimit"
+	"github.com/techschool/simple-bank/utils"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryRateLimiter(t *testing.T) {
+	policies, err := ratelimit.ParsePolicies("", "/pb.SimpleBank/Test=1/m:1")
+	require.NoError(t, err)
+	interceptor := UnaryRateLimiter(ratelimit.NewLimiter(policies, ratelimit.NewMemoryStore()))
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/pb.SimpleBank/Test"}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return "ok", nil
+	}
+
+	resp, err := interceptor(context.Background(), nil, info, handler)
+	require.NoError(t, err)
+	require.Equal(t, "ok", resp)
+
+	_, err = interceptor(context.Background(), nil, info, handler)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// other methods are not limited
+	other := &grpc.UnaryServerInfo{FullMethod: "/pb.SimpleBank/Other"}
+	_, err = interceptor(context.Background(), nil, other, handler)
+	require.NoError(t, err)
+}
+
+func TestStreamRateLimiter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	policies, err := ratelimit.ParsePolicies("", "/pb.SimpleBank/StreamAccountEvents=1/m:1")
+	require.NoError(t, err)
+	limiter := ratelimit.NewLimiter(policies, ratelimit.NewMemoryStore())
+	client := newRateLimitedTestClient(t, newTestServer(t, utils.Config{}, mockdb.NewMockStore(ctrl), events.NewBus()), limiter)
+
+	// the first stream gets through and fails because the server has no token key
+	stream, err := client.StreamAccountEvents(context.Background(), &pb.StreamAccountEventsRequest{AccountId: 0})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	stream, err = client.StreamAccountEvents(context.Background(), &pb.StreamAccountEventsRequest{AccountId: 0})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	header, err := stream.Header()
+	require.NoError(t, err)
+	values := header.Get(retryAfterMetadataKey)
+	require.Len(t, values, 1)
+	retryAfter, err := strconv.Atoi(values[0])
+	require.NoError(t, err)
+	require.InDelta(t, 60, retryAfter, 1)
+}
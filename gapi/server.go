@@ -0,0 +1,52 @@
+package gapi
+
+import (
+	"sync"
+
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/events"
+	"github.com/techschool/simple-bank/pb"
+	"github.com/techschool/simple-bank/token"
+	"github.com/techschool/simple-bank/utils"
+)
+
+// Server serves gRPC requests for our banking service
+type Server struct {
+	pb.UnimplementedSimpleBankServer
+	config utils.Config
+	store  db.Store
+	bus    *events.Bus // live balance changes, fed by the account events listener
+
+	tokenMaker *token.Maker // verifies the access tokens of the account owners, nil when TOKEN_SYMMETRIC_KEY is empty
+
+	shuttingDown chan struct{} // closed by Shutdown, ends the event streams which would never finish on their own
+	shutdownOnce sync.Once
+}
+
+// NewServer creates a new gRPC server
+// it returns an error if config.TokenSymmetricKey is invalid
+func NewServer(config utils.Config, store db.Store, bus *events.Bus) (*Server, error) {
+	server := &Server{
+		config: config,
+		store:  store,
+		bus:    bus,
+
+		shuttingDown: make(chan struct{}),
+	}
+	if config.TokenSymmetricKey != "" {
+		maker, err := token.NewMaker(config.TokenSymmetricKey)
+		if err != nil {
+			return nil, err
+		}
+		server.tokenMaker = maker
+	}
+	return server, nil
+}
+
+// Shutdown ends the open event streams, so that GracefulStop doesn't wait for them
+// the clients reconnect to another replica
+func (server *Server) Shutdown() {
+	server.shutdownOnce.Do(func() {
+		close(server.shuttingDown)
+	})
+}
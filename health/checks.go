@@ -0,0 +1,41 @@
+package health
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DatabaseCheck pings the database
+func DatabaseCheck(pool *pgxpool.Pool) CheckFunc {
+	return func(ctx context.Context) (string, error) {
+		if err := pool.Ping(ctx); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d open connections", pool.Stat().TotalConns()), nil
+	}
+}
+
+// MigrationCheck reads the version recorded by golang-migrate in schema_migrations
+// it fails when the database is behind expected or when the last migration failed half way (dirty),
+// a database that is ahead is fine: migrations are backwards compatible, so older replicas keep working during a deploy
+func MigrationCheck(pool *pgxpool.Pool, expected uint) CheckFunc {
+	return func(ctx context.Context) (string, error) {
+		var version uint
+		var dirty bool
+		err := pool.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+		if err != nil {
+			return "", fmt.Errorf("cannot read migration version: %w", err)
+		}
+
+		details := fmt.Sprintf("version %d, expected %d", version, expected)
+		if dirty {
+			return details, fmt.Errorf("migration %d is dirty", version)
+		}
+		if version < expected {
+			return details, fmt.Errorf("database is behind, run the migrations")
+		}
+		return details, nil
+	}
+}
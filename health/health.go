@@ -0,0 +1,90 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// statuses of a check and of a whole report
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// CheckFunc checks one dependency, details describe the state it found and are reported even on success
+type CheckFunc func(ctx context.Context) (details string, err error)
+
+// Result is the outcome of one check
+type Result struct {
+	Status  string `json:"status"`
+	Details string `json:"details,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Report is the outcome of all the checks, its status is only ok when every check passed
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// OK reports whether every check passed
+func (report Report) OK() bool {
+	return report.Status == StatusOK
+}
+
+type namedCheck struct {
+	name  string
+	check CheckFunc
+}
+
+// Checker runs the readiness checks of the server
+// checks are added at startup, before Run is called for the first time
+type Checker struct {
+	timeout time.Duration // bound of every check, so a hanging dependency doesn't hang the probe
+	checks  []namedCheck
+}
+
+// NewChecker creates a checker giving every check at most timeout to complete
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add registers a check under name
+func (checker *Checker) Add(name string, check CheckFunc) {
+	checker.checks = append(checker.checks, namedCheck{name: name, check: check})
+}
+
+// Run runs all the checks concurrently and collects their results
+func (checker *Checker) Run(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, checker.timeout)
+	defer cancel()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(checker.checks))}
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range checker.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			details, err := c.check(ctx)
+			result := Result{Status: StatusOK, Details: details}
+			if err != nil {
+				result.Status = StatusUnavailable
+				result.Error = err.Error()
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			report.Checks[c.name] = result
+			if err != nil {
+				report.Status = StatusUnavailable
+			}
+		}()
+	}
+	wg.Wait()
+
+	return report
+}
@@ -0,0 +1,65 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckerRun(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.Add("database", func(ctx context.Context) (string, error) {
+		return "3 open connections", nil
+	})
+
+	report := checker.Run(context.Background())
+	require.True(t, report.OK())
+	require.Equal(t, Result{Status: StatusOK, Details: "3 open connections"}, report.Checks["database"])
+
+	checker.Add("migrations", func(ctx context.Context) (string, error) {
+		return "version 4, expected 5", errors.New("database is behind")
+	})
+
+	report = checker.Run(context.Background())
+	require.False(t, report.OK())
+	require.Equal(t, StatusOK, report.Checks["database"].Status)
+	require.Equal(t, Result{
+		Status:  StatusUnavailable,
+		Details: "version 4, expected 5",
+		Error:   "database is behind",
+	}, report.Checks["migrations"])
+}
+
+func TestCheckerTimeout(t *testing.T) {
+	checker := NewChecker(10 * time.Millisecond)
+	checker.Add("hanging", func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+
+	report := checker.Run(context.Background())
+	require.False(t, report.OK())
+	require.Equal(t, context.DeadlineExceeded.Error(), report.Checks["hanging"].Error)
+}
+
+func TestHeartbeat(t *testing.T) {
+	now := time.Now()
+	heartbeat := NewHeartbeat(time.Second)
+	heartbeat.now = func() time.Time { return now }
+
+	_, err := heartbeat.Check()(context.Background())
+	require.Error(t, err) // never ran
+
+	heartbeat.Beat()
+	now = now.Add(2 * time.Second)
+	details, err := heartbeat.Check()(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "last run 2s ago", details)
+
+	now = now.Add(2 * time.Second) // 4 intervals without a beat
+	_, err = heartbeat.Check()(context.Background())
+	require.Error(t, err)
+}
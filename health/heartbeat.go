@@ -0,0 +1,46 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// heartbeatMisses is how many intervals may pass without a beat before a worker is reported as stalled
+const heartbeatMisses = 3
+
+// Heartbeat lets a background worker prove that its loop is still making progress
+// the worker calls Beat after every successful iteration
+type Heartbeat struct {
+	interval time.Duration // how often the worker is expected to beat
+	last     atomic.Int64  // unix nanoseconds of the last beat, 0 before the first one
+	now      func() time.Time
+}
+
+// NewHeartbeat creates a heartbeat for a worker beating every interval
+func NewHeartbeat(interval time.Duration) *Heartbeat {
+	return &Heartbeat{interval: interval, now: time.Now}
+}
+
+// Beat records that the worker completed an iteration
+func (heartbeat *Heartbeat) Beat() {
+	heartbeat.last.Store(heartbeat.now().UnixNano())
+}
+
+// Check fails when the worker hasn't beaten yet or missed several beats in a row
+func (heartbeat *Heartbeat) Check() CheckFunc {
+	return func(ctx context.Context) (string, error) {
+		last := heartbeat.last.Load()
+		if last == 0 {
+			return "", fmt.Errorf("not running yet")
+		}
+
+		age := heartbeat.now().Sub(time.Unix(0, last)).Truncate(time.Millisecond)
+		details := fmt.Sprintf("last run %s ago", age)
+		if age > heartbeatMisses*heartbeat.interval {
+			return details, fmt.Errorf("stalled, expected a run every %s", heartbeat.interval)
+		}
+		return details, nil
+	}
+}
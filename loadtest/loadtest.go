@@ -0,0 +1,289 @@
+// Package loadtest runs concurrent transfers against a target, the HTTP API or a db.Store, and reports
+// the throughput, the latencies and the outcomes. The accounts are created through the store first,
+// which also checks at the end that the ledger of the accounts still adds up
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	db "github.com/techschool/simple-bank/db2/sqlc"
+)
+
+// the mixes of transfers
+const (
+	MixRandom = "random" // both accounts drawn uniformly
+	MixHot    = "hot"    // HotPct percent of the transfers involve one of the HotAccounts, the rows every worker fights for
+)
+
+// Config describes the load
+type Config struct {
+	Seed           uint64        // seed of the random choices, each worker draws from its own sequence
+	Concurrency    int           // transfers in flight at once
+	Duration       time.Duration // how long the transfers are sent for, 0 for no limit
+	Transfers      int           // total transfers to send, 0 for no limit; one of Duration and Transfers must be set
+	Accounts       int           // accounts created for the test
+	Currency       string        // currency of the accounts
+	OpeningBalance int64         // balance of every account at the start, in minor units
+	MaxAmount      int64         // transfers move between 1 and MaxAmount
+	Mix            string        // one of the Mix* constants
+	HotAccounts    int           // accounts of the hot mix
+	HotPct         int           // percentage of the transfers of the hot mix that involve a hot account
+	LimitTier      string        // transfer limit tier of the accounts, empty keeps the default one
+}
+
+func (config Config) validate() error {
+	switch {
+	case config.Concurrency < 1:
+		return errors.New("the concurrency must be at least 1")
+	case config.Duration <= 0 && config.Transfers <= 0:
+		return errors.New("the load needs a duration or a number of transfers")
+	case config.Accounts < 2:
+		return errors.New("the load needs at least two accounts")
+	case config.OpeningBalance < 0 || config.MaxAmount < 1:
+		return errors.New("the opening balance must not be negative and the maximum amount must be positive")
+	case config.Mix != MixRandom && config.Mix != MixHot:
+		return fmt.Errorf("unknown mix %q, want %s or %s", config.Mix, MixRandom, MixHot)
+	case config.Mix == MixHot && (config.HotAccounts < 1 || config.HotAccounts >= config.Accounts):
+		return errors.New("the hot mix needs at least one hot account and fewer hot accounts than accounts")
+	case config.Mix == MixHot && (config.HotPct < 0 || config.HotPct > 100):
+		return errors.New("the hot percentage must be between 0 and 100")
+	}
+	return nil
+}
+
+// Report is the outcome of a run
+type Report struct {
+	Target     string         `json:"target"`
+	Mix        string         `json:"mix"`
+	Workers    int            `json:"workers"`
+	Transfers  int            `json:"transfers"` // transfers sent, whatever their outcome
+	Elapsed    Millis         `json:"elapsed_ms"`
+	Throughput float64        `json:"transfers_per_second"`
+	Latency    Latency        `json:"latency"`
+	Outcomes   map[string]int `json:"outcomes"` // completed, pending_review or the error code, see Target
+	Ledger     Ledger         `json:"ledger"`
+}
+
+// Latency sums up the time each transfer took, as seen by the worker sending it
+type Latency struct {
+	P50 Millis `json:"p50_ms"`
+	P99 Millis `json:"p99_ms"`
+	Max Millis `json:"max_ms"`
+}
+
+// Millis is a duration written in JSON as fractional milliseconds
+type Millis time.Duration
+
+func (m Millis) MarshalJSON() ([]byte, error) {
+	return fmt.Appendf(nil, "%.3f", float64(time.Duration(m))/float64(time.Millisecond)), nil
+}
+
+// Ledger is the consistency check of the accounts of the run, done once every transfer returned
+// the transfers only move money between these accounts, so their total must not change
+type Ledger struct {
+	Consistent   bool                      `json:"consistent"`
+	OpeningTotal int64                     `json:"opening_total"`
+	BalanceTotal int64                     `json:"balance_total"`
+	NegativeIDs  []int64                   `json:"negative_account_ids"` // accounts that went below zero
+	Mismatches   []db.ReconcileAccountsRow `json:"mismatches"`           // accounts whose balance, entries and transfers disagree
+	Accounts     int                       `json:"accounts"`
+}
+
+// Run creates the accounts through store, sends the transfers of config to target and checks the ledger
+// ctx should carry the audit info of whoever runs the test, cancelling it stops sending new transfers
+func Run(ctx context.Context, store db.Store, target Target, config Config) (Report, error) {
+	if err := config.validate(); err != nil {
+		return Report{}, err
+	}
+
+	accounts, err := createAccounts(ctx, store, config)
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := Report{
+		Target:   target.Name(),
+		Mix:      config.Mix,
+		Workers:  config.Concurrency,
+		Outcomes: map[string]int{},
+	}
+
+	latencies, elapsed := send(ctx, target, accounts, config, report.Outcomes)
+	report.Transfers = len(latencies)
+	report.Elapsed = Millis(elapsed)
+	if elapsed > 0 {
+		report.Throughput = float64(len(latencies)) / elapsed.Seconds()
+	}
+	report.Latency = summarize(latencies)
+
+	// the check must see every transfer, it runs even if ctx was cancelled to stop the load
+	report.Ledger, err = checkLedger(context.WithoutCancel(ctx), store, accounts, config.OpeningBalance)
+	return report, err
+}
+
+// createAccounts creates the accounts of the run, one after the other so that their ids follow each other
+func createAccounts(ctx context.Context, store db.Store, config Config) ([]int64, error) {
+	owner := fmt.Sprintf("loadtest%d_%d", config.Seed, time.Now().Unix())
+	ids := make([]int64, config.Accounts)
+	for i := range ids {
+		account, err := store.CreateAccountTx(ctx, db.CreateAccountParams{
+			Owner:    owner,
+			Balance:  config.OpeningBalance,
+			Currency: config.Currency,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("create account %d of %d: %w", i+1, config.Accounts, err)
+		}
+		ids[i] = account.ID
+
+		// the default tier caps the number of transfers a day, a long run needs a tier of its own
+		if config.LimitTier != "" {
+			_, err := store.UpdateAccountTransferLimitsTx(ctx, db.UpdateAccountTransferLimitsParams{AccountID: account.ID, Tier: config.LimitTier})
+			if err != nil {
+				return nil, fmt.Errorf("set limit tier of account %d: %w", account.ID, err)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// send runs the workers until the transfers or the duration of config are exhausted
+// it fills outcomes and returns the latency of every transfer and the time the load took
+func send(ctx context.Context, target Target, accounts []int64, config Config, outcomes map[string]int) ([]time.Duration, time.Duration) {
+	if config.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.Duration)
+		defer cancel()
+	}
+
+	// the workers take the transfers from a shared count, it is only checked if the number of transfers is capped
+	var remaining atomic.Int64
+	remaining.Store(int64(config.Transfers))
+
+	var (
+		mu        sync.Mutex
+		latencies []time.Duration
+		wg        sync.WaitGroup
+	)
+
+	start := time.Now()
+	for worker := 0; worker < config.Concurrency; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			random := rand.New(rand.NewPCG(config.Seed, uint64(worker)))
+
+			var own []time.Duration
+			counts := map[string]int{}
+			for ctx.Err() == nil {
+				if config.Transfers > 0 && remaining.Add(-1) < 0 {
+					break
+				}
+
+				from, to := pickAccounts(random, accounts, config)
+				amount := 1 + random.Int64N(config.MaxAmount)
+
+				// the transfer in flight isn't cancelled at the end of the duration, its latency would be meaningless
+				sent := time.Now()
+				outcome := target.Transfer(context.WithoutCancel(ctx), db.TransferTxParams{
+					FromAccountID: from,
+					ToAccountID:   to,
+					Amount:        amount,
+				}, config.Currency)
+				own = append(own, time.Since(sent))
+				counts[outcome]++
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			latencies = append(latencies, own...)
+			for outcome, n := range counts {
+				outcomes[outcome] += n
+			}
+		}()
+	}
+	wg.Wait()
+
+	return latencies, time.Since(start)
+}
+
+// pickAccounts draws the two distinct accounts of a transfer according to the mix of config
+// the hot accounts are the first ones, the hot end of a transfer is the sender or the receiver at random
+func pickAccounts(random *rand.Rand, accounts []int64, config Config) (from int64, to int64) {
+	if config.Mix == MixHot && random.IntN(100) < config.HotPct {
+		hot := random.IntN(config.HotAccounts)
+		other := random.IntN(len(accounts) - 1)
+		if other >= hot {
+			other++
+		}
+		if random.IntN(2) == 0 {
+			return accounts[hot], accounts[other]
+		}
+		return accounts[other], accounts[hot]
+	}
+
+	fromIndex := random.IntN(len(accounts))
+	toIndex := random.IntN(len(accounts) - 1) // among the other accounts
+	if toIndex >= fromIndex {
+		toIndex++
+	}
+	return accounts[fromIndex], accounts[toIndex]
+}
+
+// summarize returns the percentiles of latencies, nearest rank
+func summarize(latencies []time.Duration) Latency {
+	if len(latencies) == 0 {
+		return Latency{}
+	}
+
+	sorted := slices.Clone(latencies)
+	slices.Sort(sorted)
+	percentile := func(p int) Millis {
+		rank := (p*len(sorted) + 99) / 100 // ceil(p/100 * n), at least 1
+		return Millis(sorted[max(rank, 1)-1])
+	}
+	return Latency{P50: percentile(50), P99: percentile(99), Max: Millis(sorted[len(sorted)-1])}
+}
+
+// checkLedger checks that the accounts of the run hold what they started with in total,
+// that none went below zero and that ReconcileAccounts finds nothing wrong with them
+func checkLedger(ctx context.Context, store db.Store, accounts []int64, openingBalance int64) (Ledger, error) {
+	ledger := Ledger{
+		OpeningTotal: openingBalance * int64(len(accounts)),
+		NegativeIDs:  []int64{},
+		Mismatches:   []db.ReconcileAccountsRow{},
+		Accounts:     len(accounts),
+	}
+
+	for _, id := range accounts {
+		account, err := store.GetAccount(ctx, id)
+		if err != nil {
+			return ledger, fmt.Errorf("read account %d: %w", id, err)
+		}
+		ledger.BalanceTotal += account.Balance
+		if account.Balance < 0 {
+			ledger.NegativeIDs = append(ledger.NegativeIDs, id)
+		}
+	}
+
+	// the reconciliation looks at every account of the database, only the ones of the run matter here
+	mismatches, err := store.ReconcileAccounts(ctx)
+	if err != nil {
+		return ledger, err
+	}
+	for _, row := range mismatches {
+		if slices.Contains(accounts, row.ID) {
+			ledger.Mismatches = append(ledger.Mismatches, row)
+		}
+	}
+
+	ledger.Consistent = ledger.BalanceTotal == ledger.OpeningTotal && len(ledger.NegativeIDs) == 0 && len(ledger.Mismatches) == 0
+	return ledger, nil
+}
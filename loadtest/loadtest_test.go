@@ -0,0 +1,190 @@
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	memdb "github.com/techschool/simple-bank/db2/memory"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+)
+
+var testConfig = Config{
+	Seed:           7,
+	Concurrency:    8,
+	Transfers:      500,
+	Accounts:       10,
+	Currency:       "USD",
+	OpeningBalance: 1000,
+	MaxAmount:      300,
+	Mix:            MixRandom,
+}
+
+func TestRun(t *testing.T) {
+	hot := testConfig
+	hot.Mix = MixHot
+	hot.HotAccounts = 1
+	hot.HotPct = 100
+
+	for name, config := range map[string]Config{"Random": testConfig, "Hot": hot} {
+		t.Run(name, func(t *testing.T) {
+			store := memdb.NewStore()
+			report, err := Run(context.Background(), store, StoreTarget{Store: store, Label: "memory"}, config)
+			require.NoError(t, err)
+
+			require.Equal(t, "memory", report.Target)
+			require.Equal(t, config.Transfers, report.Transfers)
+			require.Positive(t, report.Throughput)
+			require.LessOrEqual(t, report.Latency.P50, report.Latency.P99)
+			require.LessOrEqual(t, report.Latency.P99, report.Latency.Max)
+
+			// the amounts are large next to the balances, some senders run dry
+			sum := 0
+			for _, n := range report.Outcomes {
+				sum += n
+			}
+			require.Equal(t, config.Transfers, sum)
+			require.Positive(t, report.Outcomes[OutcomeCompleted])
+			require.Positive(t, report.Outcomes["insufficient_funds"])
+
+			require.True(t, report.Ledger.Consistent, "%+v", report.Ledger)
+			require.Equal(t, int64(10000), report.Ledger.OpeningTotal)
+			require.Equal(t, report.Ledger.OpeningTotal, report.Ledger.BalanceTotal)
+		})
+	}
+}
+
+func TestRunDuration(t *testing.T) {
+	config := testConfig
+	config.Transfers = 0
+	config.Duration = 50 * time.Millisecond
+
+	store := memdb.NewStore()
+	report, err := Run(context.Background(), store, StoreTarget{Store: store, Label: "memory"}, config)
+	require.NoError(t, err)
+	require.Positive(t, report.Transfers)
+	require.GreaterOrEqual(t, time.Duration(report.Elapsed), config.Duration)
+	require.True(t, report.Ledger.Consistent)
+}
+
+// a ledger changed behind the back of the store doesn't add up any more
+func TestCheckLedger(t *testing.T) {
+	store := memdb.NewStore()
+	accounts, err := createAccounts(context.Background(), store, testConfig)
+	require.NoError(t, err)
+
+	ledger, err := checkLedger(context.Background(), store, accounts, testConfig.OpeningBalance)
+	require.NoError(t, err)
+	require.True(t, ledger.Consistent)
+
+	_, err = store.AddAccountBalance(context.Background(), db.AddAccountBalanceParams{ID: accounts[0], Amount: -2000})
+	require.NoError(t, err)
+
+	ledger, err = checkLedger(context.Background(), store, accounts, testConfig.OpeningBalance)
+	require.NoError(t, err)
+	require.False(t, ledger.Consistent)
+	require.Equal(t, int64(8000), ledger.BalanceTotal)
+	require.Equal(t, []int64{accounts[0]}, ledger.NegativeIDs)
+	require.Len(t, ledger.Mismatches, 1)
+}
+
+func TestConfigValidate(t *testing.T) {
+	testCases := []struct {
+		name   string
+		change func(config *Config)
+	}{
+		{"NoConcurrency", func(config *Config) { config.Concurrency = 0 }},
+		{"Unbounded", func(config *Config) { config.Transfers = 0 }},
+		{"OneAccount", func(config *Config) { config.Accounts = 1 }},
+		{"NoAmount", func(config *Config) { config.MaxAmount = 0 }},
+		{"UnknownMix", func(config *Config) { config.Mix = "uniform" }},
+		{"AllHot", func(config *Config) { config.Mix, config.HotAccounts = MixHot, 10 }},
+		{"HotPct", func(config *Config) { config.Mix, config.HotAccounts, config.HotPct = MixHot, 1, 101 }},
+	}
+
+	require.NoError(t, testConfig.validate())
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := testConfig
+			tc.change(&config)
+			require.Error(t, config.validate())
+		})
+	}
+}
+
+func TestPickAccounts(t *testing.T) {
+	accounts := []int64{1, 2, 3, 4, 5}
+	config := Config{Mix: MixHot, HotAccounts: 1, HotPct: 100}
+	random := rand.New(rand.NewPCG(1, 1))
+
+	for i := 0; i < 1000; i++ {
+		from, to := pickAccounts(random, accounts, config)
+		require.NotEqual(t, from, to)
+		require.True(t, from == 1 || to == 1, "%d -> %d misses the hot account", from, to)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	var latencies []time.Duration
+	for i := 100; i >= 1; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	require.Equal(t, Latency{
+		P50: Millis(50 * time.Millisecond),
+		P99: Millis(99 * time.Millisecond),
+		Max: Millis(100 * time.Millisecond),
+	}, summarize(latencies))
+	require.Equal(t, Latency{}, summarize(nil))
+
+	data, err := json.Marshal(Latency{P50: Millis(1500 * time.Microsecond)})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"p50_ms": 1.5, "p99_ms": 0, "max_ms": 0}`, string(data))
+}
+
+func TestHTTPTarget(t *testing.T) {
+	testCases := []struct {
+		name    string
+		status  int
+		body    string
+		outcome string
+	}{
+		{"Completed", http.StatusOK, `{"transfer":{"id":1}}`, OutcomeCompleted},
+		{"PendingReview", http.StatusAccepted, `{"transfer":{"id":1}}`, OutcomePendingReview},
+		{"Rejected", http.StatusUnprocessableEntity, `{"code":"insufficient_funds","message":"insufficient funds"}`, "insufficient_funds"},
+		{"RateLimited", http.StatusTooManyRequests, `{"code":"rate_limited","message":"too many requests"}`, "rate_limited"},
+		{"Proxy", http.StatusBadGateway, `<html>bad gateway</html>`, "http_502"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, http.MethodPost, r.Method)
+				require.Equal(t, "/transfers", r.URL.Path)
+
+				var req transferRequest
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+				require.Equal(t, transferRequest{FromAccountID: 1, ToAccountID: 2, Amount: 10, Currency: "USD"}, req)
+
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			target := NewHTTPTarget(server.URL+"/", 4)
+			outcome := target.Transfer(context.Background(), db.TransferTxParams{FromAccountID: 1, ToAccountID: 2, Amount: 10}, "USD")
+			require.Equal(t, tc.outcome, outcome)
+		})
+	}
+
+	// nothing listens there any more
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	outcome := NewHTTPTarget(server.URL, 1).Transfer(context.Background(), db.TransferTxParams{FromAccountID: 1, ToAccountID: 2, Amount: 10}, "USD")
+	require.Equal(t, OutcomeTransport, outcome)
+}
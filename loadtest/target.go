@@ -0,0 +1,130 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/techschool/simple-bank/apperror"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+)
+
+// outcomes of the transfers that didn't fail, the failed ones are counted by their apperror code
+const (
+	OutcomeCompleted     = db.TransferStatusCompleted
+	OutcomePendingReview = db.TransferStatusPendingReview
+	OutcomeTransport     = "transport_error" // the HTTP request got no response, e.g. a refused connection or a timeout
+)
+
+// Target sends the transfers of the load
+type Target interface {
+	// Name describes the target in the report
+	Name() string
+	// Transfer sends one transfer and returns its outcome: one of the Outcome* constants or the apperror code
+	// of the error, so that the same failures are counted under the same name whatever the target
+	Transfer(ctx context.Context, arg db.TransferTxParams, currency string) string
+}
+
+// StoreTarget calls TransferTx directly, it measures the database without the API in front of it
+type StoreTarget struct {
+	Store db.Store
+	Label string // name of the target, e.g. postgres or memory
+}
+
+func (target StoreTarget) Name() string {
+	return target.Label
+}
+
+func (target StoreTarget) Transfer(ctx context.Context, arg db.TransferTxParams, currency string) string {
+	result, err := target.Store.TransferTx(ctx, arg)
+	if err != nil {
+		return string(apperror.From(err).Code)
+	}
+	return result.Transfer.Status
+}
+
+// HTTPTarget posts the transfers to the /transfers route of a running server
+type HTTPTarget struct {
+	BaseURL string // e.g. http://localhost:8080
+	Client  *http.Client
+}
+
+// NewHTTPTarget returns a target keeping up to concurrency connections open to the server at baseURL,
+// the default client of package http only keeps two and would measure the cost of new connections
+func NewHTTPTarget(baseURL string, concurrency int) HTTPTarget {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = concurrency
+	transport.MaxIdleConnsPerHost = concurrency
+
+	return HTTPTarget{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Client:  &http.Client{Transport: transport},
+	}
+}
+
+func (target HTTPTarget) Name() string {
+	return target.BaseURL
+}
+
+// transferRequest is the body of POST /transfers
+type transferRequest struct {
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+}
+
+func (target HTTPTarget) Transfer(ctx context.Context, arg db.TransferTxParams, currency string) string {
+	body, err := json.Marshal(transferRequest{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+		Currency:      currency,
+	})
+	if err != nil {
+		return string(apperror.CodeInternal)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.BaseURL+"/transfers", bytes.NewReader(body))
+	if err != nil {
+		return OutcomeTransport
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := target.Client.Do(req)
+	if err != nil {
+		return OutcomeTransport
+	}
+	defer resp.Body.Close()
+
+	return httpOutcome(resp)
+}
+
+// httpOutcome reads the outcome of a transfer from the response of the server, the body is read to the end
+// so that the connection can be reused
+func httpOutcome(resp *http.Response) string {
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return OutcomeTransport
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return OutcomeCompleted
+	case http.StatusAccepted: // held by the fraud screening
+		return OutcomePendingReview
+	}
+
+	// every error response of the API carries its code, anything else came from something in front of it
+	var errorBody struct {
+		Code apperror.Code `json:"code"`
+	}
+	if err := json.Unmarshal(data, &errorBody); err != nil || errorBody.Code == "" {
+		return fmt.Sprintf("http_%d", resp.StatusCode)
+	}
+	return string(errorBody.Code)
+}
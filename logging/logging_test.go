@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// decodeLine parses the single JSON record written to buf
+func decodeLine(t *testing.T, buf *bytes.Buffer) map[string]any {
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	return record
+}
+
+func TestNewInvalid(t *testing.T) {
+	_, err := New(&bytes.Buffer{}, "loud", FormatJSON)
+	require.Error(t, err)
+
+	_, err = New(&bytes.Buffer{}, "info", "xml")
+	require.Error(t, err)
+}
+
+func TestLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "warn", FormatJSON)
+	require.NoError(t, err)
+
+	logger.Info("hidden")
+	require.Zero(t, buf.Len())
+
+	logger.Warn("shown")
+	require.Equal(t, "shown", decodeLine(t, &buf)["msg"])
+}
+
+func TestRedact(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info", FormatJSON)
+	require.NoError(t, err)
+
+	logger.Info("config", "admin_token", "s3cr3t", "Authorization", "Bearer abc", "owner", "alice")
+
+	record := decodeLine(t, &buf)
+	require.Equal(t, redactedValue, record["admin_token"])
+	require.Equal(t, redactedValue, record["Authorization"])
+	require.Equal(t, "alice", record["owner"])
+	require.NotContains(t, buf.String(), "s3cr3t")
+}
+
+func TestContextAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info", FormatJSON)
+	require.NoError(t, err)
+
+	traceID, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	require.NoError(t, err)
+	spanID, err := trace.SpanIDFromHex("00f067aa0ba902b7")
+	require.NoError(t, err)
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID})
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	ctx = trace.ContextWithSpanContext(ctx, spanContext)
+
+	logger.With("component", "test").InfoContext(ctx, "hello")
+
+	record := decodeLine(t, &buf)
+	require.Equal(t, "req-1", record["request_id"])
+	require.Equal(t, traceID.String(), record["trace_id"])
+	require.Equal(t, "test", record["component"])
+
+	// without a request the attributes are left out
+	buf.Reset()
+	logger.Info("hello")
+	record = decodeLine(t, &buf)
+	require.NotContains(t, record, "request_id")
+	require.NotContains(t, record, "trace_id")
+}
+
+func TestRequestIDOrNew(t *testing.T) {
+	require.Equal(t, "req-123_a.b:c", RequestIDOrNew("req-123_a.b:c"))
+
+	for _, id := range []string{"", "bad id", "line\nbreak", strings.Repeat("a", maxRequestIDLength+1)} {
+		generated := RequestIDOrNew(id)
+		require.NotEqual(t, id, generated)
+		require.Len(t, generated, 32)
+	}
+
+	require.NotEqual(t, NewRequestID(), NewRequestID())
+}
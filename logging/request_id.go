@@ -0,0 +1,33 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// maxRequestIDLength bounds the request ids accepted from clients, they end up in every log line
+const maxRequestIDLength = 128
+
+// NewRequestID generates a random request id
+func NewRequestID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf) // never fails, see crypto/rand.Read
+	return hex.EncodeToString(buf)
+}
+
+// RequestIDOrNew returns id when it is a usable request id sent by a client, or a new one otherwise
+// a usable id is short and only contains letters, digits, '-', '_', '.' and ':', so it can't forge log lines
+func RequestIDOrNew(id string) string {
+	if id == "" || len(id) > maxRequestIDLength {
+		return NewRequestID()
+	}
+
+	for _, c := range id {
+		valid := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == ':'
+		if !valid {
+			return NewRequestID()
+		}
+	}
+	return id
+}
@@ -1,34 +1,376 @@
 package main
 
 import (
-	"database/sql"
-	"log"
-	db "github.com/techschool/simple-bank/db2/sqlc"
-	"github.com/techschool/simple-bank/api"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
-	_ "github.com/lib/pq" // PostgreSQL driver,
-	// _ is blank identifier to avoid import error if not used
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/techschool/simple-bank/api"
+	"github.com/techschool/simple-bank/compliance"
+	"github.com/techschool/simple-bank/db2/migration"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/events"
+	"github.com/techschool/simple-bank/fraud"
+	"github.com/techschool/simple-bank/gapi"
+	"github.com/techschool/simple-bank/health"
+	"github.com/techschool/simple-bank/metrics"
+	"github.com/techschool/simple-bank/pb"
+	"github.com/techschool/simple-bank/ratelimit"
+	"github.com/techschool/simple-bank/tracing"
 	"github.com/techschool/simple-bank/utils"
+	"github.com/techschool/simple-bank/webhook"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
+// outboxBatchSize is the number of outbox events the relay publishes per transaction
+const outboxBatchSize = 100
+
+// webhookBatchSize is the number of webhook deliveries the worker attempts per transaction
+const webhookBatchSize = 20
+
+// amlBatchSize is the number of transfers the AML analyzer looks at per transaction
+const amlBatchSize = 100
 
+// shutdownGrace is added to config.ShutdownTimeout before the process gives up on a clean shutdown
+// it leaves the servers time to report that their own deadline passed
+const shutdownGrace = 5 * time.Second
+
+// dbConnectTimeout is how long startup waits for the database, e.g. while its container is still starting
+const dbConnectTimeout = 30 * time.Second
+
+// healthCheckTimeout bounds a run of the readiness checks
+const healthCheckTimeout = 2 * time.Second
+
+// grpcHealthInterval is how often the gRPC health status is refreshed
+const grpcHealthInterval = 5 * time.Second
 
 func main() {
-	config, err := utils.LoadConfig(".") // because config file is in same directory as main.go
+	if err := newRootCommand(newCLI()).Execute(); err != nil {
+		os.Exit(1) // cobra already printed the error
+	}
+}
+
+// serve runs the HTTP and gRPC servers and the background workers until SIGTERM
+func serve(config utils.Config) {
+	slog.Info("Loaded config", "config", config.String()) // passwords and tokens are redacted
+
+	shutdownTracing, err := tracing.Setup(context.Background(), config.TraceExporter, config.TraceOTLPEndpoint)
+	if err != nil {
+		fatal("Cannot set up tracing", err)
+	}
+
+	conn, err := openDatabase(config, config.DBSource)
+	if err != nil {
+		fatal("Cannot connect to database", err)
+	}
+	if config.MigrateOnStart {
+		if err := migrateOnStart(config.DBSource); err != nil {
+			fatal("Cannot migrate database", err)
+		}
+	}
+	if err := metrics.RegisterDBStats(conn, "primary"); err != nil {
+		fatal("Cannot register database metrics", err)
+	}
+
+	var storeOptions []db.StoreOption
+	var replica *pgxpool.Pool
+	if config.DBReplicaSource != "" {
+		replica, err = openDatabase(config, config.DBReplicaSource)
+		if err != nil {
+			fatal("Cannot connect to read replica", err)
+		}
+		if err := metrics.RegisterDBStats(replica, "replica"); err != nil {
+			fatal("Cannot register database metrics", err)
+		}
+		storeOptions = append(storeOptions, db.WithReadReplica(replica))
+	}
+
+	if config.FraudRulesPath != "" {
+		screener, err := fraud.LoadRules(config.FraudRulesPath)
+		if err != nil {
+			fatal("Cannot load fraud rules", err)
+		}
+		storeOptions = append(storeOptions, db.WithScreener(screener))
+	}
+	store := db.NewStore(conn, storeOptions...)
+
+	eventPublisher, err := events.NewPublisher(config.EventPublisher, config.EventLogPath)
 	if err != nil {
-		log.Fatal("Cannot load config:", err)
+		fatal("Cannot create event publisher", err)
 	}
+	// the relay publishes what TransferTx and CreateAccountTx write to the outbox,
+	// both to the configured publisher and to the webhook subscriptions
+	publisher := events.NewMultiPublisher(eventPublisher, webhook.NewDispatcher(store))
+	relay := events.NewRelay(store, publisher, config.OutboxPollInterval, outboxBatchSize, config.OutboxMaxAttempts)
 
-	conn, err := sql.Open(config.DBDriver, config.DBSource)
+	webhookWorker := webhook.NewWorker(store, webhook.WorkerConfig{
+		PollInterval: config.WebhookPollInterval,
+		BatchSize:    webhookBatchSize,
+		MaxAttempts:  config.WebhookMaxAttempts,
+		BaseBackoff:  config.WebhookBaseBackoff,
+		MaxBackoff:   config.WebhookMaxBackoff,
+		Timeout:      config.WebhookTimeout,
+		AllowPrivate: config.WebhookAllowPrivate,
+	})
+
+	amlRules, err := compliance.NewRules(
+		config.AMLThresholds,
+		config.AMLStructuringFloor,
+		config.AMLStructuringWindow,
+		config.AMLStructuringMinCount,
+	)
 	if err != nil {
-		log.Fatal("Cannot connect to database:", err)
+		fatal("Cannot load AML rules", err)
 	}
+	analyzer := compliance.NewAnalyzer(store, amlRules, config.AMLPollInterval, amlBatchSize)
+
+	// every replica listens for the balance changes committed by TransferTx and streams them to its clients
+	bus := events.NewBus()
+	listener := events.NewListener(config.DBSource, bus)
 
-	store := db.NewStore(conn)
-	server := api.NewServer(store)
+	// the replica is ready once the database is reachable and migrated and the background workers are running
+	checker := health.NewChecker(healthCheckTimeout)
+	checker.Add("database", health.DatabaseCheck(conn))
+	checker.Add("migrations", health.MigrationCheck(conn, db.SchemaVersion))
+	if replica != nil {
+		checker.Add("database_replica", health.DatabaseCheck(replica))
+	}
+	checker.Add("outbox_relay", relay.Heartbeat().Check())
+	checker.Add("webhook_worker", webhookWorker.Heartbeat().Check())
+	checker.Add("account_events_listener", listener.Heartbeat().Check())
+	checker.Add("aml_analyzer", analyzer.Heartbeat().Check())
 
-	err = server.Start(config.ServerAddress)
+	// the buckets are kept per replica, a shared ratelimit.Store would make the limits global
+	ratePolicies, err := ratelimit.ParsePolicies(config.RateLimitDefault, config.RateLimitRoutes)
 	if err != nil {
-		log.Fatal("Cannot start server:", err)
+		fatal("Cannot parse rate limits", err)
+	}
+	limiter := ratelimit.NewLimiter(ratePolicies, ratelimit.NewMemoryStore())
+
+	server, err := api.NewServer(config, store, bus, checker, limiter)
+	if err != nil {
+		fatal("Cannot create server", err)
+	}
+
+	// ctx is cancelled on Ctrl+C or on SIGTERM, which docker and kubernetes send before killing the container
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// the group cancels ctx as soon as one of its members fails, so the others stop too
+	group, ctx := errgroup.WithContext(ctx)
+
+	// the background workers drop the batch they are working on when ctx is cancelled,
+	// its transaction is rolled back and the next instance picks the rows up again
+	group.Go(func() error {
+		return ignoreCanceled(relay.Run(ctx))
+	})
+	group.Go(func() error {
+		return ignoreCanceled(webhookWorker.Run(ctx))
+	})
+	group.Go(func() error {
+		return ignoreCanceled(listener.Run(ctx))
+	})
+	group.Go(func() error {
+		return ignoreCanceled(analyzer.Run(ctx))
+	})
+	group.Go(func() error {
+		return runGrpcServer(ctx, config, store, bus, checker, limiter)
+	})
+	group.Go(func() error {
+		return server.Start(ctx, config.ServerAddress)
+	})
+	group.Go(func() error {
+		slog.Info("Start metrics server", "address", config.MetricsAddress)
+		return metrics.Serve(ctx, config.MetricsAddress, config.ShutdownTimeout)
+	})
+
+	go func() {
+		<-ctx.Done()
+		stop() // a second signal kills the process right away
+		slog.Info("Shutting down")
+
+		time.Sleep(config.ShutdownTimeout + shutdownGrace)
+		slog.Error("Shutdown took too long", "timeout", config.ShutdownTimeout)
+		os.Exit(1)
+	}()
+
+	err = group.Wait()
+
+	// nothing uses the publisher and the database anymore
+	if closer, ok := eventPublisher.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			slog.Error("Cannot close event publisher", "error", err)
+		}
+	}
+	conn.Close()
+	if replica != nil {
+		replica.Close()
+	}
+	flushCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Error("Cannot flush traces", "error", err)
+	}
+	cancel()
+
+	if err != nil {
+		fatal("Server stopped with error", err)
+	}
+	slog.Info("Server stopped")
+}
+
+// runGrpcServer serves the gRPC API until ctx is cancelled
+// it then waits up to config.ShutdownTimeout for in-flight calls before closing the remaining connections
+func runGrpcServer(
+	ctx context.Context,
+	config utils.Config,
+	store db.Store,
+	bus *events.Bus,
+	checker *health.Checker,
+	limiter *ratelimit.Limiter,
+) error {
+	server, err := gapi.NewServer(config, store, bus)
+	if err != nil {
+		return err
+	}
+	grpcServer := grpc.NewServer(
+		// the logger runs first, so that rejected calls are logged with their request id
+		grpc.ChainUnaryInterceptor(gapi.UnaryLogger, gapi.UnaryRateLimiter(limiter)),
+		grpc.ChainStreamInterceptor(gapi.StreamLogger, gapi.StreamRateLimiter(limiter)),
+	)
+	pb.RegisterSimpleBankServer(grpcServer, server)
+	reflection.Register(grpcServer) // lets clients such as grpcurl discover the services
+
+	// grpc.health.v1.Health, backed by the same checks as /readyz
+	healthServer := grpchealth.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	go gapi.WatchHealth(ctx, checker, healthServer, grpcHealthInterval)
+
+	listener, err := net.Listen("tcp", config.GRPCServerAddress)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Start gRPC server", "address", listener.Addr().String())
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- grpcServer.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	server.Shutdown() // GracefulStop would otherwise wait for the event streams to end
+
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(config.ShutdownTimeout):
+		grpcServer.Stop()
+	}
+	return nil
+}
+
+// openDatabase opens a connection pool to source, sized by the DB_* settings of config,
+// and waits for the database to answer
+func openDatabase(config utils.Config, source string) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(source)
+	if err != nil {
+		return nil, err
+	}
+
+	// zero values keep the pgx defaults
+	if config.DBMaxOpenConns > 0 {
+		poolConfig.MaxConns = config.DBMaxOpenConns
+	}
+	poolConfig.MinConns = config.DBMinConns
+	if config.DBConnMaxLifetime > 0 {
+		poolConfig.MaxConnLifetime = config.DBConnMaxLifetime
+	}
+	if config.DBConnMaxIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = config.DBConnMaxIdleTime
+	}
+
+	// the pool connects lazily, fail now rather than on the first request if the database can't be reached
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	if err != nil {
+		return nil, err
+	}
+	if err := waitForDatabase(pool, dbConnectTimeout); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return pool, nil
+}
+
+// waitForDatabase pings the database until it answers or timeout passes
+func waitForDatabase(pool *pgxpool.Pool, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	for {
+		err := pool.Ping(ctx)
+		if err == nil {
+			return nil
+		}
+
+		slog.Warn("Waiting for database", "error", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// migrateOnStart applies the pending migrations before the servers start, see MIGRATE_ON_START
+func migrateOnStart(source string) error {
+	m, err := migration.New(source)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if err := migration.Up(m); err != nil {
+		return err
+	}
+	version, _, err := m.Version()
+	if err != nil {
+		return err
+	}
+	slog.Info("Database migrated", "version", version)
+	return nil
+}
+
+// fatal logs err and exits, the slog counterpart of log.Fatal
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// ignoreCanceled treats the error returned by a worker after a shutdown as a clean exit
+func ignoreCanceled(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return nil
 	}
-}
\ No newline at end of file
+	return err
+}
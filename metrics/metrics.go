@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// namespace prefixes every metric of the service
+const namespace = "simple_bank"
+
+// HTTP metrics, recorded by the api middleware
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, method and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by route, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+// RateLimited counts the requests rejected by the rate limiter, recorded by the api middleware and the gapi interceptors
+var RateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "rate_limited_requests_total",
+	Help:      "Requests rejected by the rate limiter, by route (HTTP route or gRPC method).",
+}, []string{"route"})
+
+// database transaction metrics, recorded by execTx
+var (
+	TxDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_tx_duration_seconds",
+		Help:      "Duration of database transactions including retries, by outcome (committed or failed).",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"outcome"})
+
+	TxRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_tx_retries_total",
+		Help:      "Database transactions run again after a retryable error, by SQLSTATE.",
+	}, []string{"sqlstate"})
+
+	TxRetriesExhausted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_tx_retries_exhausted_total",
+		Help:      "Database transactions that still failed with a retryable error after the last attempt.",
+	})
+)
+
+// WebhookClaimsLost counts the webhook attempts the stores dropped because the claim of the worker ran out before
+// the attempt was recorded: the receiver may get the delivery again from the worker that took it over
+var WebhookClaimsLost = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "webhook_claims_lost_total",
+	Help:      "Webhook delivery attempts not recorded because the claim of the worker ran out first.",
+})
+
+// business metrics: the transfer handler records the pending and failed transfers, the stores record the completed
+// ones with ObserveTransferCompleted so that every caller of the store counts, not only the HTTP api
+var (
+	TransfersCompleted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfers_completed_total",
+		Help:      "Transfers committed, by currency.",
+	}, []string{"currency"})
+
+	TransferVolume = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfer_volume_total",
+		Help:      "Sum of the amounts of committed transfers in minor units, by currency.",
+	}, []string{"currency"})
+
+	TransfersPendingReview = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfers_pending_review_total",
+		Help:      "Transfers held for review by the fraud screening, by currency.",
+	}, []string{"currency"})
+
+	TransfersFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfers_failed_total",
+		Help:      "Transfer requests that were rejected or failed, by reason (the error code of the response).",
+	}, []string{"reason"})
+)
+
+// ObserveTransferCompleted counts a transfer whose money moved, the stores call it once the transaction committed
+func ObserveTransferCompleted(currency string, amount int64) {
+	TransfersCompleted.WithLabelValues(currency).Inc()
+	TransferVolume.WithLabelValues(currency).Add(float64(amount))
+}
@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// RegisterDBStats exports the statistics of the connection pool, such as open, in use and idle connections
+// role ("primary" or "replica") labels the statistics, so both pools can be told apart
+func RegisterDBStats(pool *pgxpool.Pool, role string) error {
+	registerer := prometheus.WrapRegistererWith(prometheus.Labels{"role": role}, prometheus.DefaultRegisterer)
+	return registerer.Register(newPoolCollector(pool))
+}
+
+// Serve exposes /metrics on address until ctx is cancelled
+// it runs on its own port, so the metrics are never reachable through the public API
+func Serve(ctx context.Context, address string, shutdownTimeout time.Duration) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	server := &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy is a token bucket: Burst requests may be made at once, then Rate requests per second
+type Policy struct {
+	Rate  float64 // tokens added per second
+	Burst int     // capacity of the bucket
+}
+
+// ParsePolicy parses "<count>/<period>[:<burst>]", e.g. "5/s", "100/1m" or "10/s:20"
+// the burst defaults to count
+func ParsePolicy(s string) (Policy, error) {
+	spec, burstSpec, hasBurst := strings.Cut(strings.TrimSpace(s), ":")
+
+	countSpec, periodSpec, ok := strings.Cut(spec, "/")
+	if !ok {
+		return Policy{}, fmt.Errorf("invalid rate limit policy %q: expected <count>/<period>[:<burst>]", s)
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(countSpec))
+	if err != nil || count <= 0 {
+		return Policy{}, fmt.Errorf("invalid rate limit policy %q: count must be a positive integer", s)
+	}
+
+	periodSpec = strings.TrimSpace(periodSpec)
+	if periodSpec != "" && (periodSpec[0] < '0' || periodSpec[0] > '9') {
+		periodSpec = "1" + periodSpec // "s" means "1s"
+	}
+	period, err := time.ParseDuration(periodSpec)
+	if err != nil || period <= 0 {
+		return Policy{}, fmt.Errorf("invalid rate limit policy %q: period must be a positive duration", s)
+	}
+
+	burst := count
+	if hasBurst {
+		burst, err = strconv.Atoi(strings.TrimSpace(burstSpec))
+		if err != nil || burst <= 0 {
+			return Policy{}, fmt.Errorf("invalid rate limit policy %q: burst must be a positive integer", s)
+		}
+	}
+
+	return Policy{Rate: float64(count) / period.Seconds(), Burst: burst}, nil
+}
+
+// Policies maps routes to their policy, routes without one fall back to Default
+// a nil Default leaves those routes unlimited
+type Policies struct {
+	Default *Policy
+	Routes  map[string]Policy
+}
+
+// ParsePolicies parses the default policy and a comma separated list of "<route>=<policy>"
+// a route is "<METHOD> <path>" as registered in gin, e.g. "POST /transfers", or a gRPC method such as
+// "/pb.SimpleBank/StreamAccountEvents"; empty strings mean no default and no route policies
+func ParsePolicies(defaultPolicy string, routes string) (Policies, error) {
+	policies := Policies{Routes: map[string]Policy{}}
+
+	if strings.TrimSpace(defaultPolicy) != "" {
+		policy, err := ParsePolicy(defaultPolicy)
+		if err != nil {
+			return Policies{}, err
+		}
+		policies.Default = &policy
+	}
+
+	for _, entry := range strings.Split(routes, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		route, spec, ok := strings.Cut(entry, "=")
+		route = strings.Join(strings.Fields(route), " ")
+		if !ok || route == "" {
+			return Policies{}, fmt.Errorf("invalid route rate limit %q: expected <route>=<policy>", entry)
+		}
+
+		policy, err := ParsePolicy(spec)
+		if err != nil {
+			return Policies{}, err
+		}
+		policies.Routes[route] = policy
+	}
+
+	return policies, nil
+}
+
+// For returns the policy of route, false if the route is unlimited
+func (policies Policies) For(route string) (Policy, bool) {
+	if policy, ok := policies.Routes[route]; ok {
+		return policy, true
+	}
+	if policies.Default != nil {
+		return *policies.Default, true
+	}
+	return Policy{}, false
+}
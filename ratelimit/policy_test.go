@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePolicy(t *testing.T) {
+	testCases := []struct {
+		spec   string
+		policy Policy
+		valid  bool
+	}{
+		{spec: "5/s", policy: Policy{Rate: 5, Burst: 5}, valid: true},
+		{spec: "10/s:20", policy: Policy{Rate: 10, Burst: 20}, valid: true},
+		{spec: "60/1m", policy: Policy{Rate: 1, Burst: 60}, valid: true},
+		{spec: " 2 / 500ms : 1 ", policy: Policy{Rate: 4, Burst: 1}, valid: true},
+		{spec: "5"},
+		{spec: "0/s"},
+		{spec: "5/0s"},
+		{spec: "5/fortnight"},
+		{spec: "5/s:0"},
+		{spec: "five/s"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			policy, err := ParsePolicy(tc.spec)
+			if !tc.valid {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.policy, policy)
+		})
+	}
+}
+
+func TestParsePolicies(t *testing.T) {
+	policies, err := ParsePolicies("50/s:100", "POST /transfers=5/s:10, /pb.SimpleBank/StreamAccountEvents=10/m")
+	require.NoError(t, err)
+
+	policy, ok := policies.For("POST /transfers")
+	require.True(t, ok)
+	require.Equal(t, Policy{Rate: 5, Burst: 10}, policy)
+
+	policy, ok = policies.For("/pb.SimpleBank/StreamAccountEvents")
+	require.True(t, ok)
+	require.Equal(t, 10, policy.Burst)
+
+	policy, ok = policies.For("GET /accounts/:id")
+	require.True(t, ok)
+	require.Equal(t, Policy{Rate: 50, Burst: 100}, policy)
+
+	// without a default only the listed routes are limited
+	policies, err = ParsePolicies("", "POST /transfers=5/s")
+	require.NoError(t, err)
+	_, ok = policies.For("GET /accounts/:id")
+	require.False(t, ok)
+
+	_, err = ParsePolicies("", "POST /transfers")
+	require.Error(t, err)
+	_, err = ParsePolicies("fast", "")
+	require.Error(t, err)
+}
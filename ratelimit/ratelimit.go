@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"time"
+)
+
+// Result is the outcome of taking a token from a bucket
+type Result struct {
+	Allowed    bool
+	RetryAfter time.Duration // when the next token is available, only set when the request is not allowed
+}
+
+// Store keeps the buckets, MemoryStore keeps them in the process
+// a store shared by the replicas, e.g. backed by redis, lets them enforce a single limit
+type Store interface {
+	// Take removes a token from the bucket named key, created full on first use
+	Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error)
+}
+
+// Limiter applies the policy of a route to the buckets of the clients making the request
+// a nil *Limiter allows everything
+type Limiter struct {
+	policies Policies
+	store    Store
+	now      func() time.Time
+}
+
+// NewLimiter creates a limiter enforcing policies with the buckets kept in store
+func NewLimiter(policies Policies, store Store) *Limiter {
+	return &Limiter{policies: policies, store: store, now: time.Now}
+}
+
+// Allow takes a token from the bucket of every key for route, e.g. one for the client IP and one for the user
+// the request is allowed only if every bucket had a token; errors of the store let the request through,
+// an unavailable store should not take the whole API down with it
+func (limiter *Limiter) Allow(ctx context.Context, route string, keys ...string) Result {
+	if limiter == nil {
+		return Result{Allowed: true}
+	}
+
+	policy, ok := limiter.policies.For(route)
+	if !ok {
+		return Result{Allowed: true}
+	}
+
+	now := limiter.now()
+	for _, key := range keys {
+		result, err := limiter.store.Take(ctx, route+"|"+key, policy, now)
+		if err != nil {
+			slog.WarnContext(ctx, "rate limit store failed, request allowed", "route", route, "error", err)
+			continue
+		}
+		if !result.Allowed {
+			return result
+		}
+	}
+	return Result{Allowed: true}
+}
+
+// RetryAfterSeconds rounds RetryAfter up to whole seconds, the unit of the Retry-After header
+func (result Result) RetryAfterSeconds() int {
+	return max(1, int(math.Ceil(result.RetryAfter.Seconds())))
+}
@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTestLimiter returns a limiter whose clock only moves when the returned function is called
+func newTestLimiter(policies Policies, store Store) (*Limiter, func(time.Duration)) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewLimiter(policies, store)
+	limiter.now = func() time.Time { return now }
+	return limiter, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestLimiterTokenBucket(t *testing.T) {
+	policies := Policies{Routes: map[string]Policy{"POST /transfers": {Rate: 1, Burst: 2}}}
+	limiter, advance := newTestLimiter(policies, NewMemoryStore())
+	ctx := context.Background()
+
+	// the burst is available right away
+	require.True(t, limiter.Allow(ctx, "POST /transfers", "ip:1.2.3.4").Allowed)
+	require.True(t, limiter.Allow(ctx, "POST /transfers", "ip:1.2.3.4").Allowed)
+
+	result := limiter.Allow(ctx, "POST /transfers", "ip:1.2.3.4")
+	require.False(t, result.Allowed)
+	require.Equal(t, time.Second, result.RetryAfter)
+	require.Equal(t, 1, result.RetryAfterSeconds())
+
+	// other clients and other routes have their own buckets
+	require.True(t, limiter.Allow(ctx, "POST /transfers", "ip:5.6.7.8").Allowed)
+	require.True(t, limiter.Allow(ctx, "GET /accounts/:id", "ip:1.2.3.4").Allowed)
+
+	advance(500 * time.Millisecond)
+	result = limiter.Allow(ctx, "POST /transfers", "ip:1.2.3.4")
+	require.False(t, result.Allowed)
+	require.Equal(t, 500*time.Millisecond, result.RetryAfter)
+
+	advance(500 * time.Millisecond)
+	require.True(t, limiter.Allow(ctx, "POST /transfers", "ip:1.2.3.4").Allowed)
+}
+
+func TestLimiterEveryKey(t *testing.T) {
+	policies := Policies{Default: &Policy{Rate: 1, Burst: 1}}
+	limiter, _ := newTestLimiter(policies, NewMemoryStore())
+	ctx := context.Background()
+
+	require.True(t, limiter.Allow(ctx, "GET /admin/audit_events", "user:admin").Allowed)
+
+	// the user already spent its token, even if the request comes from another IP
+	require.False(t, limiter.Allow(ctx, "GET /admin/audit_events", "ip:1.2.3.4", "user:admin").Allowed)
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error) {
+	return Result{}, errors.New("store unavailable")
+}
+
+func TestLimiterFailsOpen(t *testing.T) {
+	limiter, _ := newTestLimiter(Policies{Default: &Policy{Rate: 1, Burst: 1}}, failingStore{})
+	require.True(t, limiter.Allow(context.Background(), "POST /transfers", "ip:1.2.3.4").Allowed)
+}
+
+func TestNilLimiter(t *testing.T) {
+	var limiter *Limiter
+	require.True(t, limiter.Allow(context.Background(), "POST /transfers", "ip:1.2.3.4").Allowed)
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	store := NewMemoryStore()
+	policy := Policy{Rate: 1, Burst: 5}
+	now := time.Now()
+	ctx := context.Background()
+
+	_, err := store.Take(ctx, "a", policy, now)
+	require.NoError(t, err)
+	_, err = store.Take(ctx, "b", policy, now)
+	require.NoError(t, err)
+	require.Equal(t, 2, store.size())
+
+	// both buckets refilled long ago, the next sweep drops them and only the new one remains
+	_, err = store.Take(ctx, "c", policy, now.Add(2*sweepInterval))
+	require.NoError(t, err)
+	require.Equal(t, 1, store.size())
+}
@@ -0,0 +1,57 @@
+package seed
+
+import (
+	"context"
+	"time"
+
+	db "github.com/techschool/simple-bank/db2/sqlc"
+)
+
+// Dater moves the rows written by the seed back in time.
+// The stores date every row with the time it is written, the seed goes through them like any other client
+// and then dates what it created over the history. Nothing outside of the seed has a reason to rewrite created_at,
+// which is why the queries live here and not in the stores
+type Dater interface {
+	// DateAccount dates the account, the transfer of its opening balance and the entries of that transfer
+	DateAccount(ctx context.Context, accountID int64, at time.Time) error
+	// DateTransfer dates the transfer and its entries
+	DateTransfer(ctx context.Context, transferID int64, at time.Time) error
+}
+
+// dateAccount also finds the opening transfer: the only transfer to the account sent by an equity account
+const dateAccount = `
+WITH account AS (
+  UPDATE accounts SET created_at = $2 WHERE id = $1
+), opening AS (
+  UPDATE transfers SET created_at = $2
+  WHERE to_account_id = $1 AND from_account_id IN (SELECT id FROM accounts WHERE kind = 'equity')
+  RETURNING id
+)
+UPDATE entries SET created_at = $2 WHERE transfer_id IN (SELECT id FROM opening)`
+
+const dateTransfer = `
+WITH transfer AS (
+  UPDATE transfers SET created_at = $2 WHERE id = $1
+  RETURNING id
+)
+UPDATE entries SET created_at = $2 WHERE transfer_id IN (SELECT id FROM transfer)`
+
+// SQLDater dates the rows of a postgres database
+type SQLDater struct {
+	conn db.DBTX
+}
+
+// NewSQLDater returns a Dater updating the rows through conn, usually the pool of the store the seed writes to
+func NewSQLDater(conn db.DBTX) *SQLDater {
+	return &SQLDater{conn: conn}
+}
+
+func (dater *SQLDater) DateAccount(ctx context.Context, accountID int64, at time.Time) error {
+	_, err := dater.conn.Exec(ctx, dateAccount, accountID, at)
+	return err
+}
+
+func (dater *SQLDater) DateTransfer(ctx context.Context, transferID int64, at time.Time) error {
+	_, err := dater.conn.Exec(ctx, dateTransfer, transferID, at)
+	return err
+}
@@ -0,0 +1,237 @@
+// Package seed fills a database with demo data: users with accounts in several currencies and a history of
+// transfers between them. Everything is derived from a seed, so the same seed gives the same data on an empty database.
+// The history ends at a fixed time rather than when the seed runs, so that running it again dates the data alike.
+// The credentials of the users are access tokens signed with the key of the servers: they expire, so they are the
+// only part of the manifest that differs from one run to the next
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/token"
+)
+
+// ownerLetters is the alphabet of the random part of the owner names
+const ownerLetters = "abcdefghijklmnopqrstuvwxyz"
+
+// Config describes the data to create
+type Config struct {
+	Seed           uint64        // same seed, same data
+	Users          int           // owners to create, each one has an account in at least one of Currencies
+	Transfers      int           // transfers attempted between accounts of the same currency
+	Currencies     []string      // currencies of the accounts
+	MaxBalance     int64         // opening balances are drawn between 0 and MaxBalance, in minor units
+	MaxTransferPct int64         // a transfer moves at most this percentage of the balance of the sender
+	History        time.Duration // the accounts are opened this long before Until, the transfers are spread in between
+	Until          time.Time     // the end of the history, required: it anchors the dates of the data
+	TokenMaker     *token.Maker  // signs an access token for every user, nil leaves the manifest without credentials
+	TokenDuration  time.Duration // how long the access tokens are valid
+}
+
+// DefaultUntil is the end of the history of the seed command when none is given
+var DefaultUntil = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Manifest lists what was created, for QA and the load tests
+type Manifest struct {
+	Seed      uint64          `json:"seed"`
+	From      time.Time       `json:"from"`  // when the accounts were opened
+	Until     time.Time       `json:"until"` // the transfers are dated between From and Until
+	Users     []User          `json:"users"`
+	Transfers TransferSummary `json:"transfers"`
+}
+
+// User is one owner and the accounts created for them
+type User struct {
+	Owner       string    `json:"owner"`
+	AccessToken string    `json:"access_token,omitempty"` // sent as "Authorization: Bearer <token>" to act as the owner
+	Accounts    []Account `json:"accounts"`
+}
+
+// Account is an account created by the seed
+type Account struct {
+	ID             int64  `json:"id"`
+	Currency       string `json:"currency"`
+	OpeningBalance int64  `json:"opening_balance"`
+}
+
+// TransferSummary sorts the attempted transfers by outcome
+type TransferSummary struct {
+	Completed     []int64        `json:"completed"`      // ids of the transfers that moved money
+	PendingReview []int64        `json:"pending_review"` // ids of the transfers held by the fraud screening
+	Rejected      map[string]int `json:"rejected"`       // attempts refused by the store, counted by reason
+}
+
+// rejections are the store errors a transfer may legitimately end with, the seed carries on after them
+var rejections = []error{
+	db.ErrInsufficientFunds,
+	db.ErrLimitExceeded,
+	db.ErrTransferDenied,
+	db.ErrAccountFrozen,
+}
+
+// Run creates the users, their accounts and the transfers of config through store.
+// The random choices only depend on config, the transfers are made one after the other so that their
+// outcomes don't depend on timing either. ctx should carry the audit info of whoever runs the seed.
+// dater then dates the accounts, transfers and entries over config.History, the audit log keeps the time the seed
+// really ran; a nil dater leaves them at that time too. The limits and the fraud screening see each transfer as
+// it is made, before it is dated
+func Run(ctx context.Context, store db.Store, dater Dater, config Config) (Manifest, error) {
+	if config.Users < 1 || config.Transfers < 0 || len(config.Currencies) == 0 {
+		return Manifest{}, errors.New("the seed needs at least one user and one currency")
+	}
+	if config.MaxBalance < 0 || config.MaxTransferPct < 1 || config.MaxTransferPct > 100 {
+		return Manifest{}, errors.New("the maximum balance must not be negative and the transfer percentage must be between 1 and 100")
+	}
+	if config.History < 0 || config.Until.IsZero() {
+		return Manifest{}, errors.New("the history must not be negative and needs an end")
+	}
+	if config.TokenMaker != nil && config.TokenDuration <= 0 {
+		return Manifest{}, errors.New("the access tokens need a positive duration")
+	}
+
+	until := config.Until
+	start := until.Add(-config.History)
+
+	random := rand.New(rand.NewPCG(config.Seed, config.Seed))
+	manifest := Manifest{
+		Seed:  config.Seed,
+		From:  start,
+		Until: until,
+		Users: make([]User, 0, config.Users),
+		Transfers: TransferSummary{
+			Completed:     []int64{},
+			PendingReview: []int64{},
+			Rejected:      map[string]int{},
+		},
+	}
+
+	// the balances are followed as the transfers complete, to draw amounts the senders can afford
+	balances := map[int64]int64{}
+	byCurrency := map[string][]int64{}
+
+	// every account is opened at the start of the history, the transfers are then dated in order, each one at a
+	// random time in its share of the history, so that the daily limits and the AML analyzer see a spread of days
+	var step time.Duration
+	if config.Transfers > 0 {
+		step = config.History / time.Duration(config.Transfers)
+	}
+
+	for i := 0; i < config.Users; i++ {
+		// the seed is part of the name, so the users of a second seed are told apart from the ones of the first
+		user := User{Owner: fmt.Sprintf("seed%d_%s", config.Seed, randomLetters(random, 8))}
+		if config.TokenMaker != nil {
+			var err error
+			user.AccessToken, _, err = config.TokenMaker.CreateToken(user.Owner, config.TokenDuration)
+			if err != nil {
+				return manifest, fmt.Errorf("create access token of %s: %w", user.Owner, err)
+			}
+		}
+
+		for _, currency := range randomCurrencies(random, config.Currencies) {
+			arg := db.CreateAccountParams{
+				Owner:    user.Owner,
+				Currency: currency,
+				Balance:  random.Int64N(config.MaxBalance + 1),
+			}
+			account, err := store.CreateAccountTx(ctx, arg)
+			if err != nil {
+				return manifest, fmt.Errorf("create account of %s in %s: %w", arg.Owner, arg.Currency, err)
+			}
+			if dater != nil {
+				if err := dater.DateAccount(ctx, account.ID, start); err != nil {
+					return manifest, fmt.Errorf("date account %d: %w", account.ID, err)
+				}
+			}
+
+			user.Accounts = append(user.Accounts, Account{ID: account.ID, Currency: currency, OpeningBalance: account.Balance})
+			balances[account.ID] = account.Balance
+			byCurrency[currency] = append(byCurrency[currency], account.ID)
+		}
+		manifest.Users = append(manifest.Users, user)
+	}
+
+	for i := 0; i < config.Transfers; i++ {
+		// the currency is drawn first, a transfer needs two accounts of the same currency
+		currency := config.Currencies[random.IntN(len(config.Currencies))]
+		accounts := byCurrency[currency]
+		if len(accounts) < 2 {
+			continue
+		}
+		fromIndex := random.IntN(len(accounts))
+		toIndex := random.IntN(len(accounts) - 1) // among the other accounts
+		if toIndex >= fromIndex {
+			toIndex++
+		}
+		from, to := accounts[fromIndex], accounts[toIndex]
+
+		// most transfers fit the balance of the sender, an empty sender is refused by the store
+		amount := balances[from] * config.MaxTransferPct / 100
+		amount = 1 + random.Int64N(max(amount, 1))
+
+		at := start.Add(time.Duration(i) * step)
+		if step > 0 {
+			at = at.Add(time.Duration(random.Int64N(int64(step))))
+		}
+		result, err := store.TransferTx(ctx, db.TransferTxParams{FromAccountID: from, ToAccountID: to, Amount: amount})
+		if reason, ok := rejection(err); ok {
+			manifest.Transfers.Rejected[reason]++
+			continue
+		}
+		if err != nil {
+			return manifest, fmt.Errorf("transfer %d from %d to %d: %w", amount, from, to, err)
+		}
+		if dater != nil {
+			if err := dater.DateTransfer(ctx, result.Transfer.ID, at); err != nil {
+				return manifest, fmt.Errorf("date transfer %d: %w", result.Transfer.ID, err)
+			}
+		}
+
+		if result.Transfer.Status == db.TransferStatusPendingReview {
+			manifest.Transfers.PendingReview = append(manifest.Transfers.PendingReview, result.Transfer.ID)
+			continue
+		}
+		manifest.Transfers.Completed = append(manifest.Transfers.Completed, result.Transfer.ID)
+		balances[from] = result.FromAccount.Balance
+		balances[to] = result.ToAccount.Balance
+	}
+
+	return manifest, nil
+}
+
+// rejection returns the reason of a transfer refused by the store
+func rejection(err error) (string, bool) {
+	for _, kind := range rejections {
+		if errors.Is(err, kind) {
+			return kind.Error(), true
+		}
+	}
+	return "", false
+}
+
+// randomCurrencies picks a non-empty subset of currencies, in the order of currencies
+func randomCurrencies(random *rand.Rand, currencies []string) []string {
+	picked := make([]string, 0, len(currencies))
+	for _, currency := range currencies {
+		if random.IntN(2) == 0 {
+			picked = append(picked, currency)
+		}
+	}
+	if len(picked) == 0 {
+		picked = append(picked, currencies[random.IntN(len(currencies))])
+	}
+	return picked
+}
+
+func randomLetters(random *rand.Rand, n int) string {
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		sb.WriteByte(ownerLetters[random.IntN(len(ownerLetters))])
+	}
+	return sb.String()
+}
@@ -0,0 +1,205 @@
+package seed
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/techschool/simple-bank/db2/mock"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/token"
+	"github.com/techschool/simple-bank/utils"
+	"go.uber.org/mock/gomock"
+)
+
+var testConfig = Config{
+	Seed:           42,
+	Users:          20,
+	Transfers:      100,
+	Currencies:     []string{"USD", "EUR"},
+	MaxBalance:     10000,
+	MaxTransferPct: 50,
+	History:        30 * 24 * time.Hour,
+	Until:          time.Date(2026, 1, 31, 12, 0, 0, 0, time.UTC), // fixed, so that two runs date the same data alike
+}
+
+// fakeBank keeps just enough state behind a mock store for the seed: account ids, balances and currencies,
+// and the dates the seed gave the rows
+type fakeBank struct {
+	accounts  map[int64]db.Account
+	transfers []db.TransferTxParams
+	dates     []time.Time // of the completed transfers, in the order they were dated
+	nextID    int64
+}
+
+// DateAccount and DateTransfer make the bank the Dater of the seed as well
+func (bank *fakeBank) DateAccount(ctx context.Context, accountID int64, at time.Time) error {
+	account := bank.accounts[accountID]
+	account.CreatedAt = at
+	bank.accounts[accountID] = account
+	return nil
+}
+
+func (bank *fakeBank) DateTransfer(ctx context.Context, transferID int64, at time.Time) error {
+	bank.dates = append(bank.dates, at)
+	return nil
+}
+
+// newFakeStore returns a mock store backed by bank, transfers the sender can't afford are refused
+func newFakeStore(t *testing.T, bank *fakeBank) db.Store {
+	store := mockdb.NewMockStore(gomock.NewController(t))
+
+	store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(
+		func(ctx context.Context, arg db.CreateAccountParams) (db.Account, error) {
+			bank.nextID++
+			account := db.Account{ID: bank.nextID, Owner: arg.Owner, Balance: arg.Balance, Currency: arg.Currency}
+			bank.accounts[account.ID] = account
+			return account, nil
+		})
+
+	store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(
+		func(ctx context.Context, arg db.TransferTxParams) (db.TransferTxResult, error) {
+			bank.transfers = append(bank.transfers, arg)
+			from, to := bank.accounts[arg.FromAccountID], bank.accounts[arg.ToAccountID]
+			require.NotEqual(t, from.ID, to.ID)
+			require.Equal(t, from.Currency, to.Currency)
+			if from.Balance < arg.Amount {
+				return db.TransferTxResult{}, db.ErrInsufficientFunds
+			}
+
+			from.Balance -= arg.Amount
+			to.Balance += arg.Amount
+			bank.accounts[from.ID], bank.accounts[to.ID] = from, to
+			return db.TransferTxResult{
+				Transfer:    db.Transfer{ID: int64(len(bank.transfers)), Status: db.TransferStatusCompleted},
+				FromAccount: from,
+				ToAccount:   to,
+			}, nil
+		})
+
+	return store
+}
+
+// runSeed runs the seed with config against a new fake bank
+func runSeed(t *testing.T, config Config) (Manifest, *fakeBank) {
+	bank := &fakeBank{accounts: map[int64]db.Account{}}
+	manifest, err := Run(context.Background(), newFakeStore(t, bank), bank, config)
+	require.NoError(t, err)
+	return manifest, bank
+}
+
+func TestRunIsReproducible(t *testing.T) {
+	manifest1, bank1 := runSeed(t, testConfig)
+	manifest2, bank2 := runSeed(t, testConfig)
+	require.Equal(t, manifest1, manifest2)
+	require.Equal(t, bank1.transfers, bank2.transfers)
+
+	other := testConfig
+	other.Seed = 43
+	manifest3, bank3 := runSeed(t, other)
+	require.NotEqual(t, manifest1.Users, manifest3.Users)
+	require.NotEqual(t, bank1.transfers, bank3.transfers)
+}
+
+func TestRunManifest(t *testing.T) {
+	manifest, bank := runSeed(t, testConfig)
+
+	require.Equal(t, testConfig.Seed, manifest.Seed)
+	require.Len(t, manifest.Users, testConfig.Users)
+
+	accounts := 0
+	for _, user := range manifest.Users {
+		require.Regexp(t, `^seed42_[a-z]{8}$`, user.Owner)
+		require.NotEmpty(t, user.Accounts)
+		for _, account := range user.Accounts {
+			created := bank.accounts[account.ID]
+			require.Equal(t, user.Owner, created.Owner)
+			require.Equal(t, created.Currency, account.Currency)
+			require.LessOrEqual(t, account.OpeningBalance, testConfig.MaxBalance)
+			accounts++
+		}
+	}
+	require.Len(t, bank.accounts, accounts)
+
+	// every attempted transfer is accounted for
+	rejected := 0
+	for _, count := range manifest.Transfers.Rejected {
+		rejected += count
+	}
+	require.Len(t, bank.transfers, len(manifest.Transfers.Completed)+rejected)
+	require.NotEmpty(t, manifest.Transfers.Completed)
+	require.Subset(t, []string{db.ErrInsufficientFunds.Error()}, slices.Collect(maps.Keys(manifest.Transfers.Rejected)))
+
+	// the money only moved around
+	var total int64
+	for _, user := range manifest.Users {
+		for _, account := range user.Accounts {
+			total += account.OpeningBalance
+		}
+	}
+	for _, account := range bank.accounts {
+		total -= account.Balance
+	}
+	require.Zero(t, total)
+}
+
+func TestRunHistory(t *testing.T) {
+	manifest, bank := runSeed(t, testConfig)
+	require.Equal(t, testConfig.Until, manifest.Until)
+	require.Equal(t, testConfig.Until.Add(-testConfig.History), manifest.From)
+
+	// the accounts are opened first, the transfers follow in order over the whole history
+	for _, account := range bank.accounts {
+		require.Equal(t, manifest.From, account.CreatedAt)
+	}
+	require.Len(t, bank.dates, len(manifest.Transfers.Completed))
+	require.True(t, slices.IsSortedFunc(bank.dates, time.Time.Compare))
+	require.False(t, bank.dates[0].Before(manifest.From))
+	require.True(t, bank.dates[len(bank.dates)-1].Before(manifest.Until))
+	require.Greater(t, bank.dates[len(bank.dates)-1].Sub(bank.dates[0]), testConfig.History*9/10)
+}
+
+func TestRunCredentials(t *testing.T) {
+	maker, err := token.NewMaker(utils.RandomString(token.MinKeySize))
+	require.NoError(t, err)
+	config := testConfig
+	config.TokenMaker = maker
+	config.TokenDuration = time.Hour
+
+	manifest, _ := runSeed(t, config)
+	for _, user := range manifest.Users {
+		payload, err := maker.VerifyToken(user.AccessToken)
+		require.NoError(t, err)
+		require.Equal(t, user.Owner, payload.Owner)
+	}
+
+	// the tokens aside, the manifest is the one of a run without credentials
+	withoutCredentials, _ := runSeed(t, testConfig)
+	for i := range manifest.Users {
+		require.Empty(t, withoutCredentials.Users[i].AccessToken)
+		manifest.Users[i].AccessToken = ""
+	}
+	require.Equal(t, withoutCredentials, manifest)
+}
+
+func TestRunInvalidConfig(t *testing.T) {
+	for name, mutate := range map[string]func(config *Config){
+		"NoUsers":         func(config *Config) { config.Users = 0 },
+		"NoCurrencies":    func(config *Config) { config.Currencies = nil },
+		"ZeroPercent":     func(config *Config) { config.MaxTransferPct = 0 },
+		"NegativeBalance": func(config *Config) { config.MaxBalance = -1 },
+		"NegativeHistory": func(config *Config) { config.History = -time.Hour },
+		"NoUntil":         func(config *Config) { config.Until = time.Time{} },
+		"NoTokenDuration": func(config *Config) { config.TokenMaker = &token.Maker{} },
+	} {
+		t.Run(name, func(t *testing.T) {
+			config := testConfig
+			mutate(&config)
+			_, err := Run(context.Background(), mockdb.NewMockStore(gomock.NewController(t)), nil, config)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// Package token creates and verifies the access tokens of the account owners.
+// A token is the base64 JSON of its payload and an HMAC-SHA256 of it, signed with the symmetric key of the server:
+// the servers verify it without a lookup, and only the holders of the key can issue one
+package token
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MinKeySize is the shortest symmetric key accepted, in bytes
+const MinKeySize = 32
+
+var (
+	ErrInvalidToken = errors.New("token is invalid")
+	ErrExpiredToken = errors.New("token has expired")
+)
+
+// Payload is what a token says about its holder
+type Payload struct {
+	Owner     string    `json:"owner"` // the owner of the accounts the holder may act on
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+// Maker creates and verifies tokens with one symmetric key
+type Maker struct {
+	key []byte
+}
+
+// NewMaker returns a maker signing with key, which must be at least MinKeySize bytes long
+func NewMaker(key string) (*Maker, error) {
+	if len(key) < MinKeySize {
+		return nil, fmt.Errorf("invalid key size: must be at least %d bytes", MinKeySize)
+	}
+	return &Maker{key: []byte(key)}, nil
+}
+
+// CreateToken returns a token for owner, valid for duration
+func (maker *Maker) CreateToken(owner string, duration time.Duration) (string, Payload, error) {
+	if owner == "" {
+		return "", Payload{}, errors.New("the token needs an owner")
+	}
+	now := time.Now()
+	payload := Payload{Owner: owner, IssuedAt: now, ExpiredAt: now.Add(duration)}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", Payload{}, err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(data)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(maker.sign(encoded)), payload, nil
+}
+
+// VerifyToken returns the payload of token, ErrInvalidToken if it wasn't signed with the key of the maker
+// and ErrExpiredToken once it has expired
+func (maker *Maker) VerifyToken(token string) (Payload, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return Payload{}, ErrInvalidToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, maker.sign(encoded)) {
+		return Payload{}, ErrInvalidToken
+	}
+
+	// the payload is only decoded once it is known to come from a holder of the key
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Payload{}, ErrInvalidToken
+	}
+	var payload Payload
+	if err := json.Unmarshal(data, &payload); err != nil || payload.Owner == "" {
+		return Payload{}, ErrInvalidToken
+	}
+	if time.Now().After(payload.ExpiredAt) {
+		return Payload{}, ErrExpiredToken
+	}
+	return payload, nil
+}
+
+func (maker *Maker) sign(encoded string) []byte {
+	mac := hmac.New(sha256.New, maker.key)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
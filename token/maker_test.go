@@ -0,0 +1,71 @@
+package token
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simple-bank/utils"
+)
+
+func newTestMaker(t *testing.T) *Maker {
+	maker, err := NewMaker(utils.RandomString(MinKeySize))
+	require.NoError(t, err)
+	return maker
+}
+
+func TestMaker(t *testing.T) {
+	maker := newTestMaker(t)
+	owner := utils.RandomOwner()
+
+	token, payload, err := maker.CreateToken(owner, time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, owner, payload.Owner)
+	require.WithinDuration(t, time.Now().Add(time.Minute), payload.ExpiredAt, time.Second)
+
+	verified, err := maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.Equal(t, owner, verified.Owner)
+	require.WithinDuration(t, payload.ExpiredAt, verified.ExpiredAt, 0)
+}
+
+func TestMakerRejects(t *testing.T) {
+	maker := newTestMaker(t)
+	token, _, err := maker.CreateToken(utils.RandomOwner(), time.Minute)
+	require.NoError(t, err)
+
+	expired, _, err := maker.CreateToken(utils.RandomOwner(), -time.Minute)
+	require.NoError(t, err)
+	otherKey, _, err := newTestMaker(t).CreateToken(utils.RandomOwner(), time.Minute)
+	require.NoError(t, err)
+
+	// another owner in the payload, with the signature of the original one
+	encoded, signature, _ := strings.Cut(token, ".")
+	forged, _, err := maker.CreateToken("someone_else", time.Minute)
+	require.NoError(t, err)
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+	require.NotEqual(t, encoded, forgedPayload)
+
+	for name, tc := range map[string]struct {
+		token string
+		err   error
+	}{
+		"Expired":   {token: expired, err: ErrExpiredToken},
+		"OtherKey":  {token: otherKey, err: ErrInvalidToken},
+		"Forged":    {token: forgedPayload + "." + signature, err: ErrInvalidToken},
+		"NoDot":     {token: encoded, err: ErrInvalidToken},
+		"Empty":     {token: "", err: ErrInvalidToken},
+		"NotBase64": {token: "!!!." + signature, err: ErrInvalidToken},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := maker.VerifyToken(tc.token)
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestNewMakerKeySize(t *testing.T) {
+	_, err := NewMaker(utils.RandomString(MinKeySize - 1))
+	require.Error(t, err)
+}
@@ -0,0 +1,65 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// ServiceName is reported as service.name on every span
+const ServiceName = "simple-bank"
+
+// exporters accepted by Setup
+const (
+	ExporterNone   = "none"   // spans are created for propagation, but dropped
+	ExporterStdout = "stdout" // spans are printed as JSON, handy during development
+	ExporterOTLP   = "otlp"   // spans are sent over OTLP/HTTP to an OpenTelemetry collector
+)
+
+// Setup installs the global tracer provider and the W3C trace context propagator
+// exporter is one of the Exporter* kinds, endpoint is the host:port of the collector used by ExporterOTLP
+// the returned function flushes the pending spans and must be called before the process exits
+func Setup(ctx context.Context, exporter string, endpoint string) (func(context.Context) error, error) {
+	// traceparent and baggage headers are read from incoming requests even when spans are not exported,
+	// so the trace of a caller is never broken by this service
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(ServiceName))),
+	}
+
+	switch exporter {
+	case ExporterNone, "":
+	case ExporterStdout:
+		spanExporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, sdktrace.WithBatcher(spanExporter))
+	case ExporterOTLP:
+		// plain HTTP: the collector is expected to run next to the service, e.g. as a sidecar
+		spanExporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpoint(endpoint), otlptracehttp.WithInsecure())
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, sdktrace.WithBatcher(spanExporter))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", exporter)
+	}
+
+	provider := sdktrace.NewTracerProvider(options...)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
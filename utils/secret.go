@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// secretFileSuffix is appended to a key to read its value from a file, e.g. DB_PASSWORD_FILE
+const secretFileSuffix = "_FILE"
+
+// redactedValue replaces secrets in String
+const redactedValue = "[REDACTED]"
+
+// secretKeyFragments are the fragments of the keys whose values String never shows
+var secretKeyFragments = []string{"PASSWORD", "TOKEN", "SECRET"}
+
+// isSecretKey reports whether the value of key is, or may contain, a secret: passwords, tokens, keys and the database
+// URLs, which may carry a password. Only these are read from KEY_FILE, and none of them is a flag, where it would
+// show in the shell history and the process list
+func isSecretKey(key string) bool {
+	if strings.HasSuffix(key, "_SOURCE") {
+		return true
+	}
+	for _, fragment := range secretKeyFragments {
+		if strings.Contains(key, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// readSecretFiles sets every secret key for which KEY_FILE is set, in the environment or a config file,
+// to the content of that file without its trailing newline
+// the file wins over the environment and the config files, see LoadConfig for the order of the sources
+func readSecretFiles(v *viper.Viper) error {
+	for _, key := range configKeys() {
+		if !isSecretKey(key) {
+			continue
+		}
+		fileKey := key + secretFileSuffix
+		if err := v.BindEnv(fileKey); err != nil {
+			return err
+		}
+
+		path := v.GetString(fileKey)
+		if path == "" {
+			continue
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("cannot read %s: %w", fileKey, err)
+		}
+		v.Set(key, strings.TrimRight(string(content), "\r\n"))
+	}
+	return nil
+}
+
+// buildDBSource assembles the postgres URL from the DB_* parts, escaping the user and password
+func (config Config) buildDBSource() string {
+	port := config.DBPort
+	if port == "" {
+		port = "5432"
+	}
+
+	source := url.URL{
+		Scheme: "postgresql",
+		User:   url.UserPassword(config.DBUser, config.DBPassword),
+		Host:   net.JoinHostPort(config.DBHost, port),
+		Path:   "/" + config.DBName,
+	}
+	if config.DBPassword == "" {
+		source.User = url.User(config.DBUser)
+	}
+	if config.DBSSLMode != "" {
+		source.RawQuery = url.Values{"sslmode": {config.DBSSLMode}}.Encode()
+	}
+	return source.String()
+}
+
+// String lists the settings as KEY=value pairs, passwords, tokens and secrets redacted, so the config can be logged
+func (config Config) String() string {
+	value := reflect.ValueOf(config)
+	configType := value.Type()
+
+	pairs := make([]string, 0, configType.NumField())
+	for i := 0; i < configType.NumField(); i++ {
+		key := configType.Field(i).Tag.Get("mapstructure")
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, redactSetting(key, fmt.Sprint(value.Field(i).Interface()))))
+	}
+	return strings.Join(pairs, " ")
+}
+
+// redactSetting hides the value of secret keys, and the password in the database URLs
+func redactSetting(key string, value string) string {
+	if value == "" {
+		return value
+	}
+
+	if strings.HasSuffix(key, "_SOURCE") {
+		source, err := url.Parse(value)
+		if err != nil || source.Scheme == "" {
+			return redactedValue // not a URL, e.g. a key=value DSN: can't tell where the password is
+		}
+		return source.Redacted()
+	}
+
+	if isSecretKey(key) {
+		return redactedValue
+	}
+	return value
+}
@@ -0,0 +1,91 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"syscall"
+)
+
+// ErrForbiddenURL is returned for a webhook URL the worker must not post to: anything but http and https,
+// or a host with an internal address. The worker sends whatever a webhook points at from inside the network
+// of the bank, the loopback, private and link-local ranges would let a webhook reach the services behind it,
+// and the metadata endpoint of the cloud provider
+var ErrForbiddenURL = errors.New("webhook url must be http or https and only resolve to public addresses")
+
+// internalPrefixes are the ranges with no public address that netip.Addr has no method for
+var internalPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT, some clouds serve their metadata from it
+}
+
+// Resolver looks up the addresses of a host, net.DefaultResolver is one
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// ValidateURL checks that rawURL may be the URL of a webhook, every address of its host must be public
+// unless allowPrivate is set, for development against a local receiver.
+// The host may resolve to another address by the time a delivery is sent, which the worker checks again
+func ValidateURL(ctx context.Context, resolver Resolver, rawURL string, allowPrivate bool) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrForbiddenURL, err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("%w: scheme %q", ErrForbiddenURL, parsed.Scheme)
+	}
+	host := parsed.Hostname()
+	if host == "" {
+		return fmt.Errorf("%w: no host", ErrForbiddenURL)
+	}
+	if allowPrivate {
+		return nil
+	}
+
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("%w: cannot resolve %s: %v", ErrForbiddenURL, host, err)
+	}
+	for _, addr := range addrs {
+		ip, ok := netip.AddrFromSlice(addr.IP)
+		if !ok || !isPublic(ip) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrForbiddenURL, host, addr.IP)
+		}
+	}
+	return nil
+}
+
+// isPublic reports whether addr may be reached by a delivery
+// link-local covers 169.254.169.254, the metadata endpoint, and private covers fd00:ec2::254, its IPv6 twin
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range internalPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// dialPublic is the Control of the dialer of the worker, it refuses to connect to an internal address.
+// It sees the address actually dialed, after the resolution and for every redirect,
+// so a host resolving to an internal address since its webhook was saved is caught too
+func dialPublic(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !isPublic(addr) {
+		return fmt.Errorf("%w: %s", ErrForbiddenURL, host)
+	}
+	return nil
+}
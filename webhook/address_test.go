@@ -0,0 +1,86 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// stubResolver resolves the hosts of its map and no other
+type stubResolver map[string][]string
+
+func (resolver stubResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	ips, ok := resolver[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	addrs := make([]net.IPAddr, len(ips))
+	for i, ip := range ips {
+		addrs[i] = net.IPAddr{IP: net.ParseIP(ip)}
+	}
+	return addrs, nil
+}
+
+func TestValidateURL(t *testing.T) {
+	resolver := stubResolver{
+		"partner.example.com":  {"203.0.113.10", "2001:db8::10"},
+		"rebound.example.com":  {"203.0.113.10", "10.0.0.5"},
+		"metadata.example.com": {"169.254.169.254"},
+		"127.0.0.1":            {"127.0.0.1"}, // net.DefaultResolver returns the IP literals as they are
+	}
+
+	for _, tc := range []struct {
+		url          string
+		allowPrivate bool
+		ok           bool
+	}{
+		{url: "https://partner.example.com/hooks", ok: true},
+		{url: "http://partner.example.com:8080/hooks", ok: true},
+		{url: "ftp://partner.example.com/hooks"},
+		{url: "file:///etc/passwd"},
+		{url: "https:///hooks"},
+		{url: "https://unknown.example.com/hooks"},
+		{url: "https://rebound.example.com/hooks"},
+		{url: "https://metadata.example.com/latest/meta-data"},
+		{url: "http://127.0.0.1:8080/hooks"},
+		{url: "http://127.0.0.1:8080/hooks", allowPrivate: true, ok: true},
+		{url: "ftp://127.0.0.1/hooks", allowPrivate: true},
+	} {
+		t.Run(tc.url, func(t *testing.T) {
+			err := ValidateURL(context.Background(), resolver, tc.url, tc.allowPrivate)
+			if tc.ok {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, ErrForbiddenURL)
+			}
+		})
+	}
+}
+
+func TestIsPublic(t *testing.T) {
+	for address, public := range map[string]bool{
+		"203.0.113.10":           true,
+		"8.8.8.8":                true,
+		"2001:4860:4860::8888":   true,
+		"127.0.0.1":              false,
+		"::1":                    false,
+		"10.1.2.3":               false,
+		"172.16.0.1":             false,
+		"192.168.1.1":            false,
+		"169.254.169.254":        false,
+		"fd00:ec2::254":          false,
+		"fe80::1":                false,
+		"0.0.0.0":                false,
+		"::":                     false,
+		"100.100.100.200":        false,
+		"224.0.0.1":              false,
+		"::ffff:127.0.0.1":       false,
+		"::ffff:169.254.169.254": false,
+	} {
+		require.Equal(t, public, isPublic(netip.MustParseAddr(address)), address)
+	}
+}
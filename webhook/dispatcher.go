@@ -0,0 +1,29 @@
+package webhook
+
+import (
+	"context"
+
+	db "github.com/techschool/simple-bank/db2/sqlc"
+)
+
+// Dispatcher is an events.EventPublisher that fans outbox events out to the subscribed webhooks
+// it only queues deliveries, the Worker sends them
+type Dispatcher struct {
+	store db.Store
+}
+
+// NewDispatcher creates a dispatcher queueing deliveries in store
+func NewDispatcher(store db.Store) *Dispatcher {
+	return &Dispatcher{store: store}
+}
+
+// Publish queues one delivery per active webhook subscribed to the event type
+// publishing the same event twice queues nothing new, which absorbs the relay's at-least-once redeliveries
+func (dispatcher *Dispatcher) Publish(ctx context.Context, event db.Outbox) error {
+	_, err := dispatcher.store.CreateWebhookDeliveries(ctx, db.CreateWebhookDeliveriesParams{
+		EventID:   event.ID,
+		EventType: event.EventType,
+		Payload:   event.Payload,
+	})
+	return err
+}
@@ -0,0 +1,36 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/techschool/simple-bank/db2/mock"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"go.uber.org/mock/gomock"
+)
+
+func TestDispatcherQueuesDeliveries(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	event := db.Outbox{
+		ID:        42,
+		EventType: db.EventTransferCompleted,
+		Payload:   json.RawMessage(`{"transfer":{"id":1}}`),
+	}
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		CreateWebhookDeliveries(gomock.Any(), gomock.Eq(db.CreateWebhookDeliveriesParams{
+			EventID:   event.ID,
+			EventType: event.EventType,
+			Payload:   event.Payload,
+		})).
+		Times(1).
+		Return(int64(2), nil)
+
+	err := NewDispatcher(store).Publish(context.Background(), event)
+	require.NoError(t, err)
+}
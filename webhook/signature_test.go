@@ -0,0 +1,39 @@
+package webhook
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	secret := "0123456789abcdef"
+	body := []byte(`{"id":1}`)
+	now := time.Now()
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	signature := Sign(secret, now.Unix(), body)
+	require.NoError(t, Verify(secret, timestamp, signature, body, time.Minute, now))
+
+	// tampered body
+	err := Verify(secret, timestamp, signature, []byte(`{"id":2}`), time.Minute, now)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+
+	// wrong secret
+	err = Verify("another-secret-value", timestamp, signature, body, time.Minute, now)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+
+	// replayed too late
+	err = Verify(secret, timestamp, signature, body, time.Minute, now.Add(2*time.Minute))
+	require.ErrorIs(t, err, ErrExpiredTimestamp)
+
+	// timestamp is part of the signature
+	later := strconv.FormatInt(now.Unix()+1, 10)
+	err = Verify(secret, later, signature, body, time.Minute, now)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+
+	err = Verify(secret, "not-a-number", signature, body, time.Minute, now)
+	require.Error(t, err)
+}
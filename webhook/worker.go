@@ -0,0 +1,182 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"github.com/techschool/simple-bank/health"
+)
+
+// maxErrorLength bounds how much of a failed response is kept in the delivery log
+const maxErrorLength = 512
+
+// Envelope is the JSON body of every delivery
+type Envelope struct {
+	ID   int64           `json:"id"`   // id of the event, identical across retries so receivers can deduplicate
+	Type string          `json:"type"` // one of the db.Event* types
+	Data json.RawMessage `json:"data"`
+}
+
+// WorkerConfig tunes the delivery worker
+type WorkerConfig struct {
+	PollInterval time.Duration // how long to wait once no delivery is due
+	BatchSize    int32
+	MaxAttempts  int32         // a delivery is dead-lettered after this many failed attempts
+	BaseBackoff  time.Duration // wait after the first failure, doubled after each further failure
+	MaxBackoff   time.Duration
+	Timeout      time.Duration // timeout of a single HTTP request
+	AllowPrivate bool          // lets deliveries reach internal addresses, for development against a local receiver
+}
+
+// Worker sends queued webhook deliveries and retries failures with exponential backoff
+type Worker struct {
+	store  db.Store
+	config WorkerConfig
+	client *http.Client
+	now    func() time.Time
+
+	heartbeat *health.Heartbeat // beats after every batch that was processed without error
+}
+
+// NewWorker creates a delivery worker for the deliveries queued in store
+// unless config.AllowPrivate is set, it refuses to connect to the internal addresses rejected by ValidateURL
+func NewWorker(store db.Store, config WorkerConfig) *Worker {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !config.AllowPrivate {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: dialPublic}
+		transport.DialContext = dialer.DialContext
+		transport.Proxy = nil // a proxy would dial the receiver in our place, out of reach of the check
+	}
+
+	return &Worker{
+		store:  store,
+		config: config,
+		client: &http.Client{Timeout: config.Timeout, Transport: transport},
+		now:    time.Now,
+
+		heartbeat: health.NewHeartbeat(config.PollInterval),
+	}
+}
+
+// Heartbeat returns the heartbeat of the worker, for the readiness checks
+func (worker *Worker) Heartbeat() *health.Heartbeat {
+	return worker.heartbeat
+}
+
+// Run sends deliveries until ctx is cancelled, it always returns ctx.Err()
+func (worker *Worker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(worker.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			n, err := worker.ProcessOnce(ctx)
+			if err != nil {
+				slog.ErrorContext(ctx, "webhook worker failed", "error", err)
+				break
+			}
+			worker.heartbeat.Beat()
+			if n < int(worker.config.BatchSize) {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessOnce attempts a single batch of due deliveries and returns how many were attempted
+func (worker *Worker) ProcessOnce(ctx context.Context) (int, error) {
+	return worker.store.ProcessWebhookDeliveriesTx(ctx, worker.config.BatchSize, worker.deliver)
+}
+
+// deliver makes one attempt at sending delivery to webhook
+func (worker *Worker) deliver(ctx context.Context, delivery db.WebhookDelivery, webhook db.Webhook) db.WebhookAttempt {
+	status, err := worker.send(ctx, delivery, webhook)
+	if err == nil {
+		return db.WebhookAttempt{
+			Status:         db.WebhookDeliverySucceeded,
+			ResponseStatus: status,
+			NextAttemptAt:  worker.now(),
+		}
+	}
+
+	attempt := db.WebhookAttempt{
+		Status:         db.WebhookDeliveryPending,
+		ResponseStatus: status,
+		LastError:      truncate(err.Error(), maxErrorLength),
+		NextAttemptAt:  worker.now().Add(worker.backoff(delivery.Attempts + 1)),
+	}
+	if delivery.Attempts+1 >= worker.config.MaxAttempts {
+		attempt.Status = db.WebhookDeliveryDead
+	}
+	return attempt
+}
+
+// send posts the signed delivery, any response outside of 2xx is an error
+func (worker *Worker) send(ctx context.Context, delivery db.WebhookDelivery, webhook db.Webhook) (int32, error) {
+	body, err := json.Marshal(Envelope{
+		ID:   delivery.EventID,
+		Type: delivery.EventType,
+		Data: delivery.Payload,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := worker.now().Unix()
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(HeaderEvent, delivery.EventType)
+	request.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	request.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, body))
+
+	response, err := worker.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(response.Body, maxErrorLength))
+		return int32(response.StatusCode), fmt.Errorf("unexpected status %d: %s", response.StatusCode, snippet)
+	}
+	return int32(response.StatusCode), nil
+}
+
+// backoff returns how long to wait before the next attempt, after the given number of failed attempts
+func (worker *Worker) backoff(failures int32) time.Duration {
+	wait := worker.config.BaseBackoff
+	for i := int32(1); i < failures; i++ {
+		wait *= 2
+		if wait >= worker.config.MaxBackoff {
+			return worker.config.MaxBackoff
+		}
+	}
+	return wait
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
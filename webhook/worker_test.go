@@ -0,0 +1,162 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/techschool/simple-bank/db2/mock"
+	db "github.com/techschool/simple-bank/db2/sqlc"
+	"go.uber.org/mock/gomock"
+)
+
+const testSecret = "0123456789abcdef"
+
+func testWorkerConfig() WorkerConfig {
+	return WorkerConfig{
+		PollInterval: time.Second,
+		BatchSize:    10,
+		MaxAttempts:  3,
+		BaseBackoff:  time.Second,
+		MaxBackoff:   3 * time.Second,
+		Timeout:      time.Second,
+		AllowPrivate: true, // the receivers of the tests listen on loopback
+	}
+}
+
+// runOnce makes the worker attempt a single delivery to url and returns the recorded attempt
+func runOnce(t *testing.T, url string, delivery db.WebhookDelivery) db.WebhookAttempt {
+	return runOnceWith(t, testWorkerConfig(), url, delivery)
+}
+
+// runOnceWith is runOnce with a worker created with config
+func runOnceWith(t *testing.T, config WorkerConfig, url string, delivery db.WebhookDelivery) db.WebhookAttempt {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	webhook := db.Webhook{ID: delivery.WebhookID, Url: url, Secret: testSecret, Active: true}
+
+	var attempt db.WebhookAttempt
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ProcessWebhookDeliveriesTx(gomock.Any(), gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, batchSize int32, deliver func(context.Context, db.WebhookDelivery, db.Webhook) db.WebhookAttempt) (int, error) {
+			attempt = deliver(ctx, delivery, webhook)
+			return 1, nil
+		})
+
+	worker := NewWorker(store, config)
+	n, err := worker.ProcessOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	return attempt
+}
+
+func testDelivery() db.WebhookDelivery {
+	return db.WebhookDelivery{
+		ID:        7,
+		WebhookID: 3,
+		EventID:   42,
+		EventType: db.EventTransferCompleted,
+		Payload:   json.RawMessage(`{"transfer":{"id":1}}`),
+		Status:    db.WebhookDeliveryPending,
+	}
+}
+
+func TestWorkerDeliversSignedRequest(t *testing.T) {
+	delivery := testDelivery()
+
+	var received Envelope
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		err = Verify(testSecret, r.Header.Get(HeaderTimestamp), r.Header.Get(HeaderSignature), body, time.Minute, time.Now())
+		require.NoError(t, err)
+		require.Equal(t, db.EventTransferCompleted, r.Header.Get(HeaderEvent))
+		require.Equal(t, "7", r.Header.Get(HeaderDelivery))
+
+		require.NoError(t, json.Unmarshal(body, &received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	attempt := runOnce(t, receiver.URL, delivery)
+	require.Equal(t, db.WebhookDeliverySucceeded, attempt.Status)
+	require.Equal(t, int32(http.StatusNoContent), attempt.ResponseStatus)
+	require.Empty(t, attempt.LastError)
+
+	require.Equal(t, delivery.EventID, received.ID)
+	require.Equal(t, delivery.EventType, received.Type)
+	require.JSONEq(t, string(delivery.Payload), string(received.Data))
+}
+
+func TestWorkerRetriesWithBackoff(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "try later", http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	delivery := testDelivery()
+	before := time.Now()
+	attempt := runOnce(t, receiver.URL, delivery)
+
+	require.Equal(t, db.WebhookDeliveryPending, attempt.Status)
+	require.Equal(t, int32(http.StatusServiceUnavailable), attempt.ResponseStatus)
+	require.Contains(t, attempt.LastError, "try later")
+	require.WithinDuration(t, before.Add(time.Second), attempt.NextAttemptAt, time.Second)
+}
+
+func TestWorkerDeadLettersAfterMaxAttempts(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	delivery := testDelivery()
+	delivery.Attempts = 2 // this is the third and last attempt
+
+	attempt := runOnce(t, receiver.URL, delivery)
+	require.Equal(t, db.WebhookDeliveryDead, attempt.Status)
+}
+
+func TestWorkerUnreachableReceiver(t *testing.T) {
+	receiver := httptest.NewServer(http.NotFoundHandler())
+	url := receiver.URL
+	receiver.Close() // nothing listens anymore
+
+	attempt := runOnce(t, url, testDelivery())
+	require.Equal(t, db.WebhookDeliveryPending, attempt.Status)
+	require.Zero(t, attempt.ResponseStatus)
+	require.NotEmpty(t, attempt.LastError)
+}
+
+// the worker checks the address it dials: a webhook whose host resolves to an internal address is never reached
+func TestWorkerRefusesInternalAddress(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the delivery reached a loopback receiver")
+	}))
+	defer receiver.Close()
+
+	config := testWorkerConfig()
+	config.AllowPrivate = false
+	attempt := runOnceWith(t, config, receiver.URL, testDelivery())
+	require.Equal(t, db.WebhookDeliveryPending, attempt.Status)
+	require.Zero(t, attempt.ResponseStatus)
+	require.Contains(t, attempt.LastError, ErrForbiddenURL.Error())
+}
+
+func TestBackoff(t *testing.T) {
+	worker := NewWorker(nil, testWorkerConfig())
+
+	require.Equal(t, time.Second, worker.backoff(1))
+	require.Equal(t, 2*time.Second, worker.backoff(2))
+	require.Equal(t, 3*time.Second, worker.backoff(3)) // capped by MaxBackoff
+	require.Equal(t, 3*time.Second, worker.backoff(30))
+}